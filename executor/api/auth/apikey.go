@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKeyAuthenticator accepts the keys listed in a file, usually mounted from a secret. The file is read again when
+// it changes so keys can be rotated without a restart.
+type APIKeyAuthenticator struct {
+	filename string
+
+	mu      sync.Mutex
+	modTime time.Time
+	// Digests of the keys, so keys of any length are compared in constant time
+	keys [][sha256.Size]byte
+}
+
+func NewAPIKeyAuthenticator(filename string) (*APIKeyAuthenticator, error) {
+	a := &APIKeyAuthenticator{filename: filename}
+	if _, err := a.currentKeys(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *APIKeyAuthenticator) Authenticate(token string) error {
+	keys, err := a.currentKeys()
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(token))
+	found := 0
+	for _, key := range keys {
+		found |= subtle.ConstantTimeCompare(digest[:], key[:])
+	}
+	if found != 1 {
+		return ErrUnauthenticated
+	}
+	return nil
+}
+
+// currentKeys returns the keys, reading them again when the file changes. Keys are revoked when the file is removed,
+// can't be read or no longer lists any key, so requests are rejected until valid keys are back.
+func (a *APIKeyAuthenticator) currentKeys() ([][sha256.Size]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	info, err := os.Stat(a.filename)
+	if err == nil && a.keys != nil && info.ModTime().Equal(a.modTime) {
+		return a.keys, nil
+	}
+	var keys [][sha256.Size]byte
+	if err == nil {
+		keys, err = readAPIKeys(a.filename)
+	}
+	if err != nil {
+		a.keys, a.modTime = nil, time.Time{}
+		return nil, err
+	}
+	a.keys, a.modTime = keys, info.ModTime()
+	return keys, nil
+}
+
+func readAPIKeys(filename string) ([][sha256.Size]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var keys [][sha256.Size]byte
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			keys = append(keys, sha256.Sum256([]byte(line)))
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no API keys found in %s", filename)
+	}
+	return keys, nil
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/seldonio/seldon-core/executor/k8s"
+)
+
+const (
+	AuthorizationHeader = "Authorization"
+	// Header for static API keys, as an alternative to a bearer token
+	APIKeyHeader = "X-Api-Key"
+
+	bearerPrefix = "Bearer "
+)
+
+// ErrUnauthenticated is returned for calls without valid credentials.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// ServerAuthenticator authenticates calls to the executor's endpoints. Calls are not authenticated if it is nil.
+var ServerAuthenticator Authenticator
+
+// ExemptPaths are REST paths served without authentication, in addition to the probes and metrics.
+var ExemptPaths []string
+
+// ForwardCredentials keeps the credentials of authenticated calls in the headers sent on to the graph's components.
+// They are removed otherwise.
+var ForwardCredentials bool
+
+// Authenticator checks the credentials of a call, the bearer token or API key sent with it.
+type Authenticator interface {
+	Authenticate(token string) error
+}
+
+type AuthSettings struct {
+	// File with one API key per line
+	APIKeysFile string
+	// File or URL of the JSON Web Key Set to validate JWTs with
+	JWKS string
+	// Required issuer and audience of JWTs, not checked if empty
+	Issuer   string
+	Audience string
+	// REST paths served without authentication
+	ExemptPaths []string
+	// Send the credentials of calls on to the graph's components
+	ForwardCredentials bool
+}
+
+// AuthSettingsFromAnnotations returns nil settings if authentication is not configured.
+func AuthSettingsFromAnnotations(annotations map[string]string) (*AuthSettings, error) {
+	settings := &AuthSettings{
+		APIKeysFile: annotations[k8s.ANNOTATION_AUTH_API_KEYS_FILE],
+		JWKS:        annotations[k8s.ANNOTATION_AUTH_JWKS],
+		Issuer:      annotations[k8s.ANNOTATION_AUTH_JWT_ISSUER],
+		Audience:    annotations[k8s.ANNOTATION_AUTH_JWT_AUDIENCE],
+	}
+	if forward := annotations[k8s.ANNOTATION_AUTH_FORWARD_CREDENTIALS]; forward != "" {
+		var err error
+		if settings.ForwardCredentials, err = strconv.ParseBool(forward); err != nil {
+			return nil, fmt.Errorf("annotation %s must be a boolean: %w", k8s.ANNOTATION_AUTH_FORWARD_CREDENTIALS, err)
+		}
+	}
+	for _, path := range strings.Split(annotations[k8s.ANNOTATION_AUTH_EXEMPT_PATHS], ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			settings.ExemptPaths = append(settings.ExemptPaths, path)
+		}
+	}
+	if settings.APIKeysFile == "" && settings.JWKS == "" {
+		if settings.Issuer != "" || settings.Audience != "" {
+			return nil, fmt.Errorf("annotation %s is needed to validate JWTs", k8s.ANNOTATION_AUTH_JWKS)
+		}
+		return nil, nil
+	}
+	return settings, nil
+}
+
+// NewAuthenticator accepts calls with either a configured API key or a valid JWT.
+func NewAuthenticator(settings AuthSettings) (Authenticator, error) {
+	var authenticators anyAuthenticator
+	if settings.APIKeysFile != "" {
+		a, err := NewAPIKeyAuthenticator(settings.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+	if settings.JWKS != "" {
+		a, err := NewJWTAuthenticator(settings.JWKS, settings.Issuer, settings.Audience)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+	return authenticators, nil
+}
+
+type anyAuthenticator []Authenticator
+
+func (a anyAuthenticator) Authenticate(token string) error {
+	if token == "" {
+		return ErrUnauthenticated
+	}
+	for _, authenticator := range a {
+		if authenticator.Authenticate(token) == nil {
+			return nil
+		}
+	}
+	return ErrUnauthenticated
+}
+
+// Token returns the credentials of a call from the values of its Authorization and X-Api-Key headers.
+func Token(authorization string, apiKey string) string {
+	if len(authorization) > len(bearerPrefix) && strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+		return strings.TrimSpace(authorization[len(bearerPrefix):])
+	}
+	return apiKey
+}
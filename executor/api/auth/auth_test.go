@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/k8s"
+)
+
+func createTestJWKS(g *GomegaWithT, kid string, key *rsa.PrivateKey) []byte {
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": kid,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, err := json.Marshal(jwks)
+	g.Expect(err).To(BeNil())
+	return data
+}
+
+func createTestJWT(g *GomegaWithT, kid string, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	g.Expect(err).To(BeNil())
+	return signed
+}
+
+func TestAuthSettingsFromAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	settings, err := AuthSettingsFromAnnotations(map[string]string{})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings).To(BeNil())
+
+	settings, err = AuthSettingsFromAnnotations(map[string]string{
+		k8s.ANNOTATION_AUTH_JWKS:         "https://issuer/.well-known/jwks.json",
+		k8s.ANNOTATION_AUTH_JWT_AUDIENCE: "seldon",
+		k8s.ANNOTATION_AUTH_EXEMPT_PATHS: "/api/v1.0/doc/, /v2/docs/",
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings.Audience).To(Equal("seldon"))
+	g.Expect(settings.ExemptPaths).To(Equal([]string{"/api/v1.0/doc/", "/v2/docs/"}))
+	g.Expect(settings.ForwardCredentials).To(BeFalse())
+
+	settings, err = AuthSettingsFromAnnotations(map[string]string{
+		k8s.ANNOTATION_AUTH_API_KEYS_FILE:       "/keys",
+		k8s.ANNOTATION_AUTH_FORWARD_CREDENTIALS: "true",
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings.ForwardCredentials).To(BeTrue())
+
+	_, err = AuthSettingsFromAnnotations(map[string]string{
+		k8s.ANNOTATION_AUTH_API_KEYS_FILE:       "/keys",
+		k8s.ANNOTATION_AUTH_FORWARD_CREDENTIALS: "yes please",
+	})
+	g.Expect(err).ToNot(BeNil())
+
+	_, err = AuthSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_AUTH_JWT_ISSUER: "https://issuer"})
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestToken(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(Token("Bearer abc", "")).To(Equal("abc"))
+	g.Expect(Token("bearer abc", "key")).To(Equal("abc"))
+	g.Expect(Token("Basic abc", "key")).To(Equal("key"))
+	g.Expect(Token("", "")).To(Equal(""))
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "auth")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "keys")
+	g.Expect(ioutil.WriteFile(filename, []byte("key1\nkey2\n"), 0600)).To(BeNil())
+	modTime := time.Now().Add(-time.Minute)
+	g.Expect(os.Chtimes(filename, modTime, modTime)).To(BeNil())
+
+	authenticator, err := NewAuthenticator(AuthSettings{APIKeysFile: filename})
+	g.Expect(err).To(BeNil())
+	g.Expect(authenticator.Authenticate("key2")).To(BeNil())
+	g.Expect(authenticator.Authenticate("key3")).To(Equal(ErrUnauthenticated))
+	g.Expect(authenticator.Authenticate("")).To(Equal(ErrUnauthenticated))
+
+	// Rotated keys are read again
+	g.Expect(ioutil.WriteFile(filename, []byte("key3"), 0600)).To(BeNil())
+	g.Expect(authenticator.Authenticate("key3")).To(BeNil())
+	g.Expect(authenticator.Authenticate("key1")).ToNot(BeNil())
+
+	// Emptying or removing the file revokes the keys
+	g.Expect(ioutil.WriteFile(filename, []byte("\n"), 0600)).To(BeNil())
+	g.Expect(authenticator.Authenticate("key3")).ToNot(BeNil())
+	g.Expect(ioutil.WriteFile(filename, []byte("key4"), 0600)).To(BeNil())
+	g.Expect(authenticator.Authenticate("key4")).To(BeNil())
+	g.Expect(os.Remove(filename)).To(BeNil())
+	g.Expect(authenticator.Authenticate("key4")).ToNot(BeNil())
+
+	_, err = NewAPIKeyAuthenticator(filepath.Join(dir, "missing"))
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).To(BeNil())
+	dir, err := ioutil.TempDir("", "auth")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "jwks.json")
+	g.Expect(ioutil.WriteFile(filename, createTestJWKS(g, "k1", key), 0600)).To(BeNil())
+
+	authenticator, err := NewJWTAuthenticator(filename, "https://issuer", "seldon")
+	g.Expect(err).To(BeNil())
+
+	valid := jwt.MapClaims{"iss": "https://issuer", "aud": []string{"other", "seldon"}, "exp": time.Now().Add(time.Hour).Unix()}
+	g.Expect(authenticator.Authenticate(createTestJWT(g, "k1", key, valid))).To(BeNil())
+
+	wrongAudience := jwt.MapClaims{"iss": "https://issuer", "aud": "other", "exp": time.Now().Add(time.Hour).Unix()}
+	g.Expect(authenticator.Authenticate(createTestJWT(g, "k1", key, wrongAudience))).ToNot(BeNil())
+
+	wrongIssuer := jwt.MapClaims{"iss": "https://other", "aud": "seldon"}
+	g.Expect(authenticator.Authenticate(createTestJWT(g, "k1", key, wrongIssuer))).ToNot(BeNil())
+
+	expired := jwt.MapClaims{"iss": "https://issuer", "aud": "seldon", "exp": time.Now().Add(-time.Hour).Unix()}
+	g.Expect(authenticator.Authenticate(createTestJWT(g, "k1", key, expired))).ToNot(BeNil())
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).To(BeNil())
+	g.Expect(authenticator.Authenticate(createTestJWT(g, "k1", otherKey, valid))).ToNot(BeNil())
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, valid)
+	token, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	g.Expect(err).To(BeNil())
+	g.Expect(authenticator.Authenticate(token)).ToNot(BeNil())
+}
+
+func TestJWTAuthenticatorURL(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).To(BeNil())
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).To(BeNil())
+	var jwks atomic.Value
+	jwks.Store(createTestJWKS(g, "k1", key1))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jwks.Load().([]byte))
+	}))
+	defer server.Close()
+
+	interval := JWKSMinRefreshInterval
+	JWKSMinRefreshInterval = 0
+	defer func() { JWKSMinRefreshInterval = interval }()
+
+	authenticator, err := NewJWTAuthenticator(server.URL, "", "")
+	g.Expect(err).To(BeNil())
+	g.Expect(authenticator.Authenticate(createTestJWT(g, "k1", key1, jwt.MapClaims{}))).To(BeNil())
+
+	// Keys are fetched again for tokens signed with an unknown key
+	g.Expect(authenticator.Authenticate(createTestJWT(g, "k2", key2, jwt.MapClaims{}))).ToNot(BeNil())
+	jwks.Store(createTestJWKS(g, "k2", key2))
+	g.Expect(authenticator.Authenticate(createTestJWT(g, "k2", key2, jwt.MapClaims{}))).To(BeNil())
+}
+
+func TestJWTAuthenticatorRefreshDoesNotBlock(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).To(BeNil())
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).To(BeNil())
+	fetching := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetches, 1) > 1 {
+			fetching <- struct{}{}
+			<-release
+		}
+		w.Write(createTestJWKS(g, "k1", key1))
+	}))
+	defer server.Close()
+
+	interval := JWKSMinRefreshInterval
+	JWKSMinRefreshInterval = 0
+	defer func() { JWKSMinRefreshInterval = interval }()
+
+	authenticator, err := NewJWTAuthenticator(server.URL, "", "")
+	g.Expect(err).To(BeNil())
+
+	refreshed := make(chan error)
+	go func() {
+		refreshed <- authenticator.Authenticate(createTestJWT(g, "k2", key2, jwt.MapClaims{}))
+	}()
+	<-fetching
+
+	// Tokens with a known key are verified while the keys are being fetched
+	g.Expect(authenticator.Authenticate(createTestJWT(g, "k1", key1, jwt.MapClaims{}))).To(BeNil())
+	close(release)
+	g.Expect(<-refreshed).ToNot(BeNil())
+}
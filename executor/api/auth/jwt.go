@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	// How often keys fetched from a URL are refreshed
+	JWKSRefreshInterval = 5 * time.Minute
+	// Least time between fetches of the keys for tokens signed with an unknown key
+	JWKSMinRefreshInterval = 30 * time.Second
+)
+
+var jwtSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// JWTAuthenticator accepts JWTs signed with a key of a JSON Web Key Set, read from a file or URL. A file is read
+// again when it changes, and keys from a URL are refreshed periodically and when a token has an unknown key id.
+type JWTAuthenticator struct {
+	jwks     string
+	issuer   string
+	audience string
+	parser   *jwt.Parser
+	client   *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	loadedAt  time.Time
+	fileMtime time.Time
+	// Closed when the running refresh is done, nil if there is none
+	refreshing chan struct{}
+}
+
+func NewJWTAuthenticator(jwks string, issuer string, audience string) (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{
+		jwks:     jwks,
+		issuer:   issuer,
+		audience: audience,
+		parser:   &jwt.Parser{ValidMethods: jwtSigningMethods},
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *JWTAuthenticator) Authenticate(token string) error {
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(token, claims, a.key); err != nil {
+		return err
+	}
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return fmt.Errorf("token issuer is not %s", a.issuer)
+	}
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		return fmt.Errorf("token audience is not %s", a.audience)
+	}
+	return nil
+}
+
+// key returns the key to verify the token with, by the key id of the token.
+func (a *JWTAuthenticator) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	a.mu.Lock()
+	key, ok := a.lookup(kid)
+	stale := a.stale()
+	a.mu.Unlock()
+	if !ok || stale {
+		if err := a.refresh(!ok); err == nil {
+			a.mu.Lock()
+			key, ok = a.lookup(kid)
+			a.mu.Unlock()
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// lookup finds a key by id, a token without a key id can only be verified with a set of one key.
+func (a *JWTAuthenticator) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, true
+		}
+	}
+	key, ok := a.keys[kid]
+	return key, ok
+}
+
+func (a *JWTAuthenticator) isURL() bool {
+	return strings.HasPrefix(a.jwks, "http://") || strings.HasPrefix(a.jwks, "https://")
+}
+
+func (a *JWTAuthenticator) stale() bool {
+	if a.isURL() {
+		return time.Since(a.loadedAt) > JWKSRefreshInterval
+	}
+	info, err := os.Stat(a.jwks)
+	return err == nil && !info.ModTime().Equal(a.fileMtime)
+}
+
+// refresh loads the keys again, keeping the current keys if they can't be loaded. The keys are read without holding
+// the lock and only one refresh runs at a time. Tokens with a known key are verified with the current keys in the
+// meantime while tokens with an unknown key wait for the refresh.
+func (a *JWTAuthenticator) refresh(unknownKey bool) error {
+	a.mu.Lock()
+	if done := a.refreshing; done != nil {
+		a.mu.Unlock()
+		if unknownKey {
+			<-done
+		}
+		return nil
+	}
+	if (unknownKey && a.isURL() && time.Since(a.loadedAt) < JWKSMinRefreshInterval) || (!unknownKey && !a.stale()) {
+		a.mu.Unlock()
+		return nil
+	}
+	done := make(chan struct{})
+	a.refreshing = done
+	a.mu.Unlock()
+
+	keys, mtime, err := a.read()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.refreshing = nil
+	close(done)
+	a.loadedAt = time.Now()
+	if err != nil {
+		return err
+	}
+	a.keys, a.fileMtime = keys, mtime
+	return nil
+}
+
+func (a *JWTAuthenticator) load() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	keys, mtime, err := a.read()
+	if err != nil {
+		return err
+	}
+	a.keys, a.fileMtime, a.loadedAt = keys, mtime, time.Now()
+	return nil
+}
+
+func (a *JWTAuthenticator) read() (map[string]interface{}, time.Time, error) {
+	var data []byte
+	var mtime time.Time
+	if a.isURL() {
+		res, err := a.client.Get(a.jwks)
+		if err != nil {
+			return nil, mtime, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, mtime, fmt.Errorf("bad status %d fetching keys from %s", res.StatusCode, a.jwks)
+		}
+		if data, err = ioutil.ReadAll(res.Body); err != nil {
+			return nil, mtime, err
+		}
+	} else {
+		info, err := os.Stat(a.jwks)
+		if err != nil {
+			return nil, mtime, err
+		}
+		mtime = info.ModTime()
+		if data, err = ioutil.ReadFile(a.jwks); err != nil {
+			return nil, mtime, err
+		}
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, mtime, fmt.Errorf("failed to read keys from %s: %w", a.jwks, err)
+	}
+	return keys, mtime, nil
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	// RSA public key
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC public key
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the RSA and EC signing keys of a key set by key id.
+func parseJWKS(data []byte) (map[string]interface{}, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{})
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		var key interface{}
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaKey()
+		case "EC":
+			key, err = jwk.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no signing keys found")
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (jwk jsonWebKey) ecKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+	x, err := decodeBigInt(jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("point is not on curve %s", jwk.Crv)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("missing key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,308 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/k8s"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultCircuitBreakerMinRequests = 20
+	DefaultCircuitBreakerWindow      = 10 * time.Second
+	DefaultCircuitBreakerOpenTimeout = 30 * time.Second
+)
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitHalfOpen
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// CircuitOpenError is returned for calls to an endpoint whose circuit breaker is open.
+type CircuitOpenError struct {
+	Endpoint string
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for %s", e.Endpoint)
+}
+
+// GRPCStatus allows the error to be returned from gRPC servers and interceptors as UNAVAILABLE.
+func (e *CircuitOpenError) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
+
+type CircuitBreakerSettings struct {
+	// Trip after this many failures in a row. Disabled if 0.
+	ConsecutiveFailures int
+	// Trip when at least this percentage of calls in a window fail. Disabled if 0.
+	ErrorRatePercent int
+	// Minimum calls in a window before the error rate is considered
+	MinRequests int
+	Window      time.Duration
+	// Time spent open before a single probe call is allowed through
+	OpenTimeout time.Duration
+}
+
+func getIntFromAnnotations(annotations map[string]string, key string) (int, error) {
+	val := annotations[key]
+	if val == "" {
+		return 0, nil
+	}
+	converted, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse annotation %s: %w", key, err)
+	}
+	if converted < 0 {
+		return 0, fmt.Errorf("annotation %s can not be negative", key)
+	}
+	return converted, nil
+}
+
+// CircuitBreakerSettingsFromAnnotations returns nil settings if no circuit breaker is configured.
+func CircuitBreakerSettingsFromAnnotations(annotations map[string]string) (*CircuitBreakerSettings, error) {
+	settings := &CircuitBreakerSettings{}
+	var err error
+	if settings.ConsecutiveFailures, err = getIntFromAnnotations(annotations, k8s.ANNOTATION_CIRCUIT_BREAKER_CONSECUTIVE_FAILURES); err != nil {
+		return nil, err
+	}
+	if settings.ErrorRatePercent, err = getIntFromAnnotations(annotations, k8s.ANNOTATION_CIRCUIT_BREAKER_ERROR_RATE); err != nil {
+		return nil, err
+	}
+	if settings.ErrorRatePercent > 100 {
+		return nil, fmt.Errorf("annotation %s must be a percentage", k8s.ANNOTATION_CIRCUIT_BREAKER_ERROR_RATE)
+	}
+	if settings.ConsecutiveFailures == 0 && settings.ErrorRatePercent == 0 {
+		return nil, nil
+	}
+	if settings.MinRequests, err = getIntFromAnnotations(annotations, k8s.ANNOTATION_CIRCUIT_BREAKER_MIN_REQUESTS); err != nil {
+		return nil, err
+	}
+	if settings.MinRequests == 0 {
+		settings.MinRequests = DefaultCircuitBreakerMinRequests
+	}
+	windowMs, err := getIntFromAnnotations(annotations, k8s.ANNOTATION_CIRCUIT_BREAKER_WINDOW)
+	if err != nil {
+		return nil, err
+	}
+	settings.Window = time.Duration(windowMs) * time.Millisecond
+	if settings.Window == 0 {
+		settings.Window = DefaultCircuitBreakerWindow
+	}
+	openMs, err := getIntFromAnnotations(annotations, k8s.ANNOTATION_CIRCUIT_BREAKER_OPEN_TIMEOUT)
+	if err != nil {
+		return nil, err
+	}
+	settings.OpenTimeout = time.Duration(openMs) * time.Millisecond
+	if settings.OpenTimeout == 0 {
+		settings.OpenTimeout = DefaultCircuitBreakerOpenTimeout
+	}
+	return settings, nil
+}
+
+// CircuitBreaker tracks failures of calls to a single endpoint. A nil breaker allows every call.
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	endpoint string
+	settings *CircuitBreakerSettings
+	metrics  *metric.CircuitBreakerMetrics
+	now      func() time.Time
+	state    CircuitState
+	// Incremented on every change of state so the outcome of a call only counts towards the state it started in
+	generation     uint64
+	openedAt       time.Time
+	probing        bool
+	consecutive    int
+	windowStart    time.Time
+	windowRequests int
+	windowFailures int
+}
+
+// CircuitCall is a call allowed by a breaker. Its outcome is ignored if the state of the circuit changed since it
+// started, e.g. a call made while the circuit was closed which ends after it opened doesn't decide the probe.
+type CircuitCall struct {
+	cb         *CircuitBreaker
+	generation uint64
+}
+
+// Allow returns a CircuitOpenError if the call should not be made. Every allowed call must be followed by Done, or
+// Cancel if the call was abandoned.
+func (cb *CircuitBreaker) Allow() (CircuitCall, error) {
+	if cb == nil {
+		return CircuitCall{}, nil
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitOpen && cb.now().Sub(cb.openedAt) >= cb.settings.OpenTimeout {
+		cb.setState(CircuitHalfOpen)
+	}
+	switch cb.state {
+	case CircuitOpen:
+		cb.metrics.RejectedCounter.WithLabelValues(cb.endpoint).Inc()
+		return CircuitCall{}, &CircuitOpenError{Endpoint: cb.endpoint}
+	case CircuitHalfOpen:
+		// Only a single probe is let through until its outcome is known
+		if cb.probing {
+			cb.metrics.RejectedCounter.WithLabelValues(cb.endpoint).Inc()
+			return CircuitCall{}, &CircuitOpenError{Endpoint: cb.endpoint}
+		}
+		cb.probing = true
+	}
+	return CircuitCall{cb: cb, generation: cb.generation}, nil
+}
+
+// Done records the outcome of the call.
+func (c CircuitCall) Done(failed bool) {
+	cb := c.cb
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if c.generation != cb.generation {
+		return
+	}
+	now := cb.now()
+	switch cb.state {
+	case CircuitHalfOpen:
+		cb.probing = false
+		if failed {
+			cb.trip(now)
+		} else {
+			cb.reset(now)
+			cb.setState(CircuitClosed)
+		}
+	case CircuitClosed:
+		if now.Sub(cb.windowStart) >= cb.settings.Window {
+			cb.windowStart, cb.windowRequests, cb.windowFailures = now, 0, 0
+		}
+		cb.windowRequests++
+		if failed {
+			cb.consecutive++
+			cb.windowFailures++
+		} else {
+			cb.consecutive = 0
+		}
+		if (cb.settings.ConsecutiveFailures > 0 && cb.consecutive >= cb.settings.ConsecutiveFailures) ||
+			(cb.settings.ErrorRatePercent > 0 && cb.windowRequests >= cb.settings.MinRequests &&
+				cb.windowFailures*100 >= cb.settings.ErrorRatePercent*cb.windowRequests) {
+			cb.trip(now)
+		}
+	}
+}
+
+// Cancel releases the call without recording an outcome, for calls abandoned by the caller which say nothing about
+// the health of the endpoint. A half-open circuit lets another probe through.
+func (c CircuitCall) Cancel() {
+	cb := c.cb
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if c.generation == cb.generation && cb.state == CircuitHalfOpen {
+		cb.probing = false
+	}
+}
+
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+func (cb *CircuitBreaker) trip(now time.Time) {
+	cb.openedAt = now
+	cb.setState(CircuitOpen)
+}
+
+func (cb *CircuitBreaker) reset(now time.Time) {
+	cb.consecutive = 0
+	cb.windowStart, cb.windowRequests, cb.windowFailures = now, 0, 0
+}
+
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	if state != cb.state {
+		cb.generation++
+	}
+	cb.state = state
+	cb.metrics.StateGauge.WithLabelValues(cb.endpoint).Set(float64(state))
+}
+
+// CircuitBreakerRegistry holds a breaker per endpoint (host:port) shared by all clients.
+type CircuitBreakerRegistry struct {
+	mu       sync.Mutex
+	settings *CircuitBreakerSettings
+	metrics  *metric.CircuitBreakerMetrics
+	breakers map[string]*CircuitBreaker
+}
+
+// CircuitBreakers is the registry used by the executor clients. It has no breakers until configured at startup.
+var CircuitBreakers = NewCircuitBreakerRegistry(nil)
+
+func NewCircuitBreakerRegistry(settings *CircuitBreakerSettings) *CircuitBreakerRegistry {
+	registry := &CircuitBreakerRegistry{
+		settings: settings,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+	if settings != nil {
+		registry.metrics = metric.NewCircuitBreakerMetrics()
+	}
+	return registry
+}
+
+// Get returns the breaker for an endpoint or nil if circuit breaking is disabled.
+func (r *CircuitBreakerRegistry) Get(endpoint string) *CircuitBreaker {
+	if r.settings == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cb, ok := r.breakers[endpoint]
+	if !ok {
+		cb = &CircuitBreaker{
+			endpoint: endpoint,
+			settings: r.settings,
+			metrics:  r.metrics,
+			now:      time.Now,
+		}
+		cb.setState(CircuitClosed)
+		r.breakers[endpoint] = cb
+	}
+	return cb
+}
+
+// States returns the state of each endpoint called so far.
+func (r *CircuitBreakerRegistry) States() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	states := make(map[string]string, len(r.breakers))
+	for endpoint, cb := range r.breakers {
+		states[endpoint] = cb.State().String()
+	}
+	return states
+}
+
+func (r *CircuitBreakerRegistry) Enabled() bool {
+	return r.settings != nil
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/k8s"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCircuitBreakerSettingsFromAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	settings, err := CircuitBreakerSettingsFromAnnotations(map[string]string{})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings).To(BeNil())
+
+	settings, err = CircuitBreakerSettingsFromAnnotations(map[string]string{
+		k8s.ANNOTATION_CIRCUIT_BREAKER_CONSECUTIVE_FAILURES: "5",
+		k8s.ANNOTATION_CIRCUIT_BREAKER_OPEN_TIMEOUT:         "2000",
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings.ConsecutiveFailures).To(Equal(5))
+	g.Expect(settings.MinRequests).To(Equal(DefaultCircuitBreakerMinRequests))
+	g.Expect(settings.Window).To(Equal(DefaultCircuitBreakerWindow))
+	g.Expect(settings.OpenTimeout).To(Equal(2 * time.Second))
+
+	_, err = CircuitBreakerSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_CIRCUIT_BREAKER_ERROR_RATE: "150"})
+	g.Expect(err).ToNot(BeNil())
+
+	_, err = CircuitBreakerSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_CIRCUIT_BREAKER_CONSECUTIVE_FAILURES: "abc"})
+	g.Expect(err).ToNot(BeNil())
+}
+
+func newTestBreaker(settings *CircuitBreakerSettings, now *time.Time) *CircuitBreaker {
+	cb := NewCircuitBreakerRegistry(settings).Get("model:9000")
+	cb.now = func() time.Time { return *now }
+	return cb
+}
+
+func mustAllow(g *GomegaWithT, cb *CircuitBreaker) CircuitCall {
+	call, err := cb.Allow()
+	g.Expect(err).To(BeNil())
+	return call
+}
+
+func TestCircuitBreakerConsecutiveFailures(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Now()
+	cb := newTestBreaker(&CircuitBreakerSettings{ConsecutiveFailures: 2, MinRequests: 20, Window: time.Second, OpenTimeout: time.Second}, &now)
+
+	mustAllow(g, cb).Done(true)
+	mustAllow(g, cb).Done(false)
+	mustAllow(g, cb).Done(true)
+	g.Expect(cb.State()).To(Equal(CircuitClosed))
+	mustAllow(g, cb).Done(true)
+	g.Expect(cb.State()).To(Equal(CircuitOpen))
+
+	_, err := cb.Allow()
+	g.Expect(err).To(Equal(&CircuitOpenError{Endpoint: "model:9000"}))
+	g.Expect(status.Code(err)).To(Equal(codes.Unavailable))
+
+	// A single probe is allowed once the open timeout has passed
+	now = now.Add(time.Second)
+	probe := mustAllow(g, cb)
+	g.Expect(cb.State()).To(Equal(CircuitHalfOpen))
+	_, err = cb.Allow()
+	g.Expect(err).ToNot(BeNil())
+	probe.Done(true)
+	g.Expect(cb.State()).To(Equal(CircuitOpen))
+
+	now = now.Add(time.Second)
+	mustAllow(g, cb).Done(false)
+	g.Expect(cb.State()).To(Equal(CircuitClosed))
+}
+
+func TestCircuitBreakerErrorRate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Now()
+	cb := newTestBreaker(&CircuitBreakerSettings{ErrorRatePercent: 50, MinRequests: 4, Window: time.Second, OpenTimeout: time.Second}, &now)
+
+	for _, failed := range []bool{true, false, true} {
+		mustAllow(g, cb).Done(failed)
+	}
+	g.Expect(cb.State()).To(Equal(CircuitClosed))
+
+	// A new window starts the count again
+	now = now.Add(time.Second)
+	for _, failed := range []bool{true, false, true} {
+		mustAllow(g, cb).Done(failed)
+	}
+	g.Expect(cb.State()).To(Equal(CircuitClosed))
+	mustAllow(g, cb).Done(false)
+	g.Expect(cb.State()).To(Equal(CircuitOpen))
+}
+
+func TestCircuitBreakerCancel(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Now()
+	cb := newTestBreaker(&CircuitBreakerSettings{ConsecutiveFailures: 2, MinRequests: 20, Window: time.Second, OpenTimeout: time.Second}, &now)
+
+	// Abandoned calls don't reset the failures in a row
+	mustAllow(g, cb).Done(true)
+	mustAllow(g, cb).Cancel()
+	mustAllow(g, cb).Done(true)
+	g.Expect(cb.State()).To(Equal(CircuitOpen))
+
+	// An abandoned probe leaves the circuit half-open and lets another probe through
+	now = now.Add(time.Second)
+	probe := mustAllow(g, cb)
+	_, err := cb.Allow()
+	g.Expect(err).ToNot(BeNil())
+	probe.Cancel()
+	g.Expect(cb.State()).To(Equal(CircuitHalfOpen))
+	mustAllow(g, cb).Done(true)
+	g.Expect(cb.State()).To(Equal(CircuitOpen))
+}
+
+func TestCircuitBreakerIgnoresCallsFromEarlierStates(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Now()
+	cb := newTestBreaker(&CircuitBreakerSettings{ConsecutiveFailures: 1, MinRequests: 20, Window: time.Second, OpenTimeout: time.Second}, &now)
+
+	// A call made while closed which ends during the probe doesn't close the circuit
+	slow := mustAllow(g, cb)
+	mustAllow(g, cb).Done(true)
+	g.Expect(cb.State()).To(Equal(CircuitOpen))
+	now = now.Add(time.Second)
+	probe := mustAllow(g, cb)
+	slow.Done(false)
+	g.Expect(cb.State()).To(Equal(CircuitHalfOpen))
+	slow.Cancel()
+	_, err := cb.Allow()
+	g.Expect(err).ToNot(BeNil())
+	probe.Done(true)
+	g.Expect(cb.State()).To(Equal(CircuitOpen))
+
+	// nor does a probe of an earlier half-open state
+	now = now.Add(time.Second)
+	mustAllow(g, cb).Done(false)
+	g.Expect(cb.State()).To(Equal(CircuitClosed))
+	probe.Done(false)
+	late := mustAllow(g, cb)
+	mustAllow(g, cb).Done(true)
+	late.Done(false)
+	g.Expect(cb.State()).To(Equal(CircuitOpen))
+}
+
+func TestCircuitBreakerRegistryDisabled(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	registry := NewCircuitBreakerRegistry(nil)
+	cb := registry.Get("model:9000")
+	g.Expect(cb).To(BeNil())
+	mustAllow(g, cb).Done(true)
+	g.Expect(registry.Enabled()).To(BeFalse())
+	g.Expect(registry.States()).To(BeEmpty())
+}
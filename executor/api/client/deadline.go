@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/k8s"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DeadlineExceededError is returned when the request deadline passes while a node of the graph is being called.
+type DeadlineExceededError struct {
+	Node string
+}
+
+func (e *DeadlineExceededError) Error() string {
+	return fmt.Sprintf("request deadline exceeded calling node %s", e.Node)
+}
+
+// GRPCStatus allows the error to be returned from gRPC servers as DEADLINE_EXCEEDED.
+func (e *DeadlineExceededError) GRPCStatus() *status.Status {
+	return status.New(codes.DeadlineExceeded, e.Error())
+}
+
+// GetRequestDeadlineFromAnnotations returns the deadline applied to every request or 0 if there is none.
+func GetRequestDeadlineFromAnnotations(annotations map[string]string) (time.Duration, error) {
+	val := annotations[k8s.ANNOTATION_REQUEST_DEADLINE]
+	if val == "" {
+		return 0, nil
+	}
+	ms, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse annotation %s: %w", k8s.ANNOTATION_REQUEST_DEADLINE, err)
+	}
+	if ms < 0 {
+		return 0, fmt.Errorf("annotation %s can not be negative", k8s.ANNOTATION_REQUEST_DEADLINE)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// ContextWithRequestDeadline applies the earliest of the deadline already on the context, the deadline
+// requested by the caller in msecs and the default deadline. Zero or empty values are ignored.
+func ContextWithRequestDeadline(ctx context.Context, requested string, def time.Duration) (context.Context, context.CancelFunc, error) {
+	timeout := def
+	if requested != "" {
+		ms, err := strconv.Atoi(requested)
+		if err != nil || ms < 0 {
+			return ctx, func() {}, fmt.Errorf("invalid %s header %q", payload.SeldonDeadlineHeader, requested)
+		}
+		if requestedTimeout := time.Duration(ms) * time.Millisecond; requestedTimeout > 0 && (timeout == 0 || requestedTimeout < timeout) {
+			timeout = requestedTimeout
+		}
+	}
+	if timeout == 0 {
+		return ctx, func() {}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+// RemainingMs returns the time left in msecs before the context deadline, which is at least 1 so a
+// zero value is never taken to mean no deadline.
+func RemainingMs(ctx context.Context) (int64, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	remaining := time.Until(deadline).Milliseconds()
+	if remaining < 1 {
+		remaining = 1
+	}
+	return remaining, true
+}
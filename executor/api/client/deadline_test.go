@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/k8s"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetRequestDeadlineFromAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deadline, err := GetRequestDeadlineFromAnnotations(map[string]string{})
+	g.Expect(err).To(BeNil())
+	g.Expect(deadline).To(Equal(time.Duration(0)))
+
+	deadline, err = GetRequestDeadlineFromAnnotations(map[string]string{k8s.ANNOTATION_REQUEST_DEADLINE: "1500"})
+	g.Expect(err).To(BeNil())
+	g.Expect(deadline).To(Equal(1500 * time.Millisecond))
+
+	_, err = GetRequestDeadlineFromAnnotations(map[string]string{k8s.ANNOTATION_REQUEST_DEADLINE: "-1"})
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestContextWithRequestDeadline(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ctx, cancel, err := ContextWithRequestDeadline(context.Background(), "", 0)
+	g.Expect(err).To(BeNil())
+	defer cancel()
+	_, ok := ctx.Deadline()
+	g.Expect(ok).To(BeFalse())
+
+	// The earliest deadline is used
+	ctx, cancel, err = ContextWithRequestDeadline(context.Background(), "100", time.Minute)
+	g.Expect(err).To(BeNil())
+	defer cancel()
+	remaining, ok := RemainingMs(ctx)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(remaining).To(BeNumerically("<=", 100))
+
+	ctx, cancel, err = ContextWithRequestDeadline(context.Background(), "60000", 100*time.Millisecond)
+	g.Expect(err).To(BeNil())
+	defer cancel()
+	remaining, _ = RemainingMs(ctx)
+	g.Expect(remaining).To(BeNumerically("<=", 100))
+
+	// An existing deadline is never extended
+	parent, parentCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer parentCancel()
+	ctx, cancel, err = ContextWithRequestDeadline(parent, "60000", 0)
+	g.Expect(err).To(BeNil())
+	defer cancel()
+	remaining, _ = RemainingMs(ctx)
+	g.Expect(remaining).To(BeNumerically("<=", 100))
+
+	_, _, err = ContextWithRequestDeadline(context.Background(), "abc", 0)
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestDeadlineExceededError(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	err := &DeadlineExceededError{Node: "classifier"}
+	g.Expect(err.Error()).To(Equal("request deadline exceeded calling node classifier"))
+	g.Expect(status.Code(err)).To(Equal(codes.DeadlineExceeded))
+}
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/k8s"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultQueueTimeout = time.Second
+	RetryAfterHeader    = "Retry-After"
+
+	limitScopeServer = "server"
+	limitScopeNode   = "node"
+)
+
+// LimitExceededError is returned for requests rejected as the server or a node has too many requests in flight.
+type LimitExceededError struct {
+	// Node name, empty for the server
+	Name string
+	// Suggested time to wait before trying again
+	RetryAfter time.Duration
+}
+
+func (e *LimitExceededError) Error() string {
+	if e.Name == "" {
+		return "too many requests in flight"
+	}
+	return fmt.Sprintf("too many requests in flight for %s", e.Name)
+}
+
+// RetryAfterSeconds returns the value of a Retry-After header for the error, at least 1 second.
+func (e *LimitExceededError) RetryAfterSeconds() string {
+	seconds := int((e.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
+
+// GRPCStatus allows the error to be returned from gRPC servers and interceptors as RESOURCE_EXHAUSTED.
+func (e *LimitExceededError) GRPCStatus() *status.Status {
+	return status.New(codes.ResourceExhausted, e.Error())
+}
+
+type ConcurrencyLimitSettings struct {
+	// Requests processed at once by the server. Disabled if 0.
+	MaxInFlight int
+	// Requests waiting for a slot before more are rejected
+	MaxQueued int
+	// Calls to each node of the graph at once. Disabled if 0.
+	NodeMaxInFlight int
+	NodeMaxQueued   int
+	// Longest time a request waits for a slot
+	QueueTimeout time.Duration
+}
+
+// ConcurrencyLimitSettingsFromAnnotations returns nil settings if no limit is configured.
+func ConcurrencyLimitSettingsFromAnnotations(annotations map[string]string) (*ConcurrencyLimitSettings, error) {
+	settings := &ConcurrencyLimitSettings{}
+	var err error
+	if settings.MaxInFlight, err = getIntFromAnnotations(annotations, k8s.ANNOTATION_MAX_INFLIGHT_REQUESTS); err != nil {
+		return nil, err
+	}
+	if settings.MaxQueued, err = getIntFromAnnotations(annotations, k8s.ANNOTATION_MAX_QUEUED_REQUESTS); err != nil {
+		return nil, err
+	}
+	if settings.NodeMaxInFlight, err = getIntFromAnnotations(annotations, k8s.ANNOTATION_NODE_MAX_INFLIGHT_REQUESTS); err != nil {
+		return nil, err
+	}
+	if settings.NodeMaxQueued, err = getIntFromAnnotations(annotations, k8s.ANNOTATION_NODE_MAX_QUEUED_REQUESTS); err != nil {
+		return nil, err
+	}
+	if settings.MaxInFlight == 0 && settings.NodeMaxInFlight == 0 {
+		return nil, nil
+	}
+	timeoutMs, err := getIntFromAnnotations(annotations, k8s.ANNOTATION_QUEUE_TIMEOUT)
+	if err != nil {
+		return nil, err
+	}
+	settings.QueueTimeout = time.Duration(timeoutMs) * time.Millisecond
+	if settings.QueueTimeout == 0 {
+		settings.QueueTimeout = DefaultQueueTimeout
+	}
+	return settings, nil
+}
+
+// ConcurrencyLimiter bounds the requests in flight, with a bounded queue of requests waiting for a slot. A nil
+// limiter allows every request.
+type ConcurrencyLimiter struct {
+	name         string
+	slots        chan struct{}
+	maxQueued    int
+	queueTimeout time.Duration
+
+	mu     sync.Mutex
+	queued int
+
+	inflightGauge   prometheus.Gauge
+	queuedGauge     prometheus.Gauge
+	rejectedCounter prometheus.Counter
+}
+
+func newConcurrencyLimiter(scope string, name string, maxInFlight int, maxQueued int, queueTimeout time.Duration, metrics *metric.ConcurrencyLimitMetrics) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		name:            name,
+		slots:           make(chan struct{}, maxInFlight),
+		maxQueued:       maxQueued,
+		queueTimeout:    queueTimeout,
+		inflightGauge:   metrics.InflightGauge.WithLabelValues(scope, name),
+		queuedGauge:     metrics.QueuedGauge.WithLabelValues(scope, name),
+		rejectedCounter: metrics.RejectedCounter.WithLabelValues(scope, name),
+	}
+}
+
+// Acquire waits for a slot, returning a LimitExceededError if the queue is full or no slot is freed within the
+// queue timeout. Every successful Acquire must be followed by Release.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		l.inflightGauge.Inc()
+		return nil
+	default:
+	}
+
+	l.mu.Lock()
+	if l.queued >= l.maxQueued {
+		l.mu.Unlock()
+		return l.reject()
+	}
+	l.queued++
+	l.mu.Unlock()
+	l.queuedGauge.Inc()
+	defer func() {
+		l.mu.Lock()
+		l.queued--
+		l.mu.Unlock()
+		l.queuedGauge.Dec()
+	}()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		l.inflightGauge.Inc()
+		return nil
+	case <-timer.C:
+		return l.reject()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *ConcurrencyLimiter) Release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+	l.inflightGauge.Dec()
+}
+
+func (l *ConcurrencyLimiter) reject() error {
+	l.rejectedCounter.Inc()
+	return &LimitExceededError{Name: l.name, RetryAfter: l.queueTimeout}
+}
+
+// ConcurrencyLimitRegistry holds the limiter of the server and a limiter per graph node.
+type ConcurrencyLimitRegistry struct {
+	settings *ConcurrencyLimitSettings
+	metrics  *metric.ConcurrencyLimitMetrics
+	server   *ConcurrencyLimiter
+
+	mu    sync.Mutex
+	nodes map[string]*ConcurrencyLimiter
+}
+
+// ConcurrencyLimits is the registry used by the executor servers and clients. It has no limits until configured
+// at startup.
+var ConcurrencyLimits = NewConcurrencyLimitRegistry(nil)
+
+func NewConcurrencyLimitRegistry(settings *ConcurrencyLimitSettings) *ConcurrencyLimitRegistry {
+	registry := &ConcurrencyLimitRegistry{
+		settings: settings,
+		nodes:    make(map[string]*ConcurrencyLimiter),
+	}
+	if settings != nil {
+		registry.metrics = metric.NewConcurrencyLimitMetrics()
+		if settings.MaxInFlight > 0 {
+			registry.server = newConcurrencyLimiter(limitScopeServer, "", settings.MaxInFlight, settings.MaxQueued, settings.QueueTimeout, registry.metrics)
+		}
+	}
+	return registry
+}
+
+// Server returns the limiter of requests to the executor or nil if they are not limited.
+func (r *ConcurrencyLimitRegistry) Server() *ConcurrencyLimiter {
+	return r.server
+}
+
+// Node returns the limiter of calls to a node of the graph or nil if they are not limited.
+func (r *ConcurrencyLimitRegistry) Node(name string) *ConcurrencyLimiter {
+	if r.settings == nil || r.settings.NodeMaxInFlight == 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.nodes[name]
+	if !ok {
+		l = newConcurrencyLimiter(limitScopeNode, name, r.settings.NodeMaxInFlight, r.settings.NodeMaxQueued, r.settings.QueueTimeout, r.metrics)
+		r.nodes[name] = l
+	}
+	return l
+}
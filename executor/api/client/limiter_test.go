@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/k8s"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConcurrencyLimitSettingsFromAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	settings, err := ConcurrencyLimitSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_MAX_QUEUED_REQUESTS: "10"})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings).To(BeNil())
+
+	settings, err = ConcurrencyLimitSettingsFromAnnotations(map[string]string{
+		k8s.ANNOTATION_MAX_INFLIGHT_REQUESTS:      "100",
+		k8s.ANNOTATION_MAX_QUEUED_REQUESTS:        "50",
+		k8s.ANNOTATION_NODE_MAX_INFLIGHT_REQUESTS: "10",
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings.MaxInFlight).To(Equal(100))
+	g.Expect(settings.MaxQueued).To(Equal(50))
+	g.Expect(settings.NodeMaxInFlight).To(Equal(10))
+	g.Expect(settings.QueueTimeout).To(Equal(DefaultQueueTimeout))
+
+	_, err = ConcurrencyLimitSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_MAX_INFLIGHT_REQUESTS: "-1"})
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	registry := NewConcurrencyLimitRegistry(&ConcurrencyLimitSettings{MaxInFlight: 1, MaxQueued: 1, QueueTimeout: 50 * time.Millisecond})
+	g.Expect(registry.Node("model")).To(BeNil())
+	limiter := registry.Server()
+	ctx := context.Background()
+
+	g.Expect(limiter.Acquire(ctx)).To(BeNil())
+
+	// A queued request gets the slot once it is released
+	acquired := make(chan error)
+	go func() {
+		acquired <- limiter.Acquire(ctx)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// The queue is full
+	err := limiter.Acquire(ctx)
+	g.Expect(err).To(BeAssignableToTypeOf(&LimitExceededError{}))
+	g.Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+	g.Expect(err.(*LimitExceededError).RetryAfterSeconds()).To(Equal("1"))
+
+	limiter.Release()
+	g.Expect(<-acquired).To(BeNil())
+
+	// A queued request is rejected if no slot is released in time
+	start := time.Now()
+	g.Expect(limiter.Acquire(ctx)).To(BeAssignableToTypeOf(&LimitExceededError{}))
+	g.Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+	limiter.Release()
+
+	var disabled *ConcurrencyLimiter
+	g.Expect(disabled.Acquire(ctx)).To(BeNil())
+	disabled.Release()
+}
+
+func TestNodeConcurrencyLimiter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	registry := NewConcurrencyLimitRegistry(&ConcurrencyLimitSettings{NodeMaxInFlight: 1, QueueTimeout: time.Second})
+	g.Expect(registry.Server()).To(BeNil())
+	g.Expect(registry.Node("a")).To(BeIdenticalTo(registry.Node("a")))
+
+	g.Expect(registry.Node("a").Acquire(context.Background())).To(BeNil())
+	defer registry.Node("a").Release()
+	g.Expect(registry.Node("b").Acquire(context.Background())).To(BeNil())
+	defer registry.Node("b").Release()
+
+	err := registry.Node("a").Acquire(context.Background())
+	g.Expect(err).To(BeAssignableToTypeOf(&LimitExceededError{}))
+	g.Expect(err.(*LimitExceededError).Name).To(Equal("a"))
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	DefaultRetryMaxAttempts      = 3
+	DefaultRetryInitialBackoffMs = 50
+	DefaultRetryMaxBackoffMs     = 1000
+)
+
+var (
+	DefaultRetryableStatusCodes = []int32{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	DefaultRetryableGrpcCodes   = []string{"UNAVAILABLE"}
+)
+
+type retryPolicyKey struct{}
+
+// ContextWithRetryPolicy returns a context carrying the retry policy of the predictive unit about to be called.
+func ContextWithRetryPolicy(ctx context.Context, policy *v1.RetryPolicy) context.Context {
+	if policy == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// RetryPolicyFromContext returns the retry policy added with ContextWithRetryPolicy or nil if there is none.
+func RetryPolicyFromContext(ctx context.Context) *v1.RetryPolicy {
+	if policy, ok := ctx.Value(retryPolicyKey{}).(*v1.RetryPolicy); ok {
+		return policy
+	}
+	return nil
+}
+
+// Retrier applies a RetryPolicy, filling in defaults for any unset fields.
+type Retrier struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	statusCodes    map[int]bool
+	grpcCodes      map[codes.Code]bool
+	idempotent     bool
+}
+
+func NewRetrier(policy *v1.RetryPolicy) *Retrier {
+	if policy == nil {
+		return &Retrier{maxAttempts: 1}
+	}
+	r := &Retrier{
+		maxAttempts:    int(policy.MaxAttempts),
+		initialBackoff: time.Duration(policy.InitialBackoffMs) * time.Millisecond,
+		maxBackoff:     time.Duration(policy.MaxBackoffMs) * time.Millisecond,
+		statusCodes:    make(map[int]bool),
+		grpcCodes:      make(map[codes.Code]bool),
+		idempotent:     policy.Idempotent,
+	}
+	if r.maxAttempts <= 0 {
+		r.maxAttempts = DefaultRetryMaxAttempts
+	}
+	if r.initialBackoff <= 0 {
+		r.initialBackoff = DefaultRetryInitialBackoffMs * time.Millisecond
+	}
+	if r.maxBackoff <= 0 {
+		r.maxBackoff = DefaultRetryMaxBackoffMs * time.Millisecond
+	}
+	statusCodes := policy.RetryableStatusCodes
+	if len(statusCodes) == 0 {
+		statusCodes = DefaultRetryableStatusCodes
+	}
+	for _, code := range statusCodes {
+		r.statusCodes[int(code)] = true
+	}
+	grpcCodes := policy.RetryableGrpcCodes
+	if len(grpcCodes) == 0 {
+		grpcCodes = DefaultRetryableGrpcCodes
+	}
+	for _, name := range grpcCodes {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(name))); err == nil {
+			r.grpcCodes[code] = true
+		}
+	}
+	return r
+}
+
+func (r *Retrier) MaxAttempts() int {
+	return r.maxAttempts
+}
+
+func (r *Retrier) Idempotent() bool {
+	return r.idempotent
+}
+
+func (r *Retrier) RetryableStatusCode(code int) bool {
+	return r.statusCodes[code]
+}
+
+func (r *Retrier) RetryableGrpcCode(code codes.Code) bool {
+	return r.grpcCodes[code]
+}
+
+// Backoff returns the jittered wait before the given retry, counting from 1.
+func (r *Retrier) Backoff(retry int) time.Duration {
+	backoff := r.initialBackoff
+	for i := 1; i < retry && backoff < r.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.maxBackoff {
+		backoff = r.maxBackoff
+	}
+	// Wait between half and the full backoff so concurrent callers don't retry in lockstep
+	half := int64(backoff / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// Do calls fn until it succeeds, fails with an error which is not retryable or the attempts are exhausted.
+// onRetry is called with the failure that triggered each retry. The last error is returned.
+func (r *Retrier) Do(ctx context.Context, fn func() error, retryable func(error) bool, onRetry func(error)) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= r.maxAttempts || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+		// Retrying against an open circuit or a saturated node would only be rejected again
+		if _, ok := err.(*CircuitOpenError); ok {
+			return err
+		}
+		if _, ok := err.(*LimitExceededError); ok {
+			return err
+		}
+		if onRetry != nil {
+			onRetry(err)
+		}
+		timer := time.NewTimer(r.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+)
+
+func TestRetrierDefaults(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	retrier := NewRetrier(&v1.RetryPolicy{})
+	g.Expect(retrier.MaxAttempts()).To(Equal(DefaultRetryMaxAttempts))
+	g.Expect(retrier.Idempotent()).To(BeFalse())
+	g.Expect(retrier.RetryableStatusCode(http.StatusServiceUnavailable)).To(BeTrue())
+	g.Expect(retrier.RetryableStatusCode(http.StatusInternalServerError)).To(BeFalse())
+	g.Expect(retrier.RetryableGrpcCode(codes.Unavailable)).To(BeTrue())
+	g.Expect(retrier.RetryableGrpcCode(codes.Internal)).To(BeFalse())
+
+	g.Expect(NewRetrier(nil).MaxAttempts()).To(Equal(1))
+}
+
+func TestRetrierBackoff(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	retrier := NewRetrier(&v1.RetryPolicy{InitialBackoffMs: 100, MaxBackoffMs: 300})
+	for i := 0; i < 10; i++ {
+		g.Expect(retrier.Backoff(1)).To(BeNumerically("~", 75*time.Millisecond, 25*time.Millisecond))
+		g.Expect(retrier.Backoff(2)).To(BeNumerically("~", 150*time.Millisecond, 50*time.Millisecond))
+		g.Expect(retrier.Backoff(5)).To(BeNumerically("~", 225*time.Millisecond, 75*time.Millisecond))
+	}
+}
+
+func TestRetrierDo(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	retrier := NewRetrier(&v1.RetryPolicy{MaxAttempts: 4, InitialBackoffMs: 1, MaxBackoffMs: 1})
+	errRetry := errors.New("retry")
+	errFatal := errors.New("fatal")
+	retryable := func(err error) bool { return err == errRetry }
+
+	calls, retries := 0, 0
+	err := retrier.Do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return errRetry
+		}
+		return nil
+	}, retryable, func(err error) { retries++ })
+	g.Expect(err).To(BeNil())
+	g.Expect(calls).To(Equal(3))
+	g.Expect(retries).To(Equal(2))
+
+	calls = 0
+	err = retrier.Do(context.Background(), func() error {
+		calls++
+		return errFatal
+	}, retryable, nil)
+	g.Expect(err).To(Equal(errFatal))
+	g.Expect(calls).To(Equal(1))
+
+	calls = 0
+	err = retrier.Do(context.Background(), func() error {
+		calls++
+		return errRetry
+	}, retryable, nil)
+	g.Expect(err).To(Equal(errRetry))
+	g.Expect(calls).To(Equal(4))
+}
+
+func TestRetryPolicyContext(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ctx := context.Background()
+	g.Expect(RetryPolicyFromContext(ctx)).To(BeNil())
+	g.Expect(ContextWithRetryPolicy(ctx, nil)).To(Equal(ctx))
+
+	policy := &v1.RetryPolicy{MaxAttempts: 2}
+	g.Expect(RetryPolicyFromContext(ContextWithRetryPolicy(ctx, policy))).To(Equal(policy))
+}
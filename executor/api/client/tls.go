@@ -0,0 +1,209 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/k8s"
+)
+
+// ClientTLS is the TLS configuration for calls from the executor to the nodes of the graph. Calls are plaintext
+// unless it is configured at startup.
+var ClientTLS *ClientTLSConfig
+
+type ClientTLSSettings struct {
+	// CA bundle to verify the nodes with, the system roots if empty
+	CAFile string
+	// Client certificate and key for mutual TLS
+	CertFile string
+	KeyFile  string
+	// Name to verify the nodes' certificates for, the node host if empty
+	ServerName string
+	// Don't verify the nodes' certificates, for development only
+	InsecureSkipVerify bool
+}
+
+// ClientTLSSettingsFromAnnotations returns nil settings if client TLS is not configured.
+func ClientTLSSettingsFromAnnotations(annotations map[string]string) (*ClientTLSSettings, error) {
+	settings := &ClientTLSSettings{
+		CAFile:     annotations[k8s.ANNOTATION_CLIENT_TLS_CA_FILE],
+		CertFile:   annotations[k8s.ANNOTATION_CLIENT_TLS_CERT_FILE],
+		KeyFile:    annotations[k8s.ANNOTATION_CLIENT_TLS_KEY_FILE],
+		ServerName: annotations[k8s.ANNOTATION_CLIENT_TLS_SERVER_NAME],
+	}
+	enabled, err := getBoolFromAnnotations(annotations, k8s.ANNOTATION_CLIENT_TLS)
+	if err != nil {
+		return nil, err
+	}
+	if settings.InsecureSkipVerify, err = getBoolFromAnnotations(annotations, k8s.ANNOTATION_CLIENT_TLS_INSECURE_SKIP_VERIFY); err != nil {
+		return nil, err
+	}
+	if !enabled && settings.CAFile == "" && settings.CertFile == "" {
+		return nil, nil
+	}
+	if (settings.CertFile == "") != (settings.KeyFile == "") {
+		return nil, fmt.Errorf("annotations %s and %s must be set together", k8s.ANNOTATION_CLIENT_TLS_CERT_FILE, k8s.ANNOTATION_CLIENT_TLS_KEY_FILE)
+	}
+	return settings, nil
+}
+
+func getBoolFromAnnotations(annotations map[string]string, key string) (bool, error) {
+	val := annotations[key]
+	if val == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("annotation %s must be a boolean: %w", key, err)
+	}
+	return b, nil
+}
+
+// ClientTLSConfig creates TLS configurations for the clients. The CA bundle and client certificate are read again
+// when their files change, so rotated certificates are used for new connections without a restart.
+type ClientTLSConfig struct {
+	settings  ClientTLSSettings
+	transport *http.Transport
+
+	mu          sync.Mutex
+	caModTime   time.Time
+	roots       *x509.CertPool
+	certModTime time.Time
+	cert        *tls.Certificate
+}
+
+// NewClientTLSConfig checks the files of the settings can be loaded.
+func NewClientTLSConfig(settings ClientTLSSettings) (*ClientTLSConfig, error) {
+	c := &ClientTLSConfig{settings: settings}
+	if _, err := c.currentRoots(); err != nil {
+		return nil, err
+	}
+	if _, err := c.currentCertificate(); err != nil {
+		return nil, err
+	}
+	c.transport = http.DefaultTransport.(*http.Transport).Clone()
+	c.transport.TLSClientConfig = c.Config()
+	return c, nil
+}
+
+// Config returns a TLS configuration for a client.
+func (c *ClientTLSConfig) Config() *tls.Config {
+	return &tls.Config{
+		ServerName: c.settings.ServerName,
+		// The peer is verified in VerifyConnection with the current CA bundle
+		InsecureSkipVerify:   true,
+		VerifyConnection:     c.verifyConnection,
+		GetClientCertificate: c.getClientCertificate,
+	}
+}
+
+// Transport returns the HTTP transport for REST calls to the nodes.
+func (c *ClientTLSConfig) Transport() *http.Transport {
+	return c.transport
+}
+
+func (c *ClientTLSConfig) verifyConnection(state tls.ConnectionState) error {
+	if c.settings.InsecureSkipVerify {
+		return nil
+	}
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("no certificate from %s", state.ServerName)
+	}
+	roots, err := c.currentRoots()
+	if err != nil {
+		return err
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       state.ServerName,
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err = state.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+func (c *ClientTLSConfig) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, err := c.currentCertificate()
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		// No certificate is sent if none is configured
+		return &tls.Certificate{}, nil
+	}
+	return cert, nil
+}
+
+// currentRoots returns the CA bundle, or nil for the system roots.
+func (c *ClientTLSConfig) currentRoots() (*x509.CertPool, error) {
+	if c.settings.CAFile == "" {
+		return nil, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info, err := os.Stat(c.settings.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	if c.roots != nil && info.ModTime().Equal(c.caModTime) {
+		return c.roots, nil
+	}
+	pem, err := ioutil.ReadFile(c.settings.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", c.settings.CAFile)
+	}
+	c.roots, c.caModTime = roots, info.ModTime()
+	return roots, nil
+}
+
+// currentCertificate returns the client certificate, or nil if none is configured.
+func (c *ClientTLSConfig) currentCertificate() (*tls.Certificate, error) {
+	if c.settings.CertFile == "" {
+		return nil, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info, err := os.Stat(c.settings.CertFile)
+	if err != nil {
+		return nil, err
+	}
+	if c.cert != nil && info.ModTime().Equal(c.certModTime) {
+		return c.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.settings.CertFile, c.settings.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	c.cert, c.certModTime = &cert, info.ModTime()
+	return c.cert, nil
+}
+
+// HttpScheme returns the scheme of REST calls to the nodes.
+func HttpScheme() string {
+	if ClientTLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// HttpTransport returns the transport for REST calls to the nodes.
+func HttpTransport() http.RoundTripper {
+	if ClientTLS != nil {
+		return ClientTLS.Transport()
+	}
+	return http.DefaultTransport
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/k8s"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// createTestCert creates a certificate for 127.0.0.1 signed by the parent, or a self signed CA if parent is nil.
+func createTestCert(g *GomegaWithT, parent *testCert, serial int64) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).To(BeNil())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	g.Expect(err).To(BeNil())
+	cert, err := x509.ParseCertificate(der)
+	g.Expect(err).To(BeNil())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	g.Expect(err).To(BeNil())
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func startTestTLSServer(g *GomegaWithT, server *testCert, clientCA *testCert) *httptest.Server {
+	cert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	g.Expect(err).To(BeNil())
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	if clientCA != nil {
+		pool := x509.NewCertPool()
+		pool.AddCert(clientCA.cert)
+		ts.TLS.ClientCAs = pool
+		ts.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	ts.StartTLS()
+	return ts
+}
+
+func writeTestFile(g *GomegaWithT, filename string, data []byte, modTime time.Time) {
+	g.Expect(ioutil.WriteFile(filename, data, 0600)).To(BeNil())
+	g.Expect(os.Chtimes(filename, modTime, modTime)).To(BeNil())
+}
+
+func TestClientTLSSettingsFromAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	settings, err := ClientTLSSettingsFromAnnotations(map[string]string{})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings).To(BeNil())
+
+	settings, err = ClientTLSSettingsFromAnnotations(map[string]string{
+		k8s.ANNOTATION_CLIENT_TLS:             "true",
+		k8s.ANNOTATION_CLIENT_TLS_SERVER_NAME: "model",
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings.ServerName).To(Equal("model"))
+
+	settings, err = ClientTLSSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_CLIENT_TLS_CA_FILE: "/certs/ca.crt"})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings.CAFile).To(Equal("/certs/ca.crt"))
+
+	_, err = ClientTLSSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_CLIENT_TLS_CERT_FILE: "/certs/tls.crt"})
+	g.Expect(err).ToNot(BeNil())
+
+	_, err = ClientTLSSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_CLIENT_TLS: "yes please"})
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestClientTLSMutualAndRotation(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "client-tls")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	ca := createTestCert(g, nil, 1)
+	clientCert := createTestCert(g, ca, 2)
+	server := startTestTLSServer(g, createTestCert(g, ca, 3), ca)
+	defer server.Close()
+
+	settings := ClientTLSSettings{
+		CAFile:   filepath.Join(dir, "ca.crt"),
+		CertFile: filepath.Join(dir, "tls.crt"),
+		KeyFile:  filepath.Join(dir, "tls.key"),
+	}
+	modTime := time.Now().Add(-time.Minute)
+	writeTestFile(g, settings.CAFile, ca.certPEM, modTime)
+	writeTestFile(g, settings.CertFile, clientCert.certPEM, modTime)
+	writeTestFile(g, settings.KeyFile, clientCert.keyPEM, modTime)
+
+	config, err := NewClientTLSConfig(settings)
+	g.Expect(err).To(BeNil())
+	httpClient := &http.Client{Transport: config.Transport()}
+
+	res, err := httpClient.Get(server.URL)
+	g.Expect(err).To(BeNil())
+	res.Body.Close()
+	g.Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+	// A node with a certificate from a new CA is rejected until the CA bundle is rotated
+	newCA := createTestCert(g, nil, 4)
+	newServer := startTestTLSServer(g, createTestCert(g, newCA, 5), nil)
+	defer newServer.Close()
+	_, err = httpClient.Get(newServer.URL)
+	g.Expect(err).ToNot(BeNil())
+
+	writeTestFile(g, settings.CAFile, newCA.certPEM, time.Now())
+	res, err = httpClient.Get(newServer.URL)
+	g.Expect(err).To(BeNil())
+	res.Body.Close()
+
+	// Certificates aren't verified when skipping verification
+	insecure, err := NewClientTLSConfig(ClientTLSSettings{InsecureSkipVerify: true})
+	g.Expect(err).To(BeNil())
+	res, err = (&http.Client{Transport: insecure.Transport()}).Get(newServer.URL)
+	g.Expect(err).To(BeNil())
+	res.Body.Close()
+
+	_, err = NewClientTLSConfig(ClientTLSSettings{CAFile: filepath.Join(dir, "missing.crt")})
+	g.Expect(err).ToNot(BeNil())
+}
@@ -0,0 +1,90 @@
+package converter
+
+import (
+	"context"
+
+	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+// ProtocolClient calls nodes speaking another protocol than the executor. Messages are converted to the protocol of
+// the node before each call and its responses are converted back, so the rest of the graph only sees the protocol of
+// the executor.
+type ProtocolClient struct {
+	client.SeldonApiClient
+	// Protocol of the node
+	nodeProtocol string
+	// Protocol of the executor
+	protocol string
+}
+
+func NewProtocolClient(nodeClient client.SeldonApiClient, nodeProtocol string, protocol string) *ProtocolClient {
+	return &ProtocolClient{
+		SeldonApiClient: nodeClient,
+		nodeProtocol:    nodeProtocol,
+		protocol:        protocol,
+	}
+}
+
+func (c *ProtocolClient) toNode(msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	return ToProtocol(msg, c.nodeProtocol, false)
+}
+
+// fromNode converts the response of a node, leaving error payloads in the protocol of the node.
+func (c *ProtocolClient) fromNode(msg payload.SeldonPayload, err error) (payload.SeldonPayload, error) {
+	if err != nil || msg == nil {
+		return msg, err
+	}
+	return ToProtocol(msg, c.protocol, true)
+}
+
+func (c *ProtocolClient) Chain(ctx context.Context, modelName string, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	msg, err := c.toNode(msg)
+	if err != nil {
+		return nil, err
+	}
+	return c.SeldonApiClient.Chain(ctx, modelName, msg)
+}
+
+func (c *ProtocolClient) Predict(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	msg, err := c.toNode(msg)
+	if err != nil {
+		return nil, err
+	}
+	return c.fromNode(c.SeldonApiClient.Predict(ctx, modelName, host, port, msg, meta))
+}
+
+func (c *ProtocolClient) TransformInput(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	msg, err := c.toNode(msg)
+	if err != nil {
+		return nil, err
+	}
+	return c.fromNode(c.SeldonApiClient.TransformInput(ctx, modelName, host, port, msg, meta))
+}
+
+func (c *ProtocolClient) TransformOutput(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	msg, err := c.toNode(msg)
+	if err != nil {
+		return nil, err
+	}
+	return c.fromNode(c.SeldonApiClient.TransformOutput(ctx, modelName, host, port, msg, meta))
+}
+
+func (c *ProtocolClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) ([]int, error) {
+	msg, err := c.toNode(msg)
+	if err != nil {
+		return nil, err
+	}
+	return c.SeldonApiClient.Route(ctx, modelName, host, port, msg, meta)
+}
+
+func (c *ProtocolClient) Combine(ctx context.Context, modelName string, host string, port int32, msgs []payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	converted := make([]payload.SeldonPayload, len(msgs))
+	for i, msg := range msgs {
+		var err error
+		if converted[i], err = c.toNode(msg); err != nil {
+			return nil, err
+		}
+	}
+	return c.fromNode(c.SeldonApiClient.Combine(ctx, modelName, host, port, converted, meta))
+}
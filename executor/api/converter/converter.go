@@ -0,0 +1,264 @@
+// Package converter translates payloads between the Seldon and V2 protocols so nodes of a graph speaking different
+// protocols can be chained.
+package converter
+
+import (
+	"fmt"
+
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+const (
+	DataTypeBool  = "BOOL"
+	DataTypeBytes = "BYTES"
+	DataTypeFP64  = "FP64"
+
+	// Names of the tensor of V2 messages converted from Seldon messages
+	DefaultInputName  = "input-0"
+	DefaultOutputName = "output-0"
+)
+
+// tensor is the protocol independent form of a message, with values held as float64, string or bool.
+type tensor struct {
+	name     string
+	datatype string
+	shape    []int
+	data     []interface{}
+}
+
+// ToProtocol converts a message to the given protocol, creating a V2 response rather than a request if response is
+// true. Messages already in the protocol, or in a form which isn't converted, are returned unchanged.
+func ToProtocol(msg payload.SeldonPayload, protocol string, response bool) (payload.SeldonPayload, error) {
+	switch protocol {
+	case api.ProtocolSeldon:
+		return toSeldon(msg)
+	case api.ProtocolV2, api.ProtocolKFServing:
+		return toV2(msg, response)
+	}
+	return msg, nil
+}
+
+func toSeldon(msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	var tensors []tensor
+	var err error
+	switch v := msg.GetPayload().(type) {
+	case []byte:
+		body, err := decodeJson(msg)
+		if err != nil {
+			return nil, err
+		}
+		if !isV2Json(body) {
+			return msg, nil
+		}
+		if body, err = v2JsonToSeldon(body); err != nil {
+			return nil, err
+		}
+		return encodeJson(body, msg.GetContentType())
+	case *inference.ModelInferRequest:
+		tensors, err = inferRequestToTensors(v)
+	case *inference.ModelInferResponse:
+		tensors, err = inferResponseToTensors(v)
+	default:
+		return msg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sm, err := tensorsToSeldonMessage(tensors)
+	if err != nil {
+		return nil, err
+	}
+	return &payload.ProtoPayload{Msg: sm}, nil
+}
+
+func toV2(msg payload.SeldonPayload, response bool) (payload.SeldonPayload, error) {
+	switch v := msg.GetPayload().(type) {
+	case []byte:
+		body, err := decodeJson(msg)
+		if err != nil {
+			return nil, err
+		}
+		if isV2Json(body) {
+			return msg, nil
+		}
+		if body, err = seldonJsonToV2(body, response); err != nil {
+			return nil, err
+		}
+		return encodeJson(body, msg.GetContentType())
+	case *proto.SeldonMessage:
+		if response {
+			res, err := seldonMessageToInferResponse(v)
+			if err != nil {
+				return nil, err
+			}
+			return &payload.ProtoPayload{Msg: res}, nil
+		}
+		req, err := seldonMessageToInferRequest(v)
+		if err != nil {
+			return nil, err
+		}
+		return &payload.ProtoPayload{Msg: req}, nil
+	}
+	return msg, nil
+}
+
+// IsConvertible returns whether messages can be converted to and from the protocol.
+func IsConvertible(protocol string) bool {
+	switch protocol {
+	case api.ProtocolSeldon, api.ProtocolV2, api.ProtocolKFServing:
+		return true
+	}
+	return false
+}
+
+// columns merges tensors with the same number of rows into one 2 dimensional array, as expected in a Seldon message,
+// returning the names of the columns. A single tensor keeps its shape.
+func columns(tensors []tensor) ([]string, []int, []interface{}, error) {
+	if len(tensors) == 0 {
+		return nil, nil, nil, fmt.Errorf("no tensors to convert")
+	}
+	if len(tensors) == 1 {
+		return nil, tensors[0].shape, tensors[0].data, nil
+	}
+	rows := -1
+	widths := make([]int, len(tensors))
+	var names []string
+	for i, t := range tensors {
+		if len(t.shape) == 0 || len(t.shape) > 2 {
+			return nil, nil, nil, fmt.Errorf("tensor %s with shape %v can't be merged with other tensors", t.name, t.shape)
+		}
+		if rows >= 0 && t.shape[0] != rows {
+			return nil, nil, nil, fmt.Errorf("tensor %s has %d rows not %d", t.name, t.shape[0], rows)
+		}
+		rows = t.shape[0]
+		widths[i] = 1
+		if len(t.shape) == 2 {
+			widths[i] = t.shape[1]
+		}
+		if widths[i] == 1 {
+			names = append(names, t.name)
+		} else {
+			for j := 0; j < widths[i]; j++ {
+				names = append(names, fmt.Sprintf("%s_%d", t.name, j))
+			}
+		}
+	}
+	data := make([]interface{}, 0, rows*len(names))
+	for r := 0; r < rows; r++ {
+		for i, t := range tensors {
+			if len(t.data) < (r+1)*widths[i] {
+				return nil, nil, nil, fmt.Errorf("tensor %s has fewer values than its shape", t.name)
+			}
+			data = append(data, t.data[r*widths[i]:(r+1)*widths[i]]...)
+		}
+	}
+	return names, []int{rows, len(names)}, data, nil
+}
+
+// dataType returns the V2 datatype of values, which must all be numbers, strings or booleans.
+func dataType(data []interface{}) (string, error) {
+	datatype := DataTypeFP64
+	for i, v := range data {
+		var dt string
+		switch v.(type) {
+		case float64:
+			dt = DataTypeFP64
+		case string:
+			dt = DataTypeBytes
+		case bool:
+			dt = DataTypeBool
+		default:
+			return "", fmt.Errorf("unsupported value %v", v)
+		}
+		if i > 0 && dt != datatype {
+			return "", fmt.Errorf("values of mixed types can't be converted")
+		}
+		datatype = dt
+	}
+	return datatype, nil
+}
+
+// flatten returns the shape and values of nested arrays, which must be rectangular.
+func flatten(value interface{}) ([]int, []interface{}, error) {
+	var shape []int
+	for v := value; ; {
+		array, ok := v.([]interface{})
+		if !ok {
+			break
+		}
+		shape = append(shape, len(array))
+		if len(array) == 0 {
+			break
+		}
+		v = array[0]
+	}
+	data := make([]interface{}, 0, product(shape))
+	var walk func(v interface{}, depth int) error
+	walk = func(v interface{}, depth int) error {
+		if depth == len(shape) {
+			if _, ok := v.([]interface{}); ok {
+				return fmt.Errorf("array is not rectangular")
+			}
+			data = append(data, v)
+			return nil
+		}
+		array, ok := v.([]interface{})
+		if !ok || len(array) != shape[depth] {
+			return fmt.Errorf("array is not rectangular")
+		}
+		for _, e := range array {
+			if err := walk(e, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(value, 0); err != nil {
+		return nil, nil, err
+	}
+	return shape, data, nil
+}
+
+// nest returns values as nested arrays of the given shape.
+func nest(shape []int, data []interface{}) (interface{}, error) {
+	if product(shape) != len(data) {
+		return nil, fmt.Errorf("%d values don't match shape %v", len(data), shape)
+	}
+	if len(shape) == 0 {
+		if len(data) == 0 {
+			return []interface{}{}, nil
+		}
+		return data[0], nil
+	}
+	var build func(depth int, data []interface{}) []interface{}
+	build = func(depth int, data []interface{}) []interface{} {
+		if depth == len(shape)-1 {
+			return append([]interface{}{}, data...)
+		}
+		array := make([]interface{}, shape[depth])
+		size := len(data) / max(shape[depth], 1)
+		for i := range array {
+			array[i] = build(depth+1, data[i*size:(i+1)*size])
+		}
+		return array
+	}
+	return build(0, data), nil
+}
+
+func product(shape []int) int {
+	n := 1
+	for _, d := range shape {
+		n *= d
+	}
+	return n
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,123 @@
+package converter
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+func convertJson(g *GomegaWithT, body string, protocol string, response bool) string {
+	msg, err := ToProtocol(&payload.BytesPayload{Msg: []byte(body), ContentType: "application/json"}, protocol, response)
+	g.Expect(err).To(BeNil())
+	return string(msg.GetPayload().([]byte))
+}
+
+func TestSeldonJsonToV2(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(convertJson(g, `{"data":{"names":["a","b"],"ndarray":[[1,2],[3,4]]}}`, api.ProtocolV2, false)).
+		To(MatchJSON(`{"inputs":[{"name":"input-0","datatype":"FP64","shape":[2,2],"data":[1,2,3,4]}]}`))
+	g.Expect(convertJson(g, `{"data":{"tensor":{"shape":[1,3],"values":[1,2,3]}}}`, api.ProtocolV2, true)).
+		To(MatchJSON(`{"outputs":[{"name":"output-0","datatype":"FP64","shape":[1,3],"data":[1,2,3]}]}`))
+	g.Expect(convertJson(g, `{"strData":"hello"}`, api.ProtocolKFServing, false)).
+		To(MatchJSON(`{"inputs":[{"name":"input-0","datatype":"BYTES","shape":[1],"data":["hello"]}]}`))
+
+	// V2 messages are unchanged
+	v2 := `{"outputs":[{"name":"out","datatype":"FP32","shape":[1],"data":[1]}]}`
+	g.Expect(convertJson(g, v2, api.ProtocolV2, false)).To(Equal(v2))
+
+	_, err := ToProtocol(&payload.BytesPayload{Msg: []byte(`{"jsonData":{"a":1}}`)}, api.ProtocolV2, false)
+	g.Expect(err).ToNot(BeNil())
+	_, err = ToProtocol(&payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2],[3]]}}`)}, api.ProtocolV2, false)
+	g.Expect(err).ToNot(BeNil())
+	_, err = ToProtocol(&payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[1,"a"]}}`)}, api.ProtocolV2, false)
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestV2JsonToSeldon(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(convertJson(g, `{"outputs":[{"name":"out","datatype":"FP32","shape":[2,2],"data":[1,2,3,4]}]}`, api.ProtocolSeldon, false)).
+		To(MatchJSON(`{"data":{"ndarray":[[1,2],[3,4]]}}`))
+	g.Expect(convertJson(g, `{"inputs":[{"name":"a","datatype":"FP32","shape":[2,1],"data":[1,2]},{"name":"b","datatype":"INT64","shape":[2],"data":[3,4]}]}`, api.ProtocolSeldon, false)).
+		To(MatchJSON(`{"data":{"names":["a","b"],"ndarray":[[1,3],[2,4]]}}`))
+
+	// Seldon messages are unchanged
+	seldon := `{"data":{"ndarray":[[1,2]]}}`
+	g.Expect(convertJson(g, seldon, api.ProtocolSeldon, false)).To(Equal(seldon))
+
+	_, err := ToProtocol(&payload.BytesPayload{Msg: []byte(`{"inputs":[{"name":"a","shape":[1],"data":[1]},{"name":"b","shape":[2],"data":[1,2]}]}`)}, api.ProtocolSeldon, false)
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestSeldonMessageToV2(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var sm proto.SeldonMessage
+	g.Expect(jsonpb.UnmarshalString(`{"data":{"ndarray":[["a","b"]]}}`, &sm)).To(BeNil())
+	msg, err := ToProtocol(&payload.ProtoPayload{Msg: &sm}, api.ProtocolV2, false)
+	g.Expect(err).To(BeNil())
+	req := msg.GetPayload().(*inference.ModelInferRequest)
+	g.Expect(req.Inputs).To(HaveLen(1))
+	g.Expect(req.Inputs[0].Datatype).To(Equal(DataTypeBytes))
+	g.Expect(req.Inputs[0].Shape).To(Equal([]int64{1, 2}))
+	g.Expect(req.Inputs[0].Contents.ByteContents).To(Equal([][]byte{[]byte("a"), []byte("b")}))
+
+	g.Expect(jsonpb.UnmarshalString(`{"data":{"tensor":{"shape":[2],"values":[1.5,2.5]}}}`, &sm)).To(BeNil())
+	msg, err = ToProtocol(&payload.ProtoPayload{Msg: &sm}, api.ProtocolV2, true)
+	g.Expect(err).To(BeNil())
+	res := msg.GetPayload().(*inference.ModelInferResponse)
+	g.Expect(res.Outputs[0].Name).To(Equal(DefaultOutputName))
+	g.Expect(res.Outputs[0].Contents.Fp64Contents).To(Equal([]float64{1.5, 2.5}))
+}
+
+func TestV2ProtoToSeldon(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	res := &inference.ModelInferResponse{
+		Outputs: []*inference.ModelInferResponse_InferOutputTensor{
+			{Name: "a", Datatype: "INT32", Shape: []int64{2}, Contents: &inference.InferTensorContents{IntContents: []int32{1, 2}}},
+			{Name: "b", Datatype: "FP32", Shape: []int64{2, 1}},
+		},
+	}
+	raw := make([]byte, 8)
+	binary.LittleEndian.PutUint32(raw, math.Float32bits(0.5))
+	binary.LittleEndian.PutUint32(raw[4:], math.Float32bits(1.5))
+	res.RawOutputContents = [][]byte{nil, raw}
+
+	msg, err := ToProtocol(&payload.ProtoPayload{Msg: res}, api.ProtocolSeldon, false)
+	g.Expect(err).To(BeNil())
+	sm := msg.GetPayload().(*proto.SeldonMessage)
+	g.Expect(sm.GetData().GetNames()).To(Equal([]string{"a", "b"}))
+	g.Expect(sm.GetData().GetTensor().GetShape()).To(Equal([]int32{2, 2}))
+	g.Expect(sm.GetData().GetTensor().GetValues()).To(Equal([]float64{1, 0.5, 2, 1.5}))
+
+	req := &inference.ModelInferRequest{
+		Inputs: []*inference.ModelInferRequest_InferInputTensor{
+			{Name: "text", Datatype: "BYTES", Shape: []int64{1}, Contents: &inference.InferTensorContents{ByteContents: [][]byte{[]byte("hi")}}},
+		},
+	}
+	msg, err = ToProtocol(&payload.ProtoPayload{Msg: req}, api.ProtocolSeldon, false)
+	g.Expect(err).To(BeNil())
+	sm = msg.GetPayload().(*proto.SeldonMessage)
+	g.Expect(sm.GetData().GetNdarray().AsSlice()).To(Equal([]interface{}{"hi"}))
+}
+
+func TestDecodeRawBytes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	raw := []byte{2, 0, 0, 0, 'h', 'i', 1, 0, 0, 0, 'x'}
+	data, err := decodeRawContents(DataTypeBytes, raw)
+	g.Expect(err).To(BeNil())
+	g.Expect(data).To(Equal([]interface{}{"hi", "x"}))
+
+	_, err = decodeRawContents(DataTypeBytes, raw[:5])
+	g.Expect(err).ToNot(BeNil())
+}
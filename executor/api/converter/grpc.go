@@ -0,0 +1,280 @@
+package converter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// seldonMessageToTensor converts the data of a Seldon message to a single tensor.
+func seldonMessageToTensor(sm *proto.SeldonMessage, name string) (tensor, error) {
+	t := tensor{name: name}
+	switch data := sm.GetDataOneof().(type) {
+	case *proto.SeldonMessage_StrData:
+		t.datatype, t.shape, t.data = DataTypeBytes, []int{1}, []interface{}{data.StrData}
+	case *proto.SeldonMessage_Data:
+		if tensorData := data.Data.GetTensor(); tensorData != nil {
+			t.datatype = DataTypeFP64
+			for _, d := range tensorData.GetShape() {
+				t.shape = append(t.shape, int(d))
+			}
+			t.data = make([]interface{}, len(tensorData.GetValues()))
+			for i, v := range tensorData.GetValues() {
+				t.data[i] = v
+			}
+		} else if ndarray := data.Data.GetNdarray(); ndarray != nil {
+			var err error
+			if t.shape, t.data, err = flatten(ndarray.AsSlice()); err != nil {
+				return t, fmt.Errorf("data.ndarray: %w", err)
+			}
+			if t.datatype, err = dataType(t.data); err != nil {
+				return t, fmt.Errorf("data.ndarray: %w", err)
+			}
+		} else {
+			return t, fmt.Errorf("only ndarray and tensor data can be converted to the V2 protocol")
+		}
+	default:
+		return t, fmt.Errorf("only data and strData messages can be converted to the V2 protocol")
+	}
+	return t, nil
+}
+
+// tensorsToSeldonMessage converts tensors to a Seldon message, with numbers as a tensor and other values as an ndarray.
+func tensorsToSeldonMessage(tensors []tensor) (*proto.SeldonMessage, error) {
+	names, shape, data, err := columns(tensors)
+	if err != nil {
+		return nil, err
+	}
+	defaultData := &proto.DefaultData{Names: names}
+	if datatype, err := dataType(data); err == nil && datatype == DataTypeFP64 {
+		values := make([]float64, len(data))
+		for i, v := range data {
+			values[i] = v.(float64)
+		}
+		t := &proto.Tensor{Values: values}
+		for _, d := range shape {
+			t.Shape = append(t.Shape, int32(d))
+		}
+		defaultData.DataOneof = &proto.DefaultData_Tensor{Tensor: t}
+	} else {
+		ndarray, err := nest(shape, data)
+		if err != nil {
+			return nil, err
+		}
+		array, ok := ndarray.([]interface{})
+		if !ok {
+			array = []interface{}{ndarray}
+		}
+		list, err := structpb.NewList(array)
+		if err != nil {
+			return nil, err
+		}
+		defaultData.DataOneof = &proto.DefaultData_Ndarray{Ndarray: list}
+	}
+	return &proto.SeldonMessage{DataOneof: &proto.SeldonMessage_Data{Data: defaultData}}, nil
+}
+
+func tensorToInferContents(t tensor) *inference.InferTensorContents {
+	contents := &inference.InferTensorContents{}
+	for _, v := range t.data {
+		switch v := v.(type) {
+		case float64:
+			contents.Fp64Contents = append(contents.Fp64Contents, v)
+		case string:
+			contents.ByteContents = append(contents.ByteContents, []byte(v))
+		case bool:
+			contents.BoolContents = append(contents.BoolContents, v)
+		}
+	}
+	return contents
+}
+
+func shape64(shape []int) []int64 {
+	s := make([]int64, len(shape))
+	for i, d := range shape {
+		s[i] = int64(d)
+	}
+	return s
+}
+
+// seldonMessageToInferRequest converts a Seldon message to a V2 request with a single tensor.
+func seldonMessageToInferRequest(sm *proto.SeldonMessage) (*inference.ModelInferRequest, error) {
+	t, err := seldonMessageToTensor(sm, DefaultInputName)
+	if err != nil {
+		return nil, err
+	}
+	return &inference.ModelInferRequest{
+		Inputs: []*inference.ModelInferRequest_InferInputTensor{{
+			Name:     t.name,
+			Datatype: t.datatype,
+			Shape:    shape64(t.shape),
+			Contents: tensorToInferContents(t),
+		}},
+	}, nil
+}
+
+// seldonMessageToInferResponse converts a Seldon message to a V2 response with a single tensor.
+func seldonMessageToInferResponse(sm *proto.SeldonMessage) (*inference.ModelInferResponse, error) {
+	t, err := seldonMessageToTensor(sm, DefaultOutputName)
+	if err != nil {
+		return nil, err
+	}
+	return &inference.ModelInferResponse{
+		Outputs: []*inference.ModelInferResponse_InferOutputTensor{{
+			Name:     t.name,
+			Datatype: t.datatype,
+			Shape:    shape64(t.shape),
+			Contents: tensorToInferContents(t),
+		}},
+	}, nil
+}
+
+func inferRequestToTensors(req *inference.ModelInferRequest) ([]tensor, error) {
+	tensors := make([]tensor, len(req.GetInputs()))
+	for i, input := range req.GetInputs() {
+		var raw []byte
+		if i < len(req.GetRawInputContents()) {
+			raw = req.GetRawInputContents()[i]
+		}
+		t, err := inferTensor(input.GetName(), input.GetDatatype(), input.GetShape(), input.GetContents(), raw)
+		if err != nil {
+			return nil, fmt.Errorf("inputs[%d]: %w", i, err)
+		}
+		tensors[i] = t
+	}
+	return tensors, nil
+}
+
+func inferResponseToTensors(res *inference.ModelInferResponse) ([]tensor, error) {
+	tensors := make([]tensor, len(res.GetOutputs()))
+	for i, output := range res.GetOutputs() {
+		var raw []byte
+		if i < len(res.GetRawOutputContents()) {
+			raw = res.GetRawOutputContents()[i]
+		}
+		t, err := inferTensor(output.GetName(), output.GetDatatype(), output.GetShape(), output.GetContents(), raw)
+		if err != nil {
+			return nil, fmt.Errorf("outputs[%d]: %w", i, err)
+		}
+		tensors[i] = t
+	}
+	return tensors, nil
+}
+
+// inferTensor reads the values of a V2 tensor from its typed contents or, if set, its raw contents.
+func inferTensor(name string, datatype string, shape []int64, contents *inference.InferTensorContents, raw []byte) (tensor, error) {
+	t := tensor{name: name, datatype: datatype}
+	for _, d := range shape {
+		t.shape = append(t.shape, int(d))
+	}
+	if raw != nil {
+		var err error
+		t.data, err = decodeRawContents(datatype, raw)
+		return t, err
+	}
+	switch datatype {
+	case "BOOL":
+		for _, v := range contents.GetBoolContents() {
+			t.data = append(t.data, v)
+		}
+	case "INT8", "INT16", "INT32":
+		for _, v := range contents.GetIntContents() {
+			t.data = append(t.data, float64(v))
+		}
+	case "INT64":
+		for _, v := range contents.GetInt64Contents() {
+			t.data = append(t.data, float64(v))
+		}
+	case "UINT8", "UINT16", "UINT32":
+		for _, v := range contents.GetUintContents() {
+			t.data = append(t.data, float64(v))
+		}
+	case "UINT64":
+		for _, v := range contents.GetUint64Contents() {
+			t.data = append(t.data, float64(v))
+		}
+	case "FP32":
+		for _, v := range contents.GetFp32Contents() {
+			t.data = append(t.data, float64(v))
+		}
+	case "FP64":
+		for _, v := range contents.GetFp64Contents() {
+			t.data = append(t.data, v)
+		}
+	case "BYTES":
+		for _, v := range contents.GetByteContents() {
+			t.data = append(t.data, string(v))
+		}
+	default:
+		return t, fmt.Errorf("datatype %s can't be converted", datatype)
+	}
+	return t, nil
+}
+
+// decodeRawContents reads little endian values, with BYTES elements prefixed by their 4 byte length.
+func decodeRawContents(datatype string, raw []byte) ([]interface{}, error) {
+	var data []interface{}
+	switch datatype {
+	case "BOOL", "INT8", "UINT8":
+		for _, b := range raw {
+			switch datatype {
+			case "BOOL":
+				data = append(data, b != 0)
+			case "INT8":
+				data = append(data, float64(int8(b)))
+			default:
+				data = append(data, float64(b))
+			}
+		}
+	case "INT16", "UINT16":
+		for i := 0; i+2 <= len(raw); i += 2 {
+			v := binary.LittleEndian.Uint16(raw[i:])
+			if datatype == "INT16" {
+				data = append(data, float64(int16(v)))
+			} else {
+				data = append(data, float64(v))
+			}
+		}
+	case "INT32", "UINT32", "FP32":
+		for i := 0; i+4 <= len(raw); i += 4 {
+			v := binary.LittleEndian.Uint32(raw[i:])
+			switch datatype {
+			case "INT32":
+				data = append(data, float64(int32(v)))
+			case "UINT32":
+				data = append(data, float64(v))
+			default:
+				data = append(data, float64(math.Float32frombits(v)))
+			}
+		}
+	case "INT64", "UINT64", "FP64":
+		for i := 0; i+8 <= len(raw); i += 8 {
+			v := binary.LittleEndian.Uint64(raw[i:])
+			switch datatype {
+			case "INT64":
+				data = append(data, float64(int64(v)))
+			case "UINT64":
+				data = append(data, float64(v))
+			default:
+				data = append(data, math.Float64frombits(v))
+			}
+		}
+	case "BYTES":
+		for i := 0; i+4 <= len(raw); {
+			n := int(binary.LittleEndian.Uint32(raw[i:]))
+			i += 4
+			if i+n > len(raw) {
+				return nil, fmt.Errorf("raw BYTES contents are truncated")
+			}
+			data = append(data, string(raw[i:i+n]))
+			i += n
+		}
+	default:
+		return nil, fmt.Errorf("datatype %s can't be converted", datatype)
+	}
+	return data, nil
+}
@@ -0,0 +1,143 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+// decodeJson returns the decompressed JSON object of a REST payload.
+func decodeJson(msg payload.SeldonPayload) (map[string]interface{}, error) {
+	data, err := payload.DecompressSeldonPayload(msg)
+	if err != nil {
+		return nil, err
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func encodeJson(body map[string]interface{}, contentType string) (payload.SeldonPayload, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &payload.BytesPayload{Msg: data, ContentType: contentType}, nil
+}
+
+func isV2Json(body map[string]interface{}) bool {
+	_, inputs := body["inputs"]
+	_, outputs := body["outputs"]
+	return inputs || outputs
+}
+
+// seldonJsonToV2 converts the data of a Seldon message to a V2 request or response with a single tensor.
+func seldonJsonToV2(body map[string]interface{}, response bool) (map[string]interface{}, error) {
+	t := tensor{name: DefaultInputName}
+	if response {
+		t.name = DefaultOutputName
+	}
+	if strData, ok := body["strData"].(string); ok {
+		t.datatype, t.shape, t.data = DataTypeBytes, []int{1}, []interface{}{strData}
+	} else if data, ok := body["data"].(map[string]interface{}); ok {
+		var err error
+		if ndarray, ok := data["ndarray"]; ok {
+			if t.shape, t.data, err = flatten(ndarray); err != nil {
+				return nil, fmt.Errorf("data.ndarray: %w", err)
+			}
+			if t.datatype, err = dataType(t.data); err != nil {
+				return nil, fmt.Errorf("data.ndarray: %w", err)
+			}
+		} else if tensorData, ok := data["tensor"].(map[string]interface{}); ok {
+			var shape []interface{}
+			if shape, t.data, err = flattenTensor(tensorData); err != nil {
+				return nil, fmt.Errorf("data.tensor: %w", err)
+			}
+			for _, d := range shape {
+				n, ok := d.(float64)
+				if !ok {
+					return nil, fmt.Errorf("data.tensor: invalid shape %v", shape)
+				}
+				t.shape = append(t.shape, int(n))
+			}
+			t.datatype = DataTypeFP64
+		} else {
+			return nil, fmt.Errorf("only ndarray and tensor data can be converted to the V2 protocol")
+		}
+	} else {
+		return nil, fmt.Errorf("only data and strData messages can be converted to the V2 protocol")
+	}
+	key := "inputs"
+	if response {
+		key = "outputs"
+	}
+	return map[string]interface{}{
+		key: []interface{}{map[string]interface{}{
+			"name":     t.name,
+			"datatype": t.datatype,
+			"shape":    t.shape,
+			"data":     t.data,
+		}},
+	}, nil
+}
+
+func flattenTensor(tensorData map[string]interface{}) ([]interface{}, []interface{}, error) {
+	shape, ok := tensorData["shape"].([]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("missing shape")
+	}
+	values, _ := tensorData["values"].([]interface{})
+	return shape, values, nil
+}
+
+// v2JsonToSeldon converts the tensors of a V2 request or response to the ndarray of a Seldon message.
+func v2JsonToSeldon(body map[string]interface{}) (map[string]interface{}, error) {
+	key := "outputs"
+	if _, ok := body[key]; !ok {
+		key = "inputs"
+	}
+	items, ok := body[key].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of tensors", key)
+	}
+	tensors := make([]tensor, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] must be a tensor", key, i)
+		}
+		t := tensor{}
+		t.name, _ = m["name"].(string)
+		t.datatype, _ = m["datatype"].(string)
+		shape, _ := m["shape"].([]interface{})
+		for _, d := range shape {
+			n, ok := d.(float64)
+			if !ok {
+				return nil, fmt.Errorf("%s[%d].shape: invalid shape %v", key, i, shape)
+			}
+			t.shape = append(t.shape, int(n))
+		}
+		// Data may be flat or nested in the shape of the tensor
+		var err error
+		if _, t.data, err = flatten(m["data"]); err != nil {
+			return nil, fmt.Errorf("%s[%d].data: %w", key, i, err)
+		}
+		tensors[i] = t
+	}
+	names, shape, data, err := columns(tensors)
+	if err != nil {
+		return nil, err
+	}
+	ndarray, err := nest(shape, data)
+	if err != nil {
+		return nil, err
+	}
+	seldonData := map[string]interface{}{"ndarray": ndarray}
+	if len(names) > 0 {
+		seldonData["names"] = names
+	}
+	return map[string]interface{}{"data": seldonData}, nil
+}
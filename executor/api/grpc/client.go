@@ -4,13 +4,15 @@ import (
 	"context"
 	"github.com/go-logr/logr"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
-	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
-	"github.com/opentracing/opentracing-go"
+	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/k8s"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"strconv"
 	"time"
 )
@@ -24,11 +26,19 @@ func AddMetadataToOutgoingGrpcContext(ctx context.Context, meta map[string][]str
 	return ctx
 }
 
-func AddClientInterceptors(predictor *v1.PredictorSpec, deploymentName, modelName string, annotations map[string]string, log logr.Logger) grpc.DialOption {
-	interceptors := []grpc.UnaryClientInterceptor{metric.NewClientMetrics(predictor, deploymentName, modelName).UnaryClientInterceptor()}
-	if opentracing.IsGlobalTracerRegistered() {
-		interceptors = append(interceptors, grpc_opentracing.UnaryClientInterceptor())
+// ClientTransportCredentials returns the dial option for the transport security of calls to the nodes of the graph.
+func ClientTransportCredentials() grpc.DialOption {
+	if client.ClientTLS != nil {
+		return grpc.WithTransportCredentials(credentials.NewTLS(client.ClientTLS.Config()))
 	}
+	return grpc.WithInsecure()
+}
+
+func AddClientInterceptors(predictor *v1.PredictorSpec, deploymentName, modelName string, annotations map[string]string, log logr.Logger) grpc.DialOption {
+	clientMetrics := metric.NewClientMetrics(predictor, deploymentName, modelName)
+	// The concurrency limit is outermost so a call holds its slot through all its retries. Retries come next so each
+	// attempt goes through the circuit breaker, and is timed, traced and limited by the timeout separately.
+	interceptors := []grpc.UnaryClientInterceptor{unaryClientInterceptorWithConcurrencyLimit(client.ConcurrencyLimits.Node(modelName)), unaryClientInterceptorWithRetry(clientMetrics), unaryClientInterceptorWithCircuitBreaker(client.CircuitBreakers), clientMetrics.UnaryClientInterceptor(), unaryClientInterceptorWithTracing()}
 	if annotations != nil {
 		val := annotations[k8s.ANNOTATION_GRPC_TIMEOUT]
 		if val != "" {
@@ -54,3 +64,62 @@ func unaryClientInterceptorWithTimeout(timeout time.Duration) func(ctx context.C
 		return err
 	}
 }
+
+func unaryClientInterceptorWithRetry(clientMetrics *metric.ClientMetrics) func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		policy := client.RetryPolicyFromContext(ctx)
+		if policy == nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		retrier := client.NewRetrier(policy)
+		return retrier.Do(ctx,
+			func() error {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			},
+			func(err error) bool {
+				code := status.Code(err)
+				// A deadline exceeded on a single attempt may still have been processed by the model
+				return retrier.RetryableGrpcCode(code) || (code == codes.DeadlineExceeded && retrier.Idempotent())
+			},
+			func(err error) {
+				clientMetrics.IncRetries(method, clientMetrics.ModelName, status.Code(err).String())
+			})
+	}
+}
+
+// unaryClientInterceptorWithConcurrencyLimit bounds the calls in flight to the node, including their retries.
+func unaryClientInterceptorWithConcurrencyLimit(limiter *client.ConcurrencyLimiter) func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := limiter.Acquire(ctx); err != nil {
+			return err
+		}
+		defer limiter.Release()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func unaryClientInterceptorWithCircuitBreaker(breakers *client.CircuitBreakerRegistry) func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !breakers.Enabled() {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		breaker := breakers.Get(cc.Target())
+		call, err := breaker.Allow()
+		if err != nil {
+			return err
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		// Calls abandoned by the caller say nothing about the health of the endpoint
+		if ctx.Err() != nil {
+			call.Cancel()
+			return err
+		}
+		failed := false
+		switch status.Code(err) {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown:
+			failed = true
+		}
+		call.Done(failed)
+		return err
+	}
+}
@@ -5,9 +5,15 @@ import (
 	"github.com/golang/protobuf/jsonpb"
 	proto2 "github.com/golang/protobuf/proto"
 	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"reflect"
 	"testing"
 )
@@ -55,3 +61,38 @@ func TestUnmarshal(t *testing.T) {
 	g.Expect(err).Should(BeNil())
 	g.Expect(sm2Str).To(Equal(smStr))
 }
+
+func TestRetryInterceptor(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	predictor := &v1.PredictorSpec{Name: "p", Annotations: map[string]string{}}
+	interceptor := unaryClientInterceptorWithRetry(metric.NewClientMetrics(predictor, "dep", "model"))
+
+	calls := 0
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		if calls < 3 {
+			return status.Error(codes.Unavailable, "unavailable")
+		}
+		return nil
+	}
+
+	// No policy so no retries
+	err := interceptor(context.Background(), "/Model/Predict", nil, nil, nil, invoker)
+	g.Expect(status.Code(err)).To(Equal(codes.Unavailable))
+	g.Expect(calls).To(Equal(1))
+
+	calls = 0
+	ctx := client.ContextWithRetryPolicy(context.Background(), &v1.RetryPolicy{MaxAttempts: 3, InitialBackoffMs: 1, MaxBackoffMs: 1})
+	err = interceptor(ctx, "/Model/Predict", nil, nil, nil, invoker)
+	g.Expect(err).To(BeNil())
+	g.Expect(calls).To(Equal(3))
+
+	calls = 0
+	err = interceptor(ctx, "/Model/Predict", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		return status.Error(codes.InvalidArgument, "bad input")
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	g.Expect(calls).To(Equal(1))
+}
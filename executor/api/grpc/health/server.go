@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Interval between checks of the graph for Watch calls
+const healthWatchInterval = 5 * time.Second
+
+// Server implements the gRPC health checking protocol for the executor. The empty service is serving when
+// the whole graph is ready and the name of a node of the graph can be given to check the node and its children.
+type Server struct {
+	grpc_health_v1.UnimplementedHealthServer
+	Predictor       *predictor.ActivePredictor
+	Protocol        string
+	FullHealthCheck bool
+	Log             logr.Logger
+}
+
+func NewServer(activePredictor *predictor.ActivePredictor, protocol string, fullHealthCheck bool) *Server {
+	return &Server{
+		Predictor:       activePredictor,
+		Protocol:        protocol,
+		FullHealthCheck: fullHealthCheck,
+		Log:             logf.Log.WithName("HealthServer"),
+	}
+}
+
+func (h *Server) status(service string) (grpc_health_v1.HealthCheckResponse_ServingStatus, error) {
+	node := &h.Predictor.Get().Graph
+	if service != "" {
+		if node = v1.GetPredictiveUnit(node, service); node == nil {
+			return grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN, status.Errorf(codes.NotFound, "Unknown service %s", service)
+		}
+	}
+	if err := predictor.Ready(h.Protocol, node, h.FullHealthCheck); err != nil {
+		h.Log.Error(err, "Ready check failed", "service", service)
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING, nil
+	}
+	return grpc_health_v1.HealthCheckResponse_SERVING, nil
+}
+
+func (h *Server) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	servingStatus, err := h.status(req.GetService())
+	if err != nil {
+		return nil, err
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+// Watch sends the status of the service when the watch starts and then whenever it changes.
+func (h *Server) Watch(req *grpc_health_v1.HealthCheckRequest, server grpc_health_v1.Health_WatchServer) error {
+	last := grpc_health_v1.HealthCheckResponse_UNKNOWN
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+	for {
+		// Unknown services are reported rather than failing the watch, as they may be added by a graph reload
+		servingStatus, _ := h.status(req.GetService())
+		if servingStatus != last {
+			if err := server.Send(&grpc_health_v1.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+			last = servingStatus
+		}
+		select {
+		case <-server.Context().Done():
+			return server.Context().Err()
+		case <-ticker.C:
+		}
+	}
+}
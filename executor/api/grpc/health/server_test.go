@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestHealthCheck(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Nothing listens on the port once the listener is closed
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).Should(BeNil())
+	port := lis.Addr().(*net.TCPAddr).Port
+	lis.Close()
+
+	spec := &v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "router",
+			Children: []v1.PredictiveUnit{
+				{Name: "up"},
+				{Name: "down", Endpoint: &v1.Endpoint{ServiceHost: "127.0.0.1", ServicePort: int32(port)}},
+			},
+		},
+	}
+	server := NewServer(predictor.NewActivePredictor(spec), api.ProtocolSeldon, false)
+
+	res, err := server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	g.Expect(err).Should(BeNil())
+	g.Expect(res.Status).Should(Equal(grpc_health_v1.HealthCheckResponse_NOT_SERVING))
+
+	res, err = server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "up"})
+	g.Expect(err).Should(BeNil())
+	g.Expect(res.Status).Should(Equal(grpc_health_v1.HealthCheckResponse_SERVING))
+
+	_, err = server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+	g.Expect(status.Code(err)).Should(Equal(codes.NotFound))
+}
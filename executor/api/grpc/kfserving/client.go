@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
@@ -20,6 +21,7 @@ import (
 type KFServingGrpcClient struct {
 	Log            logr.Logger
 	callOptions    []grpc.CallOption
+	connsMu        sync.Mutex
 	conns          map[string]*grpc.ClientConn
 	Predictor      *v1.PredictorSpec
 	DeploymentName string
@@ -30,8 +32,21 @@ func (s *KFServingGrpcClient) IsGrpc() bool {
 	return true
 }
 
+// Return model's metadata decoded to payload.ModelMetadata, with the tensors as ModelMetadataResponse_TensorMetadata
 func (s *KFServingGrpcClient) ModelMetadata(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.ModelMetadata, error) {
-	panic("implement me")
+	req := &payload.ProtoPayload{Msg: &inference.ModelMetadataRequest{Name: modelName}}
+	resPayload, err := s.Metadata(ctx, modelName, host, port, req, meta)
+	if err != nil {
+		return payload.ModelMetadata{}, err
+	}
+	resp := resPayload.GetPayload().(*inference.ModelMetadataResponse)
+	return payload.ModelMetadata{
+		Name:     resp.GetName(),
+		Platform: resp.GetPlatform(),
+		Versions: resp.GetVersions(),
+		Inputs:   resp.GetInputs(),
+		Outputs:  resp.GetOutputs(),
+	}, nil
 }
 
 func NewKFServingGrpcClient(predictor *v1.PredictorSpec, deploymentName string, annotations map[string]string) client.SeldonApiClient {
@@ -52,11 +67,14 @@ func NewKFServingGrpcClient(predictor *v1.PredictorSpec, deploymentName string,
 
 func (s *KFServingGrpcClient) getConnection(host string, port int32, modelName string) (*grpc.ClientConn, error) {
 	k := fmt.Sprintf("%s:%d", host, port)
+	// Streamed requests are predicted concurrently
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
 	if conn, ok := s.conns[k]; ok {
 		return conn, nil
 	} else {
 		opts := []grpc.DialOption{
-			grpc.WithInsecure(),
+			grpc2.ClientTransportCredentials(),
 		}
 		opts = append(opts, grpc2.AddClientInterceptors(s.Predictor, s.DeploymentName, modelName, s.annotations, s.Log))
 		conn, err := grpc.Dial(fmt.Sprintf("%s:%d", host, port), opts...)
@@ -93,7 +111,7 @@ func (s *KFServingGrpcClient) TransformInput(ctx context.Context, modelName stri
 	panic("implement me")
 }
 
-func (s *KFServingGrpcClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (int, error) {
+func (s *KFServingGrpcClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) ([]int, error) {
 	panic("implement me")
 }
 
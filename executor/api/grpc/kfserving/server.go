@@ -2,7 +2,15 @@ package kfserving
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+
 	"github.com/go-logr/logr"
+	guuid "github.com/google/uuid"
+	"github.com/seldonio/seldon-core/executor/api"
 	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/grpc"
 	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
@@ -10,35 +18,60 @@ import (
 	"github.com/seldonio/seldon-core/executor/predictor"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	protoGrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	protoGrpcMetadata "google.golang.org/grpc/metadata"
-	"net/url"
+	"google.golang.org/grpc/status"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+const (
+	// Maximum number of requests from a ModelStreamInfer stream predicted at the same time
+	DefaultMaxStreamConcurrency = 16
+	ServerName                  = "seldon-core-executor"
+)
+
+// Version of the executor reported by ServerMetadata, set at build time
+var ServerVersion = "unknown"
+
+// Extensions of the V2 protocol supported by the server. The repository index lists the models of the graph, while
+// model configuration, load and unload requests are proxied to the node of the graph owning the model.
+var serverExtensions = []string{"model_configuration", "model_repository", "statistics"}
+
 type GrpcKFServingServer struct {
-	Client    client.SeldonApiClient
-	predictor *v1.PredictorSpec
-	Log       logr.Logger
-	ServerUrl *url.URL
-	Namespace string
+	Client               client.SeldonApiClient
+	Predictor            *predictor.ActivePredictor
+	Log                  logr.Logger
+	ServerUrl            *url.URL
+	Namespace            string
+	MaxStreamConcurrency int
+	FullHealthCheck      bool
+	// Checks requests against the graph metadata, nil if requests aren't validated
+	Validator *predictor.RequestValidator
+	stats     *inferStatistics
 }
 
-func NewGrpcKFServingServer(predictor *v1.PredictorSpec, client client.SeldonApiClient, serverUrl *url.URL, namespace string) *GrpcKFServingServer {
+func NewGrpcKFServingServer(spec *v1.PredictorSpec, client client.SeldonApiClient, serverUrl *url.URL, namespace string) *GrpcKFServingServer {
 	return &GrpcKFServingServer{
-		Client:    client,
-		predictor: predictor,
-		Log:       logf.Log.WithName("KFServingGrpcApi"),
-		ServerUrl: serverUrl,
-		Namespace: namespace,
+		Client:               client,
+		Predictor:            predictor.NewActivePredictor(spec),
+		Log:                  logf.Log.WithName("KFServingGrpcApi"),
+		ServerUrl:            serverUrl,
+		Namespace:            namespace,
+		MaxStreamConcurrency: DefaultMaxStreamConcurrency,
+		stats:                newInferStatistics(),
 	}
 }
 
 func (g GrpcKFServingServer) ServerLive(ctx context.Context, request *inference.ServerLiveRequest) (*inference.ServerLiveResponse, error) {
-	panic("Not Implemented")
+	return &inference.ServerLiveResponse{Live: true}, nil
 }
 
 func (g GrpcKFServingServer) ServerReady(ctx context.Context, request *inference.ServerReadyRequest) (*inference.ServerReadyResponse, error) {
-	panic("Not Implemented")
+	if err := predictor.Ready(api.ProtocolV2, &g.Predictor.Get().Graph, g.FullHealthCheck); err != nil {
+		g.Log.Error(err, "Ready check failed")
+		return &inference.ServerReadyResponse{Ready: false}, nil
+	}
+	return &inference.ServerReadyResponse{Ready: true}, nil
 }
 
 func (g GrpcKFServingServer) ModelReady(ctx context.Context, request *inference.ModelReadyRequest) (*inference.ModelReadyResponse, error) {
@@ -48,7 +81,7 @@ func (g GrpcKFServingServer) ModelReady(ctx context.Context, request *inference.
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("infer"), g.ServerUrl, g.Namespace, md, request.GetName())
 	reqPayload := payload.ProtoPayload{Msg: request}
-	resPayload, err := seldonPredictorProcess.Status(&g.predictor.Graph, request.Name, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Status(&g.Predictor.Get().Graph, request.Name, &reqPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +89,11 @@ func (g GrpcKFServingServer) ModelReady(ctx context.Context, request *inference.
 }
 
 func (g GrpcKFServingServer) ServerMetadata(ctx context.Context, request *inference.ServerMetadataRequest) (*inference.ServerMetadataResponse, error) {
-	panic("Not Implemented")
+	return &inference.ServerMetadataResponse{
+		Name:       ServerName,
+		Version:    ServerVersion,
+		Extensions: serverExtensions,
+	}, nil
 }
 
 func (g GrpcKFServingServer) ModelMetadata(ctx context.Context, request *inference.ModelMetadataRequest) (*inference.ModelMetadataResponse, error) {
@@ -66,7 +103,7 @@ func (g GrpcKFServingServer) ModelMetadata(ctx context.Context, request *inferen
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("infer"), g.ServerUrl, g.Namespace, md, request.GetName())
 	reqPayload := payload.ProtoPayload{Msg: request}
-	resPayload, err := seldonPredictorProcess.Metadata(&g.predictor.Graph, request.Name, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Metadata(&g.Predictor.Get().Graph, request.Name, &reqPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -80,57 +117,247 @@ func (g GrpcKFServingServer) ModelInfer(ctx context.Context, request *inference.
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("infer"), g.ServerUrl, g.Namespace, md, request.GetModelName())
 	reqPayload := payload.ProtoPayload{Msg: request}
-	resPayload, err := seldonPredictorProcess.Predict(&g.predictor.Graph, &reqPayload)
+	start := time.Now()
+	version := g.Predictor.Acquire()
+	defer version.Release()
+	seldonPredictorProcess.Version = version
+	spec := version.Spec
+	if err := g.Validator.Validate(&seldonPredictorProcess, spec, &reqPayload); err != nil {
+		g.stats.record(&spec.Graph, request, start, err)
+		return nil, err
+	}
+	resPayload, err := seldonPredictorProcess.Predict(&spec.Graph, &reqPayload)
+	g.stats.record(&spec.Graph, request, start, err)
 	if err != nil {
 		return nil, err
 	}
 	return resPayload.GetPayload().(*inference.ModelInferResponse), nil
 }
 
+// ModelStreamInfer predicts each request received on the stream through the graph, up to MaxStreamConcurrency at a
+// time, and streams back the responses as they complete. A failed request is answered with its error message and
+// does not end the stream, while a failure to send a response ends it and cancels the requests in flight.
 func (g GrpcKFServingServer) ModelStreamInfer(server inference.GRPCInferenceService_ModelStreamInferServer) error {
-	panic("Not Implemented")
+	md := grpc.CollectMetadata(server.Context())
+	server.SetHeader(protoGrpcMetadata.Pairs(payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0]))
+
+	concurrency := g.MaxStreamConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultMaxStreamConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(server.Context())
+	defer cancel()
+	sendMu := sync.Mutex{}
+	var sendErr error
+	// The stream ends with the error of a failed send rather than the cancellation it caused
+	streamErr := func(err error) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		if sendErr != nil {
+			return sendErr
+		}
+		return err
+	}
+
+	// Requests are received in the background so a failed send ends the stream without waiting for the next one
+	requests := make(chan *inference.ModelInferRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			request, err := server.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case requests <- request:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		var request *inference.ModelInferRequest
+		select {
+		case request = <-requests:
+		case err := <-recvErr:
+			if err != io.EOF {
+				return err
+			}
+			wg.Wait()
+			return streamErr(nil)
+		case <-ctx.Done():
+			return streamErr(ctx.Err())
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return streamErr(ctx.Err())
+		}
+		wg.Add(1)
+		go func(request *inference.ModelInferRequest) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			response := g.streamInfer(ctx, md, request)
+			sendMu.Lock()
+			defer sendMu.Unlock()
+			if sendErr != nil {
+				return
+			}
+			if sendErr = server.Send(response); sendErr != nil {
+				cancel()
+			}
+		}(request)
+	}
+}
+
+func (g GrpcKFServingServer) streamInfer(ctx context.Context, md protoGrpcMetadata.MD, request *inference.ModelInferRequest) *inference.ModelStreamInferResponse {
+	// Each request on the stream is logged and traced on its own
+	puid := request.GetId()
+	if puid == "" {
+		puid = guuid.New().String()
+	}
+	md = md.Copy()
+	md.Set(payload.SeldonPUIDHeader, puid)
+	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, puid)
+	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("infer"), g.ServerUrl, g.Namespace, md, request.GetModelName())
+	reqPayload := payload.ProtoPayload{Msg: request}
+	start := time.Now()
+	version := g.Predictor.Acquire()
+	defer version.Release()
+	seldonPredictorProcess.Version = version
+	spec := version.Spec
+	err := g.Validator.Validate(&seldonPredictorProcess, spec, &reqPayload)
+	var resPayload payload.SeldonPayload
+	if err == nil {
+		resPayload, err = seldonPredictorProcess.Predict(&spec.Graph, &reqPayload)
+	}
+	if err == nil {
+		if response, ok := resPayload.GetPayload().(*inference.ModelInferResponse); ok {
+			g.stats.record(&spec.Graph, request, start, nil)
+			response.Id = request.GetId()
+			return &inference.ModelStreamInferResponse{InferResponse: response}
+		}
+		err = fmt.Errorf("unexpected response type %T", resPayload.GetPayload())
+	}
+	g.stats.record(&spec.Graph, request, start, err)
+	return &inference.ModelStreamInferResponse{
+		ErrorMessage: err.Error(),
+		InferResponse: &inference.ModelInferResponse{
+			ModelName:    request.GetModelName(),
+			ModelVersion: request.GetModelVersion(),
+			Id:           request.GetId(),
+		},
+	}
+}
+
+// nodeClient returns a client for the node of the graph owning the model and the context to call it with, carrying
+// the incoming metadata.
+func (g GrpcKFServingServer) nodeClient(ctx context.Context, modelName string) (inference.GRPCInferenceServiceClient, context.Context, error) {
+	node := v1.GetPredictiveUnit(&g.Predictor.Get().Graph, modelName)
+	if node == nil {
+		return nil, nil, status.Errorf(codes.NotFound, "Failed to find model %s", modelName)
+	}
+	grpcClient, ok := g.Client.(*KFServingGrpcClient)
+	if !ok || node.Endpoint == nil || node.Endpoint.ServiceHost == "" || node.Endpoint.GrpcPort == 0 {
+		return nil, nil, status.Errorf(codes.Unimplemented, "Model %s is not served over gRPC", modelName)
+	}
+	conn, err := grpcClient.getConnection(node.Endpoint.ServiceHost, node.Endpoint.GrpcPort, modelName)
+	if err != nil {
+		return nil, nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return inference.NewGRPCInferenceServiceClient(conn), grpc.AddMetadataToOutgoingGrpcContext(ctx, grpc.CollectMetadata(ctx)), nil
 }
 
 func (g GrpcKFServingServer) ModelConfig(ctx context.Context, request *inference.ModelConfigRequest) (*inference.ModelConfigResponse, error) {
-	panic("Not Implemented")
+	nodeClient, ctx, err := g.nodeClient(ctx, request.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return nodeClient.ModelConfig(ctx, request)
 }
 
+// ModelStatistics returns the statistics of the inference requests received by the executor. Requests for all
+// versions of a model are counted together.
 func (g GrpcKFServingServer) ModelStatistics(ctx context.Context, request *inference.ModelStatisticsRequest) (*inference.ModelStatisticsResponse, error) {
-	panic("Not Implemented")
+	stats := g.stats.get(request.GetName())
+	if len(stats) == 0 && request.GetName() != "" {
+		if v1.GetPredictiveUnit(&g.Predictor.Get().Graph, request.GetName()) == nil {
+			return nil, status.Errorf(codes.NotFound, "Failed to find model %s", request.GetName())
+		}
+		// A model of the graph which has not received any requests yet
+		stats = append(stats, (&modelCounters{}).statistics(request.GetName()))
+	}
+	return &inference.ModelStatisticsResponse{ModelStats: stats}, nil
 }
 
+// RepositoryIndex lists the nodes of the graph with an endpoint, which are ready when they are reachable.
 func (g GrpcKFServingServer) RepositoryIndex(ctx context.Context, request *inference.RepositoryIndexRequest) (*inference.RepositoryIndexResponse, error) {
-	panic("Not Implemented")
+	var models []*inference.RepositoryIndexResponse_ModelIndex
+	for _, node := range v1.GetPredictiveUnitList(&g.Predictor.Get().Graph) {
+		if node.Endpoint == nil || node.Endpoint.ServiceHost == "" {
+			continue
+		}
+		model := &inference.RepositoryIndexResponse_ModelIndex{Name: node.Name, State: "READY"}
+		// Only the node is checked, not the rest of the graph below it
+		leaf := *node
+		leaf.Children = nil
+		if err := predictor.Ready(api.ProtocolV2, &leaf, g.FullHealthCheck); err != nil {
+			if request.GetReady() {
+				continue
+			}
+			model.State, model.Reason = "UNAVAILABLE", err.Error()
+		}
+		models = append(models, model)
+	}
+	return &inference.RepositoryIndexResponse{Models: models}, nil
 }
 
 func (g GrpcKFServingServer) RepositoryModelLoad(ctx context.Context, request *inference.RepositoryModelLoadRequest) (*inference.RepositoryModelLoadResponse, error) {
-	panic("Not Implemented")
+	nodeClient, ctx, err := g.nodeClient(ctx, request.GetModelName())
+	if err != nil {
+		return nil, err
+	}
+	return nodeClient.RepositoryModelLoad(ctx, request)
 }
 
 func (g GrpcKFServingServer) RepositoryModelUnload(ctx context.Context, request *inference.RepositoryModelUnloadRequest) (*inference.RepositoryModelUnloadResponse, error) {
-	panic("Not Implemented")
+	nodeClient, ctx, err := g.nodeClient(ctx, request.GetModelName())
+	if err != nil {
+		return nil, err
+	}
+	return nodeClient.RepositoryModelUnload(ctx, request)
 }
 
+// Shared memory can't be used between clients and models as requests pass through the executor.
+var errSharedMemoryUnimplemented = status.Error(codes.Unimplemented, "Shared memory is not supported by the executor")
+
 func (g GrpcKFServingServer) SystemSharedMemoryStatus(ctx context.Context, request *inference.SystemSharedMemoryStatusRequest) (*inference.SystemSharedMemoryStatusResponse, error) {
-	panic("Not Implemented")
+	return nil, errSharedMemoryUnimplemented
 }
 
 func (g GrpcKFServingServer) SystemSharedMemoryRegister(ctx context.Context, request *inference.SystemSharedMemoryRegisterRequest) (*inference.SystemSharedMemoryRegisterResponse, error) {
-	panic("Not Implemented")
+	return nil, errSharedMemoryUnimplemented
 }
 
 func (g GrpcKFServingServer) SystemSharedMemoryUnregister(ctx context.Context, request *inference.SystemSharedMemoryUnregisterRequest) (*inference.SystemSharedMemoryUnregisterResponse, error) {
-	panic("Not Implemented")
+	return nil, errSharedMemoryUnimplemented
 }
 
 func (g GrpcKFServingServer) CudaSharedMemoryStatus(ctx context.Context, request *inference.CudaSharedMemoryStatusRequest) (*inference.CudaSharedMemoryStatusResponse, error) {
-	panic("Not Implemented")
+	return nil, errSharedMemoryUnimplemented
 }
 
 func (g GrpcKFServingServer) CudaSharedMemoryRegister(ctx context.Context, request *inference.CudaSharedMemoryRegisterRequest) (*inference.CudaSharedMemoryRegisterResponse, error) {
-	panic("Not Implemented")
+	return nil, errSharedMemoryUnimplemented
 }
 
 func (g GrpcKFServingServer) CudaSharedMemoryUnregister(ctx context.Context, request *inference.CudaSharedMemoryUnregisterRequest) (*inference.CudaSharedMemoryUnregisterResponse, error) {
-	panic("Not Implemented")
+	return nil, errSharedMemoryUnimplemented
 }
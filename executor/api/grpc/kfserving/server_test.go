@@ -0,0 +1,320 @@
+package kfserving
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stubModelServer returns the inputs of each request as outputs and fails requests with an input named fail.
+type stubModelServer struct {
+	inference.UnimplementedGRPCInferenceServiceServer
+	delay       time.Duration
+	mu          sync.Mutex
+	inflight    int
+	maxInflight int
+}
+
+func (s *stubModelServer) ModelInfer(ctx context.Context, request *inference.ModelInferRequest) (*inference.ModelInferResponse, error) {
+	s.mu.Lock()
+	s.inflight++
+	if s.inflight > s.maxInflight {
+		s.maxInflight = s.inflight
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inflight--
+		s.mu.Unlock()
+	}()
+	time.Sleep(s.delay)
+
+	response := &inference.ModelInferResponse{ModelName: request.ModelName, Id: request.Id}
+	for _, input := range request.Inputs {
+		if input.Name == "fail" {
+			return nil, status.Error(codes.InvalidArgument, "bad input")
+		}
+		response.Outputs = append(response.Outputs, &inference.ModelInferResponse_InferOutputTensor{
+			Name:     input.Name,
+			Datatype: input.Datatype,
+			Shape:    input.Shape,
+			Contents: input.Contents,
+		})
+	}
+	return response, nil
+}
+
+func (s *stubModelServer) ModelConfig(ctx context.Context, request *inference.ModelConfigRequest) (*inference.ModelConfigResponse, error) {
+	return &inference.ModelConfigResponse{Config: &inference.ModelConfig{Name: request.Name}}, nil
+}
+
+func serveGrpc(g *GomegaWithT, register func(*grpc.Server), opts ...grpc.ServerOption) (*grpc.Server, int) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).Should(BeNil())
+	server := grpc.NewServer(opts...)
+	register(server)
+	go server.Serve(lis)
+	return server, lis.Addr().(*net.TCPAddr).Port
+}
+
+func startExecutor(g *GomegaWithT, model *stubModelServer, concurrency int, opts ...grpc.ServerOption) (inference.GRPCInferenceServiceClient, func()) {
+	modelServer, modelPort := serveGrpc(g, func(s *grpc.Server) {
+		inference.RegisterGRPCInferenceServiceServer(s, model)
+	})
+
+	modelType := v1.MODEL
+	spec := &v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "model",
+			Type: &modelType,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: "127.0.0.1",
+				GrpcPort:    int32(modelPort),
+				Type:        v1.GRPC,
+			},
+		},
+	}
+	serverUrl, _ := url.Parse("http://localhost")
+	executor := NewGrpcKFServingServer(spec, NewKFServingGrpcClient(spec, "dep", nil), serverUrl, "default")
+	executor.MaxStreamConcurrency = concurrency
+	executorServer, executorPort := serveGrpc(g, func(s *grpc.Server) {
+		inference.RegisterGRPCInferenceServiceServer(s, executor)
+	}, opts...)
+
+	conn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%d", executorPort), grpc.WithInsecure())
+	g.Expect(err).Should(BeNil())
+	return inference.NewGRPCInferenceServiceClient(conn), func() {
+		conn.Close()
+		executorServer.Stop()
+		modelServer.Stop()
+	}
+}
+
+func createInferRequest(id string, inputName string) *inference.ModelInferRequest {
+	return &inference.ModelInferRequest{
+		ModelName: "model",
+		Id:        id,
+		Inputs: []*inference.ModelInferRequest_InferInputTensor{{
+			Name:     inputName,
+			Datatype: "INT32",
+			Shape:    []int64{1},
+			Contents: &inference.InferTensorContents{IntContents: []int32{1}},
+		}},
+	}
+}
+
+func TestModelStreamInfer(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client, stop := startExecutor(g, &stubModelServer{}, 0)
+	defer stop()
+
+	stream, err := client.ModelStreamInfer(context.Background())
+	g.Expect(err).Should(BeNil())
+	for i := 0; i < 5; i++ {
+		inputName := "input"
+		if i == 3 {
+			inputName = "fail"
+		}
+		g.Expect(stream.Send(createInferRequest(fmt.Sprint(i), inputName))).Should(BeNil())
+	}
+	g.Expect(stream.CloseSend()).Should(BeNil())
+
+	responses := make(map[string]*inference.ModelStreamInferResponse)
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		g.Expect(err).Should(BeNil())
+		responses[response.GetInferResponse().GetId()] = response
+	}
+	g.Expect(responses).Should(HaveLen(5))
+	for id, response := range responses {
+		if id == "3" {
+			g.Expect(response.ErrorMessage).Should(ContainSubstring("bad input"))
+		} else {
+			g.Expect(response.ErrorMessage).Should(BeEmpty())
+			g.Expect(response.InferResponse.Outputs[0].Contents.IntContents).Should(Equal([]int32{1}))
+		}
+	}
+}
+
+func TestModelStreamInferConcurrency(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	model := &stubModelServer{delay: 20 * time.Millisecond}
+	client, stop := startExecutor(g, model, 2)
+	defer stop()
+
+	stream, err := client.ModelStreamInfer(context.Background())
+	g.Expect(err).Should(BeNil())
+	for i := 0; i < 6; i++ {
+		g.Expect(stream.Send(createInferRequest(fmt.Sprint(i), "input"))).Should(BeNil())
+	}
+	g.Expect(stream.CloseSend()).Should(BeNil())
+
+	received := 0
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		g.Expect(err).Should(BeNil())
+		received++
+	}
+	g.Expect(received).Should(Equal(6))
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	g.Expect(model.maxInflight).Should(Equal(2))
+}
+
+func TestModelStreamInferSendFailure(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Responses are too large to send
+	client, stop := startExecutor(g, &stubModelServer{}, 0, grpc.MaxSendMsgSize(10))
+	defer stop()
+
+	stream, err := client.ModelStreamInfer(context.Background())
+	g.Expect(err).Should(BeNil())
+	g.Expect(stream.Send(createInferRequest("0", "input"))).Should(BeNil())
+
+	// The stream ends with the error while the client is still sending
+	_, err = stream.Recv()
+	g.Expect(status.Code(err)).Should(Equal(codes.ResourceExhausted))
+}
+
+func TestServerHealthAndMetadata(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client, stop := startExecutor(g, &stubModelServer{}, 0)
+	defer stop()
+
+	live, err := client.ServerLive(context.Background(), &inference.ServerLiveRequest{})
+	g.Expect(err).Should(BeNil())
+	g.Expect(live.Live).Should(BeTrue())
+	ready, err := client.ServerReady(context.Background(), &inference.ServerReadyRequest{})
+	g.Expect(err).Should(BeNil())
+	g.Expect(ready.Ready).Should(BeTrue())
+
+	metadata, err := client.ServerMetadata(context.Background(), &inference.ServerMetadataRequest{})
+	g.Expect(err).Should(BeNil())
+	g.Expect(metadata.Name).Should(Equal(ServerName))
+	g.Expect(metadata.Extensions).Should(ConsistOf("model_configuration", "model_repository", "statistics"))
+
+	index, err := client.RepositoryIndex(context.Background(), &inference.RepositoryIndexRequest{Ready: true})
+	g.Expect(err).Should(BeNil())
+	g.Expect(index.Models).Should(HaveLen(1))
+	g.Expect(index.Models[0].Name).Should(Equal("model"))
+	g.Expect(index.Models[0].State).Should(Equal("READY"))
+}
+
+func TestServerNotReady(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Nothing listens on the port once the listener is closed
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).Should(BeNil())
+	port := lis.Addr().(*net.TCPAddr).Port
+	lis.Close()
+
+	spec := &v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name:     "model",
+			Endpoint: &v1.Endpoint{ServiceHost: "127.0.0.1", ServicePort: int32(port)},
+		},
+	}
+	serverUrl, _ := url.Parse("http://localhost")
+	server := NewGrpcKFServingServer(spec, NewKFServingGrpcClient(spec, "dep", nil), serverUrl, "default")
+	ready, err := server.ServerReady(context.Background(), &inference.ServerReadyRequest{})
+	g.Expect(err).Should(BeNil())
+	g.Expect(ready.Ready).Should(BeFalse())
+
+	index, err := server.RepositoryIndex(context.Background(), &inference.RepositoryIndexRequest{})
+	g.Expect(err).Should(BeNil())
+	g.Expect(index.Models).Should(HaveLen(1))
+	g.Expect(index.Models[0].State).Should(Equal("UNAVAILABLE"))
+	index, err = server.RepositoryIndex(context.Background(), &inference.RepositoryIndexRequest{Ready: true})
+	g.Expect(err).Should(BeNil())
+	g.Expect(index.Models).Should(BeEmpty())
+}
+
+func TestModelStatistics(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client, stop := startExecutor(g, &stubModelServer{}, 0)
+	defer stop()
+
+	_, err := client.ModelInfer(context.Background(), createInferRequest("1", "input"))
+	g.Expect(err).Should(BeNil())
+	_, err = client.ModelInfer(context.Background(), createInferRequest("2", "fail"))
+	g.Expect(err).ShouldNot(BeNil())
+	stream, err := client.ModelStreamInfer(context.Background())
+	g.Expect(err).Should(BeNil())
+	g.Expect(stream.Send(createInferRequest("3", "input"))).Should(BeNil())
+	g.Expect(stream.CloseSend()).Should(BeNil())
+	_, err = stream.Recv()
+	g.Expect(err).Should(BeNil())
+	_, err = stream.Recv()
+	g.Expect(err).Should(Equal(io.EOF))
+
+	stats, err := client.ModelStatistics(context.Background(), &inference.ModelStatisticsRequest{Name: "model"})
+	g.Expect(err).Should(BeNil())
+	g.Expect(stats.ModelStats).Should(HaveLen(1))
+	modelStats := stats.ModelStats[0]
+	g.Expect(modelStats.InferenceCount).Should(Equal(uint64(2)))
+	g.Expect(modelStats.ExecutionCount).Should(Equal(uint64(2)))
+	g.Expect(modelStats.LastInference).ShouldNot(BeZero())
+	g.Expect(modelStats.InferenceStats.Success.Count).Should(Equal(uint64(2)))
+	g.Expect(modelStats.InferenceStats.Fail.Count).Should(Equal(uint64(1)))
+
+	_, err = client.ModelStatistics(context.Background(), &inference.ModelStatisticsRequest{Name: "unknown"})
+	g.Expect(status.Code(err)).Should(Equal(codes.NotFound))
+
+	// Requests for models which are not in the graph are counted together
+	for _, name := range []string{"other1", "other2"} {
+		request := createInferRequest("4", "input")
+		request.ModelName = name
+		client.ModelInfer(context.Background(), request)
+	}
+	stats, err = client.ModelStatistics(context.Background(), &inference.ModelStatisticsRequest{})
+	g.Expect(err).Should(BeNil())
+	g.Expect(stats.ModelStats).Should(HaveLen(2))
+	g.Expect(stats.ModelStats[0].Name).Should(Equal(unknownModelName))
+	g.Expect(stats.ModelStats[0].InferenceStats.Success.Count + stats.ModelStats[0].InferenceStats.Fail.Count).Should(Equal(uint64(2)))
+	g.Expect(stats.ModelStats[1].Name).Should(Equal("model"))
+}
+
+func TestModelConfigAndRepository(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client, stop := startExecutor(g, &stubModelServer{}, 0)
+	defer stop()
+
+	config, err := client.ModelConfig(context.Background(), &inference.ModelConfigRequest{Name: "model"})
+	g.Expect(err).Should(BeNil())
+	g.Expect(config.Config.Name).Should(Equal("model"))
+	_, err = client.ModelConfig(context.Background(), &inference.ModelConfigRequest{Name: "unknown"})
+	g.Expect(status.Code(err)).Should(Equal(codes.NotFound))
+
+	// The model server's own status is returned for proxied requests
+	_, err = client.RepositoryModelLoad(context.Background(), &inference.RepositoryModelLoadRequest{ModelName: "model"})
+	g.Expect(status.Code(err)).Should(Equal(codes.Unimplemented))
+	_, err = client.SystemSharedMemoryStatus(context.Background(), &inference.SystemSharedMemoryStatusRequest{})
+	g.Expect(status.Code(err)).Should(Equal(codes.Unimplemented))
+}
@@ -0,0 +1,107 @@
+package kfserving
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// Name the statistics of requests for models which are not in the graph are recorded under, so the names sent by
+// clients can't grow the statistics without bound. It is not a valid node name.
+const unknownModelName = "<unknown>"
+
+type modelCounters struct {
+	lastInference  time.Time
+	inferenceCount uint64
+	executionCount uint64
+	success        inference.StatisticDuration
+	fail           inference.StatisticDuration
+}
+
+// inferStatistics counts the inference requests received by the executor for each model of the graph, whatever
+// the version requested.
+type inferStatistics struct {
+	mu     sync.Mutex
+	models map[string]*modelCounters
+}
+
+func newInferStatistics() *inferStatistics {
+	return &inferStatistics{models: make(map[string]*modelCounters)}
+}
+
+func (s *inferStatistics) record(graph *v1.PredictiveUnit, request *inference.ModelInferRequest, start time.Time, err error) {
+	end := time.Now()
+	name := request.GetModelName()
+	if v1.GetPredictiveUnit(graph, name) == nil {
+		name = unknownModelName
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counters, ok := s.models[name]
+	if !ok {
+		counters = &modelCounters{}
+		s.models[name] = counters
+	}
+	duration := &counters.success
+	if err != nil {
+		duration = &counters.fail
+	} else {
+		counters.lastInference = end
+		counters.inferenceCount += inferenceCount(request)
+		counters.executionCount++
+	}
+	duration.Count++
+	duration.Ns += uint64(end.Sub(start).Nanoseconds())
+}
+
+// inferenceCount returns the batch size of a request, taken from the first dimension of its first input.
+func inferenceCount(request *inference.ModelInferRequest) uint64 {
+	inputs := request.GetInputs()
+	if len(inputs) > 0 && len(inputs[0].GetShape()) > 1 && inputs[0].GetShape()[0] > 0 {
+		return uint64(inputs[0].GetShape()[0])
+	}
+	return 1
+}
+
+// get returns the statistics of the model with the name, or of all models if it is empty.
+func (s *inferStatistics) get(name string) []*inference.ModelStatistics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stats []*inference.ModelStatistics
+	for modelName, counters := range s.models {
+		if name != "" && modelName != name {
+			continue
+		}
+		stats = append(stats, counters.statistics(modelName))
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+func (c *modelCounters) statistics(name string) *inference.ModelStatistics {
+	var lastInference uint64
+	if !c.lastInference.IsZero() {
+		lastInference = uint64(c.lastInference.UnixNano() / int64(time.Millisecond))
+	}
+	success := c.success
+	fail := c.fail
+	return &inference.ModelStatistics{
+		Name:           name,
+		LastInference:  lastInference,
+		InferenceCount: c.inferenceCount,
+		ExecutionCount: c.executionCount,
+		InferenceStats: &inference.InferStatistics{
+			Success:       &success,
+			Fail:          &fail,
+			Queue:         &inference.StatisticDuration{},
+			ComputeInput:  &inference.StatisticDuration{},
+			ComputeInfer:  &inference.StatisticDuration{},
+			ComputeOutput: &inference.StatisticDuration{},
+		},
+	}
+}
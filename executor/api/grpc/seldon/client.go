@@ -16,6 +16,7 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/util"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"io"
 	"math"
 	"net/http"
@@ -100,7 +101,7 @@ func (s *SeldonMessageGrpcClient) getConnection(host string, port int32, modelNa
 
 func (s *SeldonMessageGrpcClient) createNewConn(modelName, host string, port int32) (*grpc.ClientConn, error) {
 	opts := []grpc.DialOption{
-		grpc.WithInsecure(),
+		grpc2.ClientTransportCredentials(),
 	}
 
 	opts = append(opts, grpc2.AddClientInterceptors(s.Predictor, s.DeploymentName, modelName, s.annotations, s.Log))
@@ -143,19 +144,21 @@ func (s *SeldonMessageGrpcClient) TransformInput(ctx context.Context, modelName
 	return &resPayload, nil
 }
 
-func (s *SeldonMessageGrpcClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (int, error) {
+func (s *SeldonMessageGrpcClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) ([]int, error) {
 	conn, err := s.getConnection(host, port, modelName)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	grpcClient := proto.NewRouterClient(conn)
 	resp, err := grpcClient.Route(grpc2.AddMetadataToOutgoingGrpcContext(ctx, meta), msg.GetPayload().(*proto.SeldonMessage), s.callOptions...)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	routes := util.ExtractRouteFromSeldonMessage(resp)
-	//Only returning first route. API could be extended to allow multiple routes
-	return routes[0], nil
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("router returned no routes")
+	}
+	return routes, nil
 }
 
 func (s *SeldonMessageGrpcClient) Combine(ctx context.Context, modelName string, host string, port int32, msgs []payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
@@ -219,8 +222,23 @@ func (s *SeldonMessageGrpcClient) CreateErrorPayload(err error) payload.SeldonPa
 	return &res
 }
 
+// Status returns the response of the node to a gRPC health check, failing if the node isn't serving.
 func (s *SeldonMessageGrpcClient) Status(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
-	return nil, errors.Errorf("Not implemented")
+	conn, err := s.getConnection(host, port, modelName)
+	if err != nil {
+		return s.CreateErrorPayload(err), err
+	}
+	grpcClient := grpc_health_v1.NewHealthClient(conn)
+	resp, err := grpcClient.Check(grpc2.AddMetadataToOutgoingGrpcContext(ctx, meta), &grpc_health_v1.HealthCheckRequest{}, s.callOptions...)
+	if err != nil {
+		return s.CreateErrorPayload(err), err
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		err = errors.Errorf("Model %s is %s", modelName, resp.Status)
+		return s.CreateErrorPayload(err), err
+	}
+	resPayload := payload.ProtoPayload{Msg: resp}
+	return &resPayload, nil
 }
 
 // Return model's metadata as payload.SeldonPaylaod (to expose as received on corresponding executor endpoint)
@@ -18,16 +18,18 @@ import (
 
 type GrpcSeldonServer struct {
 	Client    client.SeldonApiClient
-	predictor *v1.PredictorSpec
+	Predictor *predictor.ActivePredictor
 	Log       logr.Logger
 	ServerUrl *url.URL
 	Namespace string
+	// Checks requests against the graph metadata, nil if requests aren't validated
+	Validator *predictor.RequestValidator
 }
 
-func NewGrpcSeldonServer(predictor *v1.PredictorSpec, client client.SeldonApiClient, serverUrl *url.URL, namespace string) *GrpcSeldonServer {
+func NewGrpcSeldonServer(spec *v1.PredictorSpec, client client.SeldonApiClient, serverUrl *url.URL, namespace string) *GrpcSeldonServer {
 	return &GrpcSeldonServer{
 		Client:    client,
-		predictor: predictor,
+		Predictor: predictor.NewActivePredictor(spec),
 		Log:       logf.Log.WithName("SeldonGrpcApi"),
 		ServerUrl: serverUrl,
 		Namespace: namespace,
@@ -41,7 +43,14 @@ func (g GrpcSeldonServer) Predict(ctx context.Context, req *proto.SeldonMessage)
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("SeldonMessageRestClient"), g.ServerUrl, g.Namespace, md, "")
 	reqPayload := payload.ProtoPayload{Msg: req}
-	resPayload, err := seldonPredictorProcess.Predict(&g.predictor.Graph, &reqPayload)
+	version := g.Predictor.Acquire()
+	defer version.Release()
+	seldonPredictorProcess.Version = version
+	spec := version.Spec
+	if err := g.Validator.Validate(&seldonPredictorProcess, spec, &reqPayload); err != nil {
+		return nil, err
+	}
+	resPayload, err := seldonPredictorProcess.Predict(&spec.Graph, &reqPayload)
 	if err != nil {
 		g.Log.Error(err, "Failed to call predict")
 		return payloadToMessage(resPayload), err
@@ -55,7 +64,10 @@ func (g GrpcSeldonServer) SendFeedback(ctx context.Context, req *proto.Feedback)
 	protoGrpc.SetHeader(ctx, header)
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("SeldonMessageRestClient"), g.ServerUrl, g.Namespace, md, "")
 	reqPayload := payload.ProtoPayload{Msg: req}
-	resPayload, err := seldonPredictorProcess.Feedback(&g.predictor.Graph, &reqPayload)
+	version := g.Predictor.Acquire()
+	defer version.Release()
+	seldonPredictorProcess.Version = version
+	resPayload, err := seldonPredictorProcess.Feedback(&version.Spec.Graph, &reqPayload)
 	if err != nil {
 		g.Log.Error(err, "Failed to call feedback")
 		return payloadToMessage(resPayload), err
@@ -65,7 +77,7 @@ func (g GrpcSeldonServer) SendFeedback(ctx context.Context, req *proto.Feedback)
 
 func (g GrpcSeldonServer) ModelMetadata(ctx context.Context, req *proto.SeldonModelMetadataRequest) (*proto.SeldonModelMetadata, error) {
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("SeldonMessageRestClient"), g.ServerUrl, g.Namespace, grpc.CollectMetadata(ctx), req.GetName())
-	resPayload, err := seldonPredictorProcess.Metadata(&g.predictor.Graph, req.GetName(), nil)
+	resPayload, err := seldonPredictorProcess.Metadata(&g.Predictor.Get().Graph, req.GetName(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +88,7 @@ func (g GrpcSeldonServer) GraphMetadata(ctx context.Context, req *empty.Empty) (
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("SeldonMessageRestClient"), g.ServerUrl, g.Namespace, grpc.CollectMetadata(ctx), "")
 
-	graphMetadata, err := seldonPredictorProcess.GraphMetadata(g.predictor)
+	graphMetadata, err := seldonPredictorProcess.GraphMetadata(g.Predictor.Get())
 	if err != nil {
 		return nil, err
 	}
@@ -4,22 +4,29 @@ import (
 	"context"
 	"math"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	guuid "github.com/google/uuid"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
-	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
-	"github.com/opentracing/opentracing-go"
+	"github.com/seldonio/seldon-core/executor/api/auth"
+	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/k8s"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	ProtobufContentType = "application/protobuf"
+
+	// Health checks are served without authentication
+	healthServicePrefix = "/grpc.health.v1.Health/"
 )
 
 func getMaxMsgSizeFromAnnotations(annotations map[string]string) (int, error) {
@@ -38,6 +45,7 @@ func getMaxMsgSizeFromAnnotations(annotations map[string]string) (int, error) {
 
 func CreateGrpcServer(spec *v1.PredictorSpec, deploymentName string, annotations map[string]string, logger logr.Logger) (*grpc.Server, error) {
 	maxMsgSize := math.MaxInt32
+	var requestDeadline time.Duration
 	// Update from annotations
 	if annotations != nil {
 		sizeFromAnnotation, err := getMaxMsgSizeFromAnnotations(annotations)
@@ -46,6 +54,10 @@ func CreateGrpcServer(spec *v1.PredictorSpec, deploymentName string, annotations
 		} else if sizeFromAnnotation > 0 {
 			maxMsgSize = sizeFromAnnotation
 		}
+		requestDeadline, err = client.GetRequestDeadlineFromAnnotations(annotations)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	logger.Info("Setting max message size ", "size", maxMsgSize)
@@ -54,16 +66,144 @@ func CreateGrpcServer(spec *v1.PredictorSpec, deploymentName string, annotations
 		grpc.MaxSendMsgSize(maxMsgSize),
 	}
 
-	interceptors := []grpc.UnaryServerInterceptor{metric.NewServerMetrics(spec, deploymentName).UnaryServerInterceptor()}
-	if opentracing.IsGlobalTracerRegistered() {
-		interceptors = append(interceptors, grpc_opentracing.UnaryServerInterceptor())
+	interceptors := []grpc.UnaryServerInterceptor{metric.NewServerMetrics(spec, deploymentName).UnaryServerInterceptor(), unaryServerInterceptorWithDeadline(requestDeadline), unaryServerInterceptorWithTracing()}
+	var streamInterceptors []grpc.StreamServerInterceptor
+	if auth.ServerAuthenticator != nil {
+		interceptors = append([]grpc.UnaryServerInterceptor{unaryServerInterceptorWithAuth(auth.ServerAuthenticator)}, interceptors...)
+		streamInterceptors = append(streamInterceptors, streamServerInterceptorWithAuth(auth.ServerAuthenticator))
+	}
+	if limiter := client.ConcurrencyLimits.Server(); limiter != nil {
+		interceptors = append(interceptors, unaryServerInterceptorWithConcurrencyLimit(limiter))
+		streamInterceptors = append(streamInterceptors, streamServerInterceptorWithConcurrencyLimit(limiter))
 	}
 	opts = append(opts, grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(interceptors...)))
+	if len(streamInterceptors) > 0 {
+		opts = append(opts, grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamInterceptors...)))
+	}
 
 	grpcServer := grpc.NewServer(opts...)
 	return grpcServer, nil
 }
 
+// unaryServerInterceptorWithDeadline bounds the time spent on the request by the gRPC deadline, the
+// Seldon-Deadline-Ms metadata and the default deadline. The gRPC deadline is passed on to each node by the client.
+func unaryServerInterceptorWithDeadline(deadline time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requested := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if vals := md.Get(payload.SeldonDeadlineHeader); len(vals) > 0 {
+				requested = vals[0]
+				md = md.Copy()
+				md.Delete(payload.SeldonDeadlineHeader)
+				ctx = metadata.NewIncomingContext(ctx, md)
+			}
+		}
+		ctx, cancel, err := client.ContextWithRequestDeadline(ctx, requested, deadline)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// authenticate checks the bearer token or API key in the metadata of calls to methods other than health checks. It
+// returns the context of the call without the credentials, so they aren't sent on to the graph's components, unless
+// they are forwarded.
+func authenticate(ctx context.Context, authenticator auth.Authenticator, fullMethod string) (context.Context, error) {
+	if strings.HasPrefix(fullMethod, healthServicePrefix) {
+		return ctx, nil
+	}
+	var authorization, apiKey string
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		if vals := md.Get(auth.AuthorizationHeader); len(vals) > 0 {
+			authorization = vals[0]
+		}
+		if vals := md.Get(auth.APIKeyHeader); len(vals) > 0 {
+			apiKey = vals[0]
+		}
+	}
+	if err := authenticator.Authenticate(auth.Token(authorization, apiKey)); err != nil {
+		return ctx, status.Error(codes.Unauthenticated, "invalid or missing credentials")
+	}
+	if !auth.ForwardCredentials {
+		md = md.Copy()
+		md.Delete(auth.AuthorizationHeader)
+		md.Delete(auth.APIKeyHeader)
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	return ctx, nil
+}
+
+func unaryServerInterceptorWithAuth(authenticator auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, authenticator, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authenticatedStream is a server stream with the context returned by authenticate.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func streamServerInterceptorWithAuth(authenticator auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), authenticator, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// acquire waits for a slot of the limiter for calls to methods other than health checks, returning a release
+// function. The Retry-After header is sent with calls which are rejected.
+func acquire(ctx context.Context, limiter *client.ConcurrencyLimiter, fullMethod string) (func(), error) {
+	if strings.HasPrefix(fullMethod, healthServicePrefix) {
+		return func() {}, nil
+	}
+	if err := limiter.Acquire(ctx); err != nil {
+		if lerr, ok := err.(*client.LimitExceededError); ok {
+			grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(client.RetryAfterHeader), lerr.RetryAfterSeconds()))
+			return nil, err
+		}
+		return nil, status.FromContextError(err).Err()
+	}
+	return limiter.Release, nil
+}
+
+func unaryServerInterceptorWithConcurrencyLimit(limiter *client.ConcurrencyLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		release, err := acquire(ctx, limiter, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+func streamServerInterceptorWithConcurrencyLimit(limiter *client.ConcurrencyLimiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := acquire(ss.Context(), limiter, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return handler(srv, ss)
+	}
+}
+
 func CollectMetadata(ctx context.Context) metadata.MD {
 	if mdFromIncoming, ok := metadata.FromIncomingContext(ctx); ok {
 		val := mdFromIncoming.Get(payload.SeldonPUIDHeader)
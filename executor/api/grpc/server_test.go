@@ -3,10 +3,20 @@ package grpc
 import (
 	"context"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/auth"
+	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func TestAddPuid(t *testing.T) {
@@ -28,3 +38,140 @@ func TestExistingPuid(t *testing.T) {
 	g.Expect(meta.Get(payload.SeldonPUIDHeader)).NotTo(BeNil())
 	g.Expect(meta.Get(payload.SeldonPUIDHeader)[0]).To(Equal(puid))
 }
+
+func TestDeadlineServerInterceptor(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	interceptor := unaryServerInterceptorWithDeadline(time.Minute)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(payload.SeldonDeadlineHeader, "100"))
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		remaining, ok := client.RemainingMs(ctx)
+		g.Expect(ok).To(BeTrue())
+		g.Expect(remaining).To(BeNumerically("<=", 100))
+		md, _ := metadata.FromIncomingContext(ctx)
+		g.Expect(md.Get(payload.SeldonDeadlineHeader)).To(BeEmpty())
+		return nil, nil
+	})
+	g.Expect(err).To(BeNil())
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(payload.SeldonDeadlineHeader, "abc"))
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+}
+
+type testAuthenticator string
+
+func (a testAuthenticator) Authenticate(token string) error {
+	if token != string(a) {
+		return auth.ErrUnauthenticated
+	}
+	return nil
+}
+
+func TestAuthServerInterceptor(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	interceptor := unaryServerInterceptorWithAuth(testAuthenticator("secret"))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	predict := &grpc.UnaryServerInfo{FullMethod: "/seldon.protos.Seldon/Predict"}
+
+	_, err := interceptor(context.Background(), nil, predict, handler)
+	g.Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	res, err := interceptor(ctx, nil, predict, handler)
+	g.Expect(err).To(BeNil())
+	g.Expect(res).To(Equal("ok"))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "wrong"))
+	_, err = interceptor(ctx, nil, predict, handler)
+	g.Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+	g.Expect(err).To(BeNil())
+}
+
+func TestAuthServerInterceptorRemovesCredentials(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	interceptor := unaryServerInterceptorWithAuth(testAuthenticator("secret"))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return md, nil
+	}
+	predict := &grpc.UnaryServerInfo{FullMethod: "/seldon.protos.Seldon/Predict"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret", "x-tenant", "a"))
+
+	res, err := interceptor(ctx, nil, predict, handler)
+	g.Expect(err).To(BeNil())
+	g.Expect(res).To(Equal(metadata.Pairs("x-tenant", "a")))
+
+	auth.ForwardCredentials = true
+	defer func() { auth.ForwardCredentials = false }()
+	res, err = interceptor(ctx, nil, predict, handler)
+	g.Expect(err).To(BeNil())
+	g.Expect(res.(metadata.MD).Get("authorization")).To(Equal([]string{"Bearer secret"}))
+}
+
+func TestConcurrencyLimitServerInterceptor(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	limiter := client.NewConcurrencyLimitRegistry(&client.ConcurrencyLimitSettings{MaxInFlight: 1, QueueTimeout: time.Second}).Server()
+	interceptor := unaryServerInterceptorWithConcurrencyLimit(limiter)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	predict := &grpc.UnaryServerInfo{FullMethod: "/seldon.protos.Seldon/Predict"}
+
+	res, err := interceptor(context.Background(), nil, predict, handler)
+	g.Expect(err).To(BeNil())
+	g.Expect(res).To(Equal("ok"))
+
+	g.Expect(limiter.Acquire(context.Background())).To(BeNil())
+	defer limiter.Release()
+	_, err = interceptor(context.Background(), nil, predict, handler)
+	g.Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+
+	// Health checks aren't limited
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+	g.Expect(err).To(BeNil())
+}
+
+func TestTracingInterceptors(t *testing.T) {
+	g := NewGomegaWithT(t)
+	exporter, reset := test.SetupTestTracing()
+	defer reset()
+
+	traceId := "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", "00-"+traceId+"-00f067aa0ba902b7-01"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/seldon.protos.Seldon/Predict"}
+	_, err := unaryServerInterceptorWithTracing()(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		// Calls to the nodes continue the trace of the request
+		invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			g.Expect(md.Get("traceparent")).To(HaveLen(1))
+			g.Expect(md.Get("traceparent")[0]).To(ContainSubstring(traceId))
+			g.Expect(md.Get(payload.SeldonPUIDHeader)).To(Equal([]string{"1"}))
+			return status.Error(codes.Unavailable, "node down")
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, payload.SeldonPUIDHeader, "1")
+		return nil, unaryClientInterceptorWithTracing()(ctx, "/seldon.protos.Model/Predict", nil, nil, nil, invoker)
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.Unavailable))
+
+	spans := exporter.GetSpans()
+	g.Expect(spans).To(HaveLen(2))
+	clientSpan, serverSpan := spans[0], spans[1]
+	g.Expect(serverSpan.Name).To(Equal("/seldon.protos.Seldon/Predict"))
+	g.Expect(serverSpan.SpanKind).To(Equal(trace.SpanKindServer))
+	g.Expect(serverSpan.Parent.TraceID().String()).To(Equal(traceId))
+	g.Expect(serverSpan.Status.Code).To(Equal(otelcodes.Error))
+	g.Expect(clientSpan.Name).To(Equal("/seldon.protos.Model/Predict"))
+	g.Expect(clientSpan.SpanKind).To(Equal(trace.SpanKindClient))
+	g.Expect(clientSpan.Parent.SpanID()).To(Equal(serverSpan.SpanContext.SpanID()))
+	g.Expect(clientSpan.Attributes).To(ContainElement(semconv.RPCGRPCStatusCodeKey.Int(int(codes.Unavailable))))
+}
@@ -54,7 +54,7 @@ func (s *TensorflowGrpcClient) getConnection(host string, port int32, modelName
 		return conn, nil
 	} else {
 		opts := []grpc.DialOption{
-			grpc.WithInsecure(),
+			grpc2.ClientTransportCredentials(),
 		}
 		opts = append(opts, grpc2.AddClientInterceptors(s.Predictor, s.DeploymentName, modelName, s.annotations, s.Log))
 		conn, err := grpc.Dial(fmt.Sprintf("%s:%d", host, port), opts...)
@@ -115,7 +115,7 @@ func (s *TensorflowGrpcClient) TransformInput(ctx context.Context, modelName str
 	return s.Predict(ctx, modelName, host, port, msg, meta)
 }
 
-func (s *TensorflowGrpcClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (int, error) {
+func (s *TensorflowGrpcClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) ([]int, error) {
 	panic("Not implemented")
 }
 
@@ -104,7 +104,7 @@ func (s TestTensorflowClient) TransformInput(ctx context.Context, modelName stri
 	panic("")
 }
 
-func (s TestTensorflowClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (int, error) {
+func (s TestTensorflowClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) ([]int, error) {
 	panic("")
 }
 
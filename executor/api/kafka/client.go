@@ -99,10 +99,10 @@ func (kc *KafkaClient) TransformInput(ctx context.Context, modelName string, hos
 	return kc.kafkaRPC(msg, meta, modelName, client.SeldonTransformInputPath)
 }
 
-func (kc *KafkaClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (int, error) {
+func (kc *KafkaClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) ([]int, error) {
 	res, err := kc.kafkaRPC(msg, meta, modelName, client.SeldonRoutePath)
 	if err != nil {
-		return 0, err
+		return nil, err
 	} else {
 		return util.ExtractRouteFromSeldonJson(res)
 	}
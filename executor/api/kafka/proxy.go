@@ -2,7 +2,7 @@ package kafka
 
 import (
 	"context"
-	"github.com/cloudevents/sdk-go/pkg/bindings/http"
+	"github.com/cloudevents/sdk-go/v2/protocol/http"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/go-logr/logr"
 	"github.com/seldonio/seldon-core/executor/api/client"
@@ -9,7 +9,7 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/cloudevents/sdk-go/pkg/bindings/http"
+	"github.com/cloudevents/sdk-go/v2/protocol/http"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/go-logr/logr"
 	proto2 "github.com/golang/protobuf/proto"
@@ -46,7 +46,7 @@ type SeldonKafkaServer struct {
 	DeploymentName  string
 	Namespace       string
 	Transport       string
-	Predictor       *v1.PredictorSpec
+	Predictor       *predictor.ActivePredictor
 	Broker          string
 	TopicIn         string
 	TopicOut        string
@@ -67,7 +67,7 @@ func NewKafkaServer(
 	transport string,
 	annotations map[string]string,
 	serverUrl *url.URL,
-	predictor *v1.PredictorSpec,
+	spec *v1.PredictorSpec,
 	broker,
 	topicIn,
 	topicOut string,
@@ -80,21 +80,21 @@ func NewKafkaServer(
 
 	if fullGraph {
 		log.Info("Starting full graph kafka server")
-		apiClient = NewKafkaClient(serverUrl.Hostname(), deploymentName, namespace, protocol, transport, predictor, broker, log)
+		apiClient = NewKafkaClient(serverUrl.Hostname(), deploymentName, namespace, protocol, transport, spec, broker, log)
 	} else {
 		switch transport {
 		case api.TransportRest:
 			log.Info("Start http kafka graph")
-			apiClient, err = rest.NewJSONRestClient(protocol, deploymentName, predictor, annotations)
+			apiClient, err = rest.NewJSONRestClient(protocol, deploymentName, spec, annotations)
 			if err != nil {
 				return nil, err
 			}
 		case api.TransportGrpc:
 			log.Info("Start grpc kafka graph")
 			if protocol == "seldon" {
-				apiClient = seldon.NewSeldonGrpcClient(predictor, deploymentName, annotations)
+				apiClient = seldon.NewSeldonGrpcClient(spec, deploymentName, annotations)
 			} else {
-				apiClient = tensorflow.NewTensorflowGrpcClient(predictor, deploymentName, annotations)
+				apiClient = tensorflow.NewTensorflowGrpcClient(spec, deploymentName, annotations)
 			}
 		default:
 			return nil, fmt.Errorf("Unknown transport %s", transport)
@@ -124,7 +124,7 @@ func NewKafkaServer(
 		DeploymentName:  deploymentName,
 		Namespace:       namespace,
 		Transport:       transport,
-		Predictor:       predictor,
+		Predictor:       predictor.NewActivePredictor(spec),
 		Broker:          broker,
 		TopicIn:         topicIn,
 		TopicOut:        topicOut,
@@ -138,7 +138,7 @@ func NewKafkaServer(
 }
 
 func (ks *SeldonKafkaServer) getGroupName() string {
-	return ks.Predictor.Name + "." + ks.DeploymentName + "." + ks.Namespace
+	return ks.Predictor.Get().Name + "." + ks.DeploymentName + "." + ks.Namespace
 }
 
 func collectHeaders(headers []kafka.Header) map[string][]string {
@@ -198,7 +198,7 @@ func (ks *SeldonKafkaServer) Serve() error {
 	//wait for graph to be ready
 	ready := false
 	for ready == false {
-		err := predictor.Ready(ks.Protocol, &ks.Predictor.Graph, ks.FullHealthCheck)
+		err := predictor.Ready(ks.Protocol, &ks.Predictor.Get().Graph, ks.FullHealthCheck)
 		ready = err == nil
 		if !ready {
 			ks.Log.Info("Waiting for graph to be ready")
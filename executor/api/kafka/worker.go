@@ -2,12 +2,16 @@ package kafka
 
 import (
 	"context"
+	"strings"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
-	"github.com/opentracing/opentracing-go"
-	"github.com/opentracing/opentracing-go/ext"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/predictor"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -34,23 +38,27 @@ func (ks *SeldonKafkaServer) processKafkaRequest(job *KafkaJob) {
 	// Add Seldon Puid to Context
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, job.headers[payload.SeldonPUIDHeader][0])
 
-	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		tracer := opentracing.GlobalTracer()
-		serverSpan := tracer.StartSpan("kafkaServer", ext.RPCServerOption(nil))
-		ctx = opentracing.ContextWithSpan(ctx, serverSpan)
-		defer serverSpan.Finish()
-	}
+	// Continue the trace propagated in the headers of the request
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier(job.headers))
+	ctx, serverSpan := tracing.Tracer().Start(ctx, "kafkaServer",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(semconv.MessagingSystemKey.String("kafka"), semconv.MessagingDestinationKindTopic, semconv.MessagingDestinationKey.String(ks.TopicIn), semconv.MessagingOperationProcess))
+	defer serverSpan.End()
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, ks.Client, logf.Log.WithName("KafkaClient"), ks.ServerUrl, ks.Namespace, job.headers, "")
 
-	resPayload, err := seldonPredictorProcess.Predict(&ks.Predictor.Graph, job.reqPayload)
+	version := ks.Predictor.Acquire()
+	defer version.Release()
+	seldonPredictorProcess.Version = version
+	resPayload, err := seldonPredictorProcess.Predict(&version.Spec.Graph, job.reqPayload)
 	if err != nil {
+		tracing.RecordError(serverSpan, err)
 		ks.Log.Error(err, "Failed prediction")
 		return
 	}
 	resBytes, err := resPayload.GetBytes()
 	if err != nil {
+		tracing.RecordError(serverSpan, err)
 		ks.Log.Error(err, "Failed to get bytes from prediction response")
 		return
 	}
@@ -62,6 +70,13 @@ func (ks *SeldonKafkaServer) processKafkaRequest(job *KafkaJob) {
 	//	kafkaHeaders = []kafka.Header{{Key: KeyProtoName, Value: []byte(proto2.MessageName(*resPayload.GetPayload().(*proto2.Message)))}}
 	//}
 
+	// Propagate the trace to the consumers of the response
+	traceHeaders := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, traceHeaders)
+	for k, v := range traceHeaders {
+		kafkaHeaders = append(kafkaHeaders, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
 	err = ks.Producer.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &ks.TopicOut, Partition: kafka.PartitionAny},
 		Key:            job.message.Key,
@@ -70,6 +85,7 @@ func (ks *SeldonKafkaServer) processKafkaRequest(job *KafkaJob) {
 	}, nil)
 
 	if err != nil {
+		tracing.RecordError(serverSpan, err)
 		ks.Log.Error(err, "Failed to produce response")
 	}
 
@@ -83,3 +99,14 @@ func (ks *SeldonKafkaServer) processKafkaRequest(job *KafkaJob) {
 	}
 
 }
+
+// headerCarrier returns the first value of each Kafka header, with the lower case keys used by propagators.
+func headerCarrier(headers map[string][]string) propagation.MapCarrier {
+	carrier := propagation.MapCarrier{}
+	for k, vals := range headers {
+		if len(vals) > 0 {
+			carrier[strings.ToLower(k)] = vals[0]
+		}
+	}
+	return carrier
+}
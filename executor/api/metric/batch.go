@@ -0,0 +1,30 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type BatchMetrics struct {
+	BatchSizeHistogram *prometheus.HistogramVec
+}
+
+func NewBatchMetrics() *BatchMetrics {
+	histogram := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    BatchSizeMetricName,
+			Help:    "A histogram of the number of rows in the batches the executor sends to each model",
+			Buckets: BatchBuckets,
+		},
+		[]string{ModelNameMetric},
+	)
+	err := prometheus.Register(histogram)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			histogram = e.ExistingCollector.(*prometheus.HistogramVec)
+		}
+	}
+
+	return &BatchMetrics{
+		BatchSizeHistogram: histogram,
+	}
+}
@@ -0,0 +1,45 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type CacheMetrics struct {
+	HitsCounter   *prometheus.CounterVec
+	MissesCounter *prometheus.CounterVec
+}
+
+func NewCacheMetrics() *CacheMetrics {
+	hits := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: CacheHitsMetricName,
+			Help: "A counter of graph node responses served from the executor response cache",
+		},
+		[]string{ModelNameMetric},
+	)
+	err := prometheus.Register(hits)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			hits = e.ExistingCollector.(*prometheus.CounterVec)
+		}
+	}
+
+	misses := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: CacheMissesMetricName,
+			Help: "A counter of graph node requests not found in the executor response cache",
+		},
+		[]string{ModelNameMetric},
+	)
+	err = prometheus.Register(misses)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			misses = e.ExistingCollector.(*prometheus.CounterVec)
+		}
+	}
+
+	return &CacheMetrics{
+		HitsCounter:   hits,
+		MissesCounter: misses,
+	}
+}
@@ -0,0 +1,45 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type CircuitBreakerMetrics struct {
+	StateGauge      *prometheus.GaugeVec
+	RejectedCounter *prometheus.CounterVec
+}
+
+func NewCircuitBreakerMetrics() *CircuitBreakerMetrics {
+	gauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: CircuitBreakerStateMetricName,
+			Help: "State of the circuit breaker for each graph endpoint: 0 closed, 1 half-open, 2 open",
+		},
+		[]string{EndpointMetric},
+	)
+	err := prometheus.Register(gauge)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			gauge = e.ExistingCollector.(*prometheus.GaugeVec)
+		}
+	}
+
+	counter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: CircuitBreakerRejectedMetricName,
+			Help: "A counter of client calls from executor rejected by an open circuit breaker",
+		},
+		[]string{EndpointMetric},
+	)
+	err = prometheus.Register(counter)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			counter = e.ExistingCollector.(*prometheus.CounterVec)
+		}
+	}
+
+	return &CircuitBreakerMetrics{
+		StateGauge:      gauge,
+		RejectedCounter: counter,
+	}
+}
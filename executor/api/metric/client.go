@@ -14,6 +14,7 @@ import (
 type ClientMetrics struct {
 	ClientHandledHistogram *prometheus.HistogramVec
 	ClientHandledSummary   *prometheus.SummaryVec
+	ClientRetriesCounter   *prometheus.CounterVec
 	Predictor              *v1.PredictorSpec
 	DeploymentName         string
 	ModelName              string
@@ -23,6 +24,7 @@ type ClientMetrics struct {
 
 var RecreateClientHistogram = false
 var RecreateClientSummary = false
+var RecreateClientRetriesCounter = false
 
 func NewClientMetrics(spec *v1.PredictorSpec, deploymentName string, modelName string) *ClientMetrics {
 	labelNames := []string{DeploymentNameMetric, PredictorNameMetric, PredictorVersionMetric, ServiceMetric, ModelNameMetric, ModelImageMetric, ModelVersionMetric, "method", "code"}
@@ -69,6 +71,25 @@ func NewClientMetrics(spec *v1.PredictorSpec, deploymentName string, modelName s
 		}
 	}
 
+	retries := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: ClientRetriesMetricName,
+			Help: "A counter of client calls from executor retried after a failure",
+		},
+		[]string{DeploymentNameMetric, PredictorNameMetric, PredictorVersionMetric, ServiceMetric, ModelNameMetric, CodeMetric},
+	)
+	err = prometheus.Register(retries)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if RecreateClientRetriesCounter {
+				prometheus.Unregister(e.ExistingCollector)
+				prometheus.Register(retries)
+			} else {
+				retries = e.ExistingCollector.(*prometheus.CounterVec)
+			}
+		}
+	}
+
 	container := v1.GetContainerForPredictiveUnit(spec, modelName)
 	imageName := ""
 	imageVersion := ""
@@ -83,6 +104,7 @@ func NewClientMetrics(spec *v1.PredictorSpec, deploymentName string, modelName s
 	return &ClientMetrics{
 		ClientHandledHistogram: histogram,
 		ClientHandledSummary:   summary,
+		ClientRetriesCounter:   retries,
 		Predictor:              spec,
 		DeploymentName:         deploymentName,
 		ModelName:              modelName,
@@ -102,3 +124,8 @@ func (m *ClientMetrics) UnaryClientInterceptor() func(ctx context.Context, metho
 		return err
 	}
 }
+
+// IncRetries counts a retried call to the given service of a model
+func (m *ClientMetrics) IncRetries(service string, modelName string, code string) {
+	m.ClientRetriesCounter.WithLabelValues(m.DeploymentName, m.Predictor.Name, m.Predictor.Annotations["version"], service, modelName, code).Inc()
+}
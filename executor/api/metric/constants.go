@@ -10,9 +10,29 @@ const (
 	ModelNameMetric        = "model_name"
 	ModelImageMetric       = "model_image"
 	ModelVersionMetric     = "model_version"
+	EndpointMetric         = "endpoint"
+	LimitScopeMetric       = "scope" // server or node
+	ReasonMetric           = "reason"
 
 	ServerRequestsMetricName = "seldon_api_executor_server_requests_seconds"
 	ClientRequestsMetricName = "seldon_api_executor_client_requests_seconds"
+	ClientRetriesMetricName  = "seldon_api_executor_client_retries_total"
+
+	CircuitBreakerStateMetricName    = "seldon_api_executor_circuit_breaker_state"
+	CircuitBreakerRejectedMetricName = "seldon_api_executor_circuit_breaker_rejected_total"
+
+	CacheHitsMetricName   = "seldon_api_executor_cache_hits_total"
+	CacheMissesMetricName = "seldon_api_executor_cache_misses_total"
+
+	BatchSizeMetricName = "seldon_api_executor_batch_size"
+
+	InflightRequestsMetricName = "seldon_api_executor_inflight_requests"
+	QueuedRequestsMetricName   = "seldon_api_executor_queued_requests"
+	RejectedRequestsMetricName = "seldon_api_executor_rejected_requests_total"
+
+	LoggerDroppedMetricName      = "seldon_api_executor_logger_dropped_total"
+	LoggerSpoolRecordsMetricName = "seldon_api_executor_logger_spool_records"
+	LoggerSpoolBytesMetricName   = "seldon_api_executor_logger_spool_bytes"
 
 	PredictionHttpServiceName = "predictions"
 	StatusHttpServiceName     = "status"
@@ -22,5 +42,6 @@ const (
 
 var (
 	DefBuckets    = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+	BatchBuckets  = []float64{1, 2, 4, 8, 16, 32, 64, 128, 256, 512}
 	DefObjectives = map[float64]float64{0.5: 0.05, 0.75: 0.025, 0.9: 0.01, 0.98: 0.002, 0.99: 0.001, 1.0: 0}
 )
@@ -0,0 +1,61 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ConcurrencyLimitMetrics struct {
+	InflightGauge   *prometheus.GaugeVec
+	QueuedGauge     *prometheus.GaugeVec
+	RejectedCounter *prometheus.CounterVec
+}
+
+func NewConcurrencyLimitMetrics() *ConcurrencyLimitMetrics {
+	inflight := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: InflightRequestsMetricName,
+			Help: "Requests being processed by the executor, for the server or a graph node",
+		},
+		[]string{LimitScopeMetric, ModelNameMetric},
+	)
+	err := prometheus.Register(inflight)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			inflight = e.ExistingCollector.(*prometheus.GaugeVec)
+		}
+	}
+
+	queued := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: QueuedRequestsMetricName,
+			Help: "Requests waiting for the concurrency limit of the server or a graph node",
+		},
+		[]string{LimitScopeMetric, ModelNameMetric},
+	)
+	err = prometheus.Register(queued)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			queued = e.ExistingCollector.(*prometheus.GaugeVec)
+		}
+	}
+
+	rejected := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: RejectedRequestsMetricName,
+			Help: "A counter of requests rejected by the executor as the server or a graph node was saturated",
+		},
+		[]string{LimitScopeMetric, ModelNameMetric},
+	)
+	err = prometheus.Register(rejected)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			rejected = e.ExistingCollector.(*prometheus.CounterVec)
+		}
+	}
+
+	return &ConcurrencyLimitMetrics{
+		InflightGauge:   inflight,
+		QueuedGauge:     queued,
+		RejectedCounter: rejected,
+	}
+}
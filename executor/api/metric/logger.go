@@ -0,0 +1,59 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type LoggerMetrics struct {
+	DroppedCounter    *prometheus.CounterVec
+	SpoolRecordsGauge prometheus.Gauge
+	SpoolBytesGauge   prometheus.Gauge
+}
+
+func NewLoggerMetrics() *LoggerMetrics {
+	dropped := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: LoggerDroppedMetricName,
+			Help: "A counter of payload logs dropped by the executor without being sent",
+		},
+		[]string{ReasonMetric},
+	)
+	err := prometheus.Register(dropped)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			dropped = e.ExistingCollector.(*prometheus.CounterVec)
+		}
+	}
+
+	records := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: LoggerSpoolRecordsMetricName,
+			Help: "Payload logs in the spool of the executor waiting to be sent",
+		},
+	)
+	err = prometheus.Register(records)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			records = e.ExistingCollector.(prometheus.Gauge)
+		}
+	}
+
+	size := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: LoggerSpoolBytesMetricName,
+			Help: "Size in bytes of the spool files of the executor payload logger",
+		},
+	)
+	err = prometheus.Register(size)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			size = e.ExistingCollector.(prometheus.Gauge)
+		}
+	}
+
+	return &LoggerMetrics{
+		DroppedCounter:    dropped,
+		SpoolRecordsGauge: records,
+		SpoolBytesGauge:   size,
+	}
+}
@@ -7,6 +7,12 @@ import (
 const (
 	SeldonPUIDHeader        = "Seldon-Puid"
 	SeldonSkipLoggingHeader = "Seldon-Skip-Logging"
+	// Set to true to log the payloads of a request whatever the sample percentage of the logger
+	SeldonForceLoggingHeader = "Seldon-Force-Logging"
+	// Set to true to skip the response cache of graph nodes
+	SeldonCacheBypassHeader = "Seldon-Cache-Bypass"
+	// Remaining time in msecs the caller will wait for a response
+	SeldonDeadlineHeader = "Seldon-Deadline-Ms"
 )
 
 type MetaData struct {
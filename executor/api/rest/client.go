@@ -13,10 +13,9 @@ import (
 	"strings"
 	"time"
 
-	http2 "github.com/cloudevents/sdk-go/pkg/bindings/http"
+	http2 "github.com/cloudevents/sdk-go/v2/protocol/http"
 	"github.com/go-logr/logr"
 	"github.com/golang/protobuf/jsonpb"
-	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -25,9 +24,12 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/api/util"
 	"github.com/seldonio/seldon-core/executor/k8s"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -158,7 +160,7 @@ func (smc *JSONRestClient) getMetricsRoundTripper(modelName string, service stri
 		metric.ModelNameMetric:        modelName,
 		metric.ModelImageMetric:       imageName,
 		metric.ModelVersionMetric:     imageVersion,
-	}), http.DefaultTransport)
+	}), client.HttpTransport())
 
 	return promhttp.InstrumentRoundTripperDuration(smc.metrics.ClientHandledSummary.MustCurryWith(prometheus.Labels{
 		metric.DeploymentNameMetric:   smc.DeploymentName,
@@ -205,28 +207,25 @@ func (smc *JSONRestClient) doHttp(ctx context.Context, modelName string, method
 	// Add metadata passed in
 	smc.addHeaders(req, meta)
 
-	if opentracing.IsGlobalTracerRegistered() {
-		tracer := opentracing.GlobalTracer()
-
-		startSpanOptions := make([]opentracing.StartSpanOption, 0)
-		parentSpan := opentracing.SpanFromContext(ctx)
-		if parentSpan != nil {
-			startSpanOptions = append(startSpanOptions, opentracing.ChildOf(parentSpan.Context()))
-		}
-		clientSpan := opentracing.StartSpan(
-			method,
-			startSpanOptions...)
-		defer clientSpan.Finish()
-		tracer.Inject(clientSpan.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
+	// Cancel the call when the request is cancelled or runs out of time and tell the node how long it has
+	req = req.WithContext(ctx)
+	if remaining, ok := client.RemainingMs(ctx); ok {
+		req.Header.Set(payload.SeldonDeadlineHeader, strconv.FormatInt(remaining, 10))
 	}
 
+	_, clientSpan := tracing.StartClientSpan(ctx, req.Header, method, trace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(req)...))
+	defer clientSpan.End()
+
 	client := smc.httpClient
 	client.Transport = smc.getMetricsRoundTripper(modelName, method)
 
 	response, err := client.Do(req)
 	if err != nil {
+		tracing.RecordError(clientSpan, err)
 		return nil, "", "", err
 	}
+	clientSpan.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(response.StatusCode)...)
+	clientSpan.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(response.StatusCode, trace.SpanKindClient))
 
 	//Read response
 	b, err := ioutil.ReadAll(response.Body)
@@ -286,7 +285,7 @@ func (smc *JSONRestClient) modifyMethod(method string, modelName string) string
 
 func (smc *JSONRestClient) call(ctx context.Context, modelName string, method string, host string, port int32, req payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
 	url := url.URL{
-		Scheme: "http",
+		Scheme: client.HttpScheme(),
 		Host:   net.JoinHostPort(host, strconv.Itoa(int(port))),
 		Path:   method,
 	}
@@ -299,7 +298,46 @@ func (smc *JSONRestClient) call(ctx context.Context, modelName string, method st
 		contentEncoding = req.GetContentEncoding()
 	}
 
-	sm, contentType, contentEncoding, err := smc.doHttp(ctx, modelName, method, &url, bytes, meta, contentType, contentEncoding)
+	limiter := client.ConcurrencyLimits.Node(modelName)
+	if err := limiter.Acquire(ctx); err != nil {
+		return smc.CreateErrorPayload(err), err
+	}
+	defer limiter.Release()
+
+	var sm []byte
+	var contentTypeResponse, contentEncodingResponse string
+	retrier := client.NewRetrier(client.RetryPolicyFromContext(ctx))
+	breaker := client.CircuitBreakers.Get(url.Host)
+	err := retrier.Do(ctx,
+		func() error {
+			call, err := breaker.Allow()
+			if err != nil {
+				return err
+			}
+			sm, contentTypeResponse, contentEncodingResponse, err = smc.doHttp(ctx, modelName, method, &url, bytes, meta, contentType, contentEncoding)
+			// Calls abandoned by the caller say nothing about the health of the endpoint
+			if ctx.Err() == nil {
+				serr, ok := err.(*httpStatusError)
+				call.Done((err != nil && !ok) || (ok && serr.StatusCode >= http.StatusInternalServerError))
+			} else {
+				call.Cancel()
+			}
+			return err
+		},
+		func(err error) bool {
+			if serr, ok := err.(*httpStatusError); ok {
+				return retrier.RetryableStatusCode(serr.StatusCode)
+			}
+			// Transport failures may happen after the model has received the request
+			return retrier.Idempotent()
+		},
+		func(err error) {
+			code := "error"
+			if serr, ok := err.(*httpStatusError); ok {
+				code = strconv.Itoa(serr.StatusCode)
+			}
+			smc.metrics.IncRetries(method, modelName, code)
+		})
 
 	// Check if a httpStatusError was returned.
 	if err != nil {
@@ -308,7 +346,7 @@ func (smc *JSONRestClient) call(ctx context.Context, modelName string, method st
 		}
 	}
 
-	res := payload.BytesPayload{Msg: sm, ContentType: contentType, ContentEncoding: contentEncoding}
+	res := payload.BytesPayload{Msg: sm, ContentType: contentTypeResponse, ContentEncoding: contentEncodingResponse}
 	return &res, err
 }
 
@@ -361,10 +399,10 @@ func (smc *JSONRestClient) TransformInput(ctx context.Context, modelName string,
 }
 
 // Try to extract from SeldonMessage otherwise fall back to extract from Json Array
-func (smc *JSONRestClient) Route(ctx context.Context, modelName string, host string, port int32, req payload.SeldonPayload, meta map[string][]string) (int, error) {
+func (smc *JSONRestClient) Route(ctx context.Context, modelName string, host string, port int32, req payload.SeldonPayload, meta map[string][]string) ([]int, error) {
 	sp, err := smc.call(ctx, modelName, smc.modifyMethod(client.SeldonRoutePath, modelName), host, port, req, meta)
 	if err != nil {
-		return 0, err
+		return nil, err
 	} else {
 		return util.ExtractRouteFromSeldonJson(sp)
 	}
@@ -17,6 +17,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
@@ -135,7 +136,7 @@ func TestRouter(t *testing.T) {
 	route, err := seldonRestClient.Route(createTestContext(), "model", host, int32(port), createPayload(g), map[string][]string{})
 	g.Expect(err).Should(BeNil())
 
-	g.Expect(route).Should(Equal(1))
+	g.Expect(route).Should(Equal([]int{1}))
 }
 
 func TestStatus(t *testing.T) {
@@ -381,3 +382,105 @@ func TestMarshall(t *testing.T) {
 		g.Expect(w.String()).To(Equal(test.expected))
 	}
 }
+
+func TestPredictRetriesUnavailable(t *testing.T) {
+	g := NewGomegaWithT(t)
+	calls := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(okPredictResponse))
+	})
+	host, port, httpClient, teardown := testingHTTPClient(g, h)
+	defer teardown()
+	predictor := v1.PredictorSpec{
+		Name:        "test",
+		Annotations: map[string]string{},
+	}
+	seldonRestClient, err := NewJSONRestClient(api.ProtocolSeldon, "test", &predictor, nil, SetHTTPClient(httpClient))
+	g.Expect(err).To(BeNil())
+
+	ctx := client.ContextWithRetryPolicy(createTestContext(), &v1.RetryPolicy{MaxAttempts: 3, InitialBackoffMs: 1, MaxBackoffMs: 2})
+	resPayload, err := seldonRestClient.Predict(ctx, "model", host, int32(port), createPayload(g), map[string][]string{})
+	g.Expect(err).Should(BeNil())
+	g.Expect(calls).To(Equal(3))
+	data := string(resPayload.GetPayload().([]byte))
+	g.Expect(data).To(Equal(okPredictResponse))
+}
+
+func TestPredictRetriesExhausted(t *testing.T) {
+	g := NewGomegaWithT(t)
+	calls := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	host, port, httpClient, teardown := testingHTTPClient(g, h)
+	defer teardown()
+	predictor := v1.PredictorSpec{
+		Name:        "test",
+		Annotations: map[string]string{},
+	}
+	seldonRestClient, err := NewJSONRestClient(api.ProtocolSeldon, "test", &predictor, nil, SetHTTPClient(httpClient))
+	g.Expect(err).To(BeNil())
+
+	ctx := client.ContextWithRetryPolicy(createTestContext(), &v1.RetryPolicy{MaxAttempts: 2, InitialBackoffMs: 1, MaxBackoffMs: 2})
+	_, err = seldonRestClient.Predict(ctx, "model", host, int32(port), createPayload(g), map[string][]string{})
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(err.(*httpStatusError).StatusCode).To(Equal(http.StatusServiceUnavailable))
+	g.Expect(calls).To(Equal(2))
+}
+
+func TestPredictNoRetryWithoutPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+	calls := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	host, port, httpClient, teardown := testingHTTPClient(g, h)
+	defer teardown()
+	predictor := v1.PredictorSpec{
+		Name:        "test",
+		Annotations: map[string]string{},
+	}
+	seldonRestClient, err := NewJSONRestClient(api.ProtocolSeldon, "test", &predictor, nil, SetHTTPClient(httpClient))
+	g.Expect(err).To(BeNil())
+
+	_, err = seldonRestClient.Predict(createTestContext(), "model", host, int32(port), createPayload(g), map[string][]string{})
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(calls).To(Equal(1))
+}
+
+func TestPredictCircuitBreakerOpens(t *testing.T) {
+	g := NewGomegaWithT(t)
+	calls := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	host, port, httpClient, teardown := testingHTTPClient(g, h)
+	defer teardown()
+	predictor := v1.PredictorSpec{
+		Name:        "test",
+		Annotations: map[string]string{},
+	}
+	seldonRestClient, err := NewJSONRestClient(api.ProtocolSeldon, "test", &predictor, nil, SetHTTPClient(httpClient))
+	g.Expect(err).To(BeNil())
+
+	breakers := client.CircuitBreakers
+	defer func() { client.CircuitBreakers = breakers }()
+	client.CircuitBreakers = client.NewCircuitBreakerRegistry(&client.CircuitBreakerSettings{ConsecutiveFailures: 2, MinRequests: 20, Window: time.Minute, OpenTimeout: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		_, err = seldonRestClient.Predict(createTestContext(), "model", host, int32(port), createPayload(g), map[string][]string{})
+		g.Expect(err.(*httpStatusError).StatusCode).To(Equal(http.StatusInternalServerError))
+	}
+	_, err = seldonRestClient.Predict(createTestContext(), "model", host, int32(port), createPayload(g), map[string][]string{})
+	g.Expect(err).To(BeAssignableToTypeOf(&client.CircuitOpenError{}))
+	g.Expect(calls).To(Equal(2))
+	g.Expect(client.CircuitBreakers.States()).To(HaveKeyWithValue(net.JoinHostPort(host, strconv.Itoa(port)), "open"))
+}
@@ -2,8 +2,11 @@ package rest
 
 import (
 	"net/http"
+	"time"
 
 	guuid "github.com/google/uuid"
+	"github.com/seldonio/seldon-core/executor/api/auth"
+	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/api/util"
 )
@@ -48,6 +51,94 @@ func (h *CloudeventHeaderMiddleware) Middleware(next http.Handler) http.Handler
 	})
 }
 
+type RequestDeadlineMiddleware struct {
+	deadline time.Duration
+}
+
+// Middleware bounds the time spent on the request by the Seldon-Deadline-Ms header and the default deadline.
+// The header is removed as the remaining time is sent on to each node of the graph by the client.
+func (h *RequestDeadlineMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel, err := client.ContextWithRequestDeadline(r.Context(), r.Header.Get(payload.SeldonDeadlineHeader), h.deadline)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer cancel()
+		r.Header.Del(payload.SeldonDeadlineHeader)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type AuthMiddleware struct {
+	authenticator auth.Authenticator
+	// Paths served without authentication
+	exemptPaths map[string]bool
+}
+
+func NewAuthMiddleware(authenticator auth.Authenticator, exemptPaths ...string) *AuthMiddleware {
+	m := &AuthMiddleware{authenticator: authenticator, exemptPaths: make(map[string]bool)}
+	for _, path := range exemptPaths {
+		m.exemptPaths[path] = true
+	}
+	return m
+}
+
+// Middleware rejects requests to paths which aren't exempt without a valid bearer token or API key. The credentials
+// of accepted requests are removed so they aren't sent on to the graph's components, unless they are forwarded.
+func (h *AuthMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.exemptPaths[r.URL.Path] {
+			token := auth.Token(r.Header.Get(auth.AuthorizationHeader), r.Header.Get(auth.APIKeyHeader))
+			if err := h.authenticator.Authenticate(token); err != nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			if !auth.ForwardCredentials {
+				r.Header.Del(auth.AuthorizationHeader)
+				r.Header.Del(auth.APIKeyHeader)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type ConcurrencyLimitMiddleware struct {
+	limiter *client.ConcurrencyLimiter
+	// Paths served without waiting for the limit
+	exemptPaths map[string]bool
+}
+
+func NewConcurrencyLimitMiddleware(limiter *client.ConcurrencyLimiter, exemptPaths ...string) *ConcurrencyLimitMiddleware {
+	m := &ConcurrencyLimitMiddleware{limiter: limiter, exemptPaths: make(map[string]bool)}
+	for _, path := range exemptPaths {
+		m.exemptPaths[path] = true
+	}
+	return m
+}
+
+// Middleware sheds requests with 429 when the server has too many requests in flight and queued.
+func (h *ConcurrencyLimitMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.exemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := h.limiter.Acquire(r.Context()); err != nil {
+			if lerr, ok := err.(*client.LimitExceededError); ok {
+				w.Header().Set(client.RetryAfterHeader, lerr.RetryAfterSeconds())
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+			} else {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			}
+			return
+		}
+		defer h.limiter.Release()
+		next.ServeHTTP(w, r)
+	})
+}
+
 // handleCORSRequests adds CORS-required headers, and during CORS Preflight
 // requests, it will exit the request and the request status will be
 // http.StatusOK
@@ -5,8 +5,11 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/auth"
+	"github.com/seldonio/seldon-core/executor/api/client"
 )
 
 func TestEnvVars(t *testing.T) {
@@ -93,3 +96,101 @@ func TestXSSMiddleware(t *testing.T) {
 	headerVal := res.Header.Get(contentTypeOptsHeader)
 	g.Expect(headerVal).To(Equal(contentTypeOptsValue))
 }
+
+type testAuthenticator string
+
+func (a testAuthenticator) Authenticate(token string) error {
+	if token != string(a) {
+		return auth.ErrUnauthenticated
+	}
+	return nil
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	m := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	wrapped := NewAuthMiddleware(testAuthenticator("secret"), "/live").Middleware(m)
+
+	serve := func(path string, header string, value string) *http.Response {
+		req := httptest.NewRequest("POST", "http://example.com"+path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		wrapped.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	res := serve("/api/v1.0/predictions", "", "")
+	g.Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+	g.Expect(res.Header.Get("WWW-Authenticate")).To(Equal("Bearer"))
+
+	g.Expect(serve("/api/v1.0/predictions", auth.AuthorizationHeader, "Bearer wrong").StatusCode).To(Equal(http.StatusUnauthorized))
+	g.Expect(serve("/api/v1.0/predictions", auth.AuthorizationHeader, "Bearer secret").StatusCode).To(Equal(http.StatusOK))
+	g.Expect(serve("/api/v1.0/predictions", auth.APIKeyHeader, "secret").StatusCode).To(Equal(http.StatusOK))
+	g.Expect(serve("/live", "", "").StatusCode).To(Equal(http.StatusOK))
+}
+
+func TestAuthMiddlewareRemovesCredentials(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var forwarded http.Header
+	m := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header
+	})
+	wrapped := NewAuthMiddleware(testAuthenticator("secret")).Middleware(m)
+
+	serve := func() {
+		req := httptest.NewRequest("POST", "http://example.com/api/v1.0/predictions", nil)
+		req.Header.Set(auth.APIKeyHeader, "secret")
+		req.Header.Set(auth.AuthorizationHeader, "Basic other")
+		req.Header.Set("X-Tenant", "a")
+		wrapped.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve()
+	g.Expect(forwarded.Get(auth.APIKeyHeader)).To(BeEmpty())
+	g.Expect(forwarded.Get(auth.AuthorizationHeader)).To(BeEmpty())
+	g.Expect(forwarded.Get("X-Tenant")).To(Equal("a"))
+
+	auth.ForwardCredentials = true
+	defer func() { auth.ForwardCredentials = false }()
+	serve()
+	g.Expect(forwarded.Get(auth.APIKeyHeader)).To(Equal("secret"))
+}
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	limiter := client.NewConcurrencyLimitRegistry(&client.ConcurrencyLimitSettings{MaxInFlight: 1, QueueTimeout: 2500 * time.Millisecond}).Server()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	m := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/live" {
+			close(entered)
+			<-release
+		}
+	})
+	wrapped := NewConcurrencyLimitMiddleware(limiter, "/live").Middleware(m)
+
+	done := make(chan struct{})
+	go func() {
+		wrapped.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "http://example.com/api/v1.0/predictions", nil))
+		close(done)
+	}()
+	// The request in flight holds the only slot
+	<-entered
+
+	w := httptest.NewRecorder()
+	wrapped.ServeHTTP(w, httptest.NewRequest("POST", "http://example.com/api/v1.0/predictions", nil))
+	g.Expect(w.Code).To(Equal(http.StatusTooManyRequests))
+	g.Expect(w.Header().Get(client.RetryAfterHeader)).To(Equal("3"))
+
+	close(release)
+	<-done
+
+	w = httptest.NewRecorder()
+	wrapped.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/live", nil))
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+}
@@ -8,28 +8,30 @@ import (
 	"net/http"
 	"net/url"
 
-	http2 "github.com/cloudevents/sdk-go/pkg/bindings/http"
+	http2 "github.com/cloudevents/sdk-go/v2/protocol/http"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/gorilla/mux"
-	"github.com/opentracing/opentracing-go"
-	"github.com/opentracing/opentracing-go/ext"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/auth"
 	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/predictor"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 type SeldonRestApi struct {
 	Router          *mux.Router
 	Client          client.SeldonApiClient
-	predictor       *v1.PredictorSpec
+	Predictor       *predictor.ActivePredictor
 	Log             logr.Logger
 	ProbesOnly      bool
 	ServerUrl       *url.URL
@@ -39,17 +41,21 @@ type SeldonRestApi struct {
 	metrics         *metric.ServerMetrics
 	prometheusPath  string
 	fullHealthCheck bool
+	// Default time allowed for a request through the graph, 0 for none
+	RequestDeadline time.Duration
+	// Checks requests against the graph metadata, nil if requests aren't validated
+	Validator *predictor.RequestValidator
 }
 
-func NewServerRestApi(predictor *v1.PredictorSpec, client client.SeldonApiClient, probesOnly bool, serverUrl *url.URL, namespace string, protocol string, deploymentName string, prometheusPath string, fullHealthCheck bool) *SeldonRestApi {
+func NewServerRestApi(spec *v1.PredictorSpec, client client.SeldonApiClient, probesOnly bool, serverUrl *url.URL, namespace string, protocol string, deploymentName string, prometheusPath string, fullHealthCheck bool) *SeldonRestApi {
 	var serverMetrics *metric.ServerMetrics
 	if !probesOnly {
-		serverMetrics = metric.NewServerMetrics(predictor, deploymentName)
+		serverMetrics = metric.NewServerMetrics(spec, deploymentName)
 	}
 	return &SeldonRestApi{
 		mux.NewRouter(),
 		client,
-		predictor,
+		predictor.NewActivePredictor(spec),
 		logf.Log.WithName("SeldonRestApi"),
 		probesOnly,
 		serverUrl,
@@ -59,6 +65,8 @@ func NewServerRestApi(predictor *v1.PredictorSpec, client client.SeldonApiClient
 		serverMetrics,
 		prometheusPath,
 		fullHealthCheck,
+		0,
+		nil,
 	}
 }
 
@@ -95,6 +103,15 @@ func (r *SeldonRestApi) respondWithError(w http.ResponseWriter, payload payload.
 
 	if serr, ok := err.(*httpStatusError); ok {
 		w.WriteHeader(serr.StatusCode)
+	} else if _, ok := err.(*client.CircuitOpenError); ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else if lerr, ok := err.(*client.LimitExceededError); ok {
+		w.Header().Set(client.RetryAfterHeader, lerr.RetryAfterSeconds())
+		w.WriteHeader(http.StatusTooManyRequests)
+	} else if _, ok := err.(*client.DeadlineExceededError); ok {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	} else if _, ok := err.(*predictor.ValidationError); ok {
+		w.WriteHeader(http.StatusBadRequest)
 	} else {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
@@ -120,8 +137,8 @@ func (r *SeldonRestApi) wrapMetrics(service string, baseHandler http.HandlerFunc
 	handler := promhttp.InstrumentHandlerDuration(
 		r.metrics.ServerHandledHistogram.MustCurryWith(prometheus.Labels{
 			metric.DeploymentNameMetric:   r.DeploymentName,
-			metric.PredictorNameMetric:    r.predictor.Name,
-			metric.PredictorVersionMetric: r.predictor.Annotations["version"],
+			metric.PredictorNameMetric:    r.Predictor.Get().Name,
+			metric.PredictorVersionMetric: r.Predictor.Get().Annotations["version"],
 			metric.ServiceMetric:          service}),
 		baseHandler,
 	)
@@ -129,8 +146,8 @@ func (r *SeldonRestApi) wrapMetrics(service string, baseHandler http.HandlerFunc
 	handler = promhttp.InstrumentHandlerDuration(
 		r.metrics.ServerHandledSummary.MustCurryWith(prometheus.Labels{
 			metric.DeploymentNameMetric:   r.DeploymentName,
-			metric.PredictorNameMetric:    r.predictor.Name,
-			metric.PredictorVersionMetric: r.predictor.Annotations["version"],
+			metric.PredictorNameMetric:    r.Predictor.Get().Name,
+			metric.PredictorVersionMetric: r.Predictor.Get().Annotations["version"],
 			metric.ServiceMetric:          service}),
 		handler,
 	)
@@ -141,14 +158,25 @@ func (r *SeldonRestApi) wrapMetrics(service string, baseHandler http.HandlerFunc
 func (r *SeldonRestApi) Initialise() {
 	r.Router.HandleFunc("/ready", r.checkReady)
 	r.Router.HandleFunc("/live", r.alive)
+	r.Router.HandleFunc("/admin/graph", r.graphVersion).Methods("GET")
 	r.Router.Handle(r.prometheusPath, promhttp.Handler())
 	if !r.ProbesOnly {
 		cloudeventHeaderMiddleware := CloudeventHeaderMiddleware{deploymentName: r.DeploymentName, namespace: r.Namespace}
+		requestDeadlineMiddleware := RequestDeadlineMiddleware{deadline: r.RequestDeadline}
 		r.Router.Use(puidHeader)
+		r.Router.Use(requestDeadlineMiddleware.Middleware)
 		r.Router.Use(cloudeventHeaderMiddleware.Middleware)
 		r.Router.Use(xssMiddleware)
 		r.Router.Use(mux.CORSMethodMiddleware(r.Router))
 		r.Router.Use(handleCORSRequests)
+		probePaths := []string{"/live", "/ready", r.prometheusPath}
+		if auth.ServerAuthenticator != nil {
+			exemptPaths := append(append([]string{}, probePaths...), auth.ExemptPaths...)
+			r.Router.Use(NewAuthMiddleware(auth.ServerAuthenticator, exemptPaths...).Middleware)
+		}
+		if limiter := client.ConcurrencyLimits.Server(); limiter != nil {
+			r.Router.Use(NewConcurrencyLimitMiddleware(limiter, probePaths...).Middleware)
+		}
 
 		switch r.Protocol {
 		case api.ProtocolSeldon:
@@ -195,42 +223,58 @@ func (r *SeldonRestApi) Initialise() {
 }
 
 func (r *SeldonRestApi) checkReady(w http.ResponseWriter, req *http.Request) {
-	err := predictor.Ready(r.Protocol, &r.predictor.Graph, r.fullHealthCheck)
+	// Open circuits are reported for visibility but don't fail readiness as the pod itself is healthy
+	if client.CircuitBreakers.Enabled() {
+		w.Header().Set("Content-Type", ContentTypeJSON)
+	}
+	err := predictor.Ready(r.Protocol, &r.Predictor.Get().Graph, r.fullHealthCheck)
 	if err != nil {
 		r.Log.Error(err, "Ready check failed")
 		w.WriteHeader(http.StatusServiceUnavailable)
 	} else {
 		w.WriteHeader(http.StatusOK)
 	}
+	if client.CircuitBreakers.Enabled() {
+		err = json.NewEncoder(w).Encode(map[string]interface{}{"circuitBreakers": client.CircuitBreakers.States()})
+		if err != nil {
+			r.Log.Error(err, "Failed to write circuit breaker states")
+		}
+	}
 }
 
 func (r *SeldonRestApi) alive(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func setupTracing(ctx context.Context, req *http.Request, spanName string) (context.Context, opentracing.Span) {
-	tracer := opentracing.GlobalTracer()
-	spanCtx, _ := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
-	serverSpan := tracer.StartSpan(spanName, ext.RPCServerOption(spanCtx))
-	ctx = opentracing.ContextWithSpan(ctx, serverSpan)
-	return ctx, serverSpan
+// graphVersion returns the version of the predictor graph requests are served with.
+func (r *SeldonRestApi) graphVersion(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(r.Predictor.Version()); err != nil {
+		r.Log.Error(err, "Failed to write graph version")
+	}
+}
+
+func setupTracing(ctx context.Context, req *http.Request, spanName string) (context.Context, trace.Span) {
+	route := req.URL.Path
+	if r := mux.CurrentRoute(req); r != nil {
+		if template, err := r.GetPathTemplate(); err == nil {
+			route = template
+		}
+	}
+	return tracing.StartServerSpan(ctx, req.Header, spanName, trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", route, req)...))
 }
 
 func (r *SeldonRestApi) metadata(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 
-	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
-		ctx, serverSpan = setupTracing(ctx, req, TracingMetadataName)
-		defer serverSpan.Finish()
-	}
+	ctx, serverSpan := setupTracing(ctx, req, TracingMetadataName)
+	defer serverSpan.End()
 
 	vars := mux.Vars(req)
 	modelName := vars[ModelHttpPathVariable]
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header, modelName)
-	resPayload, err := seldonPredictorProcess.Metadata(&r.predictor.Graph, modelName, nil)
+	resPayload, err := seldonPredictorProcess.Metadata(&r.Predictor.Get().Graph, modelName, nil)
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
@@ -241,18 +285,14 @@ func (r *SeldonRestApi) metadata(w http.ResponseWriter, req *http.Request) {
 func (r *SeldonRestApi) status(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 
-	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
-		ctx, serverSpan = setupTracing(ctx, req, TracingStatusName)
-		defer serverSpan.Finish()
-	}
+	ctx, serverSpan := setupTracing(ctx, req, TracingStatusName)
+	defer serverSpan.End()
 
 	vars := mux.Vars(req)
 	modelName := vars[ModelHttpPathVariable]
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header, modelName)
-	resPayload, err := seldonPredictorProcess.Status(&r.predictor.Graph, modelName, nil)
+	resPayload, err := seldonPredictorProcess.Status(&r.Predictor.Get().Graph, modelName, nil)
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
@@ -264,12 +304,8 @@ func (r *SeldonRestApi) feedback(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, req.Header.Get(payload.SeldonPUIDHeader))
 
-	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
-		ctx, serverSpan = setupTracing(ctx, req, TracingStatusName)
-		defer serverSpan.Finish()
-	}
+	ctx, serverSpan := setupTracing(ctx, req, TracingStatusName)
+	defer serverSpan.End()
 
 	bodyBytes, err := ioutil.ReadAll(req.Body)
 	if err != nil {
@@ -284,7 +320,10 @@ func (r *SeldonRestApi) feedback(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	resPayload, err := seldonPredictorProcess.Feedback(&r.predictor.Graph, reqPayload)
+	version := r.Predictor.Acquire()
+	defer version.Release()
+	seldonPredictorProcess.Version = version
+	resPayload, err := seldonPredictorProcess.Feedback(&version.Spec.Graph, reqPayload)
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
@@ -299,12 +338,8 @@ func (r *SeldonRestApi) predictions(w http.ResponseWriter, req *http.Request) {
 	// Add Seldon Puid to Context
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, req.Header.Get(payload.SeldonPUIDHeader))
 
-	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
-		ctx, serverSpan = setupTracing(ctx, req, TracingPredictionsName)
-		defer serverSpan.Finish()
-	}
+	ctx, serverSpan := setupTracing(ctx, req, TracingPredictionsName)
+	defer serverSpan.End()
 
 	bodyBytes, err := ioutil.ReadAll(req.Body)
 	if err != nil {
@@ -323,7 +358,15 @@ func (r *SeldonRestApi) predictions(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	resPayload, err := seldonPredictorProcess.Predict(&r.predictor.Graph, reqPayload)
+	version := r.Predictor.Acquire()
+	defer version.Release()
+	seldonPredictorProcess.Version = version
+	spec := version.Spec
+	if err := r.Validator.Validate(&seldonPredictorProcess, spec, reqPayload); err != nil {
+		r.respondWithError(w, nil, err)
+		return
+	}
+	resPayload, err := seldonPredictorProcess.Predict(&spec.Graph, reqPayload)
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
@@ -336,16 +379,12 @@ func (r *SeldonRestApi) graphMetadata(w http.ResponseWriter, req *http.Request)
 
 	ctx := req.Context()
 
-	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
-		ctx, serverSpan = setupTracing(ctx, req, TracingMetadataName)
-		defer serverSpan.Finish()
-	}
+	ctx, serverSpan := setupTracing(ctx, req, TracingMetadataName)
+	defer serverSpan.End()
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header, "")
 
-	graphMetadata, err := seldonPredictorProcess.GraphMetadata(r.predictor)
+	graphMetadata, err := seldonPredictorProcess.GraphMetadata(r.Predictor.Get())
 
 	if err != nil {
 		r.respondWithError(w, nil, err)
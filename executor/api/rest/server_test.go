@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	guuid "github.com/google/uuid"
 	. "github.com/onsi/gomega"
@@ -16,7 +18,9 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/api/test"
+	"github.com/seldonio/seldon-core/executor/predictor"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
 )
 
 const (
@@ -708,3 +712,168 @@ func TestTensorflowModelBadModelName(t *testing.T) {
 	r.Router.ServeHTTP(res, req)
 	g.Expect(res.Code).To(Equal(200))
 }
+
+func TestRequestDeadlineWithServer(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remaining, err := strconv.Atoi(r.Header.Get(payload.SeldonDeadlineHeader))
+		g.Expect(err).To(BeNil())
+		g.Expect(remaining).To(BeNumerically("<=", 50))
+		ioutil.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	url, err := url.Parse(server.URL)
+	g.Expect(err).Should(BeNil())
+	urlParts := strings.Split(url.Host, ":")
+	port, err := strconv.Atoi(urlParts[1])
+	g.Expect(err).Should(BeNil())
+
+	model := v1.MODEL
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "classifier",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: urlParts[0],
+				ServicePort: int32(port),
+				Type:        v1.REST,
+				HttpPort:    int32(port),
+			},
+		},
+	}
+
+	client, err := NewJSONRestClient(api.ProtocolSeldon, "dep", &p, nil)
+	g.Expect(err).To(BeNil())
+	r := NewServerRestApi(&p, client, false, url, "default", api.ProtocolSeldon, "test", "/metrics", true)
+	r.RequestDeadline = time.Second
+	r.Initialise()
+	var data = ` {"data":{"ndarray":[1.1,2.0]}}`
+
+	req, _ := http.NewRequest("POST", "/api/v0.1/predictions", strings.NewReader(data))
+	req.Header = map[string][]string{"Content-Type": []string{"application/json"}, payload.SeldonDeadlineHeader: []string{"50"}}
+	res := httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusGatewayTimeout))
+	g.Expect(res.Body.String()).To(ContainSubstring("request deadline exceeded calling node classifier"))
+
+	req, _ = http.NewRequest("POST", "/api/v0.1/predictions", strings.NewReader(data))
+	req.Header = map[string][]string{"Content-Type": []string{"application/json"}, payload.SeldonDeadlineHeader: []string{"abc"}}
+	res = httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusBadRequest))
+}
+
+func TestGraphVersionEndpoint(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "model",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: "foo",
+				ServicePort: 9000,
+				Type:        v1.REST,
+			},
+		},
+	}
+
+	url, _ := url.Parse("http://localhost")
+	r := NewServerRestApi(&p, &test.SeldonMessageTestClient{}, false, url, "default", api.ProtocolSeldon, "test", "/metrics", true)
+	r.Initialise()
+
+	updated := *p.DeepCopy()
+	updated.Graph.Endpoint.ServicePort = 9001
+	changed, err := r.Predictor.Update(&updated)
+	g.Expect(err).Should(BeNil())
+	g.Expect(changed).Should(BeTrue())
+
+	req, _ := http.NewRequest("GET", "/admin/graph", nil)
+	res := httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(200))
+	var version map[string]interface{}
+	g.Expect(json.Unmarshal(res.Body.Bytes(), &version)).Should(BeNil())
+	g.Expect(version["name"]).Should(Equal("p"))
+	g.Expect(version["generation"]).Should(Equal(float64(2)))
+	g.Expect(version["hash"]).Should(Equal(r.Predictor.Version().Hash))
+}
+
+func TestTracingWithServer(t *testing.T) {
+	g := NewGomegaWithT(t)
+	exporter, reset := test.SetupTestTracing()
+	defer reset()
+
+	traceId := "4bf92f3577b34da6a3ce929d0e0e4736"
+	var nodeTraceparent, nodeB3TraceId string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bodyBytes, err := ioutil.ReadAll(r.Body)
+		g.Expect(err).To(BeNil())
+		nodeTraceparent = r.Header.Get("traceparent")
+		nodeB3TraceId = r.Header.Get("X-B3-TraceId")
+		w.Write(bodyBytes)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	url, err := url.Parse(server.URL)
+	g.Expect(err).Should(BeNil())
+	urlParts := strings.Split(url.Host, ":")
+	port, err := strconv.Atoi(urlParts[1])
+	g.Expect(err).Should(BeNil())
+
+	model := v1.MODEL
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "model",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: urlParts[0],
+				ServicePort: int32(port),
+				Type:        v1.REST,
+				HttpPort:    int32(port),
+			},
+		},
+	}
+
+	client, err := NewJSONRestClient(api.ProtocolSeldon, "dep", &p, nil)
+	g.Expect(err).To(BeNil())
+	r := NewServerRestApi(&p, client, false, url, "default", api.ProtocolSeldon, "test", "/metrics", true)
+	r.Initialise()
+
+	req, _ := http.NewRequest("POST", "/api/v0.1/predictions", strings.NewReader(`{"data":{"ndarray":[1.1,2.0]}}`))
+	req.Header = map[string][]string{
+		"Content-Type": {"application/json"},
+		"Traceparent":  {"00-" + traceId + "-00f067aa0ba902b7-01"},
+	}
+	res := httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(200))
+
+	spans := exporter.GetSpans()
+	g.Expect(spans).To(HaveLen(4))
+	clientSpan, phaseSpan, nodeSpan, serverSpan := spans[0], spans[1], spans[2], spans[3]
+	g.Expect(serverSpan.Name).To(Equal(TracingPredictionsName))
+	g.Expect(serverSpan.Parent.TraceID().String()).To(Equal(traceId))
+	g.Expect(serverSpan.Attributes).To(ContainElement(semconv.HTTPRouteKey.String("/api/v0.1/predictions")))
+	g.Expect(nodeSpan.Name).To(Equal("model"))
+	g.Expect(nodeSpan.Parent.SpanID()).To(Equal(serverSpan.SpanContext.SpanID()))
+	g.Expect(phaseSpan.Name).To(Equal(predictor.SpanTransformInput))
+	g.Expect(phaseSpan.Parent.SpanID()).To(Equal(nodeSpan.SpanContext.SpanID()))
+	g.Expect(clientSpan.Name).To(Equal("/predict"))
+	g.Expect(clientSpan.Parent.SpanID()).To(Equal(phaseSpan.SpanContext.SpanID()))
+	g.Expect(clientSpan.Attributes).To(ContainElement(semconv.HTTPStatusCodeKey.Int(200)))
+
+	// The node continues the trace from the span of the call in both W3C and B3 headers
+	g.Expect(nodeTraceparent).To(Equal("00-" + traceId + "-" + clientSpan.SpanContext.SpanID().String() + "-01"))
+	g.Expect(nodeB3TraceId).To(Equal(traceId))
+}
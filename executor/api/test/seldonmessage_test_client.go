@@ -11,12 +11,16 @@ import (
 )
 
 type SeldonMessageTestClient struct {
-	ChosenRoute      int
+	ChosenRoute int
+	// Overrides ChosenRoute to route to several children
+	ChosenRoutes     []int
 	MetadataResponse payload.SeldonPayload
 	ModelMetadataMap map[string]payload.ModelMetadata
 	ErrMethod        *v1.PredictiveUnitMethod
 	Err              error
 	ErrPayload       payload.SeldonPayload
+	// Only fail calls to this host if set
+	ErrHost string
 }
 
 const (
@@ -87,21 +91,24 @@ func (s SeldonMessageTestClient) CreateErrorPayload(err error) payload.SeldonPay
 }
 
 func (s SeldonMessageTestClient) Predict(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
-	if s.ErrMethod != nil && *s.ErrMethod == v1.TRANSFORM_INPUT {
+	if s.ErrMethod != nil && *s.ErrMethod == v1.TRANSFORM_INPUT && (s.ErrHost == "" || s.ErrHost == host) {
 		return s.ErrPayload, s.Err
 	}
 	return msg, nil
 }
 
 func (s SeldonMessageTestClient) TransformInput(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
-	if s.ErrMethod != nil && *s.ErrMethod == v1.TRANSFORM_INPUT {
+	if s.ErrMethod != nil && *s.ErrMethod == v1.TRANSFORM_INPUT && (s.ErrHost == "" || s.ErrHost == host) {
 		return s.ErrPayload, s.Err
 	}
 	return msg, nil
 }
 
-func (s SeldonMessageTestClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (int, error) {
-	return s.ChosenRoute, nil
+func (s SeldonMessageTestClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) ([]int, error) {
+	if s.ChosenRoutes != nil {
+		return s.ChosenRoutes, nil
+	}
+	return []int{s.ChosenRoute}, nil
 }
 
 func (s SeldonMessageTestClient) Combine(ctx context.Context, modelName string, host string, port int32, msgs []payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
@@ -113,7 +120,7 @@ func (s SeldonMessageTestClient) TransformOutput(ctx context.Context, modelName
 }
 
 func (s SeldonMessageTestClient) Feedback(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
-	if s.ErrMethod != nil && *s.ErrMethod == v1.SEND_FEEDBACK {
+	if s.ErrMethod != nil && *s.ErrMethod == v1.SEND_FEEDBACK && (s.ErrHost == "" || s.ErrHost == host) {
 		return nil, s.Err
 	}
 	protoFeedback, ok := msg.GetPayload().(*proto.Feedback)
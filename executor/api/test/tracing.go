@@ -0,0 +1,27 @@
+package test
+
+import (
+	"context"
+
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SetupTestTracing records the spans of the executor in memory and propagates them with the default propagators. The
+// returned function restores the global tracer provider and propagator.
+func SetupTestTracing() (*tracetest.InMemoryExporter, func()) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	propagator, _ := tracing.NewPropagator(tracing.PropagatorTraceContext, tracing.PropagatorBaggage, tracing.PropagatorB3Multi, tracing.PropagatorJaeger)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	return exporter, func() {
+		_ = provider.Shutdown(context.Background())
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	}
+}
@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+)
+
+const (
+	EnvOtlpEndpoint       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	EnvOtlpTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	EnvOtlpProtocol       = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	EnvOtlpTracesProtocol = "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"
+
+	OtlpProtocolGrpc         = "grpc"
+	OtlpProtocolHttpProtobuf = "http/protobuf"
+)
+
+// NewOtlpClientFromEnv returns the client exporting spans over the protocol set in OTEL_EXPORTER_OTLP_PROTOCOL,
+// gRPC by default. The endpoint, headers, timeout, TLS and compression are read from the other
+// OTEL_EXPORTER_OTLP_* environment variables by the exporter itself.
+func NewOtlpClientFromEnv() (otlptrace.Client, error) {
+	return NewOtlpClient(strings.ToLower(otlpEnv(EnvOtlpTracesProtocol, EnvOtlpProtocol)))
+}
+
+// NewOtlpClient returns the client sending spans to a collector over gRPC or HTTP.
+func NewOtlpClient(protocol string) (otlptrace.Client, error) {
+	switch protocol {
+	case OtlpProtocolGrpc, "":
+		return otlptracegrpc.NewClient(), nil
+	case OtlpProtocolHttpProtobuf:
+		return otlptracehttp.NewClient(), nil
+	}
+	return nil, fmt.Errorf("unsupported OTLP protocol %s", protocol)
+}
+
+func otlpEnv(tracesKey string, key string) string {
+	if val := os.Getenv(tracesKey); val != "" {
+		return val
+	}
+	return os.Getenv(key)
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	EnvPropagators = "OTEL_PROPAGATORS"
+
+	PropagatorTraceContext = "tracecontext"
+	PropagatorBaggage      = "baggage"
+	PropagatorB3           = "b3"
+	PropagatorB3Multi      = "b3multi"
+	PropagatorJaeger       = "jaeger"
+	PropagatorNone         = "none"
+
+	// W3C trace context is propagated alongside the B3 and Jaeger headers used by older Seldon components
+	DefaultPropagators = "tracecontext,baggage,b3multi,jaeger"
+)
+
+// PropagatorFromEnv returns the propagators listed in OTEL_PROPAGATORS, or the default propagators if it's not set.
+func PropagatorFromEnv() (propagation.TextMapPropagator, error) {
+	names := os.Getenv(EnvPropagators)
+	if strings.TrimSpace(names) == "" {
+		names = DefaultPropagators
+	}
+	return NewPropagator(strings.Split(names, ",")...)
+}
+
+// NewPropagator returns a propagator injecting and extracting trace context in all the given formats. When a request
+// carries several formats the last one listed wins.
+func NewPropagator(names ...string) (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case PropagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case PropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		case PropagatorB3:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case PropagatorB3Multi:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case PropagatorJaeger:
+			propagators = append(propagators, jaeger.Jaeger{})
+		case PropagatorNone, "":
+		default:
+			return nil, fmt.Errorf("unsupported propagator %s in %s", name, EnvPropagators)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
@@ -1,52 +1,145 @@
 package tracing
 
 import (
+	"context"
 	"io"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
-	"github.com/opentracing/opentracing-go"
-	jaegercfg "github.com/uber/jaeger-client-go/config"
-	"github.com/uber/jaeger-client-go/zipkin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	// Name of the tracer of the executor
+	TracerName = "github.com/seldonio/seldon-core/executor"
+
+	DefaultServiceName = "executor"
+
+	EnvTracesExporter = "OTEL_TRACES_EXPORTER"
+	EnvSdkDisabled    = "OTEL_SDK_DISABLED"
+
+	ExporterOtlp = "otlp"
+	ExporterNone = "none"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+type tracerProviderCloser struct {
+	provider *sdktrace.TracerProvider
+}
+
+// Close flushes the spans which haven't been exported yet and stops the exporter.
+func (c tracerProviderCloser) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return c.provider.Shutdown(ctx)
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// InitTracing sets the global propagator and, if an OTLP exporter is configured through the OTEL_* environment
+// variables, a tracer provider exporting the spans of the executor. Without an exporter trace context is still
+// propagated from requests to the nodes of the graph but no spans are recorded.
 func InitTracing() (io.Closer, error) {
-	//Initialise tracing
-	cfg, err := jaegercfg.FromEnv()
+	propagator, err := PropagatorFromEnv()
 	if err != nil {
-		// parsing errors might happen here, such as when we get a string where we expect a number
 		return nil, err
 	}
+	otel.SetTextMapPropagator(propagator)
 
-	if cfg.ServiceName == "" {
-		cfg.ServiceName = "executor"
+	if !exporterEnabled() {
+		return nopCloser{}, nil
 	}
-
-	propagation := os.Getenv("JAEGER_TRACE_PROPAGATION_TYPE")
-	closer, err := newTraceByPropagation(propagation, cfg)
+	client, err := NewOtlpClientFromEnv()
 	if err != nil {
 		return nil, err
 	}
-	return closer, nil
+	// The clients connect lazily so a collector which isn't up yet doesn't stop the executor starting
+	exporter, err := otlptrace.New(context.Background(), client)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := newTracerProvider(exporter)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetTracerProvider(provider)
+	return tracerProviderCloser{provider: provider}, nil
 }
 
-func newTraceByPropagation(propagation string, cfg *jaegercfg.Configuration) (closer io.Closer, err error) {
-	switch propagation {
-	case "b3":
-		return newB3Tracer(cfg)
+// exporterEnabled returns whether spans should be exported. OTLP is the only exporter supported, used when it's
+// selected explicitly or an OTLP endpoint is set.
+func exporterEnabled() bool {
+	if strings.EqualFold(os.Getenv(EnvSdkDisabled), "true") {
+		return false
 	}
-	return newDefaultTracer(cfg)
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(EnvTracesExporter))) {
+	case ExporterOtlp:
+		return true
+	case ExporterNone:
+		return false
+	}
+	return os.Getenv(EnvOtlpEndpoint) != "" || os.Getenv(EnvOtlpTracesEndpoint) != ""
 }
 
-func newB3Tracer(cfg *jaegercfg.Configuration) (io.Closer, error) {
-	zipkinPropagator := zipkin.NewZipkinB3HTTPHeaderPropagator()
-	closer, err := cfg.InitGlobalTracer(cfg.ServiceName,
-		jaegercfg.Injector(opentracing.HTTPHeaders, zipkinPropagator),
-		jaegercfg.Extractor(opentracing.HTTPHeaders, zipkinPropagator),
-		jaegercfg.ZipkinSharedRPCSpan(true))
+func newTracerProvider(exporter sdktrace.SpanExporter) (*sdktrace.TracerProvider, error) {
+	// The service name and attributes from OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(semconv.ServiceNameKey.String(DefaultServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// The sampler is set from OTEL_TRACES_SAMPLER and batching from the OTEL_BSP_* variables by the SDK
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	), nil
+}
+
+// Tracer returns the tracer of the executor from the global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
 
-	return closer, err
+// StartServerSpan starts the span of a request received by the executor, continuing the trace propagated in its
+// headers.
+func StartServerSpan(ctx context.Context, header http.Header, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+	opts = append(opts, trace.WithSpanKind(trace.SpanKindServer))
+	return Tracer().Start(ctx, spanName, opts...)
 }
-func newDefaultTracer(cfg *jaegercfg.Configuration) (io.Closer, error) {
-	closer, err := cfg.InitGlobalTracer(cfg.ServiceName)
-	return closer, err
+
+// StartClientSpan starts the span of a call made by the executor, adding the headers propagating the trace to
+// header.
+func StartClientSpan(ctx context.Context, header http.Header, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	opts = append(opts, trace.WithSpanKind(trace.SpanKindClient))
+	ctx, span := Tracer().Start(ctx, spanName, opts...)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	return ctx, span
+}
+
+// RecordError records the error on the span and marks the span as failed.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
 }
@@ -1,44 +1,211 @@
 package tracing
 
 import (
-	"github.com/opentracing/opentracing-go"
-	"github.com/uber/jaeger-client-go"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 )
 
-var (
-	nonRootNonSampledHeader = opentracing.TextMapCarrier{
-		"x-b3-traceid":      "1",
-		"x-b3-spanid":       "2",
-		"x-b3-parentspanid": "1",
-		"x-b3-sampled":      "0",
-	}
+const (
+	testTraceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanId  = "00f067aa0ba902b7"
 )
 
-func newSpanContext(traceID, spanID, parentID uint64, sampled bool, baggage map[string]string) jaeger.SpanContext {
-	return jaeger.NewSpanContext(
-		jaeger.TraceID{Low: traceID},
-		jaeger.SpanID(spanID),
-		jaeger.SpanID(parentID),
-		sampled,
-		baggage,
-	)
+func setupTestTracing(g *GomegaWithT) (*tracetest.InMemoryExporter, func()) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	propagator, err := NewPropagator(strings.Split(DefaultPropagators, ",")...)
+	g.Expect(err).To(BeNil())
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	return exporter, func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	}
 }
 
-func TestInitTracing(t *testing.T) {
-	os.Setenv("JAEGER_TRACE_PROPAGATION_TYPE", "b3")
-	closer, err := InitTracing()
-	if err != nil {
-		t.Error("init trace err", err.Error())
-	}
-	defer closer.Close()
-	if !opentracing.IsGlobalTracerRegistered() {
-		t.Error("trace is not registered")
+func extract(propagator propagation.TextMapPropagator, header http.Header) trace.SpanContext {
+	return trace.SpanContextFromContext(propagator.Extract(context.Background(), propagation.HeaderCarrier(header)))
+}
+
+func TestPropagators(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	propagator, err := NewPropagator("tracecontext", "b3", "b3multi", "jaeger")
+	g.Expect(err).To(BeNil())
+	g.Expect(propagator.Fields()).To(ContainElements("traceparent", "b3", "x-b3-traceid", "uber-trace-id"))
+	_, err = NewPropagator("xray")
+	g.Expect(err).ToNot(BeNil())
+
+	traceId, _ := trace.TraceIDFromHex(testTraceId)
+	spanId, _ := trace.SpanIDFromHex(testSpanId)
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceId, SpanID: spanId, TraceFlags: trace.FlagsSampled})
+	header := http.Header{}
+	propagator.Inject(trace.ContextWithSpanContext(context.Background(), sc), propagation.HeaderCarrier(header))
+	g.Expect(header.Get("traceparent")).To(Equal("00-" + testTraceId + "-" + testSpanId + "-01"))
+	g.Expect(header.Get("b3")).To(Equal(testTraceId + "-" + testSpanId + "-1"))
+	g.Expect(header.Get("X-B3-TraceId")).To(Equal(testTraceId))
+	g.Expect(header.Get("X-B3-SpanId")).To(Equal(testSpanId))
+	g.Expect(header.Get("X-B3-Sampled")).To(Equal("1"))
+	g.Expect(header.Get("uber-trace-id")).To(Equal(testTraceId + ":" + testSpanId + ":0:1"))
+
+	// Each format is extracted on its own
+	for _, fields := range [][]string{{"traceparent"}, {"b3"}, {"X-B3-TraceId", "X-B3-SpanId", "X-B3-Sampled"}, {"uber-trace-id"}} {
+		single := http.Header{}
+		for _, field := range fields {
+			single.Set(field, header.Get(field))
+		}
+		extracted := extract(propagator, single)
+		g.Expect(extracted.TraceID()).To(Equal(traceId), fields[0])
+		g.Expect(extracted.SpanID()).To(Equal(spanId), fields[0])
+		g.Expect(extracted.IsSampled()).To(BeTrue(), fields[0])
+		g.Expect(extracted.IsRemote()).To(BeTrue(), fields[0])
 	}
-	tracer := opentracing.GlobalTracer()
-	_, err = tracer.Extract(opentracing.HTTPHeaders, nonRootNonSampledHeader)
-	if err != nil {
-		t.Error("trace extract error", err.Error())
+}
+
+func TestServerAndClientSpans(t *testing.T) {
+	g := NewGomegaWithT(t)
+	exporter, reset := setupTestTracing(g)
+	defer reset()
+
+	header := http.Header{"Traceparent": {"00-" + testTraceId + "-" + testSpanId + "-01"}}
+	ctx, serverSpan := StartServerSpan(context.Background(), header, "predictions")
+	nodeHeader := http.Header{}
+	_, clientSpan := StartClientSpan(ctx, nodeHeader, "/predict")
+	clientSpan.End()
+	RecordError(serverSpan, context.DeadlineExceeded)
+	serverSpan.End()
+
+	spans := exporter.GetSpans()
+	g.Expect(spans).To(HaveLen(2))
+	client, server := spans[0], spans[1]
+	g.Expect(server.Name).To(Equal("predictions"))
+	g.Expect(server.SpanKind).To(Equal(trace.SpanKindServer))
+	g.Expect(server.Parent.TraceID().String()).To(Equal(testTraceId))
+	g.Expect(server.Parent.SpanID().String()).To(Equal(testSpanId))
+	g.Expect(server.Status.Description).To(Equal(context.DeadlineExceeded.Error()))
+	g.Expect(client.SpanKind).To(Equal(trace.SpanKindClient))
+	g.Expect(client.Parent.SpanID()).To(Equal(server.SpanContext.SpanID()))
+
+	// The node is called with the span of the client in all formats
+	g.Expect(nodeHeader.Get("traceparent")).To(Equal("00-" + testTraceId + "-" + client.SpanContext.SpanID().String() + "-01"))
+	g.Expect(nodeHeader.Get("X-B3-SpanId")).To(Equal(client.SpanContext.SpanID().String()))
+	g.Expect(nodeHeader.Get("uber-trace-id")).ToNot(BeEmpty())
+}
+
+// setOtlpEnv sets the OTLP environment variables read by the exporters, restoring them when the test ends.
+func setOtlpEnv(t *testing.T, env map[string]string) {
+	for key, value := range env {
+		old := os.Getenv(key)
+		key := key
+		t.Cleanup(func() { os.Setenv(key, old) })
+		os.Setenv(key, value)
 	}
 }
+
+func exportTestSpan(g *GomegaWithT) {
+	client, err := NewOtlpClientFromEnv()
+	g.Expect(err).To(BeNil())
+	exporter, err := otlptrace.New(context.Background(), client)
+	g.Expect(err).To(BeNil())
+	provider, err := newTracerProvider(exporter)
+	g.Expect(err).To(BeNil())
+	_, span := provider.Tracer(TracerName).Start(context.Background(), "predictions")
+	span.End()
+	g.Expect(provider.Shutdown(context.Background())).To(BeNil())
+}
+
+func TestOtlpHttpClient(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var received []*tracepb.ResourceSpans
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.URL.Path).To(Equal("/v1/traces"))
+		g.Expect(r.Header.Get("api-key")).To(Equal("secret"))
+		body, err := ioutil.ReadAll(r.Body)
+		g.Expect(err).To(BeNil())
+		req := &coltracepb.ExportTraceServiceRequest{}
+		g.Expect(proto.Unmarshal(body, req)).To(BeNil())
+		received = append(received, req.ResourceSpans...)
+	}))
+	defer server.Close()
+
+	setOtlpEnv(t, map[string]string{
+		EnvOtlpProtocol:              OtlpProtocolHttpProtobuf,
+		EnvOtlpEndpoint:              server.URL,
+		"OTEL_EXPORTER_OTLP_HEADERS": "api-key=secret",
+		"OTEL_EXPORTER_OTLP_TIMEOUT": "1000",
+	})
+	exportTestSpan(g)
+	g.Expect(received).To(HaveLen(1))
+	g.Expect(received[0].ScopeSpans[0].Spans[0].Name).To(Equal("predictions"))
+}
+
+type testTraceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+	received []*tracepb.ResourceSpans
+}
+
+func (s *testTraceService) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	s.received = append(s.received, req.ResourceSpans...)
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func TestOtlpGrpcClient(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	service := &testTraceService{}
+	server := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(server, service)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).To(BeNil())
+	go server.Serve(lis)
+	defer server.Stop()
+
+	setOtlpEnv(t, map[string]string{
+		EnvOtlpProtocol: OtlpProtocolGrpc,
+		EnvOtlpEndpoint: "http://" + lis.Addr().String(),
+	})
+	exportTestSpan(g)
+	g.Expect(service.received).To(HaveLen(1))
+	g.Expect(service.received[0].ScopeSpans[0].Spans[0].Name).To(Equal("predictions"))
+
+	_, err = NewOtlpClient("http/json")
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestInitTracingWithoutExporter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer os.Setenv(EnvTracesExporter, os.Getenv(EnvTracesExporter))
+	os.Setenv(EnvTracesExporter, ExporterNone)
+	closer, err := InitTracing()
+	g.Expect(err).To(BeNil())
+	defer closer.Close()
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	// Trace context is still propagated to the nodes
+	header := http.Header{"Traceparent": {"00-" + testTraceId + "-" + testSpanId + "-01"}}
+	ctx, span := StartServerSpan(context.Background(), header, "predictions")
+	g.Expect(span.IsRecording()).To(BeFalse())
+	nodeHeader := http.Header{}
+	StartClientSpan(ctx, nodeHeader, "/predict")
+	g.Expect(nodeHeader.Get("traceparent")).To(ContainSubstring(testTraceId))
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"strconv"
 
@@ -21,7 +22,7 @@ func ExtractRouteAsJsonArray(msg []byte) ([]int, error) {
 	}
 }
 
-func ExtractRouteFromSeldonJson(sp payload.SeldonPayload) (int, error) {
+func ExtractRouteFromSeldonJson(sp payload.SeldonPayload) ([]int, error) {
 	var routes []int
 	msg := sp.GetPayload().([]byte)
 
@@ -34,50 +35,92 @@ func ExtractRouteFromSeldonJson(sp payload.SeldonPayload) (int, error) {
 	} else {
 		routes, err = ExtractRouteAsJsonArray(msg)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
 	}
 
-	//Only returning first route. API could be extended to allow multiple routes
-	return routes[0], nil
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("router returned no routes")
+	}
+	return routes, nil
 }
 
-func RouteFromFeedbackJsonMeta(sp payload.SeldonPayload, predictorName string) int {
+// RouteFromFeedbackJsonMeta returns the routes recorded for the router in the response of the feedback, or false if
+// there are none.
+func RouteFromFeedbackJsonMeta(sp payload.SeldonPayload, predictorName string) ([]int, bool) {
 	msg := sp.GetPayload().([]byte)
 
 	var fm proto.Feedback
 	value := string(msg)
 	err := jsonpb.UnmarshalString(value, &fm)
 	if err != nil {
-		return -1
+		return nil, false
 	}
 
 	return RouteFromFeedbackMessageMeta(&fm, predictorName)
 }
 
-func RouteFromFeedbackMessageMeta(msg *proto.Feedback, predictorName string) int {
-	routing := msg.GetResponse().GetMeta().GetRouting()
-	if route, ok := routing[predictorName]; ok {
-		return int(route)
+func RewardFromFeedbackJson(sp payload.SeldonPayload) (float32, error) {
+	msg := sp.GetPayload().([]byte)
+
+	var fm proto.Feedback
+	err := jsonpb.UnmarshalString(string(msg), &fm)
+	if err != nil {
+		return 0, err
+	}
+	return fm.GetReward(), nil
+}
+
+// RouteFromFeedbackMessageMeta returns the routes recorded for the router in the response of the feedback, or false if
+// there are none.
+func RouteFromFeedbackMessageMeta(msg *proto.Feedback, predictorName string) ([]int, bool) {
+	routes := RoutesFromRouting(msg.GetResponse().GetMeta().GetRouting(), predictorName)
+	return routes, len(routes) > 0
+}
+
+// MultiRouteKey is the routing key of the i'th child a router chose when it routed to several children. The first
+// child is kept under the router's name so single routes are unchanged.
+func MultiRouteKey(routerName string, i int) string {
+	if i == 0 {
+		return routerName
+	}
+	return fmt.Sprintf("%s[%d]", routerName, i)
+}
+
+// RoutesFromRouting returns all the routes recorded for a router in the routing meta data.
+func RoutesFromRouting(routing map[string]int32, routerName string) []int {
+	var routes []int
+	for i := 0; ; i++ {
+		route, ok := routing[MultiRouteKey(routerName, i)]
+		if !ok {
+			return routes
+		}
+		routes = append(routes, int(route))
 	}
-	return -1
 }
 
+// ExtractRouteFromSeldonMessage returns the children chosen by a router, e.g. [0,2] routes to the first and third
+// child. The routes of a 2-D response are its first row, e.g. [[0,2]] or [[0],[1]] for a route per row of a batch.
 func ExtractRouteFromSeldonMessage(msg *proto.SeldonMessage) []int {
 	switch msg.GetData().DataOneof.(type) {
 	case *proto.DefaultData_Ndarray:
 		values := msg.GetData().GetNdarray().GetValues()
+		if len(values) > 0 {
+			if listValue := values[0].GetListValue(); listValue != nil {
+				values = listValue.GetValues()
+			}
+		}
 		routeArr := make([]int, len(values))
 		for i, value := range values {
-			if listValue := value.GetListValue(); listValue != nil {
-				routeArr[i] = int(listValue.GetValues()[0].GetNumberValue())
-			} else {
-				routeArr[i] = int(value.GetNumberValue())
-			}
+			routeArr[i] = int(value.GetNumberValue())
 		}
 		return routeArr
 	case *proto.DefaultData_Tensor:
 		values := msg.GetData().GetTensor().Values
+		shape := msg.GetData().GetTensor().GetShape()
+		if len(shape) > 1 {
+			values = values[:firstRowSize(len(values), shape[1:])]
+		}
 		routeArr := make([]int, len(values))
 		for i, value := range values {
 			routeArr[i] = int(value)
@@ -85,6 +128,14 @@ func ExtractRouteFromSeldonMessage(msg *proto.SeldonMessage) []int {
 		return routeArr
 	case *proto.DefaultData_Tftensor:
 		values := msg.GetData().GetTftensor().GetIntVal()
+		dims := msg.GetData().GetTftensor().GetTensorShape().GetDim()
+		if len(dims) > 1 {
+			rowShape := make([]int32, len(dims)-1)
+			for i, dim := range dims[1:] {
+				rowShape[i] = int32(dim.GetSize())
+			}
+			values = values[:firstRowSize(len(values), rowShape)]
+		}
 		routeArr := make([]int, len(values))
 		for i, value := range values {
 			routeArr[i] = int(value)
@@ -94,10 +145,28 @@ func ExtractRouteFromSeldonMessage(msg *proto.SeldonMessage) []int {
 	return []int{-1}
 }
 
+// firstRowSize returns the number of values in the first row of a tensor, at most the number of values it has.
+func firstRowSize(values int, rowShape []int32) int {
+	size := 1
+	for _, dim := range rowShape {
+		size *= int(dim)
+	}
+	if size < 0 || size > values {
+		return values
+	}
+	return size
+}
+
 func InsertRouteToSeldonPredictPayload(msg payload.SeldonPayload, routing *map[string]int32) (payload.SeldonPayload, error) {
 
 	if msg.GetContentType() == payload.APPLICATION_TYPE_PROTOBUF {
-		sm := msg.GetPayload().(*proto.SeldonMessage)
+		sm, ok := msg.GetPayload().(*proto.SeldonMessage)
+		if !ok {
+			return nil, fmt.Errorf("can not add routing to %T", msg.GetPayload())
+		}
+		if sm.Meta == nil {
+			sm.Meta = &proto.Meta{}
+		}
 		sm.Meta.Routing = *routing
 		return &payload.ProtoPayload{Msg: sm}, nil
 	} else {
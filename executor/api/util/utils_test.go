@@ -29,9 +29,42 @@ func TestExtractRouteFromSeldonMessage(t *testing.T) {
 			msg:      `{"data":{"ndarray":[3,4]}}`,
 			expected: []int{3, 4},
 		},
+		// The routes of a 2-D response are its first row
 		{
 			msg:      `{"data":{"names":["X1L","X2L"],"ndarray":[[1,2],[3,4]]}}`,
-			expected: []int{1, 3},
+			expected: []int{1, 2},
+		},
+		{
+			msg:      `{"data":{"ndarray":[[0,2]]}}`,
+			expected: []int{0, 2},
+		},
+		{
+			msg:      `{"data":{"ndarray":[[0],[1]]}}`,
+			expected: []int{0},
+		},
+		{
+			msg:      `{"data":{"ndarray":[[]]}}`,
+			expected: []int{},
+		},
+		{
+			msg:      `{"data":{"tensor":{"shape":[2,1],"values":[2,1]}}}`,
+			expected: []int{2},
+		},
+		{
+			msg:      `{"data":{"tensor":{"shape":[1,2],"values":[0,2]}}}`,
+			expected: []int{0, 2},
+		},
+		{
+			msg:      `{"data":{"tensor":{"shape":[2],"values":[0,2]}}}`,
+			expected: []int{0, 2},
+		},
+		{
+			msg:      `{"data":{"tftensor":{"dtype":"DT_INT32","tensorShape":{"dim":[{"size":"2"},{"size":"1"}]},"intVal":[1,0]}}}`,
+			expected: []int{1},
+		},
+		{
+			msg:      `{"data":{"tftensor":{"dtype":"DT_INT32","tensorShape":{"dim":[{"size":"1"},{"size":"2"}]},"intVal":[1,0]}}}`,
+			expected: []int{1, 0},
 		},
 		{
 			msg:      `{"data":{"ndarray":[]}}`,
@@ -48,6 +81,21 @@ func TestExtractRouteFromSeldonMessage(t *testing.T) {
 	}
 }
 
+func TestRouteFromFeedbackJsonMeta(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	msg := &payload.BytesPayload{Msg: []byte(`{"response":{"meta":{"routing":{"router":0,"router[1]":2}}},"reward":1}`)}
+	routes, ok := RouteFromFeedbackJsonMeta(msg, "router")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(routes).To(Equal([]int{0, 2}))
+
+	_, ok = RouteFromFeedbackJsonMeta(msg, "other")
+	g.Expect(ok).To(BeFalse())
+
+	_, ok = RouteFromFeedbackJsonMeta(&payload.BytesPayload{Msg: []byte(`not json`)}, "router")
+	g.Expect(ok).To(BeFalse())
+}
+
 func TestGetEnvAsBool(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -142,6 +190,30 @@ func TestInjectRouteSeldonJson(t *testing.T) {
 	g.Expect(routes).To(Equal(testRouting))
 }
 
+func TestExtractRouteFromSeldonJson(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	routes, err := ExtractRouteFromSeldonJson(&payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[0,2]}}`)})
+	g.Expect(err).To(BeNil())
+	g.Expect(routes).To(Equal([]int{0, 2}))
+
+	routes, err = ExtractRouteFromSeldonJson(&payload.BytesPayload{Msg: []byte(`[1]`)})
+	g.Expect(err).To(BeNil())
+	g.Expect(routes).To(Equal([]int{1}))
+
+	_, err = ExtractRouteFromSeldonJson(&payload.BytesPayload{Msg: []byte(`[]`)})
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestRoutesFromRouting(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	routing := map[string]int32{"router": 0, "router[1]": 2, "other": 1}
+	g.Expect(RoutesFromRouting(routing, "router")).To(Equal([]int{0, 2}))
+	g.Expect(RoutesFromRouting(routing, "other")).To(Equal([]int{1}))
+	g.Expect(RoutesFromRouting(routing, "missing")).To(BeEmpty())
+}
+
 func TestSSLSecurityProtocol(t *testing.T) {
 	g := NewGomegaWithT(t)
 	os.Setenv("KAFKA_SECURITY_PROTOCOL", "ssl")
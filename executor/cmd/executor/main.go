@@ -21,8 +21,11 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/auth"
 	seldonclient "github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/converter"
 	"github.com/seldonio/seldon-core/executor/api/grpc"
+	"github.com/seldonio/seldon-core/executor/api/grpc/health"
 	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving"
 	kfproto "github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon"
@@ -39,6 +42,7 @@ import (
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	"go.uber.org/automaxprocs/maxprocs"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	zapf "sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -103,13 +107,16 @@ func getServerUrl(hostname string, port int) (*url.URL, error) {
 	return url.Parse(fmt.Sprintf("http://%s:%d/", hostname, port))
 }
 
-func runHttpServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, logger logr.Logger, predictor *v1.PredictorSpec, client seldonclient.SeldonApiClient, port int, probesOnly bool, serverUrl *url.URL, namespace string, protocol string, deploymentName string, prometheusPath string, fullHealthChecks bool) {
+func runHttpServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, logger logr.Logger, activePredictor *predictor2.ActivePredictor, client seldonclient.SeldonApiClient, port int, probesOnly bool, serverUrl *url.URL, namespace string, protocol string, deploymentName string, prometheusPath string, fullHealthChecks bool, requestDeadline time.Duration, validator *predictor2.RequestValidator) {
 	wg.Add(1)
 	defer wg.Done()
 	defer lis.Close()
 
 	// Create REST API
-	seldonRest := rest.NewServerRestApi(predictor, client, probesOnly, serverUrl, namespace, protocol, deploymentName, prometheusPath, fullHealthChecks)
+	seldonRest := rest.NewServerRestApi(activePredictor.Get(), client, probesOnly, serverUrl, namespace, protocol, deploymentName, prometheusPath, fullHealthChecks)
+	seldonRest.Predictor = activePredictor
+	seldonRest.RequestDeadline = requestDeadline
+	seldonRest.Validator = validator
 	seldonRest.Initialise()
 	srv := seldonRest.CreateHttpServer(port)
 
@@ -136,10 +143,11 @@ func runHttpServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, log
 	logger.Info("http server shutdown")
 }
 
-func runGrpcServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, logger logr.Logger, predictor *v1.PredictorSpec, client seldonclient.SeldonApiClient, serverUrl *url.URL, namespace string, protocol string, deploymentName string, annotations map[string]string) {
+func runGrpcServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, logger logr.Logger, activePredictor *predictor2.ActivePredictor, client seldonclient.SeldonApiClient, serverUrl *url.URL, namespace string, protocol string, deploymentName string, annotations map[string]string, fullHealthChecks bool, validator *predictor2.RequestValidator) {
 	wg.Add(1)
 	defer wg.Done()
 	defer lis.Close()
+	predictor := activePredictor.Get()
 	grpcServer, err := grpc.CreateGrpcServer(predictor, deploymentName, annotations, logger)
 	if err != nil {
 		log.Fatalf("Failed to create gRPC server: %v", err)
@@ -147,17 +155,24 @@ func runGrpcServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, log
 	switch protocol {
 	case api.ProtocolSeldon:
 		seldonGrpcServer := seldon.NewGrpcSeldonServer(predictor, client, serverUrl, namespace)
+		seldonGrpcServer.Predictor = activePredictor
+		seldonGrpcServer.Validator = validator
 		proto.RegisterSeldonServer(grpcServer, seldonGrpcServer)
 		// Register reflection service on gRPC server.
 		reflection.Register(grpcServer)
 	case api.ProtocolTensorflow:
 		tensorflowGrpcServer := tensorflow.NewGrpcTensorflowServer(predictor, client, serverUrl, namespace)
+		tensorflowGrpcServer.Predictor = activePredictor
 		serving.RegisterPredictionServiceServer(grpcServer, tensorflowGrpcServer)
 		serving.RegisterModelServiceServer(grpcServer, tensorflowGrpcServer)
 	case api.ProtocolV2, api.ProtocolKFServing:
 		kfservingGrpcServer := kfserving.NewGrpcKFServingServer(predictor, client, serverUrl, namespace)
+		kfservingGrpcServer.Predictor = activePredictor
+		kfservingGrpcServer.FullHealthCheck = fullHealthChecks
+		kfservingGrpcServer.Validator = validator
 		kfproto.RegisterGRPCInferenceServiceServer(grpcServer, kfservingGrpcServer)
 	}
+	grpc_health_v1.RegisterHealthServer(grpcServer, health.NewServer(activePredictor, protocol, fullHealthChecks))
 
 	go func() {
 		logger.Info("gRPC server started")
@@ -350,6 +365,35 @@ func main() {
 
 	}
 
+	activePredictor := predictor2.NewActivePredictor(predictor)
+	reloadPredictor := func(reason string) {
+		spec, err := predictor2.GetPredictor(*predictorName, *filename, *sdepName, *namespace, configPath)
+		if err != nil {
+			logger.Error(err, "Failed to reload predictor", "reason", reason)
+			return
+		}
+		changed, err := activePredictor.Update(spec)
+		if err != nil {
+			logger.Error(err, "Invalid predictor not loaded", "reason", reason)
+		} else if changed {
+			version := activePredictor.Version()
+			logger.Info("Reloaded predictor", "reason", reason, "generation", version.Generation, "hash", version.Hash)
+		}
+	}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			reloadPredictor("SIGHUP")
+		}
+	}()
+	if *filename != "" {
+		err = predictor2.WatchPredictorFile(*filename, func() { reloadPredictor("file changed") }, nil, logger)
+		if err != nil {
+			logger.Error(err, "Failed to watch predictor file, changes will need a SIGHUP to reload", "file", *filename)
+		}
+	}
+
 	// Ensure standard OpenAPI seldon API file has this deployment's values
 	err = rest.EmbedSeldonDeploymentValuesInSwaggerFile(*namespace, *sdepName)
 	if err != nil {
@@ -361,6 +405,92 @@ func main() {
 		logger.Error(err, "Failed to load annotations")
 	}
 
+	circuitBreakerSettings, err := seldonclient.CircuitBreakerSettingsFromAnnotations(annotations)
+	if err != nil {
+		log.Fatalf("Failed to parse circuit breaker annotations: %v", err)
+	}
+	seldonclient.CircuitBreakers = seldonclient.NewCircuitBreakerRegistry(circuitBreakerSettings)
+
+	authSettings, err := auth.AuthSettingsFromAnnotations(annotations)
+	if err != nil {
+		log.Fatalf("Failed to parse auth annotations: %v", err)
+	}
+	if authSettings != nil {
+		auth.ServerAuthenticator, err = auth.NewAuthenticator(*authSettings)
+		if err != nil {
+			log.Fatalf("Failed to load auth keys: %v", err)
+		}
+		auth.ExemptPaths = authSettings.ExemptPaths
+		auth.ForwardCredentials = authSettings.ForwardCredentials
+	}
+
+	clientTLSSettings, err := seldonclient.ClientTLSSettingsFromAnnotations(annotations)
+	if err != nil {
+		log.Fatalf("Failed to parse client TLS annotations: %v", err)
+	}
+	if clientTLSSettings != nil {
+		seldonclient.ClientTLS, err = seldonclient.NewClientTLSConfig(*clientTLSSettings)
+		if err != nil {
+			log.Fatalf("Failed to load client TLS certificates: %v", err)
+		}
+	}
+
+	concurrencyLimitSettings, err := seldonclient.ConcurrencyLimitSettingsFromAnnotations(annotations)
+	if err != nil {
+		log.Fatalf("Failed to parse concurrency limit annotations: %v", err)
+	}
+	seldonclient.ConcurrencyLimits = seldonclient.NewConcurrencyLimitRegistry(concurrencyLimitSettings)
+
+	requestDeadline, err := seldonclient.GetRequestDeadlineFromAnnotations(annotations)
+	if err != nil {
+		log.Fatalf("Failed to parse request deadline annotation: %v", err)
+	}
+
+	validator, err := predictor2.RequestValidatorFromAnnotations(annotations, *protocol)
+	if err != nil {
+		log.Fatalf("Failed to parse request validation annotation: %v", err)
+	}
+
+	if banditStateDir := annotations[k8s.ANNOTATION_BANDIT_STATE_DIR]; banditStateDir != "" {
+		banditStore, err := predictor2.NewFileBanditStore(banditStateDir)
+		if err != nil {
+			log.Fatalf("Failed to create bandit state store: %v", err)
+		}
+		predictor2.BanditStateStore = banditStore
+	}
+
+	redisCacheSettings, err := predictor2.RedisCacheSettingsFromAnnotations(annotations)
+	if err != nil {
+		log.Fatalf("Failed to parse response cache annotations: %v", err)
+	}
+	if redisCacheSettings != nil {
+		redisCache, err := predictor2.NewRedisResponseCache(*redisCacheSettings, *sdepName+"/"+*predictorName+"/")
+		if err != nil {
+			log.Fatalf("Failed to create Redis response cache: %v", err)
+		}
+		predictor2.ResponseCacheBackend = func(node *v1.PredictiveUnit) predictor2.ResponseCache {
+			return redisCache
+		}
+	}
+
+	spoolSettings, err := loghandler.SpoolSettingsFromAnnotations(annotations)
+	if err != nil {
+		log.Fatalf("Failed to parse logger spool annotations: %v", err)
+	}
+	if spoolSettings != nil {
+		if err := loghandler.EnableSpool(*spoolSettings, logger); err != nil {
+			log.Fatalf("Failed to open logger spool: %v", err)
+		}
+		defer loghandler.CloseSpool()
+	}
+	batchSettings, err := loghandler.BatchSettingsFromAnnotations(annotations)
+	if err != nil {
+		log.Fatalf("Failed to parse logger batch annotations: %v", err)
+	}
+	if batchSettings != nil {
+		loghandler.EnableBatching(*batchSettings)
+	}
+
 	//Start Logger Dispacther
 	err = loghandler.StartDispatcher(*logWorkers, *logWorkBufferSize, *logWriteTimeoutMs, logger, *sdepName, *namespace, *predictorName, *logKafkaBroker, *logKafkaTopic, *protocol)
 	if err != nil {
@@ -370,7 +500,7 @@ func main() {
 	//Init Tracing
 	closer, err := tracing.InitTracing()
 	if err != nil {
-		log.Fatal("Could not initialize jaeger tracer", err.Error())
+		log.Fatal("Could not initialize tracing", err.Error())
 	}
 	defer closer.Close()
 
@@ -380,6 +510,7 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to create kafka server: %v", err)
 		}
+		kafkaServer.Predictor = activePredictor
 		go func() {
 			err = kafkaServer.Serve()
 			if err != nil {
@@ -405,14 +536,37 @@ func main() {
 		log.Fatalf("Failed to create grpc client. Unknown protocol %s: %v", *protocol, err)
 	}
 
+	// Nodes of the graph may speak another protocol, with messages converted by the executor
+	if converter.IsConvertible(*protocol) {
+		nodeRestClients := make(map[string]seldonclient.SeldonApiClient)
+		for _, nodeProtocol := range []string{api.ProtocolSeldon, api.ProtocolV2} {
+			nodeRestClients[nodeProtocol], err = rest.NewJSONRestClient(nodeProtocol, *sdepName, predictor, annotations)
+			if err != nil {
+				log.Fatalf("Failed to create http client: %v", err)
+			}
+		}
+		predictor2.NodeProtocols = predictor2.NewNodeProtocolClients(*protocol, func(nodeProtocol string, grpc bool) seldonclient.SeldonApiClient {
+			switch {
+			case nodeProtocol == api.ProtocolSeldon && grpc:
+				return seldon.NewSeldonGrpcClient(predictor, *sdepName, annotations)
+			case grpc:
+				return kfserving.NewKFServingGrpcClient(predictor, *sdepName, annotations)
+			case nodeProtocol == api.ProtocolSeldon:
+				return nodeRestClients[api.ProtocolSeldon]
+			default:
+				return nodeRestClients[api.ProtocolV2]
+			}
+		})
+	}
+
 	wg := sync.WaitGroup{}
 	logger.Info("Running http server ", "port", *httpPort)
 	httpStop := make(chan bool, 1)
-	go runHttpServer(&wg, httpStop, createListener(*httpPort, logger), logger, predictor, clientRest, *httpPort, false, serverUrl, *namespace, *protocol, *sdepName, *prometheusPath, *fullHealthChecks)
+	go runHttpServer(&wg, httpStop, createListener(*httpPort, logger), logger, activePredictor, clientRest, *httpPort, false, serverUrl, *namespace, *protocol, *sdepName, *prometheusPath, *fullHealthChecks, requestDeadline, validator)
 
 	logger.Info("Running grpc server ", "port", *grpcPort)
 	grpcStop := make(chan bool, 1)
-	go runGrpcServer(&wg, grpcStop, createListener(*grpcPort, logger), logger, predictor, clientGrpc, serverUrl, *namespace, *protocol, *sdepName, annotations)
+	go runGrpcServer(&wg, grpcStop, createListener(*grpcPort, logger), logger, activePredictor, clientGrpc, serverUrl, *namespace, *protocol, *sdepName, annotations, *fullHealthChecks, validator)
 	waitForShutdown(logger, &wg, httpStop, grpcStop)
 }
 
@@ -12,6 +12,52 @@ const (
 	ANNOTATION_GRPC_MAX_MESSAGE_SIZE = "seldon.io/grpc-max-message-size"
 	ANNOTATION_GRPC_TIMEOUT          = "seldon.io/grpc-timeout"
 	ANNOTATION_REST_TIMEOUT          = "seldon.io/rest-timeout"
+	ANNOTATION_REQUEST_DEADLINE      = "seldon.io/request-deadline"
+	ANNOTATION_BANDIT_STATE_DIR      = "seldon.io/bandit-state-dir"
+
+	ANNOTATION_CACHE_REDIS_ADDRESS       = "seldon.io/cache-redis-address"
+	ANNOTATION_CACHE_REDIS_USERNAME      = "seldon.io/cache-redis-username"
+	ANNOTATION_CACHE_REDIS_PASSWORD_FILE = "seldon.io/cache-redis-password-file"
+	ANNOTATION_CACHE_REDIS_DB            = "seldon.io/cache-redis-db"
+	ANNOTATION_CACHE_REDIS_TLS           = "seldon.io/cache-redis-tls"
+	ANNOTATION_CACHE_REDIS_TLS_CA_FILE   = "seldon.io/cache-redis-tls-ca-file"
+
+	ANNOTATION_CIRCUIT_BREAKER_CONSECUTIVE_FAILURES = "seldon.io/circuit-breaker-consecutive-failures"
+	ANNOTATION_CIRCUIT_BREAKER_ERROR_RATE           = "seldon.io/circuit-breaker-error-rate"
+	ANNOTATION_CIRCUIT_BREAKER_MIN_REQUESTS         = "seldon.io/circuit-breaker-min-requests"
+	ANNOTATION_CIRCUIT_BREAKER_WINDOW               = "seldon.io/circuit-breaker-window"
+	ANNOTATION_CIRCUIT_BREAKER_OPEN_TIMEOUT         = "seldon.io/circuit-breaker-open-timeout"
+
+	ANNOTATION_MAX_INFLIGHT_REQUESTS      = "seldon.io/max-inflight-requests"
+	ANNOTATION_MAX_QUEUED_REQUESTS        = "seldon.io/max-queued-requests"
+	ANNOTATION_QUEUE_TIMEOUT              = "seldon.io/queue-timeout"
+	ANNOTATION_NODE_MAX_INFLIGHT_REQUESTS = "seldon.io/node-max-inflight-requests"
+	ANNOTATION_NODE_MAX_QUEUED_REQUESTS   = "seldon.io/node-max-queued-requests"
+
+	ANNOTATION_AUTH_API_KEYS_FILE       = "seldon.io/auth-api-keys-file"
+	ANNOTATION_AUTH_JWKS                = "seldon.io/auth-jwks"
+	ANNOTATION_AUTH_JWT_ISSUER          = "seldon.io/auth-jwt-issuer"
+	ANNOTATION_AUTH_JWT_AUDIENCE        = "seldon.io/auth-jwt-audience"
+	ANNOTATION_AUTH_EXEMPT_PATHS        = "seldon.io/auth-exempt-paths"
+	ANNOTATION_AUTH_FORWARD_CREDENTIALS = "seldon.io/auth-forward-credentials"
+
+	ANNOTATION_CLIENT_TLS                      = "seldon.io/client-tls"
+	ANNOTATION_CLIENT_TLS_CA_FILE              = "seldon.io/client-tls-ca-file"
+	ANNOTATION_CLIENT_TLS_CERT_FILE            = "seldon.io/client-tls-cert-file"
+	ANNOTATION_CLIENT_TLS_KEY_FILE             = "seldon.io/client-tls-key-file"
+	ANNOTATION_CLIENT_TLS_SERVER_NAME          = "seldon.io/client-tls-server-name"
+	ANNOTATION_CLIENT_TLS_INSECURE_SKIP_VERIFY = "seldon.io/client-tls-insecure-skip-verify"
+
+	ANNOTATION_VALIDATE_REQUESTS = "seldon.io/validate-requests"
+
+	ANNOTATION_LOGGER_SPOOL_DIR           = "seldon.io/logger-spool-dir"
+	ANNOTATION_LOGGER_SPOOL_MAX_BYTES     = "seldon.io/logger-spool-max-bytes"
+	ANNOTATION_LOGGER_SPOOL_SEGMENT_BYTES = "seldon.io/logger-spool-segment-bytes"
+	ANNOTATION_LOGGER_SPOOL_SYNC          = "seldon.io/logger-spool-sync"
+	ANNOTATION_LOGGER_SPOOL_SYNC_INTERVAL = "seldon.io/logger-spool-sync-interval"
+
+	ANNOTATION_LOGGER_BATCH_SIZE    = "seldon.io/logger-batch-size"
+	ANNOTATION_LOGGER_BATCH_TIMEOUT = "seldon.io/logger-batch-timeout"
 )
 
 func trimQuotes(v string) string {
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/k8s"
+)
+
+const (
+	DefaultBatchTimeoutMs = 100
+)
+
+type BatchSettings struct {
+	// Maximum number of logs sent in one request
+	Size int
+	// Maximum time to wait for more logs before sending a batch which isn't full
+	Timeout time.Duration
+}
+
+// BatchSettingsFromAnnotations returns nil settings if logs aren't batched.
+func BatchSettingsFromAnnotations(annotations map[string]string) (*BatchSettings, error) {
+	size, err := getInt64FromAnnotations(annotations, k8s.ANNOTATION_LOGGER_BATCH_SIZE, 1)
+	if err != nil {
+		return nil, err
+	}
+	if size == 1 {
+		return nil, nil
+	}
+	timeoutMs, err := getInt64FromAnnotations(annotations, k8s.ANNOTATION_LOGGER_BATCH_TIMEOUT, DefaultBatchTimeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	return &BatchSettings{Size: int(size), Timeout: time.Duration(timeoutMs) * time.Millisecond}, nil
+}
+
+// collectBatch adds the logs queued within the batch timeout to the batch started by first, until it is full.
+func (w *Worker) collectBatch(first LogRequest) []LogRequest {
+	batch := []LogRequest{first}
+	timer := time.NewTimer(w.Batch.Timeout)
+	defer timer.Stop()
+	for len(batch) < w.Batch.Size {
+		select {
+		case work := <-w.Work:
+			batch = append(batch, work)
+		case <-timer.C:
+			return batch
+		}
+	}
+	return batch
+}
+
+// groupByUrl splits the batch into the logs for each logger, keeping their order.
+func groupByUrl(batch []LogRequest) [][]LogRequest {
+	var groups [][]LogRequest
+	index := make(map[string]int)
+	for _, logReq := range batch {
+		url := logReq.Url.String()
+		i, ok := index[url]
+		if !ok {
+			i = len(groups)
+			index[url] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], logReq)
+	}
+	return groups
+}
@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/k8s"
+)
+
+func TestBatchSettingsFromAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	settings, err := BatchSettingsFromAnnotations(map[string]string{})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings).To(BeNil())
+
+	settings, err = BatchSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_LOGGER_BATCH_SIZE: "1"})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings).To(BeNil())
+
+	settings, err = BatchSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_LOGGER_BATCH_SIZE: "50"})
+	g.Expect(err).To(BeNil())
+	g.Expect(*settings).To(Equal(BatchSettings{Size: 50, Timeout: 100 * time.Millisecond}))
+
+	settings, err = BatchSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_LOGGER_BATCH_SIZE: "50", k8s.ANNOTATION_LOGGER_BATCH_TIMEOUT: "20"})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings.Timeout).To(Equal(20 * time.Millisecond))
+
+	_, err = BatchSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_LOGGER_BATCH_SIZE: "0"})
+	g.Expect(err).ToNot(BeNil())
+	_, err = BatchSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_LOGGER_BATCH_SIZE: "many"})
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestGroupByUrl(t *testing.T) {
+	g := NewGomegaWithT(t)
+	a, _ := url.Parse("http://a")
+	b, _ := url.Parse("http://b")
+	batch := []LogRequest{{Url: a, Id: "1"}, {Url: b, Id: "2"}, {Url: a, Id: "3"}}
+
+	groups := groupByUrl(batch)
+	g.Expect(groups).To(HaveLen(2))
+	g.Expect(groups[0]).To(Equal([]LogRequest{batch[0], batch[2]}))
+	g.Expect(groups[1]).To(Equal([]LogRequest{batch[1]}))
+}
+
+// startWorker starts a worker reading from its own queue.
+func startWorker(g *GomegaWithT, batch *BatchSettings) (*Worker, chan LogRequest) {
+	queue := make(chan LogRequest, 10)
+	w, err := NewWorker(1, queue, logr.Discard(), "dep", "default", "p", "", "", "seldon")
+	g.Expect(err).To(BeNil())
+	w.Batch = batch
+	w.Start()
+	return w, queue
+}
+
+func TestCloudEventClientIsReused(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var connections, received int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		atomic.AddInt32(&received, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	w, queue := startWorker(g, nil)
+	defer w.Stop()
+	for _, id := range []string{"1", "2", "3"} {
+		logReq := createSpoolLogRequest(id)
+		logReq.Url, _ = url.Parse(server.URL)
+		queue <- logReq
+	}
+
+	g.Eventually(func() int32 { return atomic.LoadInt32(&received) }, 5*time.Second).Should(Equal(int32(3)))
+	g.Expect(w.ceClients).To(HaveLen(1))
+	g.Expect(atomic.LoadInt32(&connections)).To(Equal(int32(1)))
+}
+
+func TestCloudEventsAreBatched(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var mu sync.Mutex
+	var contentTypes []string
+	var batches [][]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+		batches = append(batches, batch)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	w, queue := startWorker(g, &BatchSettings{Size: 2, Timeout: 50 * time.Millisecond})
+	defer w.Stop()
+	for _, id := range []string{"1", "2", "3"} {
+		logReq := createSpoolLogRequest(id)
+		logReq.Url, _ = url.Parse(server.URL)
+		queue <- logReq
+	}
+
+	// The last log is sent once the batch timeout expires
+	g.Eventually(func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(batches)
+	}, 5*time.Second).Should(Equal(2))
+	mu.Lock()
+	defer mu.Unlock()
+	g.Expect(contentTypes).To(ConsistOf(cloudevents.ApplicationCloudEventsBatchJSON, cloudevents.ApplicationCloudEventsBatchJSON))
+	g.Expect(batches[0]).To(HaveLen(2))
+	g.Expect(batches[1]).To(HaveLen(1))
+	g.Expect(batches[0][0]["id"]).To(Equal("1"))
+	g.Expect(batches[0][0]["type"]).To(Equal(CEInferenceRequest))
+	g.Expect(batches[0][0][ModelIdAttr]).To(Equal("model"))
+	g.Expect(batches[0][0]["data"]).To(Equal(map[string]interface{}{"data": map[string]interface{}{"ndarray": []interface{}{float64(1)}}}))
+	g.Expect(batches[0][1]["id"]).To(Equal("2"))
+	g.Expect(batches[1][0]["id"]).To(Equal("3"))
+}
+
+func TestSpooledBatchesAreRetried(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var attempts, received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []interface{}
+		_ = json.NewDecoder(r.Body).Decode(&batch)
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		atomic.AddInt32(&received, int32(len(batch)))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	s := createSpool(g, t.TempDir(), DefaultSpoolMaxBytes, DefaultSpoolSegmentBytes)
+	defer s.Close()
+	queue := make(chan LogRequest)
+	go s.drain(queue)
+	w, err := NewWorker(1, queue, logr.Discard(), "dep", "default", "p", "", "", "seldon")
+	g.Expect(err).To(BeNil())
+	w.Batch = &BatchSettings{Size: 2, Timeout: time.Second}
+	w.Start()
+	defer w.Stop()
+
+	for _, id := range []string{"1", "2"} {
+		logReq := createSpoolLogRequest(id)
+		logReq.Url, _ = url.Parse(server.URL)
+		g.Expect(s.Append(logReq)).To(BeNil())
+	}
+
+	g.Eventually(func() int32 { return atomic.LoadInt32(&received) }, 5*time.Second).Should(Equal(int32(2)))
+	g.Eventually(func() int {
+		pending, _ := s.Depth()
+		return pending
+	}).Should(Equal(0))
+	g.Expect(atomic.LoadInt32(&attempts)).To(Equal(int32(2)))
+}
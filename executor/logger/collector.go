@@ -2,7 +2,10 @@ package logger
 
 import (
 	"errors"
+	"sync"
 	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/metric"
 )
 
 const (
@@ -10,21 +13,55 @@ const (
 	DefaultWriteTimeoutMilliseconds = 2000
 )
 
+// Reasons for dropping logs, counted in the dropped logs metric.
+const (
+	DroppedBufferFull = "buffer_full"
+	DroppedSpoolFull  = "spool_full"
+	DroppedSpoolError = "spool_error"
+	DroppedInvalid    = "invalid"
+)
+
 var (
 	// Default values of these variables are declared here. StartDispatcher can overwrite them with user provided values.
 	// workQueue is a buffered channel that we can send work requests on.
 	workQueue = make(chan LogRequest, DefaultWorkQueueSize)
 	// writeTimeoutMilliseconds is the timeout for waiting for work to be written to the queue. If 0, will not wait if buffer is full.
 	writeTimeoutMilliseconds = DefaultWriteTimeoutMilliseconds
+	// spool saves logs on disk before they are sent, if enabled with EnableSpool.
+	spool *Spool
+	// batchSettings batches CloudEvents, if enabled with EnableBatching.
+	batchSettings *BatchSettings
+
+	loggerMetrics   *metric.LoggerMetrics
+	loggerMetricsMu sync.Mutex
 )
 
+func getLoggerMetrics() *metric.LoggerMetrics {
+	loggerMetricsMu.Lock()
+	defer loggerMetricsMu.Unlock()
+	if loggerMetrics == nil {
+		loggerMetrics = metric.NewLoggerMetrics()
+	}
+	return loggerMetrics
+}
+
 func QueueLogRequest(req LogRequest) error {
+	if spool != nil {
+		err := spool.Append(req)
+		if err == ErrSpoolFull {
+			getLoggerMetrics().DroppedCounter.WithLabelValues(DroppedSpoolFull).Inc()
+		} else if err != nil {
+			getLoggerMetrics().DroppedCounter.WithLabelValues(DroppedSpoolError).Inc()
+		}
+		return err
+	}
 	timer := time.NewTimer(time.Duration(writeTimeoutMilliseconds) * time.Millisecond)
 	defer timer.Stop()
 	select {
 	case workQueue <- req:
 		return nil
 	case <-timer.C:
+		getLoggerMetrics().DroppedCounter.WithLabelValues(DroppedBufferFull).Inc()
 		return errors.New("timed out waiting to queue log request: buffer is full")
 	}
 }
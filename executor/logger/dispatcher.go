@@ -26,6 +26,10 @@ func StartDispatcher(nworkers int, logBufferSize int, writeTimeoutMs int, log lo
 
 	workQueue = make(chan LogRequest, logBufferSize)
 	writeTimeoutMilliseconds = writeTimeoutMs
+	if spool != nil {
+		log.Info("Sending logs from spool", "dir", spool.settings.Dir)
+		go spool.drain(workQueue)
+	}
 	// Now, create all of our workers.
 	for i := 0; i < nworkers; i++ {
 		log.Info("Starting", "worker", i+1)
@@ -38,3 +42,28 @@ func StartDispatcher(nworkers int, logBufferSize int, writeTimeoutMs int, log lo
 
 	return nil
 }
+
+// EnableSpool saves logs in a spool on disk before they are sent, instead of dropping them when the work queue is
+// full. It must be called before StartDispatcher.
+func EnableSpool(settings SpoolSettings, log logr.Logger) error {
+	s, err := OpenSpool(settings, log)
+	if err != nil {
+		return err
+	}
+	spool = s
+	return nil
+}
+
+// EnableBatching sends the CloudEvents of logs for the same logger in batches, instead of a request each. It must be
+// called before StartDispatcher.
+func EnableBatching(settings BatchSettings) {
+	batchSettings = &settings
+}
+
+// CloseSpool syncs the spool, if enabled, so the logs which haven't been sent are sent after a restart.
+func CloseSpool() error {
+	if spool == nil {
+		return nil
+	}
+	return spool.Close()
+}
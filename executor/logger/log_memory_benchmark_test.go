@@ -1,13 +1,18 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/go-logr/logr"
 	"github.com/seldonio/seldon-core/executor/api"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	_ "net/http/pprof"
 	"net/url"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -90,3 +95,79 @@ func startSlowLogListener() int {
 
 	return port
 }
+
+/*
+Benchmark of the throughput of sending logs to a fast request logger, with each log in its own request and in batches.
+The allocations show the cost of each log, which used to include a new CloudEvents client and connection.
+To run:
+go test -bench=CloudEventsDelivery -run=^$ .
+*/
+func BenchmarkCloudEventsDelivery(b *testing.B) {
+	for _, bc := range []struct {
+		name  string
+		batch *BatchSettings
+	}{
+		{name: "single"},
+		{name: "batch-10", batch: &BatchSettings{Size: 10, Timeout: 10 * time.Millisecond}},
+		{name: "batch-100", batch: &BatchSettings{Size: 100, Timeout: 10 * time.Millisecond}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			benchmarkCloudEventsDelivery(b, bc.batch)
+		})
+	}
+}
+
+func benchmarkCloudEventsDelivery(b *testing.B, batch *BatchSettings) {
+	var received int64
+	done := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int64(1)
+		if batch != nil {
+			var events []json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&events); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			n = int64(len(events))
+		} else {
+			_, _ = ioutil.ReadAll(r.Body)
+		}
+		if atomic.AddInt64(&received, n) == int64(b.N) {
+			close(done)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	logURL, err := url.Parse(server.URL)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	queue := make(chan LogRequest, DefaultWorkQueueSize)
+	for i := 0; i < 5; i++ {
+		worker, err := NewWorker(i+1, queue, logr.Discard(), "test-name", "test-namespace", "test-predictor", "", "", api.ProtocolSeldon)
+		if err != nil {
+			b.Fatal(err)
+		}
+		worker.Batch = batch
+		worker.Start()
+		defer worker.Stop()
+	}
+
+	testMessage := []byte(`{"data":{"ndarray":[[1,2,3,4]]}}`)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		queue <- LogRequest{
+			Url:         logURL,
+			Bytes:       &testMessage,
+			ContentType: "application/json",
+			ReqType:     InferenceRequest,
+			Id:          fmt.Sprint(i),
+			SourceUri:   logURL,
+			ModelId:     "1",
+			RequestId:   "1",
+		}
+	}
+	<-done
+}
@@ -0,0 +1,623 @@
+package logger
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/seldonio/seldon-core/executor/k8s"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Policies for syncing the spool files to disk.
+const (
+	// Sync after each log so no acknowledged log is lost if the node fails
+	SpoolSyncAlways = "always"
+	// Sync periodically, losing at most the logs of the last interval if the node fails
+	SpoolSyncInterval = "interval"
+	// Leave syncing to the operating system
+	SpoolSyncNever = "never"
+)
+
+const (
+	DefaultSpoolMaxBytes       = 1 << 30
+	DefaultSpoolSegmentBytes   = 16 << 20
+	DefaultSpoolSyncIntervalMs = 1000
+
+	spoolSegmentSuffix   = ".spool"
+	spoolFrameHeaderSize = 8
+)
+
+var (
+	ErrSpoolFull      = errors.New("log spool is full")
+	errSpoolClosed    = errors.New("log spool is closed")
+	errSpoolCorrupted = errors.New("log spool record is corrupted")
+)
+
+type SpoolSettings struct {
+	// Directory of the spool files, on a volume which outlives the executor container
+	Dir string
+	// Size of the spool above which new logs are dropped
+	MaxBytes int64
+	// Size of each spool file, which is deleted once all its logs are sent
+	SegmentBytes int64
+	Sync         string
+	SyncInterval time.Duration
+}
+
+func getInt64FromAnnotations(annotations map[string]string, key string, defaultValue int64) (int64, error) {
+	val, ok := annotations[key]
+	if !ok || val == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse annotation %s: %w", key, err)
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("annotation %s must be positive, got %d", key, n)
+	}
+	return n, nil
+}
+
+// SpoolSettingsFromAnnotations returns nil settings if no spool directory is configured.
+func SpoolSettingsFromAnnotations(annotations map[string]string) (*SpoolSettings, error) {
+	dir := annotations[k8s.ANNOTATION_LOGGER_SPOOL_DIR]
+	if dir == "" {
+		return nil, nil
+	}
+	settings := &SpoolSettings{Dir: dir, Sync: SpoolSyncInterval}
+	var err error
+	if settings.MaxBytes, err = getInt64FromAnnotations(annotations, k8s.ANNOTATION_LOGGER_SPOOL_MAX_BYTES, DefaultSpoolMaxBytes); err != nil {
+		return nil, err
+	}
+	if settings.SegmentBytes, err = getInt64FromAnnotations(annotations, k8s.ANNOTATION_LOGGER_SPOOL_SEGMENT_BYTES, DefaultSpoolSegmentBytes); err != nil {
+		return nil, err
+	}
+	if settings.SegmentBytes > settings.MaxBytes {
+		settings.SegmentBytes = settings.MaxBytes
+	}
+	if sync := annotations[k8s.ANNOTATION_LOGGER_SPOOL_SYNC]; sync != "" {
+		switch sync {
+		case SpoolSyncAlways, SpoolSyncInterval, SpoolSyncNever:
+			settings.Sync = sync
+		default:
+			return nil, fmt.Errorf("invalid %s %q, must be one of %s, %s or %s", k8s.ANNOTATION_LOGGER_SPOOL_SYNC, sync, SpoolSyncAlways, SpoolSyncInterval, SpoolSyncNever)
+		}
+	}
+	intervalMs, err := getInt64FromAnnotations(annotations, k8s.ANNOTATION_LOGGER_SPOOL_SYNC_INTERVAL, DefaultSpoolSyncIntervalMs)
+	if err != nil {
+		return nil, err
+	}
+	settings.SyncInterval = time.Duration(intervalMs) * time.Millisecond
+	return settings, nil
+}
+
+// spooledRequest is the form of a LogRequest saved in the spool.
+type spooledRequest struct {
+	Url             string         `json:"url"`
+	Bytes           []byte         `json:"bytes"`
+	ContentType     string         `json:"contentType,omitempty"`
+	ContentEncoding string         `json:"contentEncoding,omitempty"`
+	ReqType         LogRequestType `json:"type"`
+	Id              string         `json:"id"`
+	SourceUri       string         `json:"sourceUri"`
+	ModelId         string         `json:"modelId,omitempty"`
+	RequestId       string         `json:"requestId,omitempty"`
+	TraceId         string         `json:"traceId,omitempty"`
+	SpanId          string         `json:"spanId,omitempty"`
+	TraceFlags      byte           `json:"traceFlags,omitempty"`
+}
+
+func urlString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+func encodeLogRequest(req LogRequest) ([]byte, error) {
+	spooled := spooledRequest{
+		Url:             urlString(req.Url),
+		ContentType:     req.ContentType,
+		ContentEncoding: req.ContentEncoding,
+		ReqType:         req.ReqType,
+		Id:              req.Id,
+		SourceUri:       urlString(req.SourceUri),
+		ModelId:         req.ModelId,
+		RequestId:       req.RequestId,
+	}
+	if req.Bytes != nil {
+		spooled.Bytes = *req.Bytes
+	}
+	if req.SpanContext.IsValid() {
+		spooled.TraceId = req.SpanContext.TraceID().String()
+		spooled.SpanId = req.SpanContext.SpanID().String()
+		spooled.TraceFlags = byte(req.SpanContext.TraceFlags())
+	}
+	return json.Marshal(spooled)
+}
+
+func decodeLogRequest(data []byte) (LogRequest, error) {
+	var spooled spooledRequest
+	if err := json.Unmarshal(data, &spooled); err != nil {
+		return LogRequest{}, err
+	}
+	logUrl, err := url.Parse(spooled.Url)
+	if err != nil {
+		return LogRequest{}, err
+	}
+	sourceUri, err := url.Parse(spooled.SourceUri)
+	if err != nil {
+		return LogRequest{}, err
+	}
+	req := LogRequest{
+		Url:             logUrl,
+		Bytes:           &spooled.Bytes,
+		ContentType:     spooled.ContentType,
+		ContentEncoding: spooled.ContentEncoding,
+		ReqType:         spooled.ReqType,
+		Id:              spooled.Id,
+		SourceUri:       sourceUri,
+		ModelId:         spooled.ModelId,
+		RequestId:       spooled.RequestId,
+	}
+	if spooled.TraceId != "" {
+		// The trace is only continued from a valid span so a malformed one is ignored
+		traceId, _ := trace.TraceIDFromHex(spooled.TraceId)
+		spanId, _ := trace.SpanIDFromHex(spooled.SpanId)
+		req.SpanContext = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceId,
+			SpanID:     spanId,
+			TraceFlags: trace.TraceFlags(spooled.TraceFlags),
+			Remote:     true,
+		})
+	}
+	return req, nil
+}
+
+// A spool file holds a sequence of records, each a 4 byte length and a 4 byte CRC-32 of the data followed by the data.
+func encodeFrame(data []byte) []byte {
+	frame := make([]byte, spoolFrameHeaderSize+len(data))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(data))
+	copy(frame[spoolFrameHeaderSize:], data)
+	return frame
+}
+
+// readFrame returns the data of the record at the offset, io.EOF at the end of the file or errSpoolCorrupted if the
+// record is incomplete or doesn't match its checksum.
+func readFrame(file *os.File, offset int64, size int64) ([]byte, error) {
+	if offset >= size {
+		return nil, io.EOF
+	}
+	if size-offset < spoolFrameHeaderSize {
+		return nil, errSpoolCorrupted
+	}
+	header := make([]byte, spoolFrameHeaderSize)
+	if _, err := file.ReadAt(header, offset); err != nil {
+		return nil, err
+	}
+	length := int64(binary.BigEndian.Uint32(header[0:4]))
+	if size-offset-spoolFrameHeaderSize < length {
+		return nil, errSpoolCorrupted
+	}
+	data := make([]byte, length)
+	if _, err := file.ReadAt(data, offset+spoolFrameHeaderSize); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errSpoolCorrupted
+	}
+	return data, nil
+}
+
+type spoolSegment struct {
+	seq  uint64
+	path string
+	file *os.File
+	size int64
+	// Records written, handed to the workers and acknowledged
+	records int
+	read    int
+	acked   int
+	// Offset of the next record to hand out
+	readOffset int64
+	// Sealed segments are no longer written to
+	sealed bool
+	dirty  bool
+}
+
+// spoolRecord is a log handed out by the spool, which must be acknowledged once sent.
+type spoolRecord struct {
+	spool   *Spool
+	segment *spoolSegment
+	data    []byte
+}
+
+func (r *spoolRecord) ack() {
+	r.spool.ack(r)
+}
+
+// Spool is a write-ahead log of payload logs on disk, so logs waiting to be sent survive logger outages and
+// restarts of the executor. Logs are appended to segment files which are deleted once all their logs are sent. Logs
+// handed out but not acknowledged when the executor stops are sent again after a restart, so a log may be sent more
+// than once.
+type Spool struct {
+	settings SpoolSettings
+	log      logr.Logger
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	segments []*spoolSegment
+	nextSeq  uint64
+	size     int64
+	pending  int
+	closed   bool
+	done     chan struct{}
+}
+
+// OpenSpool opens the spool in the directory, recovering the logs left by a previous executor.
+func OpenSpool(settings SpoolSettings, log logr.Logger) (*Spool, error) {
+	if err := os.MkdirAll(settings.Dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &Spool{
+		settings: settings,
+		log:      log.WithName("LogSpool"),
+		nextSeq:  1,
+		done:     make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	if err := s.recover(); err != nil {
+		s.closeFiles()
+		return nil, err
+	}
+	if s.pending > 0 {
+		s.log.Info("Recovered logs from spool", "dir", settings.Dir, "logs", s.pending, "bytes", s.size)
+	}
+	s.updateMetrics()
+	if settings.Sync == SpoolSyncInterval {
+		go s.syncLoop()
+	}
+	return s, nil
+}
+
+func (s *Spool) recover() error {
+	entries, err := ioutil.ReadDir(s.settings.Dir)
+	if err != nil {
+		return err
+	}
+	var seqs []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, spoolSegmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	for _, seq := range seqs {
+		seg, err := s.recoverSegment(seq)
+		if err != nil {
+			return err
+		}
+		if seg != nil {
+			s.segments = append(s.segments, seg)
+			s.size += seg.size
+			s.pending += seg.records
+		}
+		s.nextSeq = seq + 1
+	}
+	return nil
+}
+
+// recoverSegment scans a segment left by a previous executor, truncating any record which was partially written
+// when it stopped. Empty segments are removed.
+func (s *Spool) recoverSegment(seq uint64) (*spoolSegment, error) {
+	path := s.segmentPath(seq)
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	seg := &spoolSegment{seq: seq, path: path, file: file, sealed: true}
+	for {
+		data, err := readFrame(file, seg.size, info.Size())
+		if err == io.EOF {
+			break
+		} else if err == errSpoolCorrupted {
+			s.log.Info("Truncating incomplete record of log spool", "file", path, "offset", seg.size)
+			if err := file.Truncate(seg.size); err != nil {
+				file.Close()
+				return nil, err
+			}
+			break
+		} else if err != nil {
+			file.Close()
+			return nil, err
+		}
+		seg.size += spoolFrameHeaderSize + int64(len(data))
+		seg.records++
+	}
+	if seg.records == 0 {
+		file.Close()
+		return nil, os.Remove(path)
+	}
+	return seg, nil
+}
+
+func (s *Spool) segmentPath(seq uint64) string {
+	return filepath.Join(s.settings.Dir, fmt.Sprintf("%020d%s", seq, spoolSegmentSuffix))
+}
+
+// Append saves the log in the spool, returning ErrSpoolFull if there is no room for it.
+func (s *Spool) Append(req LogRequest) error {
+	data, err := encodeLogRequest(req)
+	if err != nil {
+		return err
+	}
+	frame := encodeFrame(data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errSpoolClosed
+	}
+	if s.size+int64(len(frame)) > s.settings.MaxBytes {
+		return ErrSpoolFull
+	}
+	seg, err := s.writableSegment(int64(len(frame)))
+	if err != nil {
+		return err
+	}
+	if _, err := seg.file.WriteAt(frame, seg.size); err != nil {
+		// Drop any partial record so the next one is written in its place
+		_ = seg.file.Truncate(seg.size)
+		return err
+	}
+	seg.size += int64(len(frame))
+	seg.records++
+	s.size += int64(len(frame))
+	s.pending++
+	if s.settings.Sync == SpoolSyncAlways {
+		if err := seg.file.Sync(); err != nil {
+			s.log.Error(err, "Failed to sync log spool", "file", seg.path)
+		}
+	} else {
+		seg.dirty = true
+	}
+	s.updateMetrics()
+	s.cond.Signal()
+	return nil
+}
+
+// writableSegment returns the segment to append a record of the given size to, starting a new one when the last is
+// full.
+func (s *Spool) writableSegment(n int64) (*spoolSegment, error) {
+	if len(s.segments) > 0 {
+		last := s.segments[len(s.segments)-1]
+		if !last.sealed {
+			if last.size == 0 || last.size+n <= s.settings.SegmentBytes {
+				return last, nil
+			}
+			s.seal(last)
+		}
+	}
+	seg := &spoolSegment{seq: s.nextSeq, path: s.segmentPath(s.nextSeq)}
+	file, err := os.OpenFile(seg.path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	seg.file = file
+	s.nextSeq++
+	if s.settings.Sync != SpoolSyncNever {
+		// Make sure the new file itself survives a failure of the node
+		if dir, err := os.Open(s.settings.Dir); err == nil {
+			_ = dir.Sync()
+			dir.Close()
+		}
+	}
+	s.segments = append(s.segments, seg)
+	return seg, nil
+}
+
+func (s *Spool) seal(seg *spoolSegment) {
+	seg.sealed = true
+	if seg.acked == seg.records {
+		s.remove(seg)
+		return
+	}
+	if seg.dirty && s.settings.Sync != SpoolSyncNever {
+		if err := seg.file.Sync(); err != nil {
+			s.log.Error(err, "Failed to sync log spool", "file", seg.path)
+		}
+		seg.dirty = false
+	}
+}
+
+func (s *Spool) remove(seg *spoolSegment) {
+	seg.file.Close()
+	if err := os.Remove(seg.path); err != nil {
+		s.log.Error(err, "Failed to remove log spool file", "file", seg.path)
+	}
+	s.size -= seg.size
+	for i, other := range s.segments {
+		if other == seg {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+}
+
+// next waits for a log which hasn't been handed out yet, oldest first.
+func (s *Spool) next() (*spoolRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		if s.closed {
+			return nil, errSpoolClosed
+		}
+		for _, seg := range s.segments {
+			if seg.read == seg.records {
+				continue
+			}
+			data, err := readFrame(seg.file, seg.readOffset, seg.size)
+			if err != nil {
+				// The rest of the segment can't be read so its logs are lost
+				lost := seg.records - seg.read
+				s.log.Error(err, "Failed to read log spool", "file", seg.path, "logs", lost)
+				getLoggerMetrics().DroppedCounter.WithLabelValues(DroppedSpoolError).Add(float64(lost))
+				seg.read = seg.records
+				seg.acked += lost
+				s.pending -= lost
+				s.removeIfSent(seg)
+				s.updateMetrics()
+				break
+			}
+			seg.readOffset += spoolFrameHeaderSize + int64(len(data))
+			seg.read++
+			return &spoolRecord{spool: s, segment: seg, data: data}, nil
+		}
+		if s.hasUnread() {
+			continue
+		}
+		s.cond.Wait()
+	}
+}
+
+func (s *Spool) hasUnread() bool {
+	for _, seg := range s.segments {
+		if seg.read < seg.records {
+			return true
+		}
+	}
+	return false
+}
+
+// removeIfSent removes a segment once all its logs are sent. The last segment is removed too, rather than kept for
+// appends, so the logs already sent aren't sent again after a restart.
+func (s *Spool) removeIfSent(seg *spoolSegment) {
+	if seg.read == seg.records && seg.acked == seg.records {
+		seg.sealed = true
+		s.remove(seg)
+	}
+}
+
+func (s *Spool) ack(rec *spoolRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		// The log is sent again after a restart
+		return
+	}
+	rec.segment.acked++
+	s.pending--
+	s.removeIfSent(rec.segment)
+	s.updateMetrics()
+}
+
+// Depth returns the number of logs in the spool which haven't been sent yet and the size of the spool files.
+func (s *Spool) Depth() (int, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending, s.size
+}
+
+func (s *Spool) updateMetrics() {
+	metrics := getLoggerMetrics()
+	metrics.SpoolRecordsGauge.Set(float64(s.pending))
+	metrics.SpoolBytesGauge.Set(float64(s.size))
+}
+
+func (s *Spool) syncLoop() {
+	ticker := time.NewTicker(s.settings.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			s.syncDirty()
+			s.mu.Unlock()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Spool) syncDirty() {
+	for _, seg := range s.segments {
+		if seg.dirty {
+			if err := seg.file.Sync(); err != nil {
+				s.log.Error(err, "Failed to sync log spool", "file", seg.path)
+			}
+			seg.dirty = false
+		}
+	}
+}
+
+// drain hands out the logs of the spool to the workers until the spool is closed.
+func (s *Spool) drain(queue chan<- LogRequest) {
+	for {
+		rec, err := s.next()
+		if err != nil {
+			return
+		}
+		req, err := decodeLogRequest(rec.data)
+		if err != nil {
+			s.log.Error(err, "Dropping invalid log from spool", "file", rec.segment.path)
+			getLoggerMetrics().DroppedCounter.WithLabelValues(DroppedInvalid).Inc()
+			rec.ack()
+			continue
+		}
+		req.spooled = rec
+		select {
+		case queue <- req:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close syncs the spool files and stops handing out logs. Logs which haven't been sent stay in the spool.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	s.cond.Broadcast()
+	if s.settings.Sync != SpoolSyncNever {
+		s.syncDirty()
+	}
+	s.closeFiles()
+	return nil
+}
+
+func (s *Spool) closeFiles() {
+	for _, seg := range s.segments {
+		seg.file.Close()
+	}
+}
@@ -0,0 +1,267 @@
+package logger
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/k8s"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func createSpool(g *GomegaWithT, dir string, maxBytes int64, segmentBytes int64) *Spool {
+	s, err := OpenSpool(SpoolSettings{Dir: dir, MaxBytes: maxBytes, SegmentBytes: segmentBytes, Sync: SpoolSyncAlways}, logr.Discard())
+	g.Expect(err).To(BeNil())
+	return s
+}
+
+func createSpoolLogRequest(id string) LogRequest {
+	logUrl, _ := url.Parse("http://logger.default")
+	sourceUri, _ := url.Parse("http://executor:8000")
+	data := []byte(`{"data":{"ndarray":[1]}}`)
+	return LogRequest{
+		Url:         logUrl,
+		Bytes:       &data,
+		ContentType: "application/json",
+		ReqType:     InferenceRequest,
+		Id:          id,
+		SourceUri:   sourceUri,
+		ModelId:     "model",
+		RequestId:   "puid",
+	}
+}
+
+func spoolFiles(g *GomegaWithT, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+spoolSegmentSuffix))
+	g.Expect(err).To(BeNil())
+	return files
+}
+
+func nextLogRequest(g *GomegaWithT, s *Spool) (LogRequest, *spoolRecord) {
+	rec, err := s.next()
+	g.Expect(err).To(BeNil())
+	req, err := decodeLogRequest(rec.data)
+	g.Expect(err).To(BeNil())
+	return req, rec
+}
+
+func TestSpoolSettingsFromAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	settings, err := SpoolSettingsFromAnnotations(map[string]string{})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings).To(BeNil())
+
+	settings, err = SpoolSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_LOGGER_SPOOL_DIR: "/spool"})
+	g.Expect(err).To(BeNil())
+	g.Expect(*settings).To(Equal(SpoolSettings{
+		Dir:          "/spool",
+		MaxBytes:     DefaultSpoolMaxBytes,
+		SegmentBytes: DefaultSpoolSegmentBytes,
+		Sync:         SpoolSyncInterval,
+		SyncInterval: time.Second,
+	}))
+
+	settings, err = SpoolSettingsFromAnnotations(map[string]string{
+		k8s.ANNOTATION_LOGGER_SPOOL_DIR:           "/spool",
+		k8s.ANNOTATION_LOGGER_SPOOL_MAX_BYTES:     "1000",
+		k8s.ANNOTATION_LOGGER_SPOOL_SEGMENT_BYTES: "2000",
+		k8s.ANNOTATION_LOGGER_SPOOL_SYNC:          SpoolSyncAlways,
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(settings.SegmentBytes).To(Equal(int64(1000)))
+	g.Expect(settings.Sync).To(Equal(SpoolSyncAlways))
+
+	_, err = SpoolSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_LOGGER_SPOOL_DIR: "/spool", k8s.ANNOTATION_LOGGER_SPOOL_SYNC: "sometimes"})
+	g.Expect(err).ToNot(BeNil())
+	_, err = SpoolSettingsFromAnnotations(map[string]string{k8s.ANNOTATION_LOGGER_SPOOL_DIR: "/spool", k8s.ANNOTATION_LOGGER_SPOOL_MAX_BYTES: "-1"})
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestSpoolAppendAndAck(t *testing.T) {
+	g := NewGomegaWithT(t)
+	dir := t.TempDir()
+	s := createSpool(g, dir, DefaultSpoolMaxBytes, DefaultSpoolSegmentBytes)
+	defer s.Close()
+
+	traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	first := createSpoolLogRequest("1")
+	first.SpanContext = trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceId, SpanID: spanId, TraceFlags: trace.FlagsSampled})
+	g.Expect(s.Append(first)).To(BeNil())
+	g.Expect(s.Append(createSpoolLogRequest("2"))).To(BeNil())
+	pending, size := s.Depth()
+	g.Expect(pending).To(Equal(2))
+	g.Expect(size).To(BeNumerically(">", 0))
+
+	req, rec1 := nextLogRequest(g, s)
+	g.Expect(req.Id).To(Equal("1"))
+	g.Expect(req.Url.String()).To(Equal("http://logger.default"))
+	g.Expect(*req.Bytes).To(Equal(*first.Bytes))
+	g.Expect(req.SpanContext.TraceID()).To(Equal(traceId))
+	g.Expect(req.SpanContext.SpanID()).To(Equal(spanId))
+	g.Expect(req.SpanContext.IsSampled()).To(BeTrue())
+	req, rec2 := nextLogRequest(g, s)
+	g.Expect(req.Id).To(Equal("2"))
+	g.Expect(req.SpanContext.IsValid()).To(BeFalse())
+
+	// The file is removed once all its logs are sent, whatever the order
+	rec2.ack()
+	g.Expect(spoolFiles(g, dir)).To(HaveLen(1))
+	rec1.ack()
+	g.Expect(spoolFiles(g, dir)).To(HaveLen(0))
+	pending, size = s.Depth()
+	g.Expect(pending).To(Equal(0))
+	g.Expect(size).To(Equal(int64(0)))
+
+	g.Expect(s.Append(createSpoolLogRequest("3"))).To(BeNil())
+	req, _ = nextLogRequest(g, s)
+	g.Expect(req.Id).To(Equal("3"))
+}
+
+func TestSpoolSegmentsAndMaxBytes(t *testing.T) {
+	g := NewGomegaWithT(t)
+	dir := t.TempDir()
+	data, err := encodeLogRequest(createSpoolLogRequest("1"))
+	g.Expect(err).To(BeNil())
+	frameSize := int64(spoolFrameHeaderSize + len(data))
+	s := createSpool(g, dir, 5*frameSize, 2*frameSize)
+	defer s.Close()
+
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		g.Expect(s.Append(createSpoolLogRequest(id))).To(BeNil())
+	}
+	g.Expect(spoolFiles(g, dir)).To(HaveLen(3))
+	g.Expect(s.Append(createSpoolLogRequest("6"))).To(Equal(ErrSpoolFull))
+
+	// Sending the logs of the first file makes room for more
+	_, rec1 := nextLogRequest(g, s)
+	_, rec2 := nextLogRequest(g, s)
+	rec1.ack()
+	rec2.ack()
+	g.Expect(spoolFiles(g, dir)).To(HaveLen(2))
+	g.Expect(s.Append(createSpoolLogRequest("6"))).To(BeNil())
+	for _, id := range []string{"3", "4", "5", "6"} {
+		req, _ := nextLogRequest(g, s)
+		g.Expect(req.Id).To(Equal(id))
+	}
+}
+
+func TestSpoolRecovery(t *testing.T) {
+	g := NewGomegaWithT(t)
+	dir := t.TempDir()
+	s := createSpool(g, dir, DefaultSpoolMaxBytes, DefaultSpoolSegmentBytes)
+	for _, id := range []string{"1", "2", "3"} {
+		g.Expect(s.Append(createSpoolLogRequest(id))).To(BeNil())
+	}
+	_, rec := nextLogRequest(g, s)
+	rec.ack()
+	g.Expect(s.Close()).To(BeNil())
+
+	// Simulate a record partially written when the executor stopped
+	files := spoolFiles(g, dir)
+	g.Expect(files).To(HaveLen(1))
+	file, err := os.OpenFile(files[0], os.O_WRONLY|os.O_APPEND, 0644)
+	g.Expect(err).To(BeNil())
+	_, err = file.Write([]byte{0, 0, 1, 0, 1, 2})
+	g.Expect(err).To(BeNil())
+	g.Expect(file.Close()).To(BeNil())
+
+	// Logs acknowledged in a file which still has logs to send are sent again
+	s = createSpool(g, dir, DefaultSpoolMaxBytes, DefaultSpoolSegmentBytes)
+	defer s.Close()
+	pending, _ := s.Depth()
+	g.Expect(pending).To(Equal(3))
+	g.Expect(s.Append(createSpoolLogRequest("4"))).To(BeNil())
+	for _, id := range []string{"1", "2", "3", "4"} {
+		req, _ := nextLogRequest(g, s)
+		g.Expect(req.Id).To(Equal(id))
+	}
+	g.Expect(spoolFiles(g, dir)).To(HaveLen(2))
+}
+
+func TestSpooledLogsAreRetried(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var attempts, received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		atomic.AddInt32(&received, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	g.Expect(EnableSpool(SpoolSettings{Dir: dir, MaxBytes: DefaultSpoolMaxBytes, SegmentBytes: DefaultSpoolSegmentBytes, Sync: SpoolSyncNever}, logr.Discard())).To(BeNil())
+	defer func() {
+		_ = CloseSpool()
+		spool = nil
+	}()
+	g.Expect(StartDispatcher(1, DefaultWorkQueueSize, DefaultWriteTimeoutMilliseconds, logr.Discard(), "dep", "default", "p", "", "", "seldon")).To(BeNil())
+
+	req := createSpoolLogRequest("1")
+	req.Url, _ = url.Parse(server.URL)
+	g.Expect(QueueLogRequest(req)).To(BeNil())
+
+	g.Eventually(func() int32 { return atomic.LoadInt32(&received) }, 5*time.Second).Should(Equal(int32(1)))
+	g.Eventually(func() int {
+		pending, _ := spool.Depth()
+		return pending
+	}).Should(Equal(0))
+	g.Expect(atomic.LoadInt32(&attempts)).To(Equal(int32(2)))
+	g.Expect(spoolFiles(g, dir)).To(HaveLen(0))
+}
+
+func TestRejectedSpooledLogsAreDropped(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	g.Expect(EnableSpool(SpoolSettings{Dir: dir, MaxBytes: DefaultSpoolMaxBytes, SegmentBytes: DefaultSpoolSegmentBytes, Sync: SpoolSyncNever}, logr.Discard())).To(BeNil())
+	defer func() {
+		_ = CloseSpool()
+		spool = nil
+	}()
+	g.Expect(StartDispatcher(1, DefaultWorkQueueSize, DefaultWriteTimeoutMilliseconds, logr.Discard(), "dep", "default", "p", "", "", "seldon")).To(BeNil())
+
+	req := createSpoolLogRequest("1")
+	req.Url, _ = url.Parse(server.URL)
+	g.Expect(QueueLogRequest(req)).To(BeNil())
+
+	// The log is acknowledged without being sent again
+	g.Eventually(func() int {
+		pending, _ := spool.Depth()
+		return pending
+	}, 5*time.Second).Should(Equal(0))
+	g.Consistently(func() int32 { return atomic.LoadInt32(&attempts) }, time.Second).Should(Equal(int32(1)))
+}
+
+func TestStatusError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	err := errors.New("while sending event")
+
+	for _, statusCode := range []int{http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge} {
+		g.Expect(statusError(statusCode, err)).To(BeAssignableToTypeOf(&permanentError{}), "%d", statusCode)
+	}
+	for _, statusCode := range []int{http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		g.Expect(statusError(statusCode, err)).To(Equal(err), "%d", statusCode)
+	}
+}
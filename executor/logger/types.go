@@ -2,6 +2,8 @@ package logger
 
 import (
 	"net/url"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type LogRequestType string
@@ -22,4 +24,8 @@ type LogRequest struct {
 	SourceUri       *url.URL
 	ModelId         string
 	RequestId       string
+	// Span of the request whose payload is logged, continued when the payload is sent
+	SpanContext trace.SpanContext
+	// Record of the log in the spool, acknowledged once the log is sent
+	spooled *spoolRecord
 }
@@ -1,18 +1,30 @@
 package logger
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"time"
 
-	cloudevents "github.com/cloudevents/sdk-go"
-	"github.com/cloudevents/sdk-go/pkg/cloudevents/transport"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/event"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/go-logr/logr"
+	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/api/util"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -28,8 +40,31 @@ const (
 	ProtocolAttr             = "protocol"
 	KafkaTypeHeader          = "type"
 	KafkaContentTypeHeader   = "content-type"
+
+	TracingLogPayloadName = "logPayload"
+
+	CloudEventsTimeout = 60 * time.Second
+	// Time to wait for Kafka to acknowledge a log before sending it again
+	KafkaDeliveryTimeout = 60 * time.Second
+
+	SpoolRetryInitialBackoffMs = 500
+	SpoolRetryMaxBackoffMs     = 30000
 )
 
+// permanentError is a failure to send a log which retrying won't fix, such as an invalid payload.
+type permanentError struct {
+	error
+}
+
+// statusError returns the error of a response from a logger. Client errors other than timeouts and rate limiting are
+// permanent as the logger would reject the log again.
+func statusError(statusCode int, err error) error {
+	if statusCode >= 400 && statusCode < 500 && statusCode != http.StatusRequestTimeout && statusCode != http.StatusTooManyRequests {
+		return &permanentError{err}
+	}
+	return err
+}
+
 // NewWorker creates, and returns a new Worker object. Its only argument
 // is a channel that the worker can add itself to whenever it is done its
 // work.
@@ -50,6 +85,10 @@ func NewWorker(
 	if kafkaBroker != "" {
 		log.Info("Creating producer", "broker", kafkaBroker, "topic", kafkaTopic)
 		producerConfig := util.GetKafkaProducerConfig(kafkaBroker)
+		// Logs are only acknowledged once Kafka has them, from the report sent to the channel of each message
+		if err = producerConfig.SetKey("go.delivery.reports", true); err != nil {
+			return nil, err
+		}
 		producer, err = kafka.NewProducer(producerConfig)
 		if err != nil {
 			return nil, err
@@ -63,33 +102,41 @@ func NewWorker(
 		ID:       id,
 		Work:     workQueue,
 		QuitChan: make(chan bool),
-		Client: http.Client{
-			Timeout: 60 * time.Second,
+		// Connections to the loggers are kept alive and reused by all the logs the worker sends
+		Client: &http.Client{
+			Transport: tracingRoundTripper{base: http.DefaultTransport.(*http.Transport).Clone()},
+			Timeout:   CloudEventsTimeout,
 		},
-		CeCtx:           cloudevents.ContextWithEncoding(context.Background(), cloudevents.Binary),
+		ceClients:       make(map[string]cloudevents.Client),
 		SdepName:        sdepName,
 		Namespace:       namespace,
 		PredictorName:   predictorName,
 		KafkaTopic:      kafkaTopic,
 		Producer:        producer,
 		PayloadProtocol: protocol,
+		Retrier:         client.NewRetrier(&v1.RetryPolicy{InitialBackoffMs: SpoolRetryInitialBackoffMs, MaxBackoffMs: SpoolRetryMaxBackoffMs}),
+		Batch:           batchSettings,
 	}, nil
 }
 
 type Worker struct {
-	Log             logr.Logger
-	ID              int
-	Work            chan LogRequest
-	QuitChan        chan bool
-	Client          http.Client
-	CeCtx           context.Context
-	CeTransport     transport.Transport
+	Log      logr.Logger
+	ID       int
+	Work     chan LogRequest
+	QuitChan chan bool
+	Client   *http.Client
+	// CloudEvents client of each logger URL, sending with Client
+	ceClients       map[string]cloudevents.Client
 	SdepName        string
 	Namespace       string
 	PredictorName   string
 	KafkaTopic      string
 	Producer        *kafka.Producer
 	PayloadProtocol string
+	// Backoff between attempts to send spooled logs
+	Retrier *client.Retrier
+	// Batching of CloudEvents, nil to send each log in its own request
+	Batch *BatchSettings
 }
 
 func getCEType(logReq LogRequest) (string, error) {
@@ -105,16 +152,54 @@ func getCEType(logReq LogRequest) (string, error) {
 	}
 }
 
-func (w *Worker) sendKafkaEvent(logReq LogRequest) error {
+// startSpan starts the span of sending a payload, as part of the trace of the request it was logged from.
+func (w *Worker) startSpan(logReq LogRequest) (context.Context, trace.Span) {
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), logReq.SpanContext)
+	return tracing.Tracer().Start(ctx, TracingLogPayloadName,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("seldon.log.type", string(logReq.ReqType)),
+			attribute.String("seldon.log.model", logReq.ModelId),
+			attribute.String("seldon.log.request_id", logReq.RequestId),
+		))
+}
+
+// startBatchSpan starts the span of sending a batch of payloads, linked to the traces of the requests they were
+// logged from.
+func (w *Worker) startBatchSpan(logReqs []LogRequest) (context.Context, trace.Span) {
+	links := make([]trace.Link, 0, len(logReqs))
+	for _, logReq := range logReqs {
+		if logReq.SpanContext.IsValid() {
+			links = append(links, trace.Link{SpanContext: logReq.SpanContext})
+		}
+	}
+	return tracing.Tracer().Start(context.Background(), TracingLogPayloadName,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("seldon.log.batch_size", len(logReqs))))
+}
+
+// tracingRoundTripper propagates the trace of the request context.
+type tracingRoundTripper struct {
+	base http.RoundTripper
+}
+
+func (t tracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return t.base.RoundTrip(req)
+}
+
+func (w *Worker) sendKafkaEvent(ctx context.Context, logReq LogRequest) error {
 
 	data, err := payload.DecompressBytes(*logReq.Bytes, logReq.ContentEncoding)
 	if err != nil {
-		return fmt.Errorf("while creating kafka transport: %s", err)
+		return &permanentError{fmt.Errorf("while creating kafka transport: %s", err)}
 	}
 
 	reqType, err := getCEType(logReq)
 	if err != nil {
-		return err
+		return &permanentError{err}
 	}
 
 	kafkaHeaders := []kafka.Header{
@@ -127,21 +212,38 @@ func (w *Worker) sendKafkaEvent(logReq LogRequest) error {
 		{Key: EndpointAttr, Value: []byte(w.PredictorName)},
 		{Key: ProtocolAttr, Value: []byte(w.PayloadProtocol)},
 	}
+	traceHeaders := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, traceHeaders)
+	for k, v := range traceHeaders {
+		kafkaHeaders = append(kafkaHeaders, kafka.Header{Key: k, Value: []byte(v)})
+	}
 	w.Log.Info("kafkaHeaders is", "kafkaHeaders", kafkaHeaders)
+	deliveryChan := make(chan kafka.Event, 1)
 	err = w.Producer.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &w.KafkaTopic, Partition: kafka.PartitionAny},
 		Value:          data,
 		Headers:        kafkaHeaders,
-	}, nil)
+	}, deliveryChan)
 	if err != nil {
 		w.Log.Error(err, "Failed to produce response")
 		return err
 	}
 
+	timer := time.NewTimer(KafkaDeliveryTimeout)
+	defer timer.Stop()
+	select {
+	case e := <-deliveryChan:
+		if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+			return fmt.Errorf("while delivering kafka message: %s", m.TopicPartition.Error)
+		}
+	case <-timer.C:
+		return fmt.Errorf("timed out waiting for delivery of kafka message to %s", w.KafkaTopic)
+	}
 	return nil
 }
 
-func (w *Worker) sendCloudEvent(logReq LogRequest) error {
+// newCloudEvent creates the CloudEvent of a log. Its errors are permanent.
+func (w *Worker) newCloudEvent(logReq LogRequest) (*event.Event, error) {
 
 	// This temporary fix related to the fact that Triton server responses
 	// are now gzipped compressed. Until we introduce support for gzip
@@ -149,51 +251,184 @@ func (w *Worker) sendCloudEvent(logReq LogRequest) error {
 	// header in the CloudEvent messages this can serve as temporary solution.
 	data, err := payload.DecompressBytes(*logReq.Bytes, logReq.ContentEncoding)
 	if err != nil {
-		return fmt.Errorf("while creating http transport: %s", err)
+		return nil, &permanentError{fmt.Errorf("while decompressing payload: %s", err)}
+	}
+
+	ce := cloudevents.NewEvent(cloudevents.VersionV1)
+	ce.SetID(logReq.Id)
+	if refType, err := getCEType(logReq); err == nil {
+		ce.SetType(refType)
+	} else {
+		return nil, &permanentError{err}
 	}
 
-	t, err := cloudevents.NewHTTPTransport(
-		cloudevents.WithTarget(logReq.Url.String()),
-		cloudevents.WithEncoding(cloudevents.HTTPBinaryV1),
-	)
+	ce.SetExtension(ModelIdAttr, logReq.ModelId)
+	ce.SetExtension(RequestIdAttr, logReq.RequestId)
+	ce.SetExtension(InferenceServiceNameAttr, w.SdepName)
+	ce.SetExtension(NamespaceAttr, w.Namespace)
+	//use 'endpoint' for the header to align with kfserving - https://github.com/kubeflow/kfserving/pull/699/files#r385360114
+	ce.SetExtension(EndpointAttr, w.PredictorName)
+	ce.SetExtension(ProtocolAttr, w.PayloadProtocol)
+
+	ce.SetSource(logReq.SourceUri.String())
+	if err := ce.SetData(logReq.ContentType, data); err != nil {
+		return nil, &permanentError{fmt.Errorf("while setting cloudevents data: %s", err)}
+	}
+	// JSON payloads are embedded as they are, rather than in base64, in batches
+	if mediaType := ce.DataMediaType(); (mediaType == cloudevents.ApplicationJSON || mediaType == event.TextJSON) && json.Valid(data) {
+		ce.DataBase64 = false
+	}
+	if err := ce.Validate(); err != nil {
+		return nil, &permanentError{fmt.Errorf("invalid cloudevent: %s", err)}
+	}
+	return &ce, nil
+}
 
+// cloudEventClient returns the client of the logger, creating it on first use.
+func (w *Worker) cloudEventClient(target string) (cloudevents.Client, error) {
+	if c, ok := w.ceClients[target]; ok {
+		return c, nil
+	}
+	p, err := cehttp.New(cehttp.WithTarget(target), cehttp.WithClient(*w.Client))
 	if err != nil {
-		return fmt.Errorf("while creating http transport: %s", err)
+		return nil, &permanentError{fmt.Errorf("while creating http transport: %s", err)}
 	}
-	c, err := cloudevents.NewClient(t,
-		cloudevents.WithTimeNow(),
-	)
+	c, err := cloudevents.NewClient(p, cloudevents.WithTimeNow())
 	if err != nil {
-		return fmt.Errorf("while creating new cloudevents client: %s", err)
+		return nil, fmt.Errorf("while creating new cloudevents client: %s", err)
 	}
-	event := cloudevents.NewEvent(cloudevents.VersionV1)
-	event.SetID(logReq.Id)
-	if refType, err := getCEType(logReq); err == nil {
-		event.SetType(refType)
-	} else {
+	w.ceClients[target] = c
+	return c, nil
+}
+
+func (w *Worker) sendCloudEvent(ctx context.Context, logReq LogRequest) error {
+	ce, err := w.newCloudEvent(logReq)
+	if err != nil {
 		return err
 	}
+	c, err := w.cloudEventClient(logReq.Url.String())
+	if err != nil {
+		return err
+	}
+	// The span is propagated in the headers of the request by the transport
+	if result := c.Send(cloudevents.WithEncodingBinary(ctx), *ce); !cloudevents.IsACK(result) {
+		err := fmt.Errorf("while sending event: %s", result)
+		var httpResult *cehttp.Result
+		if cloudevents.ResultAs(result, &httpResult) {
+			return statusError(httpResult.StatusCode, err)
+		}
+		return err
+	}
+	return nil
+}
 
-	event.SetExtension(ModelIdAttr, logReq.ModelId)
-	event.SetExtension(RequestIdAttr, logReq.RequestId)
-	event.SetExtension(InferenceServiceNameAttr, w.SdepName)
-	event.SetExtension(NamespaceAttr, w.Namespace)
-	//use 'endpoint' for the header to align with kfserving - https://github.com/kubeflow/kfserving/pull/699/files#r385360114
-	event.SetExtension(EndpointAttr, w.PredictorName)
-	event.SetExtension(ProtocolAttr, w.PayloadProtocol)
+// sendCloudEventBatch sends the events in one request in the CloudEvents JSON batch format.
+func (w *Worker) sendCloudEventBatch(ctx context.Context, target string, events []event.Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return &permanentError{fmt.Errorf("while encoding cloudevents batch: %s", err)}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{fmt.Errorf("while creating batch request: %s", err)}
+	}
+	req.Header.Set("Content-Type", cloudevents.ApplicationCloudEventsBatchJSON)
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("while sending batch: %s", err)
+	}
+	defer resp.Body.Close()
+	// Read the response so the connection is reused
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return statusError(resp.StatusCode, fmt.Errorf("while sending batch: logger returned %s", resp.Status))
+	}
+	return nil
+}
+
+// send sends the log to Kafka or as a CloudEvent.
+func (w *Worker) send(work LogRequest) error {
+	ctx, span := w.startSpan(work)
+	defer span.End()
+	var err error
+	if w.KafkaTopic != "" {
+		if err = w.sendKafkaEvent(ctx, work); err != nil {
+			w.Log.Error(err, "Failed to send kafka log", "Topic", w.KafkaTopic)
+		}
+	} else {
+		if err = w.sendCloudEvent(ctx, work); err != nil {
+			w.Log.Error(err, "Failed to send cloudevent log", "URL", work.Url.String())
+		}
+	}
+	tracing.RecordError(span, err)
+	return err
+}
 
-	event.SetSource(logReq.SourceUri.String())
-	event.SetDataContentType(logReq.ContentType)
-	if err := event.SetData(data); err != nil {
-		return fmt.Errorf("while setting cloudevents data: %s", err)
+// sendBatch sends the events of logs for the same logger in one request.
+func (w *Worker) sendBatch(logReqs []LogRequest, events []event.Event) error {
+	ctx, span := w.startBatchSpan(logReqs)
+	defer span.End()
+	err := w.sendCloudEventBatch(ctx, logReqs[0].Url.String(), events)
+	if err != nil {
+		w.Log.Error(err, "Failed to send cloudevent batch", "URL", logReqs[0].Url.String(), "size", len(events))
 	}
+	tracing.RecordError(span, err)
+	return err
+}
 
-	//fmt.Printf("%+v\n", event)
+// deliver sends the logs with send. Logs from the spool are retried until they are sent, or dropped on a permanent
+// error, and then acknowledged. It returns false if the worker was stopped first, leaving the logs in the spool.
+func (w *Worker) deliver(logReqs []LogRequest, send func() error) bool {
+	err := send()
+	if logReqs[0].spooled == nil {
+		return true
+	}
+	for retry := 1; err != nil; retry++ {
+		if _, ok := err.(*permanentError); ok {
+			getLoggerMetrics().DroppedCounter.WithLabelValues(DroppedInvalid).Add(float64(len(logReqs)))
+			break
+		}
+		timer := time.NewTimer(w.Retrier.Backoff(retry))
+		select {
+		case <-timer.C:
+		case <-w.QuitChan:
+			timer.Stop()
+			return false
+		}
+		err = send()
+	}
+	for _, logReq := range logReqs {
+		logReq.spooled.ack()
+	}
+	return true
+}
 
-	if _, _, err := c.Send(w.CeCtx, event); err != nil {
-		return fmt.Errorf("while sending event: %s", err)
+// deliverBatch sends the logs of the batch in a request per logger. Logs whose event can't be created are dropped.
+func (w *Worker) deliverBatch(batch []LogRequest) bool {
+	for _, logReqs := range groupByUrl(batch) {
+		valid := make([]LogRequest, 0, len(logReqs))
+		events := make([]event.Event, 0, len(logReqs))
+		for _, logReq := range logReqs {
+			e, err := w.newCloudEvent(logReq)
+			if err != nil {
+				w.Log.Error(err, "Failed to create cloudevent log", "URL", logReq.Url.String())
+				if logReq.spooled != nil {
+					getLoggerMetrics().DroppedCounter.WithLabelValues(DroppedInvalid).Inc()
+					logReq.spooled.ack()
+				}
+				continue
+			}
+			valid = append(valid, logReq)
+			events = append(events, *e)
+		}
+		if len(valid) == 0 {
+			continue
+		}
+		if !w.deliver(valid, func() error { return w.sendBatch(valid, events) }) {
+			return false
+		}
 	}
-	return nil
+	return true
 }
 
 // This function "starts" the worker by starting a goroutine, that is
@@ -204,15 +439,15 @@ func (w *Worker) Start() {
 			select {
 			case work := <-w.Work:
 				// Receive a work request.
-
-				if w.KafkaTopic != "" {
-					if err := w.sendKafkaEvent(work); err != nil {
-						w.Log.Error(err, "Failed to send kafka log", "Topic", w.KafkaTopic)
-					}
+				var delivered bool
+				if w.Batch != nil && w.KafkaTopic == "" {
+					delivered = w.deliverBatch(w.collectBatch(work))
 				} else {
-					if err := w.sendCloudEvent(work); err != nil {
-						w.Log.Error(err, "Failed to send cloudevent log", "URL", work.Url.String())
-					}
+					delivered = w.deliver([]LogRequest{work}, func() error { return w.send(work) })
+				}
+				if !delivered {
+					fmt.Printf("worker %d stopping\n", w.ID)
+					return
 				}
 
 			case <-w.QuitChan:
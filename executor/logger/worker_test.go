@@ -3,13 +3,19 @@ package logger
 import (
 	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 
 	_ "net/http/pprof"
 	"testing"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-logr/logr"
 	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -258,3 +264,46 @@ func TestWorkerKafkaConfigurationsString(t *testing.T) {
 		})
 	}
 }
+
+func TestCloudEventContinuesTrace(t *testing.T) {
+	g := NewGomegaWithT(t)
+	exporter, reset := test.SetupTestTracing()
+	defer reset()
+
+	var traceparent, ceId string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		ceId = r.Header.Get("Ce-Id")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	logUrl, _ := url.Parse(server.URL)
+
+	traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	data := []byte(`{"data":{"ndarray":[1]}}`)
+	logReq := LogRequest{
+		Url:         logUrl,
+		Bytes:       &data,
+		ContentType: "application/json",
+		ReqType:     InferenceRequest,
+		Id:          "1",
+		SourceUri:   logUrl,
+		ModelId:     "model",
+		RequestId:   "puid",
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceId, SpanID: spanId, TraceFlags: trace.FlagsSampled}),
+	}
+	w, err := NewWorker(1, nil, logr.Discard(), "dep", "default", "p", "", "", "seldon")
+	g.Expect(err).To(BeNil())
+	ctx, span := w.startSpan(logReq)
+	g.Expect(w.sendCloudEvent(ctx, logReq)).To(BeNil())
+	span.End()
+
+	spans := exporter.GetSpans()
+	g.Expect(spans).To(HaveLen(1))
+	g.Expect(spans[0].Name).To(Equal(TracingLogPayloadName))
+	g.Expect(spans[0].Parent.SpanID()).To(Equal(spanId))
+	g.Expect(traceparent).To(Equal("00-" + traceId.String() + "-" + spans[0].SpanContext.SpanID().String() + "-01"))
+	// Events are sent in binary mode
+	g.Expect(ceId).To(Equal("1"))
+}
@@ -0,0 +1,303 @@
+package predictor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// PredictorVersion is a version of the predictor spec loaded by the executor.
+type PredictorVersion struct {
+	Spec *v1.PredictorSpec `json:"-"`
+	Name string            `json:"name"`
+	// Number of the version, starting at 1 for the spec loaded at startup
+	Generation int64 `json:"generation"`
+	// SHA-256 of the spec
+	Hash     string    `json:"hash"`
+	LoadedAt time.Time `json:"loadedAt"`
+
+	// Keys of the node state used by the version, computed once when it's loaded
+	keys *nodeStateKeys
+	// Requests using the version and whether it has been replaced, its node state being released once both
+	mu       sync.Mutex
+	requests int
+	replaced bool
+}
+
+// newPredictorVersion loads a version of the spec, holding on to the node state it uses until it is released.
+func newPredictorVersion(spec *v1.PredictorSpec, generation int64, hash string) *PredictorVersion {
+	v := &PredictorVersion{
+		Spec:       spec,
+		Name:       predictorName(spec),
+		Generation: generation,
+		Hash:       hash,
+		LoadedAt:   time.Now(),
+		keys:       newNodeStateKeys(spec),
+	}
+	retainNodeState(v.keys)
+	return v
+}
+
+// acquire adds a request to the version, failing if it has already been replaced.
+func (v *PredictorVersion) acquire() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.replaced {
+		return false
+	}
+	v.requests++
+	return true
+}
+
+// retain adds a request to the version on behalf of one already using it, e.g. a mirrored request outliving it.
+func (v *PredictorVersion) retain() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.requests++
+}
+
+// Release ends a request using the version.
+func (v *PredictorVersion) Release() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.requests--
+	if v.replaced && v.requests == 0 {
+		releaseNodeState(v.keys)
+	}
+}
+
+// replace marks the version as replaced, releasing its node state once the requests using it are done.
+func (v *PredictorVersion) replace() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.replaced = true
+	if v.requests == 0 {
+		releaseNodeState(v.keys)
+	}
+}
+
+// nodeStateKey returns the key of the batcher and cache of the node in this version.
+func (v *PredictorVersion) nodeStateKey(node *v1.PredictiveUnit) string {
+	if v != nil {
+		if key, ok := v.keys.nodes[node.Name]; ok {
+			return key
+		}
+	}
+	return nodeStateKey(node)
+}
+
+// banditKey returns the key of the router of the bandit node in this version.
+func (v *PredictorVersion) banditKey(node *v1.PredictiveUnit) string {
+	if v != nil {
+		if key, ok := v.keys.bandits[node.Name]; ok {
+			return key
+		}
+	}
+	return banditKey(node)
+}
+
+// cacheable returns whether the responses of the node can be cached. They can't if a router below it may route the
+// same request differently, as a cache hit would skip its routing.
+func (v *PredictorVersion) cacheable(node *v1.PredictiveUnit) bool {
+	if v != nil {
+		if _, ok := v.keys.nodes[node.Name]; ok {
+			return !v.keys.uncacheable[node.Name]
+		}
+	}
+	return v1.NondeterministicRouter(node) == ""
+}
+
+// ActivePredictor holds the predictor spec requests are served with. The spec can be swapped for a new version of
+// the graph while requests are in flight, each request keeping the spec it started with.
+type ActivePredictor struct {
+	// Serializes updates
+	mu      sync.Mutex
+	current atomic.Value
+}
+
+func NewActivePredictor(spec *v1.PredictorSpec) *ActivePredictor {
+	a := &ActivePredictor{}
+	a.current.Store(newPredictorVersion(spec, 1, hashPredictor(spec)))
+	return a
+}
+
+// Get returns the active spec. Callers should use the returned spec for the whole of a request.
+func (a *ActivePredictor) Get() *v1.PredictorSpec {
+	return a.Version().Spec
+}
+
+// Acquire returns the active version for a request, which must release it once done. The node state of a version,
+// e.g. its batch queues and cache, is kept until the requests using it are done even if it has been replaced.
+func (a *ActivePredictor) Acquire() *PredictorVersion {
+	for {
+		if v := a.Version(); v.acquire() {
+			return v
+		}
+	}
+}
+
+func (a *ActivePredictor) Version() *PredictorVersion {
+	return a.current.Load().(*PredictorVersion)
+}
+
+// Update validates the spec and makes it the active one. It returns whether the graph changed, the spec being
+// ignored if it is identical to the active one.
+func (a *ActivePredictor) Update(spec *v1.PredictorSpec) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	current := a.Version()
+	if err := ValidatePredictorUpdate(current.Spec, spec); err != nil {
+		return false, err
+	}
+	hash := hashPredictor(spec)
+	if hash == current.Hash {
+		return false, nil
+	}
+	a.current.Store(newPredictorVersion(spec, current.Generation+1, hash))
+	current.replace()
+	return true, nil
+}
+
+// ValidatePredictorUpdate checks a new version of the spec can replace the current one.
+func ValidatePredictorUpdate(current *v1.PredictorSpec, spec *v1.PredictorSpec) error {
+	if spec == nil {
+		return fmt.Errorf("Predictor is empty")
+	}
+	// Clients and metrics are created for the predictor at startup
+	if current != nil && spec.Name != current.Name {
+		return fmt.Errorf("Predictor name can't change from %s to %s", current.Name, spec.Name)
+	}
+	names := make(map[string]bool)
+	for _, node := range v1.GetPredictiveUnitList(&spec.Graph) {
+		if node.Name == "" {
+			return fmt.Errorf("Graph has a node with no name")
+		}
+		if names[node.Name] {
+			return fmt.Errorf("Graph has more than one node named %s", node.Name)
+		}
+		names[node.Name] = true
+		// Nodes not implemented by the executor call a container
+		if (node.Implementation == nil || *node.Implementation == v1.UNKNOWN_IMPLEMENTATION || v1.IsPrepack(node)) &&
+			(node.Endpoint == nil || node.Endpoint.ServiceHost == "") {
+			return fmt.Errorf("Graph node %s has no endpoint", node.Name)
+		}
+	}
+	return nil
+}
+
+func predictorName(spec *v1.PredictorSpec) string {
+	if spec == nil {
+		return ""
+	}
+	return spec.Name
+}
+
+func hashPredictor(spec *v1.PredictorSpec) string {
+	data, _ := json.Marshal(spec)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// nodeStateKey keys the state shared by requests to a node, e.g. its batcher or cache, on its name and spec so
+// requests using different versions of the graph never share state.
+func nodeStateKey(node *v1.PredictiveUnit) string {
+	data, _ := json.Marshal(node)
+	sum := sha256.Sum256(data)
+	return node.Name + "/" + hex.EncodeToString(sum[:])
+}
+
+// nodeStateKeys are the keys of the node state of a version of the graph.
+type nodeStateKeys struct {
+	// Keys of the batchers and caches by node name
+	nodes map[string]string
+	// Keys of the bandit routers by node name
+	bandits map[string]string
+	// Expressions of the conditional routers
+	conditions []string
+	// Nodes with a cache policy whose responses can't be cached
+	uncacheable map[string]bool
+}
+
+func newNodeStateKeys(spec *v1.PredictorSpec) *nodeStateKeys {
+	keys := &nodeStateKeys{nodes: make(map[string]string), bandits: make(map[string]string), uncacheable: make(map[string]bool)}
+	if spec == nil {
+		return keys
+	}
+	for _, node := range v1.GetPredictiveUnitList(&spec.Graph) {
+		keys.nodes[node.Name] = nodeStateKey(node)
+		if isBanditRouter(node) {
+			keys.bandits[node.Name] = banditKey(node)
+		}
+		if node.Cache != nil && v1.NondeterministicRouter(node) != "" {
+			keys.uncacheable[node.Name] = true
+		}
+		if isConditionalRouter(node) {
+			for _, rule := range node.Parameters {
+				keys.conditions = append(keys.conditions, rule.Value)
+			}
+		}
+	}
+	return keys
+}
+
+var (
+	nodeStateMu sync.Mutex
+	// Number of loaded versions using each entry of the node state
+	nodeStateRefs      = make(map[string]int)
+	banditStateRefs    = make(map[string]int)
+	conditionStateRefs = make(map[string]int)
+)
+
+func retainNodeState(keys *nodeStateKeys) {
+	nodeStateMu.Lock()
+	defer nodeStateMu.Unlock()
+	for _, key := range keys.nodes {
+		nodeStateRefs[key]++
+	}
+	for _, key := range keys.bandits {
+		banditStateRefs[key]++
+	}
+	for _, expr := range keys.conditions {
+		conditionStateRefs[expr]++
+	}
+}
+
+// releaseNodeState frees the entries of the node state no loaded version uses any more.
+func releaseNodeState(keys *nodeStateKeys) {
+	nodeStateMu.Lock()
+	defer nodeStateMu.Unlock()
+	for _, key := range keys.nodes {
+		if releaseRef(nodeStateRefs, key) {
+			batchers.Delete(key)
+			responseCaches.Delete(key)
+		}
+	}
+	for _, key := range keys.bandits {
+		if releaseRef(banditStateRefs, key) {
+			banditsMutex.Lock()
+			delete(bandits, key)
+			banditsMutex.Unlock()
+		}
+	}
+	for _, expr := range keys.conditions {
+		if releaseRef(conditionStateRefs, expr) {
+			conditions.Delete(expr)
+		}
+	}
+}
+
+// releaseRef decrements the references to the key and returns whether it has none left.
+func releaseRef(refs map[string]int, key string) bool {
+	refs[key]--
+	if refs[key] > 0 {
+		return false
+	}
+	delete(refs, key)
+	return true
+}
@@ -0,0 +1,153 @@
+package predictor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func createActiveTestPredictor() *v1.PredictorSpec {
+	model := v1.MODEL
+	router := v1.SIMPLE_ROUTER
+	return &v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name:           "router",
+			Implementation: &router,
+			Children: []v1.PredictiveUnit{
+				{
+					Name:     "model",
+					Type:     &model,
+					Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000},
+				},
+			},
+		},
+	}
+}
+
+func TestActivePredictorUpdate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createActiveTestPredictor()
+	active := NewActivePredictor(spec)
+	g.Expect(active.Version().Generation).Should(Equal(int64(1)))
+
+	// An identical spec is not a new version
+	changed, err := active.Update(createActiveTestPredictor())
+	g.Expect(err).Should(BeNil())
+	g.Expect(changed).Should(BeFalse())
+
+	updated := createActiveTestPredictor()
+	updated.Graph.Children[0].Endpoint.ServicePort = 9001
+	changed, err = active.Update(updated)
+	g.Expect(err).Should(BeNil())
+	g.Expect(changed).Should(BeTrue())
+	g.Expect(active.Get()).Should(Equal(updated))
+	g.Expect(active.Version().Generation).Should(Equal(int64(2)))
+	g.Expect(active.Version().Hash).ShouldNot(Equal(hashPredictor(spec)))
+}
+
+// createTwoModelPredictor returns a predictor whose first model is batched and second model cached.
+func createTwoModelPredictor(port int32) *v1.PredictorSpec {
+	spec := createActiveTestPredictor()
+	spec.Graph.Children = append(spec.Graph.Children, spec.Graph.Children[0])
+	spec.Graph.Children[0].Batching = &v1.BatchingPolicy{}
+	spec.Graph.Children[0].Endpoint = &v1.Endpoint{ServiceHost: "foo", ServicePort: port}
+	spec.Graph.Children[1].Name = "cached"
+	spec.Graph.Children[1].Cache = &v1.CachePolicy{}
+	return spec
+}
+
+func TestNodeStateKeptUntilRequestsAreDone(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	active := NewActivePredictor(createTwoModelPredictor(9000))
+	previous := active.Acquire()
+	model := &previous.Spec.Graph.Children[0]
+	key := previous.nodeStateKey(model)
+	b := getBatcher(key, model)
+	cached := &previous.Spec.Graph.Children[1]
+	cacheKey := previous.nodeStateKey(cached)
+	cache := getResponseCache(cacheKey, cached)
+
+	changed, err := active.Update(createTwoModelPredictor(9001))
+	g.Expect(err).Should(BeNil())
+	g.Expect(changed).Should(BeTrue())
+
+	// A request still using the previous version keeps its batcher
+	g.Expect(getBatcher(previous.nodeStateKey(model), model)).Should(BeIdenticalTo(b))
+	current := active.Acquire()
+	g.Expect(current).ShouldNot(BeIdenticalTo(previous))
+	g.Expect(current.nodeStateKey(&current.Spec.Graph.Children[0])).ShouldNot(Equal(key))
+	current.Release()
+
+	// and it's freed once the request is done, unlike the state of the unchanged node
+	previous.Release()
+	_, ok := batchers.Load(key)
+	g.Expect(ok).Should(BeFalse())
+	g.Expect(current.nodeStateKey(&current.Spec.Graph.Children[1])).Should(Equal(cacheKey))
+	g.Expect(getResponseCache(cacheKey, &current.Spec.Graph.Children[1])).Should(BeIdenticalTo(cache))
+}
+
+func TestNodeStateKeyedOnSpec(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	node := createModelNode("versioned")
+	node.Batching = &v1.BatchingPolicy{MaxBatchSize: 4}
+	b := getBatcher(nodeStateKey(node), node)
+	same := createModelNode("versioned")
+	same.Batching = &v1.BatchingPolicy{MaxBatchSize: 4}
+	g.Expect(getBatcher(nodeStateKey(same), same)).Should(BeIdenticalTo(b))
+
+	// Requests still using the previous version never share a batcher with the new one
+	updated := createModelNode("versioned")
+	updated.Batching = &v1.BatchingPolicy{MaxBatchSize: 8}
+	g.Expect(getBatcher(nodeStateKey(updated), updated)).ShouldNot(BeIdenticalTo(b))
+	g.Expect(getBatcher(nodeStateKey(updated), updated).maxBatchSize).Should(Equal(8))
+	g.Expect(getBatcher(nodeStateKey(node), node).maxBatchSize).Should(Equal(4))
+}
+
+func TestActivePredictorUpdateInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	active := NewActivePredictor(createActiveTestPredictor())
+
+	renamed := createActiveTestPredictor()
+	renamed.Name = "other"
+	noEndpoint := createActiveTestPredictor()
+	noEndpoint.Graph.Children[0].Endpoint = nil
+	duplicate := createActiveTestPredictor()
+	duplicate.Graph.Children[0].Name = "router"
+
+	for _, spec := range []*v1.PredictorSpec{nil, renamed, noEndpoint, duplicate} {
+		changed, err := active.Update(spec)
+		g.Expect(err).ShouldNot(BeNil())
+		g.Expect(changed).Should(BeFalse())
+	}
+	g.Expect(active.Version().Generation).Should(Equal(int64(1)))
+}
+
+func TestWatchPredictorFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "predictor")
+	g.Expect(err).Should(BeNil())
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "sdep.yaml")
+	g.Expect(ioutil.WriteFile(filename, []byte("spec: {}"), 0644)).Should(BeNil())
+
+	reloads := make(chan struct{}, 10)
+	stop := make(chan struct{})
+	defer close(stop)
+	err = WatchPredictorFile(filename, func() { reloads <- struct{}{} }, stop, logf.Log.WithName("watch"))
+	g.Expect(err).Should(BeNil())
+
+	g.Expect(ioutil.WriteFile(filename, []byte("spec: {predictors: []}"), 0644)).Should(BeNil())
+	g.Eventually(reloads, 5*time.Second).Should(Receive())
+}
@@ -0,0 +1,222 @@
+package predictor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/util"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+const (
+	banditEpsilonParameter = "epsilon"
+	defaultBanditEpsilon   = 0.1
+)
+
+// BanditStateStore is where the built-in bandit routers keep their state. It is in memory unless replaced at startup.
+var BanditStateStore BanditStore = NewInMemoryBanditStore()
+
+var (
+	banditsMutex sync.Mutex
+	// Routers shared by all requests, keyed by banditKey and freed once no loaded version uses them.
+	bandits = make(map[string]*banditRouter)
+)
+
+type banditRouter struct {
+	mu    sync.Mutex
+	key   string
+	state *BanditState
+	// Number of updates of the state, the last one saved being kept under saveMu
+	updates int64
+	saveMu  sync.Mutex
+	saved   int64
+}
+
+func isBanditRouter(node *v1.PredictiveUnit) bool {
+	return node.Implementation != nil && (*node.Implementation == v1.EPSILON_GREEDY || *node.Implementation == v1.THOMPSON_SAMPLING)
+}
+
+// banditKey keys the router of a node, and its saved state, on its name and the names of the children it routes to
+// so children which are renamed or reordered never inherit each other's rewards.
+func banditKey(node *v1.PredictiveUnit) string {
+	h := sha256.New()
+	for _, child := range withoutShadow(node).Children {
+		h.Write([]byte(child.Name))
+		h.Write([]byte{0})
+	}
+	return node.Name + "-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// getBanditRouter returns the router shared by all requests for the node, loading its saved state on first use.
+// Versions of the graph routing to the same children share the router.
+func getBanditRouter(key string, node *v1.PredictiveUnit) (*banditRouter, error) {
+	// Routes and feedback only refer to the children which serve requests
+	children := childNames(withoutShadow(node))
+	banditsMutex.Lock()
+	defer banditsMutex.Unlock()
+	if b, ok := bandits[key]; ok {
+		return b, nil
+	}
+	state, err := BanditStateStore.Load(key)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || !state.routesTo(children) {
+		state = newBanditState(children)
+	}
+	b := &banditRouter{key: key, state: state}
+	bandits[key] = b
+	return b, nil
+}
+
+func childNames(node *v1.PredictiveUnit) []string {
+	names := make([]string, len(node.Children))
+	for i, child := range node.Children {
+		names[i] = child.Name
+	}
+	return names
+}
+
+func getBanditEpsilon(node *v1.PredictiveUnit) (float64, error) {
+	for _, param := range node.Parameters {
+		if param.Name == banditEpsilonParameter {
+			return strconv.ParseFloat(param.Value, 64)
+		}
+	}
+	return defaultBanditEpsilon, nil
+}
+
+func (p *PredictorProcess) banditRoute(node *v1.PredictiveUnit) (int, error) {
+	if len(node.Children) == 0 {
+		return 0, fmt.Errorf("bandit router %s has no children", node.Name)
+	}
+	b, err := getBanditRouter(p.Version.banditKey(node), node)
+	if err != nil {
+		return 0, err
+	}
+	if *node.Implementation == v1.EPSILON_GREEDY {
+		epsilon, err := getBanditEpsilon(node)
+		if err != nil {
+			return 0, err
+		}
+		return b.epsilonGreedy(epsilon), nil
+	}
+	return b.thompsonSampling(), nil
+}
+
+// banditFeedback updates the router with the reward for the child it chose for the request.
+func (p *PredictorProcess) banditFeedback(node *v1.PredictiveUnit, msg payload.SeldonPayload) error {
+	routes, ok := feedbackRoutes(node, msg)
+	if !ok || len(routes) != 1 || routes[0] < 0 || routes[0] >= len(node.Children) {
+		p.Log.Info("Ignoring feedback without a route for bandit router", "node", node.Name)
+		return nil
+	}
+	route := routes[0]
+	var reward float32
+	var err error
+	if fm, ok := msg.GetPayload().(*proto.Feedback); ok {
+		reward = fm.GetReward()
+	} else if reward, err = util.RewardFromFeedbackJson(msg); err != nil {
+		return err
+	}
+	b, err := getBanditRouter(p.Version.banditKey(node), node)
+	if err != nil {
+		return err
+	}
+	if *node.Implementation == v1.THOMPSON_SAMPLING {
+		// Rewards are success rates so keep them within [0,1] for the Beta posterior
+		return b.update(route, math.Max(0, math.Min(1, float64(reward))))
+	}
+	return b.update(route, float64(reward))
+}
+
+func (b *banditRouter) epsilonGreedy(epsilon float64) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if rand.Float64() < epsilon {
+		return rand.Intn(len(b.state.Counts))
+	}
+	best, bestMean := 0, math.Inf(-1)
+	for i, count := range b.state.Counts {
+		mean := 0.0
+		if count > 0 {
+			mean = b.state.Rewards[i] / count
+		}
+		if mean > bestMean {
+			best, bestMean = i, mean
+		}
+	}
+	return best
+}
+
+// thompsonSampling treats rewards as successes and picks the child with the highest draw from its Beta posterior.
+func (b *banditRouter) thompsonSampling() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	best, bestDraw := 0, -1.0
+	for i, count := range b.state.Counts {
+		draw := sampleBeta(1+b.state.Rewards[i], 1+count-b.state.Rewards[i])
+		if draw > bestDraw {
+			best, bestDraw = i, draw
+		}
+	}
+	return best
+}
+
+// update adds the reward to the child and saves the state. The state is saved outside the lock so routing never
+// waits for the store.
+func (b *banditRouter) update(route int, reward float64) error {
+	b.mu.Lock()
+	if route < 0 || route >= len(b.state.Counts) {
+		b.mu.Unlock()
+		return fmt.Errorf("bandit router %s has no child %d", b.key, route)
+	}
+	b.state.Counts[route]++
+	b.state.Rewards[route] += reward
+	b.updates++
+	update, state := b.updates, b.state.copy()
+	b.mu.Unlock()
+
+	b.saveMu.Lock()
+	defer b.saveMu.Unlock()
+	// A later update has already been saved
+	if update <= b.saved {
+		return nil
+	}
+	if err := BanditStateStore.Save(b.key, state); err != nil {
+		return err
+	}
+	b.saved = update
+	return nil
+}
+
+func sampleBeta(alpha, beta float64) float64 {
+	x := sampleGamma(alpha)
+	y := sampleGamma(beta)
+	return x / (x + y)
+}
+
+// sampleGamma uses the Marsaglia and Tsang method which requires a shape of at least 1.
+func sampleGamma(shape float64) float64 {
+	d := shape - 1.0/3.0
+	c := 1.0 / math.Sqrt(9*d)
+	for {
+		x := rand.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rand.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
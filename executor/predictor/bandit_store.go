@@ -0,0 +1,148 @@
+package predictor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// BanditState is the learnt state of a bandit router with an entry per child.
+type BanditState struct {
+	// Names of the children, in the order of the routes
+	Children []string `json:"children"`
+	// Number of feedback rewards received for each child
+	Counts []float64 `json:"counts"`
+	// Sum of the rewards received for each child
+	Rewards []float64 `json:"rewards"`
+}
+
+func newBanditState(children []string) *BanditState {
+	return &BanditState{
+		Children: append([]string(nil), children...),
+		Counts:   make([]float64, len(children)),
+		Rewards:  make([]float64, len(children)),
+	}
+}
+
+func (s *BanditState) copy() *BanditState {
+	return &BanditState{
+		Children: append([]string(nil), s.Children...),
+		Counts:   append([]float64(nil), s.Counts...),
+		Rewards:  append([]float64(nil), s.Rewards...),
+	}
+}
+
+// routesTo returns whether the state was learnt for the children, in the same order.
+func (s *BanditState) routesTo(children []string) bool {
+	if len(s.Children) != len(children) || len(s.Counts) != len(children) || len(s.Rewards) != len(children) {
+		return false
+	}
+	for i, child := range children {
+		if s.Children[i] != child {
+			return false
+		}
+	}
+	return true
+}
+
+// BanditStore persists the state of the built-in bandit routers so learning survives restarts.
+type BanditStore interface {
+	// Load returns the saved state or nil if there is none.
+	Load(key string) (*BanditState, error)
+	Save(key string, state *BanditState) error
+}
+
+type InMemoryBanditStore struct {
+	mu     sync.Mutex
+	states map[string]*BanditState
+}
+
+func NewInMemoryBanditStore() *InMemoryBanditStore {
+	return &InMemoryBanditStore{states: make(map[string]*BanditState)}
+}
+
+func (s *InMemoryBanditStore) Load(key string) (*BanditState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[key]
+	if !ok {
+		return nil, nil
+	}
+	return state.copy(), nil
+}
+
+func (s *InMemoryBanditStore) Save(key string, state *BanditState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[key] = state.copy()
+	return nil
+}
+
+// FileBanditStore saves the state of each router as a JSON file in a directory, e.g. on a persistent volume.
+type FileBanditStore struct {
+	dir string
+}
+
+func NewFileBanditStore(dir string) (*FileBanditStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileBanditStore{dir: dir}, nil
+}
+
+func (s *FileBanditStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+func (s *FileBanditStore) Load(key string) (*BanditState, error) {
+	data, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	state := &BanditState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *FileBanditStore) Save(key string, state *BanditState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	// Write to a synced temporary file and rename so a crash never leaves a partial state behind
+	tmp, err := ioutil.TempFile(s.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	// Sync the directory so the rename survives a crash
+	dir, err := os.Open(s.dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,241 @@
+package predictor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func resetBandits(store BanditStore) {
+	banditsMutex.Lock()
+	defer banditsMutex.Unlock()
+	bandits = make(map[string]*banditRouter)
+	BanditStateStore = store
+}
+
+func createBanditFeedback(route int, reward float64) payload.SeldonPayload {
+	data := fmt.Sprintf(`{"response":{"meta":{"routing":{"bandit":%d}}},"reward":%v}`, route, reward)
+	return &payload.BytesPayload{Msg: []byte(data), ContentType: "application/json"}
+}
+
+// trainBandit sends feedback where only child 2 is ever rewarded.
+func trainBandit(g *GomegaWithT, pp *PredictorProcess, graph *v1.PredictiveUnit) {
+	for i := 0; i < 50; i++ {
+		for route := 0; route < 3; route++ {
+			reward := 0.0
+			if route == 2 {
+				reward = 1.0
+			}
+			_, err := pp.Feedback(graph, createBanditFeedback(route, reward))
+			g.Expect(err).Should(BeNil())
+		}
+	}
+}
+
+func countRoutes(g *GomegaWithT, pp *PredictorProcess, graph *v1.PredictiveUnit) []int {
+	counts := make([]int, len(graph.Children))
+	for i := 0; i < 200; i++ {
+		routes, err := pp.route(graph, createPredictPayload(g))
+		g.Expect(err).Should(BeNil())
+		counts[routes[0]]++
+	}
+	return counts
+}
+
+func TestEpsilonGreedyRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetBandits(NewInMemoryBanditStore())
+
+	graph := createBuiltinGraph("bandit", v1.EPSILON_GREEDY, []v1.Parameter{{Name: "epsilon", Value: "0.1", Type: v1.DOUBLE}})
+	pp := createPredictorProcess(t)
+	trainBandit(g, pp, graph)
+
+	counts := countRoutes(g, pp, graph)
+	g.Expect(counts[2]).Should(BeNumerically(">", 150))
+}
+
+func TestThompsonSamplingRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetBandits(NewInMemoryBanditStore())
+
+	graph := createBuiltinGraph("bandit", v1.THOMPSON_SAMPLING, nil)
+	pp := createPredictorProcess(t)
+	trainBandit(g, pp, graph)
+
+	counts := countRoutes(g, pp, graph)
+	g.Expect(counts[2]).Should(BeNumerically(">", 190))
+}
+
+func TestBanditRouterReturnsRouting(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetBandits(NewInMemoryBanditStore())
+
+	graph := createBuiltinGraph("bandit", v1.EPSILON_GREEDY, nil)
+	pResp, err := createPredictorProcess(t).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	routing := pResp.GetPayload().(*proto.SeldonMessage).GetMeta().GetRouting()
+	g.Expect(routing).Should(HaveKey("bandit"))
+}
+
+func TestBanditStatePersisted(t *testing.T) {
+	g := NewGomegaWithT(t)
+	dir, err := ioutil.TempDir("", "bandit")
+	g.Expect(err).Should(BeNil())
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileBanditStore(dir)
+	g.Expect(err).Should(BeNil())
+	resetBandits(store)
+	defer resetBandits(NewInMemoryBanditStore())
+
+	graph := createBuiltinGraph("bandit", v1.THOMPSON_SAMPLING, nil)
+	trainBandit(g, createPredictorProcess(t), graph)
+
+	// A restarted executor loads the saved state
+	store, err = NewFileBanditStore(dir)
+	g.Expect(err).Should(BeNil())
+	resetBandits(store)
+	state, err := store.Load(banditKey(graph))
+	g.Expect(err).Should(BeNil())
+	g.Expect(state.Children).Should(Equal([]string{"model0", "model1", "model2"}))
+	g.Expect(state.Counts).Should(Equal([]float64{50, 50, 50}))
+	g.Expect(state.Rewards).Should(Equal([]float64{0, 0, 50}))
+	counts := countRoutes(g, createPredictorProcess(t), graph)
+	g.Expect(counts[2]).Should(BeNumerically(">", 190))
+}
+
+func TestInMemoryBanditStore(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	store := NewInMemoryBanditStore()
+	state, err := store.Load("bandit")
+	g.Expect(err).Should(BeNil())
+	g.Expect(state).Should(BeNil())
+
+	saved := &BanditState{Children: []string{"a", "b"}, Counts: []float64{1, 2}, Rewards: []float64{0.5, 1}}
+	g.Expect(store.Save("bandit", saved)).Should(Succeed())
+	saved.Counts[0] = 10
+	state, err = store.Load("bandit")
+	g.Expect(err).Should(BeNil())
+	g.Expect(state.Counts).Should(Equal([]float64{1, 2}))
+}
+
+func TestBanditRouterFollowsChildrenChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetBandits(NewInMemoryBanditStore())
+
+	graph := createBuiltinGraph("bandit", v1.EPSILON_GREEDY, nil)
+	pp := createPredictorProcess(t)
+	trainBandit(g, pp, graph)
+
+	// A child added by a reload is routed to and rewarded
+	added := createBuiltinGraph("bandit", v1.EPSILON_GREEDY, nil)
+	added.Children = append(added.Children, added.Children[0])
+	added.Children[3].Name = "model3"
+	_, err := pp.Feedback(added, createBanditFeedback(3, 1))
+	g.Expect(err).Should(BeNil())
+	counts := countRoutes(g, pp, added)
+	g.Expect(counts[3]).Should(BeNumerically(">", 150))
+
+	// Routes stay within the children left after a reload
+	removed := createBuiltinGraph("bandit", v1.EPSILON_GREEDY, nil)
+	removed.Children = removed.Children[:2]
+	g.Expect(countRoutes(g, pp, removed)).Should(HaveLen(2))
+
+	b, err := getBanditRouter(banditKey(removed), removed)
+	g.Expect(err).Should(BeNil())
+	g.Expect(b.update(2, 1)).ShouldNot(BeNil())
+}
+
+func TestEpsilonGreedyRewardsAreNotClamped(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetBandits(NewInMemoryBanditStore())
+
+	graph := createBuiltinGraph("bandit", v1.EPSILON_GREEDY, nil)
+	pp := createPredictorProcess(t)
+	_, err := pp.Feedback(graph, createBanditFeedback(1, 5))
+	g.Expect(err).Should(BeNil())
+	_, err = pp.Feedback(graph, createBanditFeedback(2, -3))
+	g.Expect(err).Should(BeNil())
+	state, err := BanditStateStore.Load(banditKey(graph))
+	g.Expect(err).Should(BeNil())
+	g.Expect(state.Rewards).Should(Equal([]float64{0, 5, -3}))
+
+	resetBandits(NewInMemoryBanditStore())
+	graph = createBuiltinGraph("bandit", v1.THOMPSON_SAMPLING, nil)
+	_, err = pp.Feedback(graph, createBanditFeedback(1, 5))
+	g.Expect(err).Should(BeNil())
+	state, err = BanditStateStore.Load(banditKey(graph))
+	g.Expect(err).Should(BeNil())
+	g.Expect(state.Rewards).Should(Equal([]float64{0, 1, 0}))
+}
+
+func TestBanditStateKeyedOnChildren(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetBandits(NewInMemoryBanditStore())
+
+	graph := createBuiltinGraph("bandit", v1.EPSILON_GREEDY, nil)
+	trainBandit(g, createPredictorProcess(t), graph)
+
+	// Reordered children don't inherit each other's rewards
+	reordered := createBuiltinGraph("bandit", v1.EPSILON_GREEDY, nil)
+	reordered.Children[0], reordered.Children[2] = reordered.Children[2], reordered.Children[0]
+	g.Expect(banditKey(reordered)).ShouldNot(Equal(banditKey(graph)))
+	b, err := getBanditRouter(banditKey(reordered), reordered)
+	g.Expect(err).Should(BeNil())
+	g.Expect(b.state.Rewards).Should(Equal([]float64{0, 0, 0}))
+
+	// Nor does a state saved for other children
+	renamed := createBuiltinGraph("bandit", v1.EPSILON_GREEDY, nil)
+	g.Expect(BanditStateStore.Save(banditKey(renamed), &BanditState{Children: []string{"a", "b", "c"}, Counts: []float64{1, 1, 1}, Rewards: []float64{1, 1, 1}})).Should(Succeed())
+	resetBandits(BanditStateStore)
+	b, err = getBanditRouter(banditKey(renamed), renamed)
+	g.Expect(err).Should(BeNil())
+	g.Expect(b.state.Children).Should(Equal([]string{"model0", "model1", "model2"}))
+	g.Expect(b.state.Counts).Should(Equal([]float64{0, 0, 0}))
+}
+
+// blockingBanditStore blocks saves until released.
+type blockingBanditStore struct {
+	*InMemoryBanditStore
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingBanditStore) Save(key string, state *BanditState) error {
+	s.saving <- struct{}{}
+	<-s.release
+	return s.InMemoryBanditStore.Save(key, state)
+}
+
+func TestBanditRoutesWhileSaving(t *testing.T) {
+	g := NewGomegaWithT(t)
+	store := &blockingBanditStore{InMemoryBanditStore: NewInMemoryBanditStore(), saving: make(chan struct{}), release: make(chan struct{})}
+	resetBandits(store)
+	defer resetBandits(NewInMemoryBanditStore())
+
+	graph := createBuiltinGraph("bandit", v1.EPSILON_GREEDY, nil)
+	pp := createPredictorProcess(t)
+	done := make(chan error)
+	go func() {
+		_, err := pp.Feedback(graph, createBanditFeedback(1, 1))
+		done <- err
+	}()
+	<-store.saving
+
+	// Requests are routed while the state is being saved
+	routes, err := pp.route(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(routes).Should(HaveLen(1))
+	close(store.release)
+	g.Expect(<-done).Should(BeNil())
+	state, err := store.Load(banditKey(graph))
+	g.Expect(err).Should(BeNil())
+	g.Expect(state.Counts).Should(Equal([]float64{0, 1, 0}))
+}
@@ -0,0 +1,517 @@
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	protov1 "github.com/golang/protobuf/proto"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+// batchPart is a request decoded so it can be concatenated with others along the batch dimension.
+type batchPart struct {
+	// Requests can only be batched together if their signatures match
+	signature   string
+	rows        int
+	contentType string
+	seldon      *proto.SeldonMessage
+	infer       *inference.ModelInferRequest
+	v2          map[string]interface{}
+}
+
+// newBatchPart decodes a request with Seldon ndarray or tensor data or V2 inputs. It returns false if the request
+// can't be batched, e.g. it has no batch dimension or uses another data type.
+func newBatchPart(msg payload.SeldonPayload) (*batchPart, bool) {
+	part := &batchPart{contentType: msg.GetContentType()}
+	var ok bool
+	switch req := msg.GetPayload().(type) {
+	case *proto.SeldonMessage:
+		part.seldon = req
+		part.signature, part.rows, ok = seldonBatchSignature(req)
+	case *inference.ModelInferRequest:
+		part.infer = req
+		part.signature, part.rows, ok = inferBatchSignature(req)
+	case []byte:
+		if msg.GetContentEncoding() != "" {
+			return nil, false
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(req, &body); err != nil {
+			return nil, false
+		}
+		if _, isV2 := body["inputs"]; isV2 {
+			part.v2 = body
+			part.signature, part.rows, ok = v2JsonBatchSignature(body)
+		} else {
+			part.seldon = &proto.SeldonMessage{}
+			if err := jsonpb.UnmarshalString(string(req), part.seldon); err != nil {
+				return nil, false
+			}
+			part.signature, part.rows, ok = seldonBatchSignature(part.seldon)
+		}
+	}
+	if !ok || part.rows <= 0 {
+		return nil, false
+	}
+	part.signature = part.contentType + "|" + part.signature
+	return part, true
+}
+
+// concatBatch concatenates requests with the same signature into one request.
+func concatBatch(parts []*batchPart) (payload.SeldonPayload, error) {
+	switch {
+	case parts[0].infer != nil:
+		return &payload.ProtoPayload{Msg: concatInferRequests(parts)}, nil
+	case parts[0].v2 != nil:
+		data, err := json.Marshal(concatV2Json(parts))
+		if err != nil {
+			return nil, err
+		}
+		return &payload.BytesPayload{Msg: data, ContentType: parts[0].contentType}, nil
+	default:
+		sm := concatSeldonMessages(parts)
+		if parts[0].contentType == payload.APPLICATION_TYPE_PROTOBUF {
+			return &payload.ProtoPayload{Msg: sm}, nil
+		}
+		ma := jsonpb.Marshaler{}
+		data, err := ma.MarshalToString(sm)
+		if err != nil {
+			return nil, err
+		}
+		return &payload.BytesPayload{Msg: []byte(data), ContentType: parts[0].contentType}, nil
+	}
+}
+
+// splitBatch splits the response to a batch into a response for each request by row.
+func splitBatch(response payload.SeldonPayload, parts []*batchPart) ([]payload.SeldonPayload, error) {
+	rows := make([]int, len(parts))
+	total := 0
+	for i, part := range parts {
+		rows[i] = part.rows
+		total += part.rows
+	}
+	responses := make([]payload.SeldonPayload, len(parts))
+	switch res := response.GetPayload().(type) {
+	case *proto.SeldonMessage:
+		sms, err := splitSeldonMessage(res, rows, total)
+		if err != nil {
+			return nil, err
+		}
+		for i, sm := range sms {
+			responses[i] = &payload.ProtoPayload{Msg: sm}
+		}
+	case *inference.ModelInferResponse:
+		irs, err := splitInferResponse(res, parts, total)
+		if err != nil {
+			return nil, err
+		}
+		for i, ir := range irs {
+			responses[i] = &payload.ProtoPayload{Msg: ir}
+		}
+	case []byte:
+		data, err := payload.DecompressSeldonPayload(response)
+		if err != nil {
+			return nil, err
+		}
+		var bodies [][]byte
+		if parts[0].v2 != nil {
+			bodies, err = splitV2Json(data, parts, total)
+		} else {
+			bodies, err = splitSeldonJson(data, rows, total)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, body := range bodies {
+			responses[i] = &payload.BytesPayload{Msg: body, ContentType: response.GetContentType()}
+		}
+	default:
+		return nil, fmt.Errorf("can not split a batch response of type %T", res)
+	}
+	return responses, nil
+}
+
+func seldonBatchSignature(sm *proto.SeldonMessage) (string, int, bool) {
+	meta := ""
+	if sm.GetMeta() != nil {
+		meta = protov1.CompactTextString(sm.GetMeta())
+	}
+	names := strings.Join(sm.GetData().GetNames(), ",")
+	switch data := sm.GetData().GetDataOneof().(type) {
+	case *proto.DefaultData_Ndarray:
+		for _, row := range data.Ndarray.GetValues() {
+			if row.GetListValue() == nil {
+				return "", 0, false
+			}
+		}
+		return "ndarray|" + names + "|" + meta, len(data.Ndarray.GetValues()), true
+	case *proto.DefaultData_Tensor:
+		shape := data.Tensor.GetShape()
+		if len(shape) < 2 || product(shape) != len(data.Tensor.GetValues()) {
+			return "", 0, false
+		}
+		return fmt.Sprintf("tensor|%v|%s|%s", shape[1:], names, meta), int(shape[0]), true
+	}
+	return "", 0, false
+}
+
+func concatSeldonMessages(parts []*batchPart) *proto.SeldonMessage {
+	sm := protov1.Clone(parts[0].seldon).(*proto.SeldonMessage)
+	switch data := sm.GetData().GetDataOneof().(type) {
+	case *proto.DefaultData_Ndarray:
+		for _, part := range parts[1:] {
+			data.Ndarray.Values = append(data.Ndarray.Values, part.seldon.GetData().GetNdarray().GetValues()...)
+		}
+	case *proto.DefaultData_Tensor:
+		for _, part := range parts[1:] {
+			data.Tensor.Values = append(data.Tensor.Values, part.seldon.GetData().GetTensor().GetValues()...)
+			data.Tensor.Shape[0] += part.seldon.GetData().GetTensor().GetShape()[0]
+		}
+	}
+	return sm
+}
+
+func splitSeldonMessage(res *proto.SeldonMessage, rows []int, total int) ([]*proto.SeldonMessage, error) {
+	if res.GetData() == nil {
+		return nil, fmt.Errorf("can not split a batch response without data")
+	}
+	// Copy everything but the data into each response
+	dataOneof := res.GetData().DataOneof
+	res.GetData().DataOneof = nil
+	defer func() { res.GetData().DataOneof = dataOneof }()
+
+	sms := make([]*proto.SeldonMessage, len(rows))
+	start := 0
+	switch data := dataOneof.(type) {
+	case *proto.DefaultData_Ndarray:
+		values := data.Ndarray.GetValues()
+		if len(values) != total {
+			return nil, fmt.Errorf("model returned %d rows for a batch of %d", len(values), total)
+		}
+		for i, n := range rows {
+			sms[i] = protov1.Clone(res).(*proto.SeldonMessage)
+			sms[i].GetData().DataOneof = &proto.DefaultData_Ndarray{Ndarray: &_struct.ListValue{Values: values[start : start+n]}}
+			start += n
+		}
+	case *proto.DefaultData_Tensor:
+		shape := data.Tensor.GetShape()
+		if len(shape) == 0 || int(shape[0]) != total || len(data.Tensor.GetValues())%total != 0 {
+			return nil, fmt.Errorf("model returned a tensor of shape %v for a batch of %d", shape, total)
+		}
+		rowSize := len(data.Tensor.GetValues()) / total
+		for i, n := range rows {
+			partShape := append([]int32{int32(n)}, shape[1:]...)
+			sms[i] = protov1.Clone(res).(*proto.SeldonMessage)
+			sms[i].GetData().DataOneof = &proto.DefaultData_Tensor{Tensor: &proto.Tensor{
+				Shape:  partShape,
+				Values: data.Tensor.Values[start*rowSize : (start+n)*rowSize],
+			}}
+			start += n
+		}
+	default:
+		return nil, fmt.Errorf("can only split ndarray or tensor batch responses")
+	}
+	return sms, nil
+}
+
+func splitSeldonJson(data []byte, rows []int, total int) ([][]byte, error) {
+	var sm proto.SeldonMessage
+	if err := jsonpb.UnmarshalString(string(data), &sm); err != nil {
+		return nil, err
+	}
+	sms, err := splitSeldonMessage(&sm, rows, total)
+	if err != nil {
+		return nil, err
+	}
+	bodies := make([][]byte, len(sms))
+	ma := jsonpb.Marshaler{}
+	for i, part := range sms {
+		body, err := ma.MarshalToString(part)
+		if err != nil {
+			return nil, err
+		}
+		bodies[i] = []byte(body)
+	}
+	return bodies, nil
+}
+
+func inferBatchSignature(req *inference.ModelInferRequest) (string, int, bool) {
+	if len(req.GetRawInputContents()) > 0 || len(req.GetInputs()) == 0 {
+		return "", 0, false
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%s|%s|%s", req.GetModelName(), req.GetModelVersion(), parametersSignature(req.GetParameters()), outputsSignature(req.GetOutputs()))
+	rows := int64(-1)
+	for _, input := range req.GetInputs() {
+		if len(input.GetShape()) < 2 || (rows >= 0 && input.GetShape()[0] != rows) {
+			return "", 0, false
+		}
+		rows = input.GetShape()[0]
+		fmt.Fprintf(&b, "|%s:%s:%v:%s", input.GetName(), input.GetDatatype(), input.GetShape()[1:], parametersSignature(input.GetParameters()))
+	}
+	return b.String(), int(rows), true
+}
+
+func parametersSignature(parameters map[string]*inference.InferParameter) string {
+	return protov1.CompactTextString(&inference.ModelInferRequest{Parameters: parameters})
+}
+
+func outputsSignature(outputs []*inference.ModelInferRequest_InferRequestedOutputTensor) string {
+	return protov1.CompactTextString(&inference.ModelInferRequest{Outputs: outputs})
+}
+
+func concatInferRequests(parts []*batchPart) *inference.ModelInferRequest {
+	req := protov1.Clone(parts[0].infer).(*inference.ModelInferRequest)
+	req.Id = ""
+	for i, input := range req.Inputs {
+		if input.Contents == nil {
+			input.Contents = &inference.InferTensorContents{}
+		}
+		for _, part := range parts[1:] {
+			partInput := part.infer.Inputs[i]
+			appendInferTensorContents(input.Contents, partInput.GetContents())
+			input.Shape[0] += partInput.Shape[0]
+		}
+	}
+	return req
+}
+
+func splitInferResponse(res *inference.ModelInferResponse, parts []*batchPart, total int) ([]*inference.ModelInferResponse, error) {
+	for _, output := range res.GetOutputs() {
+		if len(output.GetShape()) == 0 || int(output.GetShape()[0]) != total {
+			return nil, fmt.Errorf("model returned output %s of shape %v for a batch of %d", output.GetName(), output.GetShape(), total)
+		}
+	}
+	responses := make([]*inference.ModelInferResponse, len(parts))
+	start := 0
+	for i, part := range parts {
+		end := start + part.rows
+		ir := &inference.ModelInferResponse{
+			ModelName:    res.ModelName,
+			ModelVersion: res.ModelVersion,
+			Id:           part.infer.GetId(),
+			Parameters:   res.Parameters,
+		}
+		for j, output := range res.Outputs {
+			rowSize := product64(output.Shape[1:])
+			partOutput := &inference.ModelInferResponse_InferOutputTensor{
+				Name:       output.Name,
+				Datatype:   output.Datatype,
+				Shape:      append([]int64{int64(part.rows)}, output.Shape[1:]...),
+				Parameters: output.Parameters,
+			}
+			if len(res.RawOutputContents) > j {
+				raw := res.RawOutputContents[j]
+				if len(raw)%total != 0 {
+					return nil, fmt.Errorf("can not split %d bytes of output %s into %d rows", len(raw), output.Name, total)
+				}
+				rowBytes := len(raw) / total
+				ir.RawOutputContents = append(ir.RawOutputContents, raw[start*rowBytes:end*rowBytes])
+			} else {
+				contents, err := sliceInferTensorContents(output.GetContents(), start*rowSize, end*rowSize)
+				if err != nil {
+					return nil, fmt.Errorf("can not split output %s: %w", output.Name, err)
+				}
+				partOutput.Contents = contents
+			}
+			ir.Outputs = append(ir.Outputs, partOutput)
+		}
+		responses[i] = ir
+		start = end
+	}
+	return responses, nil
+}
+
+func appendInferTensorContents(dst *inference.InferTensorContents, src *inference.InferTensorContents) {
+	dst.BoolContents = append(dst.BoolContents, src.GetBoolContents()...)
+	dst.IntContents = append(dst.IntContents, src.GetIntContents()...)
+	dst.Int64Contents = append(dst.Int64Contents, src.GetInt64Contents()...)
+	dst.UintContents = append(dst.UintContents, src.GetUintContents()...)
+	dst.Uint64Contents = append(dst.Uint64Contents, src.GetUint64Contents()...)
+	dst.Fp32Contents = append(dst.Fp32Contents, src.GetFp32Contents()...)
+	dst.Fp64Contents = append(dst.Fp64Contents, src.GetFp64Contents()...)
+	dst.ByteContents = append(dst.ByteContents, src.GetByteContents()...)
+}
+
+// sliceInferTensorContents returns the elements from one index to another of the populated contents.
+func sliceInferTensorContents(contents *inference.InferTensorContents, from int, to int) (*inference.InferTensorContents, error) {
+	sliced := &inference.InferTensorContents{}
+	switch {
+	case len(contents.GetBoolContents()) >= to:
+		sliced.BoolContents = contents.BoolContents[from:to]
+	case len(contents.GetIntContents()) >= to:
+		sliced.IntContents = contents.IntContents[from:to]
+	case len(contents.GetInt64Contents()) >= to:
+		sliced.Int64Contents = contents.Int64Contents[from:to]
+	case len(contents.GetUintContents()) >= to:
+		sliced.UintContents = contents.UintContents[from:to]
+	case len(contents.GetUint64Contents()) >= to:
+		sliced.Uint64Contents = contents.Uint64Contents[from:to]
+	case len(contents.GetFp32Contents()) >= to:
+		sliced.Fp32Contents = contents.Fp32Contents[from:to]
+	case len(contents.GetFp64Contents()) >= to:
+		sliced.Fp64Contents = contents.Fp64Contents[from:to]
+	case len(contents.GetByteContents()) >= to:
+		sliced.ByteContents = contents.ByteContents[from:to]
+	default:
+		return nil, fmt.Errorf("contents have fewer than %d elements", to)
+	}
+	return sliced, nil
+}
+
+func v2JsonBatchSignature(body map[string]interface{}) (string, int, bool) {
+	inputs, _ := body["inputs"].([]interface{})
+	if len(inputs) == 0 {
+		return "", 0, false
+	}
+	rest := make(map[string]interface{})
+	for key, value := range body {
+		if key != "inputs" && key != "id" {
+			rest[key] = value
+		}
+	}
+	restJson, err := json.Marshal(rest)
+	if err != nil {
+		return "", 0, false
+	}
+	var b strings.Builder
+	b.Write(restJson)
+	rows := -1
+	for _, in := range inputs {
+		input, _ := in.(map[string]interface{})
+		shape, ok := jsonShape(input["shape"])
+		if !ok || len(shape) < 2 || (rows >= 0 && shape[0] != rows) {
+			return "", 0, false
+		}
+		rows = shape[0]
+		params, _ := json.Marshal(input["parameters"])
+		fmt.Fprintf(&b, "|%v:%v:%v:%s", input["name"], input["datatype"], shape[1:], params)
+	}
+	return b.String(), rows, true
+}
+
+func jsonShape(value interface{}) ([]int, bool) {
+	dims, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	shape := make([]int, len(dims))
+	for i, dim := range dims {
+		d, ok := dim.(float64)
+		if !ok {
+			return nil, false
+		}
+		shape[i] = int(d)
+	}
+	return shape, true
+}
+
+func concatV2Json(parts []*batchPart) map[string]interface{} {
+	body := make(map[string]interface{})
+	for key, value := range parts[0].v2 {
+		if key != "inputs" && key != "id" {
+			body[key] = value
+		}
+	}
+	total := 0
+	for _, part := range parts {
+		total += part.rows
+	}
+	first, _ := parts[0].v2["inputs"].([]interface{})
+	inputs := make([]interface{}, len(first))
+	for i := range first {
+		var data []interface{}
+		for _, part := range parts {
+			partInput := part.v2["inputs"].([]interface{})[i].(map[string]interface{})
+			data = flattenJsonData(partInput["data"], data)
+		}
+		input := make(map[string]interface{})
+		for key, value := range first[i].(map[string]interface{}) {
+			input[key] = value
+		}
+		shape, _ := jsonShape(input["shape"])
+		shape[0] = total
+		input["shape"] = shape
+		input["data"] = data
+		inputs[i] = input
+	}
+	body["inputs"] = inputs
+	return body
+}
+
+func splitV2Json(data []byte, parts []*batchPart, total int) ([][]byte, error) {
+	var res map[string]interface{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	outputs, _ := res["outputs"].([]interface{})
+	flattened := make([][]interface{}, len(outputs))
+	rowSizes := make([]int, len(outputs))
+	for j, out := range outputs {
+		output, _ := out.(map[string]interface{})
+		shape, ok := jsonShape(output["shape"])
+		if !ok || len(shape) == 0 || shape[0] != total {
+			return nil, fmt.Errorf("model returned output %v of shape %v for a batch of %d", output["name"], output["shape"], total)
+		}
+		flattened[j] = flattenJsonData(output["data"], nil)
+		if len(flattened[j])%total != 0 {
+			return nil, fmt.Errorf("can not split %d values of output %v into %d rows", len(flattened[j]), output["name"], total)
+		}
+		rowSizes[j] = len(flattened[j]) / total
+	}
+
+	bodies := make([][]byte, len(parts))
+	start := 0
+	for i, part := range parts {
+		end := start + part.rows
+		body := make(map[string]interface{})
+		for key, value := range res {
+			body[key] = value
+		}
+		if id, ok := part.v2["id"]; ok {
+			body["id"] = id
+		} else {
+			delete(body, "id")
+		}
+		partOutputs := make([]interface{}, len(outputs))
+		for j, out := range outputs {
+			partOutput := make(map[string]interface{})
+			for key, value := range out.(map[string]interface{}) {
+				partOutput[key] = value
+			}
+			shape, _ := jsonShape(partOutput["shape"])
+			shape[0] = part.rows
+			partOutput["shape"] = shape
+			partOutput["data"] = flattened[j][start*rowSizes[j] : end*rowSizes[j]]
+			partOutputs[j] = partOutput
+		}
+		body["outputs"] = partOutputs
+		var err error
+		if bodies[i], err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+		start = end
+	}
+	return bodies, nil
+}
+
+func product(shape []int32) int {
+	n := 1
+	for _, dim := range shape {
+		n *= int(dim)
+	}
+	return n
+}
+
+func product64(shape []int64) int {
+	n := 1
+	for _, dim := range shape {
+		n *= int(dim)
+	}
+	return n
+}
@@ -0,0 +1,226 @@
+package predictor
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultMaxBatchSize    = 32
+	defaultMaxBatchLatency = 10 * time.Millisecond
+)
+
+var (
+	// Batchers shared by all requests, keyed by nodeStateKey and freed once no loaded version uses them.
+	batchers       sync.Map
+	batchMetrics   *metric.BatchMetrics
+	batchMetricsMu sync.Mutex
+)
+
+type batchResult struct {
+	msg payload.SeldonPayload
+	err error
+}
+
+type batchCall struct {
+	part   *batchPart
+	result chan batchResult
+	// Deadline of the request or zero if it has none
+	deadline time.Time
+	meta     map[string][]string
+}
+
+type batch struct {
+	calls []*batchCall
+	rows  int
+	// Closed when the batch is full
+	full chan struct{}
+}
+
+// batcher collects concurrent requests to a model into batches. The first request of a batch waits for the batch
+// to fill or for the maximum latency and then calls the model for all of them.
+type batcher struct {
+	mu           sync.Mutex
+	maxBatchSize int
+	maxLatency   time.Duration
+	// Batches still accepting requests, keyed by signature
+	open map[string]*batch
+}
+
+func newBatcher(policy *v1.BatchingPolicy) *batcher {
+	b := &batcher{
+		maxBatchSize: defaultMaxBatchSize,
+		maxLatency:   defaultMaxBatchLatency,
+		open:         make(map[string]*batch),
+	}
+	if policy.MaxBatchSize > 0 {
+		b.maxBatchSize = int(policy.MaxBatchSize)
+	}
+	if policy.MaxLatencyMs > 0 {
+		b.maxLatency = time.Duration(policy.MaxLatencyMs) * time.Millisecond
+	}
+	return b
+}
+
+func getBatcher(key string, node *v1.PredictiveUnit) *batcher {
+	if b, ok := batchers.Load(key); ok {
+		return b.(*batcher)
+	}
+	b, _ := batchers.LoadOrStore(key, newBatcher(node.Batching))
+	return b.(*batcher)
+}
+
+func getBatchMetrics() *metric.BatchMetrics {
+	batchMetricsMu.Lock()
+	defer batchMetricsMu.Unlock()
+	if batchMetrics == nil {
+		batchMetrics = metric.NewBatchMetrics()
+	}
+	return batchMetrics
+}
+
+// join adds a call to the open batch with its signature and returns the batch and whether the call leads it.
+func (b *batcher) join(call *batchCall) (*batch, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	signature := call.part.signature
+	bt, ok := b.open[signature]
+	if ok && bt.rows+call.part.rows > b.maxBatchSize {
+		// Send the open batch now rather than exceed the maximum size
+		b.close(signature, bt)
+		ok = false
+	}
+	if !ok {
+		bt = &batch{full: make(chan struct{})}
+		b.open[signature] = bt
+	}
+	bt.calls = append(bt.calls, call)
+	bt.rows += call.part.rows
+	if bt.rows >= b.maxBatchSize {
+		b.close(signature, bt)
+	}
+	return bt, !ok
+}
+
+func (b *batcher) close(signature string, bt *batch) {
+	if b.open[signature] == bt {
+		delete(b.open, signature)
+		close(bt.full)
+	}
+}
+
+// wait blocks the leading call until its batch is full or has waited for the maximum latency.
+func (b *batcher) wait(signature string, bt *batch) {
+	timer := time.NewTimer(b.maxLatency)
+	defer timer.Stop()
+	select {
+	case <-bt.full:
+	case <-timer.C:
+		b.mu.Lock()
+		b.close(signature, bt)
+		b.mu.Unlock()
+	}
+}
+
+// batchedPredict sends the request to the model in a batch with concurrent requests with the same signature.
+// Requests which can't be batched are sent on their own.
+func (p *PredictorProcess) batchedPredict(node *v1.PredictiveUnit, modelName string, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	part, ok := newBatchPart(msg)
+	if !ok {
+		return p.nodeClient(node).Predict(p.nodeContext(node), modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+	}
+	b := getBatcher(p.Version.nodeStateKey(node), node)
+	call := &batchCall{part: part, result: make(chan batchResult, 1), meta: p.Meta.Meta}
+	call.deadline, _ = p.Ctx.Deadline()
+	bt, leader := b.join(call)
+	if leader {
+		b.wait(part.signature, bt)
+		p.predictBatch(node, modelName, msg, bt)
+	}
+	select {
+	case result := <-call.result:
+		return result.msg, result.err
+	case <-p.Ctx.Done():
+		return nil, p.Ctx.Err()
+	}
+}
+
+func (p *PredictorProcess) predictBatch(node *v1.PredictiveUnit, modelName string, msg payload.SeldonPayload, bt *batch) {
+	getBatchMetrics().BatchSizeHistogram.WithLabelValues(node.Name).Observe(float64(bt.rows))
+	if len(bt.calls) == 1 {
+		response, err := p.nodeClient(node).Predict(p.nodeContext(node), modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+		bt.calls[0].result <- batchResult{msg: response, err: err}
+		return
+	}
+
+	parts := make([]*batchPart, len(bt.calls))
+	for i, call := range bt.calls {
+		parts[i] = call.part
+	}
+	var responses []payload.SeldonPayload
+	batchMsg, err := concatBatch(parts)
+	if err == nil {
+		ctx, cancel := batchContext(bt.calls)
+		defer cancel()
+		ctx = trace.ContextWithSpanContext(ctx, trace.SpanContextFromContext(p.Ctx))
+		ctx = client.ContextWithRetryPolicy(ctx, node.Retry)
+		var response payload.SeldonPayload
+		response, err = p.nodeClient(node).Predict(ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), batchMsg, batchMeta(bt.calls))
+		if err != nil {
+			for _, call := range bt.calls {
+				call.result <- batchResult{msg: response, err: err}
+			}
+			return
+		}
+		responses, err = splitBatch(response, parts)
+	}
+	if err != nil {
+		p.Log.Error(err, "Failed to batch requests", "node", node.Name, "requests", len(bt.calls))
+		for _, call := range bt.calls {
+			call.result <- batchResult{msg: p.nodeClient(node).CreateErrorPayload(err), err: err}
+		}
+		return
+	}
+	for i, call := range bt.calls {
+		call.result <- batchResult{msg: responses[i]}
+	}
+}
+
+// batchContext returns the context for a batch, which isn't cancelled with any one of its requests and ends at the
+// latest of their deadlines.
+func batchContext(calls []*batchCall) (context.Context, context.CancelFunc) {
+	var latest time.Time
+	for _, call := range calls {
+		if call.deadline.IsZero() {
+			return context.WithCancel(context.Background())
+		}
+		if call.deadline.After(latest) {
+			latest = call.deadline
+		}
+	}
+	return context.WithDeadline(context.Background(), latest)
+}
+
+// batchMeta returns the headers which have the same values in all requests of a batch. Only these are sent with
+// the batch so no request's own headers, e.g. its id, are attached to the others.
+func batchMeta(calls []*batchCall) map[string][]string {
+	meta := make(map[string][]string)
+	for key, values := range calls[0].meta {
+		shared := true
+		for _, call := range calls[1:] {
+			shared = shared && reflect.DeepEqual(call.meta[key], values)
+		}
+		if shared {
+			meta[key] = values
+		}
+	}
+	return meta
+}
@@ -0,0 +1,238 @@
+package predictor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// batchRecordingClient echoes requests like the test client and records the requests the model receives.
+type batchRecordingClient struct {
+	test.SeldonMessageTestClient
+	mu       sync.Mutex
+	requests []payload.SeldonPayload
+}
+
+func (c *batchRecordingClient) Predict(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, msg)
+	return msg, nil
+}
+
+func createSeldonPayload(g *GomegaWithT, data string) payload.SeldonPayload {
+	var sm proto.SeldonMessage
+	g.Expect(jsonpb.UnmarshalString(data, &sm)).Should(BeNil())
+	return &payload.ProtoPayload{Msg: &sm}
+}
+
+func TestBatchedPredict(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &batchRecordingClient{}
+	url, _ := url.Parse(testSourceUrl)
+	graph := createModelNode("batched")
+	graph.Batching = &v1.BatchingPolicy{MaxBatchSize: 4, MaxLatencyMs: 60000}
+
+	responses := make([]payload.SeldonPayload, 4)
+	errs := make([]error, 4)
+	wg := sync.WaitGroup{}
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid)
+			pp := NewPredictorProcess(ctx, client, logf.Log.WithName("batcher"), url, "default", map[string][]string{}, "")
+			responses[i], errs[i] = pp.Predict(graph, createSeldonPayload(g, fmt.Sprintf(`{"data":{"ndarray":[[%d,%d]]}}`, i, i)))
+		}(i)
+	}
+	wg.Wait()
+
+	// The batch is only sent once full as the maximum latency is long
+	g.Expect(client.requests).Should(HaveLen(1))
+	g.Expect(client.requests[0].GetPayload().(*proto.SeldonMessage).GetData().GetNdarray().GetValues()).Should(HaveLen(4))
+	for i, response := range responses {
+		g.Expect(errs[i]).Should(BeNil())
+		rows := response.GetPayload().(*proto.SeldonMessage).GetData().GetNdarray().GetValues()
+		g.Expect(rows).Should(HaveLen(1))
+		g.Expect(rows[0].GetListValue().GetValues()[0].GetNumberValue()).Should(Equal(float64(i)))
+	}
+}
+
+func TestBatchedPredictMaxLatency(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &batchRecordingClient{}
+	url, _ := url.Parse(testSourceUrl)
+	ctx := context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid)
+	pp := NewPredictorProcess(ctx, client, logf.Log.WithName("batcher"), url, "default", map[string][]string{}, "")
+	graph := createModelNode("batchedLatency")
+	graph.Batching = &v1.BatchingPolicy{MaxLatencyMs: 5}
+
+	msg := createSeldonPayload(g, `{"data":{"tensor":{"shape":[2,2],"values":[1,2,3,4]}}}`)
+	response, err := pp.Predict(graph, msg)
+	g.Expect(err).Should(BeNil())
+	g.Expect(response).Should(Equal(msg))
+
+	// Requests without a batch dimension are sent on their own
+	msg = createSeldonPayload(g, `{"jsonData":{"a":1}}`)
+	response, err = pp.Predict(graph, msg)
+	g.Expect(err).Should(BeNil())
+	g.Expect(response).Should(Equal(msg))
+	g.Expect(client.requests).Should(HaveLen(2))
+}
+
+func TestBatchSeldonTensor(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	parts := make([]*batchPart, 2)
+	for i, data := range []string{
+		`{"data":{"names":["a","b"],"tensor":{"shape":[1,2],"values":[1,2]}}}`,
+		`{"data":{"names":["a","b"],"tensor":{"shape":[2,2],"values":[3,4,5,6]}}}`,
+	} {
+		var ok bool
+		parts[i], ok = newBatchPart(&payload.BytesPayload{Msg: []byte(data), ContentType: "application/json"})
+		g.Expect(ok).Should(BeTrue())
+	}
+	g.Expect(parts[0].signature).Should(Equal(parts[1].signature))
+
+	other, _ := newBatchPart(&payload.BytesPayload{Msg: []byte(`{"data":{"names":["c","d"],"tensor":{"shape":[1,2],"values":[1,2]}}}`), ContentType: "application/json"})
+	g.Expect(other.signature).ShouldNot(Equal(parts[0].signature))
+
+	batchMsg, err := concatBatch(parts)
+	g.Expect(err).Should(BeNil())
+	body, _ := batchMsg.GetBytes()
+	g.Expect(string(body)).Should(Equal(`{"data":{"names":["a","b"],"tensor":{"shape":[3,2],"values":[1,2,3,4,5,6]}}}`))
+
+	response := &payload.BytesPayload{Msg: []byte(`{"meta":{"tags":{"t":1}},"data":{"tensor":{"shape":[3,1],"values":[7,8,9]}}}`), ContentType: "application/json"}
+	responses, err := splitBatch(response, parts)
+	g.Expect(err).Should(BeNil())
+	first, _ := responses[0].GetBytes()
+	g.Expect(string(first)).Should(Equal(`{"meta":{"tags":{"t":1}},"data":{"tensor":{"shape":[1,1],"values":[7]}}}`))
+	second, _ := responses[1].GetBytes()
+	g.Expect(string(second)).Should(Equal(`{"meta":{"tags":{"t":1}},"data":{"tensor":{"shape":[2,1],"values":[8,9]}}}`))
+
+	_, err = splitBatch(&payload.BytesPayload{Msg: []byte(`{"data":{"tensor":{"shape":[2,1],"values":[7,8]}}}`)}, parts)
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestBatchV2Json(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	parts := make([]*batchPart, 2)
+	for i, data := range []string{
+		`{"id":"1","inputs":[{"name":"x","datatype":"FP32","shape":[1,2],"data":[1,2]}]}`,
+		`{"id":"2","inputs":[{"name":"x","datatype":"FP32","shape":[2,2],"data":[[3,4],[5,6]]}]}`,
+	} {
+		var ok bool
+		parts[i], ok = newBatchPart(&payload.BytesPayload{Msg: []byte(data), ContentType: "application/json"})
+		g.Expect(ok).Should(BeTrue())
+	}
+	g.Expect(parts[0].signature).Should(Equal(parts[1].signature))
+
+	batchMsg, err := concatBatch(parts)
+	g.Expect(err).Should(BeNil())
+	body, _ := batchMsg.GetBytes()
+	g.Expect(string(body)).Should(MatchJSON(`{"inputs":[{"name":"x","datatype":"FP32","shape":[3,2],"data":[1,2,3,4,5,6]}]}`))
+
+	response := &payload.BytesPayload{Msg: []byte(`{"model_name":"m","outputs":[{"name":"y","datatype":"INT64","shape":[3],"data":[0,1,0]}]}`), ContentType: "application/json"}
+	responses, err := splitBatch(response, parts)
+	g.Expect(err).Should(BeNil())
+	first, _ := responses[0].GetBytes()
+	g.Expect(string(first)).Should(MatchJSON(`{"id":"1","model_name":"m","outputs":[{"name":"y","datatype":"INT64","shape":[1],"data":[0]}]}`))
+	second, _ := responses[1].GetBytes()
+	g.Expect(string(second)).Should(MatchJSON(`{"id":"2","model_name":"m","outputs":[{"name":"y","datatype":"INT64","shape":[2],"data":[1,0]}]}`))
+}
+
+func TestBatchV2Grpc(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	parts := make([]*batchPart, 2)
+	for i, rows := range []int64{1, 2} {
+		req := &inference.ModelInferRequest{
+			ModelName: "m",
+			Id:        fmt.Sprint(i),
+			Inputs: []*inference.ModelInferRequest_InferInputTensor{{
+				Name:     "x",
+				Datatype: "INT64",
+				Shape:    []int64{rows, 2},
+				Contents: &inference.InferTensorContents{Int64Contents: make([]int64, rows*2)},
+			}},
+		}
+		var ok bool
+		parts[i], ok = newBatchPart(&payload.ProtoPayload{Msg: req})
+		g.Expect(ok).Should(BeTrue())
+	}
+
+	batchMsg, err := concatBatch(parts)
+	g.Expect(err).Should(BeNil())
+	req := batchMsg.GetPayload().(*inference.ModelInferRequest)
+	g.Expect(req.Inputs[0].Shape).Should(Equal([]int64{3, 2}))
+	g.Expect(req.Inputs[0].Contents.Int64Contents).Should(HaveLen(6))
+
+	res := &inference.ModelInferResponse{
+		ModelName: "m",
+		Outputs: []*inference.ModelInferResponse_InferOutputTensor{{
+			Name:     "y",
+			Datatype: "FP32",
+			Shape:    []int64{3, 2},
+			Contents: &inference.InferTensorContents{Fp32Contents: []float32{1, 2, 3, 4, 5, 6}},
+		}},
+	}
+	responses, err := splitBatch(&payload.ProtoPayload{Msg: res}, parts)
+	g.Expect(err).Should(BeNil())
+	second := responses[1].GetPayload().(*inference.ModelInferResponse)
+	g.Expect(second.Id).Should(Equal("1"))
+	g.Expect(second.Outputs[0].Shape).Should(Equal([]int64{2, 2}))
+	g.Expect(second.Outputs[0].Contents.Fp32Contents).Should(Equal([]float32{3, 4, 5, 6}))
+}
+
+func TestBatchV2JsonSignature(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var body map[string]interface{}
+	g.Expect(json.Unmarshal([]byte(`{"inputs":[{"name":"x","datatype":"FP32","shape":[2],"data":[1,2]}]}`), &body)).Should(BeNil())
+	_, _, ok := v2JsonBatchSignature(body)
+	g.Expect(ok).Should(BeFalse())
+}
+
+func TestBatchContext(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	early := time.Now().Add(time.Second)
+	late := early.Add(time.Second)
+	ctx, cancel := batchContext([]*batchCall{{deadline: late}, {deadline: early}})
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	g.Expect(ok).Should(BeTrue())
+	g.Expect(deadline).Should(Equal(late))
+
+	// A request without a deadline leaves the batch without one
+	ctx, cancel = batchContext([]*batchCall{{deadline: early}, {}})
+	defer cancel()
+	_, ok = ctx.Deadline()
+	g.Expect(ok).Should(BeFalse())
+}
+
+func TestBatchMeta(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	meta := batchMeta([]*batchCall{
+		{meta: map[string][]string{payload.SeldonPUIDHeader: {"1"}, "X-Tenant": {"a"}, "X-Region": {"eu"}}},
+		{meta: map[string][]string{payload.SeldonPUIDHeader: {"2"}, "X-Tenant": {"a"}}},
+	})
+	g.Expect(meta).Should(Equal(map[string][]string{"X-Tenant": {"a"}}))
+}
@@ -0,0 +1,421 @@
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	protov1 "github.com/golang/protobuf/proto"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+const combinerWeightsParameter = "weights"
+
+// combiner reduces the flattened outputs of the children, one slice per child, into a single output.
+type combiner struct {
+	weights []float64
+	// Take a majority vote rather than the mean
+	vote bool
+}
+
+func isBuiltinCombiner(node *v1.PredictiveUnit) bool {
+	return node.Implementation != nil && (*node.Implementation == v1.AVERAGE_COMBINER || *node.Implementation == v1.MAJORITY_VOTE_COMBINER)
+}
+
+// combinerWeights returns the weights of the given children from the comma separated weights parameter, which
+// default to 1 for every child.
+func combinerWeights(node *v1.PredictiveUnit, children []int) ([]float64, error) {
+	all := make([]float64, len(node.Children))
+	for i := range all {
+		all[i] = 1
+	}
+	for _, param := range node.Parameters {
+		if param.Name != combinerWeightsParameter {
+			continue
+		}
+		parts := strings.Split(param.Value, ",")
+		if len(parts) != len(node.Children) {
+			return nil, fmt.Errorf("combiner %s has %d weights for %d children", node.Name, len(parts), len(node.Children))
+		}
+		for i, part := range parts {
+			weight, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight %q for combiner %s", part, node.Name)
+			}
+			all[i] = weight
+		}
+	}
+	weights := make([]float64, len(children))
+	for i, child := range children {
+		weights[i] = all[child]
+	}
+	return weights, nil
+}
+
+// combine aggregates the responses of the given children in the executor for the built-in combiners.
+func (p *PredictorProcess) combine(node *v1.PredictiveUnit, cmsgs []payload.SeldonPayload, children []int) (payload.SeldonPayload, error) {
+	weights, err := combinerWeights(node, children)
+	if err != nil {
+		return nil, err
+	}
+	c := &combiner{weights: weights, vote: *node.Implementation == v1.MAJORITY_VOTE_COMBINER}
+
+	switch cmsgs[0].GetPayload().(type) {
+	case *proto.SeldonMessage:
+		msgs := make([]*proto.SeldonMessage, len(cmsgs))
+		for i, cmsg := range cmsgs {
+			sm, ok := cmsg.GetPayload().(*proto.SeldonMessage)
+			if !ok {
+				return nil, fmt.Errorf("combiner %s can not combine %T", node.Name, cmsg.GetPayload())
+			}
+			msgs[i] = sm
+		}
+		sm, err := c.combineSeldonMessages(msgs)
+		if err != nil {
+			return nil, err
+		}
+		return &payload.ProtoPayload{Msg: sm}, nil
+	case *inference.ModelInferResponse:
+		msgs := make([]*inference.ModelInferResponse, len(cmsgs))
+		for i, cmsg := range cmsgs {
+			res, ok := cmsg.GetPayload().(*inference.ModelInferResponse)
+			if !ok {
+				return nil, fmt.Errorf("combiner %s can not combine %T", node.Name, cmsg.GetPayload())
+			}
+			msgs[i] = res
+		}
+		res, err := c.combineInferResponses(msgs)
+		if err != nil {
+			return nil, err
+		}
+		return &payload.ProtoPayload{Msg: res}, nil
+	case []byte:
+		return c.combineJson(cmsgs)
+	default:
+		return nil, fmt.Errorf("combiner %s can not combine %T", node.Name, cmsgs[0].GetPayload())
+	}
+}
+
+// reduce checks that the children returned outputs of the same size before reducing them.
+func (c *combiner) reduce(values [][]interface{}) ([]interface{}, error) {
+	for _, childValues := range values[1:] {
+		if len(childValues) != len(values[0]) {
+			return nil, fmt.Errorf("can not combine outputs of different sizes %d and %d", len(values[0]), len(childValues))
+		}
+	}
+	if c.vote {
+		return weightedVote(values, c.weights), nil
+	}
+	return weightedMean(values, c.weights)
+}
+
+func weightedMean(values [][]interface{}, weights []float64) ([]interface{}, error) {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("combiner weights must sum to more than 0")
+	}
+	mean := make([]interface{}, len(values[0]))
+	for i := range mean {
+		sum := 0.0
+		for child, childValues := range values {
+			value, ok := childValues[i].(float64)
+			if !ok {
+				return nil, fmt.Errorf("can not average non numeric value %v", childValues[i])
+			}
+			sum += weights[child] * value
+		}
+		mean[i] = sum / total
+	}
+	return mean, nil
+}
+
+// weightedVote picks the value with the largest total weight for each element, preferring earlier children on ties.
+func weightedVote(values [][]interface{}, weights []float64) []interface{} {
+	votes := make([]interface{}, len(values[0]))
+	for i := range votes {
+		tally := make(map[interface{}]float64)
+		best := values[0][i]
+		for child, childValues := range values {
+			value := childValues[i]
+			tally[value] += weights[child]
+			if tally[value] > tally[best] {
+				best = value
+			}
+		}
+		votes[i] = best
+	}
+	return votes
+}
+
+func (c *combiner) combineSeldonMessages(msgs []*proto.SeldonMessage) (*proto.SeldonMessage, error) {
+	values := make([][]interface{}, len(msgs))
+	for i, sm := range msgs {
+		switch sm.GetData().GetDataOneof().(type) {
+		case *proto.DefaultData_Tensor:
+			tensor := sm.GetData().GetTensor()
+			values[i] = make([]interface{}, len(tensor.Values))
+			for j, value := range tensor.Values {
+				values[i][j] = value
+			}
+		case *proto.DefaultData_Ndarray:
+			values[i] = flattenListValue(sm.GetData().GetNdarray(), nil)
+		default:
+			return nil, fmt.Errorf("combiner only supports ndarray and tensor data")
+		}
+	}
+	combined, err := c.reduce(values)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the names and shape of the first response
+	sm := protov1.Clone(msgs[0]).(*proto.SeldonMessage)
+	switch data := sm.GetData().GetDataOneof().(type) {
+	case *proto.DefaultData_Tensor:
+		for j, value := range combined {
+			v, ok := value.(float64)
+			if !ok {
+				return nil, fmt.Errorf("combined tensor value %v is not a number", value)
+			}
+			data.Tensor.Values[j] = v
+		}
+	case *proto.DefaultData_Ndarray:
+		rebuildListValue(data.Ndarray, combined)
+	}
+	return sm, nil
+}
+
+func flattenListValue(list *_struct.ListValue, values []interface{}) []interface{} {
+	for _, value := range list.GetValues() {
+		switch kind := value.GetKind().(type) {
+		case *_struct.Value_ListValue:
+			values = flattenListValue(kind.ListValue, values)
+		case *_struct.Value_NumberValue:
+			values = append(values, kind.NumberValue)
+		case *_struct.Value_StringValue:
+			values = append(values, kind.StringValue)
+		case *_struct.Value_BoolValue:
+			values = append(values, kind.BoolValue)
+		default:
+			values = append(values, nil)
+		}
+	}
+	return values
+}
+
+// rebuildListValue replaces the leaves of the list in order and returns those not used.
+func rebuildListValue(list *_struct.ListValue, values []interface{}) []interface{} {
+	for i, value := range list.GetValues() {
+		if nested := value.GetListValue(); nested != nil {
+			values = rebuildListValue(nested, values)
+			continue
+		}
+		switch v := values[0].(type) {
+		case float64:
+			list.Values[i] = &_struct.Value{Kind: &_struct.Value_NumberValue{NumberValue: v}}
+		case string:
+			list.Values[i] = &_struct.Value{Kind: &_struct.Value_StringValue{StringValue: v}}
+		case bool:
+			list.Values[i] = &_struct.Value{Kind: &_struct.Value_BoolValue{BoolValue: v}}
+		default:
+			list.Values[i] = &_struct.Value{Kind: &_struct.Value_NullValue{}}
+		}
+		values = values[1:]
+	}
+	return values
+}
+
+func (c *combiner) combineInferResponses(msgs []*inference.ModelInferResponse) (*inference.ModelInferResponse, error) {
+	res := protov1.Clone(msgs[0]).(*inference.ModelInferResponse)
+	for i, output := range res.Outputs {
+		values := make([][]interface{}, len(msgs))
+		for child, msg := range msgs {
+			if len(msg.GetRawOutputContents()) > 0 {
+				return nil, fmt.Errorf("combiner does not support raw output contents")
+			}
+			if len(msg.Outputs) != len(res.Outputs) {
+				return nil, fmt.Errorf("can not combine responses with different numbers of outputs")
+			}
+			values[child] = inferTensorValues(msg.Outputs[i].Datatype, msg.Outputs[i].Contents)
+		}
+		combined, err := c.reduce(values)
+		if err != nil {
+			return nil, err
+		}
+		if !c.vote && output.Datatype != "FP32" {
+			output.Datatype = "FP64"
+		}
+		output.Contents, err = newInferTensorContents(output.Datatype, combined)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func inferTensorValues(datatype string, contents *inference.InferTensorContents) []interface{} {
+	var values []interface{}
+	switch datatype {
+	case "BOOL":
+		for _, v := range contents.GetBoolContents() {
+			values = append(values, v)
+		}
+	case "INT8", "INT16", "INT32":
+		for _, v := range contents.GetIntContents() {
+			values = append(values, float64(v))
+		}
+	case "INT64":
+		for _, v := range contents.GetInt64Contents() {
+			values = append(values, float64(v))
+		}
+	case "UINT8", "UINT16", "UINT32":
+		for _, v := range contents.GetUintContents() {
+			values = append(values, float64(v))
+		}
+	case "UINT64":
+		for _, v := range contents.GetUint64Contents() {
+			values = append(values, float64(v))
+		}
+	case "FP32":
+		for _, v := range contents.GetFp32Contents() {
+			values = append(values, float64(v))
+		}
+	case "FP64":
+		for _, v := range contents.GetFp64Contents() {
+			values = append(values, v)
+		}
+	case "BYTES":
+		for _, v := range contents.GetByteContents() {
+			values = append(values, string(v))
+		}
+	}
+	return values
+}
+
+func newInferTensorContents(datatype string, values []interface{}) (*inference.InferTensorContents, error) {
+	contents := &inference.InferTensorContents{}
+	for _, value := range values {
+		if datatype == "BYTES" {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("combined value %v is not bytes", value)
+			}
+			contents.ByteContents = append(contents.ByteContents, []byte(s))
+			continue
+		} else if datatype == "BOOL" {
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("combined value %v is not a bool", value)
+			}
+			contents.BoolContents = append(contents.BoolContents, b)
+			continue
+		}
+		v, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("combined value %v is not a number", value)
+		}
+		switch datatype {
+		case "INT8", "INT16", "INT32":
+			contents.IntContents = append(contents.IntContents, int32(v))
+		case "INT64":
+			contents.Int64Contents = append(contents.Int64Contents, int64(v))
+		case "UINT8", "UINT16", "UINT32":
+			contents.UintContents = append(contents.UintContents, uint32(v))
+		case "UINT64":
+			contents.Uint64Contents = append(contents.Uint64Contents, uint64(v))
+		case "FP32":
+			contents.Fp32Contents = append(contents.Fp32Contents, float32(v))
+		default:
+			contents.Fp64Contents = append(contents.Fp64Contents, v)
+		}
+	}
+	return contents, nil
+}
+
+// combineJson combines REST responses in either the Seldon protocol or the V2 protocol, which has outputs.
+func (c *combiner) combineJson(cmsgs []payload.SeldonPayload) (payload.SeldonPayload, error) {
+	bodies := make([]map[string]interface{}, len(cmsgs))
+	for i, cmsg := range cmsgs {
+		data, err := cmsg.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &bodies[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := bodies[0]["outputs"]; !ok {
+		msgs := make([]*proto.SeldonMessage, len(cmsgs))
+		for i, cmsg := range cmsgs {
+			data, _ := cmsg.GetBytes()
+			msgs[i] = &proto.SeldonMessage{}
+			if err := jsonpb.UnmarshalString(string(data), msgs[i]); err != nil {
+				return nil, err
+			}
+		}
+		sm, err := c.combineSeldonMessages(msgs)
+		if err != nil {
+			return nil, err
+		}
+		ma := jsonpb.Marshaler{}
+		data, err := ma.MarshalToString(sm)
+		if err != nil {
+			return nil, err
+		}
+		return &payload.BytesPayload{Msg: []byte(data), ContentType: cmsgs[0].GetContentType()}, nil
+	}
+
+	res := bodies[0]
+	outputs, _ := res["outputs"].([]interface{})
+	for i := range outputs {
+		values := make([][]interface{}, len(bodies))
+		for child, body := range bodies {
+			childOutputs, _ := body["outputs"].([]interface{})
+			if len(childOutputs) != len(outputs) {
+				return nil, fmt.Errorf("can not combine responses with different numbers of outputs")
+			}
+			childOutput, ok := childOutputs[i].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("can not combine responses with outputs which are not objects")
+			}
+			values[child] = flattenJsonData(childOutput["data"], nil)
+		}
+		combined, err := c.reduce(values)
+		if err != nil {
+			return nil, err
+		}
+		output, ok := outputs[i].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("can not combine responses with outputs which are not objects")
+		}
+		output["data"] = combined
+		if !c.vote && output["datatype"] != "FP32" {
+			output["datatype"] = "FP64"
+		}
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	return &payload.BytesPayload{Msg: data, ContentType: cmsgs[0].GetContentType()}, nil
+}
+
+func flattenJsonData(data interface{}, values []interface{}) []interface{} {
+	if list, ok := data.([]interface{}); ok {
+		for _, value := range list {
+			values = flattenJsonData(value, values)
+		}
+		return values
+	}
+	return append(values, data)
+}
@@ -0,0 +1,161 @@
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func createSeldonPayloads(g *GomegaWithT, msgs ...string) []payload.SeldonPayload {
+	var cmsgs []payload.SeldonPayload
+	for _, msg := range msgs {
+		var sm proto.SeldonMessage
+		g.Expect(jsonpb.UnmarshalString(msg, &sm)).Should(Succeed())
+		cmsgs = append(cmsgs, &payload.ProtoPayload{Msg: &sm})
+	}
+	return cmsgs
+}
+
+func TestAverageCombinerNdarray(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createBuiltinGraph("combiner", v1.AVERAGE_COMBINER, nil)
+	cmsgs := createSeldonPayloads(g,
+		`{"data":{"names":["a","b"],"ndarray":[[0.2,0.8]]}}`,
+		`{"data":{"names":["a","b"],"ndarray":[[0.4,0.6]]}}`,
+		`{"data":{"names":["a","b"],"ndarray":[[0.6,0.4]]}}`)
+	res, err := createPredictorProcess(t).combine(graph, cmsgs, []int{0, 1, 2})
+	g.Expect(err).Should(BeNil())
+
+	data := res.GetPayload().(*proto.SeldonMessage).GetData()
+	g.Expect(data.GetNames()).Should(Equal([]string{"a", "b"}))
+	row := data.GetNdarray().GetValues()[0].GetListValue().GetValues()
+	g.Expect(row[0].GetNumberValue()).Should(BeNumerically("~", 0.4, 1e-9))
+	g.Expect(row[1].GetNumberValue()).Should(BeNumerically("~", 0.6, 1e-9))
+}
+
+func TestWeightedAverageCombinerTensor(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createBuiltinGraph("combiner", v1.AVERAGE_COMBINER, []v1.Parameter{{Name: "weights", Value: "1,1,2", Type: v1.STRING}})
+	cmsgs := createSeldonPayloads(g,
+		`{"data":{"tensor":{"shape":[1,2],"values":[0,1]}}}`,
+		`{"data":{"tensor":{"shape":[1,2],"values":[0,1]}}}`,
+		`{"data":{"tensor":{"shape":[1,2],"values":[1,0]}}}`)
+	res, err := createPredictorProcess(t).combine(graph, cmsgs, []int{0, 1, 2})
+	g.Expect(err).Should(BeNil())
+
+	tensor := res.GetPayload().(*proto.SeldonMessage).GetData().GetTensor()
+	g.Expect(tensor.GetShape()).Should(Equal([]int32{1, 2}))
+	g.Expect(tensor.GetValues()).Should(Equal([]float64{0.5, 0.5}))
+
+	// Only the weights of the children which responded are used
+	res, err = createPredictorProcess(t).combine(graph, cmsgs[1:], []int{1, 2})
+	g.Expect(err).Should(BeNil())
+	tensor = res.GetPayload().(*proto.SeldonMessage).GetData().GetTensor()
+	g.Expect(tensor.GetValues()).Should(Equal([]float64{2.0 / 3, 1.0 / 3}))
+}
+
+func TestMajorityVoteCombiner(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cmsgs := createSeldonPayloads(g,
+		`{"data":{"ndarray":["cat","dog"]}}`,
+		`{"data":{"ndarray":["cat","bird"]}}`,
+		`{"data":{"ndarray":["fish","bird"]}}`)
+	graph := createBuiltinGraph("combiner", v1.MAJORITY_VOTE_COMBINER, nil)
+	res, err := createPredictorProcess(t).combine(graph, cmsgs, []int{0, 1, 2})
+	g.Expect(err).Should(BeNil())
+	values := res.GetPayload().(*proto.SeldonMessage).GetData().GetNdarray().GetValues()
+	g.Expect(values[0].GetStringValue()).Should(Equal("cat"))
+	g.Expect(values[1].GetStringValue()).Should(Equal("bird"))
+
+	graph = createBuiltinGraph("combiner", v1.MAJORITY_VOTE_COMBINER, []v1.Parameter{{Name: "weights", Value: "1,1,3", Type: v1.STRING}})
+	res, err = createPredictorProcess(t).combine(graph, cmsgs, []int{0, 1, 2})
+	g.Expect(err).Should(BeNil())
+	values = res.GetPayload().(*proto.SeldonMessage).GetData().GetNdarray().GetValues()
+	g.Expect(values[0].GetStringValue()).Should(Equal("fish"))
+	g.Expect(values[1].GetStringValue()).Should(Equal("bird"))
+}
+
+func TestAverageCombinerV2Json(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var cmsgs []payload.SeldonPayload
+	for _, data := range []string{"[[1,2],[3,4]]", "[[3,4],[5,6]]"} {
+		msg := fmt.Sprintf(`{"model_name":"m","outputs":[{"name":"predict","shape":[2,2],"datatype":"INT64","data":%s}]}`, data)
+		cmsgs = append(cmsgs, &payload.BytesPayload{Msg: []byte(msg), ContentType: "application/json"})
+	}
+	graph := createBuiltinGraph("combiner", v1.AVERAGE_COMBINER, nil)
+	graph.Children = graph.Children[:2]
+	res, err := createPredictorProcess(t).combine(graph, cmsgs, []int{0, 1})
+	g.Expect(err).Should(BeNil())
+
+	var body map[string]interface{}
+	g.Expect(json.Unmarshal(res.GetPayload().([]byte), &body)).Should(Succeed())
+	output := body["outputs"].([]interface{})[0].(map[string]interface{})
+	g.Expect(output["datatype"]).Should(Equal("FP64"))
+	g.Expect(output["shape"]).Should(Equal([]interface{}{2.0, 2.0}))
+	g.Expect(output["data"]).Should(Equal([]interface{}{2.0, 3.0, 4.0, 5.0}))
+}
+
+func TestAverageCombinerV2JsonInvalidOutputs(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cmsgs := []payload.SeldonPayload{
+		&payload.BytesPayload{Msg: []byte(`{"model_name":"m","outputs":[1]}`), ContentType: "application/json"},
+		&payload.BytesPayload{Msg: []byte(`{"model_name":"m","outputs":[2]}`), ContentType: "application/json"},
+	}
+	graph := createBuiltinGraph("combiner", v1.AVERAGE_COMBINER, nil)
+	graph.Children = graph.Children[:2]
+	_, err := createPredictorProcess(t).combine(graph, cmsgs, []int{0, 1})
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestMajorityVoteCombinerV2Grpc(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var cmsgs []payload.SeldonPayload
+	for _, labels := range [][]int32{{1, 0, 2}, {1, 2, 2}, {0, 2, 1}} {
+		cmsgs = append(cmsgs, &payload.ProtoPayload{Msg: &inference.ModelInferResponse{
+			ModelName: "m",
+			Outputs: []*inference.ModelInferResponse_InferOutputTensor{
+				{Name: "predict", Datatype: "INT32", Shape: []int64{3}, Contents: &inference.InferTensorContents{IntContents: labels}},
+			},
+		}})
+	}
+	graph := createBuiltinGraph("combiner", v1.MAJORITY_VOTE_COMBINER, nil)
+	res, err := createPredictorProcess(t).combine(graph, cmsgs, []int{0, 1, 2})
+	g.Expect(err).Should(BeNil())
+
+	output := res.GetPayload().(*inference.ModelInferResponse).GetOutputs()[0]
+	g.Expect(output.GetDatatype()).Should(Equal("INT32"))
+	g.Expect(output.GetContents().GetIntContents()).Should(Equal([]int32{1, 2, 2}))
+}
+
+func TestCombinerDifferentSizes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cmsgs := createSeldonPayloads(g, `{"data":{"ndarray":[1,2]}}`, `{"data":{"ndarray":[1,2,3]}}`)
+	graph := createBuiltinGraph("combiner", v1.AVERAGE_COMBINER, nil)
+	_, err := createPredictorProcess(t).combine(graph, cmsgs, []int{0, 1})
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestAverageCombinerGraph(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createBuiltinGraph("combiner", v1.AVERAGE_COMBINER, nil)
+	pResp, err := createPredictorProcess(t).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	values := pResp.GetPayload().(*proto.SeldonMessage).GetData().GetNdarray().GetValues()
+	g.Expect(values[0].GetNumberValue()).Should(BeNumerically("~", 1.1, 1e-9))
+	g.Expect(values[1].GetNumberValue()).Should(BeNumerically("~", 2.0, 1e-9))
+}
@@ -0,0 +1,108 @@
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"github.com/seldonio/seldon-core/operator/utils/condition"
+)
+
+// Conditions compiled once and shared by all requests, keyed by expression.
+var conditions sync.Map
+
+func isConditionalRouter(node *v1.PredictiveUnit) bool {
+	return node.Implementation != nil && *node.Implementation == v1.CONDITIONAL_ROUTER
+}
+
+func compileCondition(expr string) (*condition.Expression, error) {
+	if compiled, ok := conditions.Load(expr); ok {
+		return compiled.(*condition.Expression), nil
+	}
+	compiled, err := condition.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	conditions.Store(expr, compiled)
+	return compiled, nil
+}
+
+// conditionalRoute returns the child named by the first rule whose condition matches the request.
+func (p *PredictorProcess) conditionalRoute(node *v1.PredictiveUnit, msg payload.SeldonPayload) (int, error) {
+	vars, err := p.conditionVariables(msg)
+	if err != nil {
+		return 0, err
+	}
+	for _, rule := range node.Parameters {
+		expr, err := compileCondition(rule.Value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid condition for child %s of router %s: %w", rule.Name, node.Name, err)
+		}
+		if !expr.Match(vars) {
+			continue
+		}
+		for i, child := range node.Children {
+			if child.Name == rule.Name {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("router %s has no child %s", node.Name, rule.Name)
+	}
+	return 0, fmt.Errorf("no condition of router %s matched the request", node.Name)
+}
+
+// conditionVariables collects the request tags, headers and V2 parameters which conditions can use.
+func (p *PredictorProcess) conditionVariables(msg payload.SeldonPayload) (map[string]interface{}, error) {
+	headers := make(map[string]interface{})
+	for key, values := range p.Meta.Meta {
+		if len(values) > 0 {
+			headers[strings.ToLower(key)] = values[0]
+		}
+	}
+	vars := map[string]interface{}{"headers": headers}
+
+	switch req := msg.GetPayload().(type) {
+	case *proto.SeldonMessage:
+		if req.GetMeta() != nil {
+			ma := jsonpb.Marshaler{}
+			data, err := ma.MarshalToString(req.GetMeta())
+			if err != nil {
+				return nil, err
+			}
+			var meta map[string]interface{}
+			if err := json.Unmarshal([]byte(data), &meta); err != nil {
+				return nil, err
+			}
+			vars["tags"] = meta["tags"]
+		}
+	case *inference.ModelInferRequest:
+		parameters := make(map[string]interface{})
+		for key, param := range req.GetParameters() {
+			switch choice := param.GetParameterChoice().(type) {
+			case *inference.InferParameter_BoolParam:
+				parameters[key] = choice.BoolParam
+			case *inference.InferParameter_Int64Param:
+				parameters[key] = choice.Int64Param
+			case *inference.InferParameter_StringParam:
+				parameters[key] = choice.StringParam
+			}
+		}
+		vars["parameters"] = parameters
+	case []byte:
+		var body map[string]interface{}
+		if err := json.Unmarshal(req, &body); err != nil {
+			return nil, err
+		}
+		if meta, ok := body["meta"].(map[string]interface{}); ok {
+			vars["tags"] = meta["tags"]
+		}
+		vars["parameters"] = body["parameters"]
+	}
+	return vars, nil
+}
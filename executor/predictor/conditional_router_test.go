@@ -0,0 +1,86 @@
+package predictor
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func createTaggedPayload(g *GomegaWithT, tags string) payload.SeldonPayload {
+	var sm proto.SeldonMessage
+	err := jsonpb.UnmarshalString(`{"data":{"ndarray":[1.1,2.0]},"meta":{"tags":`+tags+`}}`, &sm)
+	g.Expect(err).Should(BeNil())
+	return &payload.ProtoPayload{Msg: &sm}
+}
+
+func TestConditionalRouterTags(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createBuiltinGraph("conditional", v1.CONDITIONAL_ROUTER, []v1.Parameter{
+		{Name: "model1", Value: `tags.country == "DE"`, Type: v1.STRING},
+		{Name: "model2", Value: `tags.country in ["FR", "ES"] && tags.score > 0.5`, Type: v1.STRING},
+		{Name: "model0", Value: `true`, Type: v1.STRING},
+	})
+	pp := createPredictorProcess(t)
+
+	routes, err := pp.route(graph, createTaggedPayload(g, `{"country":"DE"}`))
+	g.Expect(err).Should(BeNil())
+	g.Expect(routes).Should(Equal([]int{1}))
+
+	routes, err = pp.route(graph, createTaggedPayload(g, `{"country":"ES","score":0.9}`))
+	g.Expect(err).Should(BeNil())
+	g.Expect(routes).Should(Equal([]int{2}))
+
+	routes, err = pp.route(graph, createTaggedPayload(g, `{"country":"ES","score":0.1}`))
+	g.Expect(err).Should(BeNil())
+	g.Expect(routes).Should(Equal([]int{0}))
+
+	pResp, err := pp.Predict(graph, createTaggedPayload(g, `{"country":"DE"}`))
+	g.Expect(err).Should(BeNil())
+	g.Expect(pResp.GetPayload().(*proto.SeldonMessage).GetData().GetNdarray().GetValues()).Should(HaveLen(2))
+	g.Expect(pp.Routing["conditional"]).Should(Equal(int32(1)))
+}
+
+func TestConditionalRouterHeaders(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createBuiltinGraph("conditional", v1.CONDITIONAL_ROUTER, []v1.Parameter{{Name: "model2", Value: `headers["x-tier"] == "gold"`, Type: v1.STRING}})
+	pp := createPredictorProcessWithMeta(t, map[string][]string{"X-Tier": {"gold"}})
+	routes, err := pp.route(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(routes).Should(Equal([]int{2}))
+
+	// No rule matching is an error unless there is a fallback child
+	_, err = createPredictorProcess(t).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+
+	graph.Fallback = &v1.FallbackPolicy{FallbackChild: "model0"}
+	pp = createPredictorProcess(t)
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(pp.Routing["conditional"]).Should(Equal(int32(0)))
+}
+
+func TestConditionalRouterV2Parameters(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createBuiltinGraph("conditional", v1.CONDITIONAL_ROUTER, []v1.Parameter{{Name: "model1", Value: `parameters.priority >= 2`, Type: v1.STRING}})
+	pp := createPredictorProcess(t)
+
+	msg := &payload.BytesPayload{Msg: []byte(`{"parameters":{"priority":3},"inputs":[]}`), ContentType: "application/json"}
+	routes, err := pp.route(graph, msg)
+	g.Expect(err).Should(BeNil())
+	g.Expect(routes).Should(Equal([]int{1}))
+
+	req := &inference.ModelInferRequest{Parameters: map[string]*inference.InferParameter{
+		"priority": {ParameterChoice: &inference.InferParameter_Int64Param{Int64Param: 2}},
+	}}
+	routes, err = pp.route(graph, &payload.ProtoPayload{Msg: req})
+	g.Expect(err).Should(BeNil())
+	g.Expect(routes).Should(Equal([]int{1}))
+}
@@ -0,0 +1,45 @@
+package predictor
+
+import (
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// fallbackChild returns the index of the child to use if routing fails or -1 if there is none.
+func fallbackChild(node *v1.PredictiveUnit) int {
+	if node.Fallback == nil || node.Fallback.FallbackChild == "" {
+		return -1
+	}
+	for i, child := range node.Children {
+		if child.Name == node.Fallback.FallbackChild {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasQuorum returns whether the responses of the successful children can be aggregated without the failed ones.
+func hasQuorum(node *v1.PredictiveUnit, succeeded int) bool {
+	if node.Fallback == nil || node.Fallback.MinSuccessfulChildren == 0 {
+		return false
+	}
+	return succeeded >= int(node.Fallback.MinSuccessfulChildren)
+}
+
+func hasDefaultResponse(node *v1.PredictiveUnit) bool {
+	return node.Fallback != nil && node.Fallback.DefaultResponse != ""
+}
+
+func (p *PredictorProcess) defaultResponse(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	if p.Client.IsGrpc() {
+		var sm proto.SeldonMessage
+		if err := jsonpb.UnmarshalString(node.Fallback.DefaultResponse, &sm); err != nil {
+			return nil, err
+		}
+		return &payload.ProtoPayload{Msg: &sm}, nil
+	}
+	// The default response is JSON like the request it replaces the response of
+	return &payload.BytesPayload{Msg: []byte(node.Fallback.DefaultResponse), ContentType: msg.GetContentType()}, nil
+}
@@ -0,0 +1,159 @@
+package predictor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// RedactedValue replaces the redacted fields of logged payloads.
+const RedactedValue = "REDACTED"
+
+// shouldLog samples the requests whose payloads are logged. Requests are sampled on their puid so the payloads of
+// all the nodes of a request are logged together.
+func (p *PredictorProcess) shouldLog(logger *v1.Logger, puid string) bool {
+	if logger.SamplePercent == nil || *logger.SamplePercent >= 100 {
+		return true
+	}
+	header := logger.ForceHeader
+	if header == "" {
+		header = payload.SeldonForceLoggingHeader
+	}
+	// REST headers are canonicalized while gRPC metadata keys are lowercase
+	for _, key := range []string{header, http.CanonicalHeaderKey(header), strings.ToLower(header)} {
+		if p.Meta.GetAsBoolean(key, false) {
+			return true
+		}
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(puid))
+	return int32(h.Sum32()%100) < *logger.SamplePercent
+}
+
+// logBytes returns the payload to log with the fields in the redaction list of the logger replaced. Redacted payloads
+// are logged as uncompressed JSON, protobuf messages being converted to their JSON form first.
+func logBytes(logger *v1.Logger, msg payload.SeldonPayload) ([]byte, string, string, error) {
+	if len(logger.Redact) == 0 {
+		data, err := msg.GetBytes()
+		return data, msg.GetContentType(), msg.GetContentEncoding(), err
+	}
+	var data []byte
+	var err error
+	switch m := msg.GetPayload().(type) {
+	case []byte:
+		data, err = payload.DecompressBytes(m, msg.GetContentEncoding())
+	case proto.Message:
+		var buf bytes.Buffer
+		err = (&jsonpb.Marshaler{}).Marshal(&buf, m)
+		data = buf.Bytes()
+	default:
+		err = fmt.Errorf("unsupported payload type %T", m)
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+	data, err = redactJSON(data, logger.Redact)
+	return data, cloudevents.ApplicationJSON, "", err
+}
+
+// redactJSON replaces the values at the JSON pointers of the redaction list, and the data of the V2 tensors named in
+// it.
+func redactJSON(data []byte, redact []string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers as they are rather than converting them to floats
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return nil, err
+	}
+	tensors := make(map[string]bool)
+	for _, r := range redact {
+		if strings.HasPrefix(r, "/") {
+			redactPointer(body, r)
+		} else {
+			tensors[r] = true
+		}
+	}
+	if len(tensors) > 0 {
+		redactTensors(body, tensors)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(body); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// redactPointer replaces the value at the JSON pointer, if there is one.
+func redactPointer(body interface{}, pointer string) {
+	tokens := strings.Split(pointer[1:], "/")
+	parent := body
+	for i, token := range tokens {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		last := i == len(tokens)-1
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return
+			}
+			if last {
+				node[token] = RedactedValue
+			}
+			parent = child
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return
+			}
+			if last {
+				node[index] = RedactedValue
+			}
+			parent = node[index]
+		default:
+			return
+		}
+	}
+}
+
+// redactTensors replaces the data of the named input and output tensors of a V2 payload, including their raw
+// contents in gRPC payloads.
+func redactTensors(body interface{}, names map[string]bool) {
+	obj, ok := body.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, fields := range []struct{ tensors, raw string }{{"inputs", "rawInputContents"}, {"outputs", "rawOutputContents"}} {
+		tensors, _ := obj[fields.tensors].([]interface{})
+		raw, _ := obj[fields.raw].([]interface{})
+		for i, t := range tensors {
+			tensor, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if name, _ := tensor["name"].(string); !names[name] {
+				continue
+			}
+			for _, key := range []string{"data", "contents"} {
+				if _, ok := tensor[key]; ok {
+					tensor[key] = RedactedValue
+				}
+			}
+			if i < len(raw) {
+				raw[i] = RedactedValue
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package predictor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/logger"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestShouldLogSamplesRequests(t *testing.T) {
+	g := NewGomegaWithT(t)
+	p := createPredictorProcess(t)
+
+	g.Expect(p.shouldLog(&v1.Logger{}, "puid")).To(BeTrue())
+	none := int32(0)
+	g.Expect(p.shouldLog(&v1.Logger{SamplePercent: &none}, "puid")).To(BeFalse())
+
+	half := int32(50)
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		puid := fmt.Sprintf("puid-%d", i)
+		logged := p.shouldLog(&v1.Logger{SamplePercent: &half}, puid)
+		// The payloads of a request are all logged or none is
+		g.Expect(p.shouldLog(&v1.Logger{SamplePercent: &half}, puid)).To(Equal(logged))
+		if logged {
+			sampled++
+		}
+	}
+	g.Expect(sampled).To(BeNumerically("~", 500, 75))
+}
+
+func TestShouldLogForcedByHeader(t *testing.T) {
+	g := NewGomegaWithT(t)
+	none := int32(0)
+
+	p := createPredictorProcessWithMeta(t, map[string][]string{payload.SeldonForceLoggingHeader: {"true"}})
+	g.Expect(p.shouldLog(&v1.Logger{SamplePercent: &none}, "puid")).To(BeTrue())
+
+	// gRPC metadata keys are lowercase
+	p = createPredictorProcessWithMeta(t, map[string][]string{"x-debug": {"true"}})
+	g.Expect(p.shouldLog(&v1.Logger{SamplePercent: &none, ForceHeader: "X-Debug"}, "puid")).To(BeTrue())
+	g.Expect(p.shouldLog(&v1.Logger{SamplePercent: &none}, "puid")).To(BeFalse())
+
+	p = createPredictorProcessWithMeta(t, map[string][]string{"X-Debug": {"false"}})
+	g.Expect(p.shouldLog(&v1.Logger{SamplePercent: &none, ForceHeader: "x-debug"}, "puid")).To(BeFalse())
+}
+
+func TestRedactSeldonJSON(t *testing.T) {
+	g := NewGomegaWithT(t)
+	msg := &payload.BytesPayload{
+		Msg:         []byte(`{"data":{"names":["a","b"],"ndarray":[[1,2],[3,4]]},"meta":{"tags":{"user/id":12345678901234567890,"note":"<b>"}}}`),
+		ContentType: "application/json",
+	}
+	data, contentType, contentEncoding, err := logBytes(&v1.Logger{Redact: []string{"/data/names", "/data/ndarray/1", "/meta/tags/user~1id", "/missing/field", "/data/ndarray/7"}}, msg)
+	g.Expect(err).To(BeNil())
+	g.Expect(contentType).To(Equal("application/json"))
+	g.Expect(contentEncoding).To(Equal(""))
+	g.Expect(string(data)).To(MatchJSON(`{"data":{"names":"REDACTED","ndarray":[[1,2],"REDACTED"]},"meta":{"tags":{"user/id":"REDACTED","note":"<b>"}}}`))
+	g.Expect(string(data)).To(ContainSubstring(`"<b>"`))
+
+	// Numbers are logged as they are
+	data, _, _, err = logBytes(&v1.Logger{Redact: []string{"/data"}}, msg)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(data)).To(ContainSubstring("12345678901234567890"))
+
+	// Without redaction the payload is logged unchanged
+	data, _, _, err = logBytes(&v1.Logger{}, msg)
+	g.Expect(err).To(BeNil())
+	g.Expect(data).To(Equal(msg.Msg))
+
+	_, _, _, err = logBytes(&v1.Logger{Redact: []string{"/data"}}, &payload.BytesPayload{Msg: []byte("not json")})
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestRedactCompressedV2JSON(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(`{"inputs":[{"name":"ssn","shape":[1],"datatype":"BYTES","data":["123-45-6789"]},{"name":"age","shape":[1],"datatype":"INT32","data":[42]}]}`))
+	g.Expect(zw.Close()).To(BeNil())
+
+	data, contentType, contentEncoding, err := logBytes(&v1.Logger{Redact: []string{"ssn"}}, &payload.BytesPayload{Msg: buf.Bytes(), ContentType: "application/json", ContentEncoding: "gzip"})
+	g.Expect(err).To(BeNil())
+	g.Expect(contentType).To(Equal("application/json"))
+	g.Expect(contentEncoding).To(Equal(""))
+	g.Expect(string(data)).To(MatchJSON(`{"inputs":[{"name":"ssn","shape":[1],"datatype":"BYTES","data":"REDACTED"},{"name":"age","shape":[1],"datatype":"INT32","data":[42]}]}`))
+}
+
+func TestRedactV2Proto(t *testing.T) {
+	g := NewGomegaWithT(t)
+	req := &inference.ModelInferRequest{
+		ModelName: "model",
+		Inputs: []*inference.ModelInferRequest_InferInputTensor{
+			{Name: "age", Datatype: "INT32", Shape: []int64{1}},
+			{Name: "ssn", Datatype: "BYTES", Shape: []int64{1}},
+		},
+		RawInputContents: [][]byte{{42, 0, 0, 0}, []byte("123-45-6789")},
+	}
+
+	data, contentType, _, err := logBytes(&v1.Logger{Redact: []string{"ssn", "/modelName"}}, &payload.ProtoPayload{Msg: req})
+	g.Expect(err).To(BeNil())
+	g.Expect(contentType).To(Equal("application/json"))
+	var logged struct {
+		ModelName        string   `json:"modelName"`
+		RawInputContents []string `json:"rawInputContents"`
+	}
+	g.Expect(json.Unmarshal(data, &logged)).To(BeNil())
+	g.Expect(logged.ModelName).To(Equal(RedactedValue))
+	g.Expect(logged.RawInputContents).To(Equal([]string{"KgAAAA==", RedactedValue}))
+	// The message being processed is left untouched
+	g.Expect(req.RawInputContents[1]).To(Equal([]byte("123-45-6789")))
+}
+
+func TestModelLogsRedactedPayloads(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, logf.Log.WithName("test"), "", "", "", "", "", api.ProtocolSeldon)
+
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name: "model",
+		Type: &model,
+		Endpoint: &v1.Endpoint{
+			ServiceHost: "foo",
+			ServicePort: 9000,
+			Type:        v1.REST,
+		},
+		Logger: &v1.Logger{
+			Mode:   v1.LogRequest,
+			Url:    &server.URL,
+			Redact: []string{"/data/ndarray/0"},
+		},
+	}
+
+	_, err := createPredictorProcess(t).Predict(graph, createPredictPayload(g))
+	g.Expect(err).To(BeNil())
+	g.Eventually(func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return bodies
+	}).Should(Equal([]string{`{"data":{"ndarray":["REDACTED",2]}}`}))
+}
@@ -0,0 +1,114 @@
+package predictor
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	protov1 "github.com/golang/protobuf/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	payloadLogger "github.com/seldonio/seldon-core/executor/logger"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// mirrorTimeout bounds how long a mirrored request can run once the primary request has finished.
+	mirrorTimeout = 30 * time.Second
+	// maxMirroredInFlight bounds the mirrored requests running at once, further requests are not mirrored.
+	maxMirroredInFlight = 100
+)
+
+var mirrorSlots = make(chan struct{}, maxMirroredInFlight)
+
+// shadowChild returns the index of the child which receives mirrored requests or -1 if there is none.
+func shadowChild(node *v1.PredictiveUnit) int {
+	if node.Mirror == nil {
+		return -1
+	}
+	for i, child := range node.Children {
+		if child.Name == node.Mirror.Child {
+			return i
+		}
+	}
+	return -1
+}
+
+// withoutShadow returns a copy of the node without its shadow child so routing and aggregation never see it.
+func withoutShadow(node *v1.PredictiveUnit) *v1.PredictiveUnit {
+	shadow := shadowChild(node)
+	if shadow < 0 {
+		return node
+	}
+	primary := *node
+	primary.Children = make([]v1.PredictiveUnit, 0, len(node.Children)-1)
+	primary.Children = append(primary.Children, node.Children[:shadow]...)
+	primary.Children = append(primary.Children, node.Children[shadow+1:]...)
+	return &primary
+}
+
+func shouldMirror(node *v1.PredictiveUnit) bool {
+	if shadowChild(node) < 0 {
+		return false
+	}
+	percent := node.Mirror.Percent
+	if percent == 0 {
+		percent = 100
+	}
+	return rand.Int31n(100) < percent
+}
+
+// mirror sends a copy of the request to the shadow child in the background and logs its response so it can be
+// compared with the response of the node.
+func (p *PredictorProcess) mirror(node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) {
+	shadow := node.Children[shadowChild(node)]
+	select {
+	case mirrorSlots <- struct{}{}:
+	default:
+		p.Log.V(1).Info("Too many mirrored requests in flight, not mirroring", "node", node.Name, "shadow", shadow.Name)
+		return
+	}
+	if pp, ok := msg.(*payload.ProtoPayload); ok {
+		msg = &payload.ProtoPayload{Msg: protov1.Clone(pp.Msg)}
+	}
+	// The request context ends with the primary response so the shadow needs its own
+	ctx, cancel := context.WithTimeout(context.Background(), mirrorTimeout)
+	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, puid)
+	ctx = trace.ContextWithSpanContext(ctx, trace.SpanContextFromContext(p.Ctx))
+	sp := NewPredictorProcess(ctx, p.Client, p.Log.WithName("Mirror"), p.ServerUrl, p.Namespace, p.Meta.Meta, "")
+	// The shadow keeps the node state of the version until it's done
+	if p.Version != nil {
+		p.Version.retain()
+		sp.Version = p.Version
+	}
+	go func() {
+		defer func() { <-mirrorSlots }()
+		defer cancel()
+		if sp.Version != nil {
+			defer sp.Version.Release()
+		}
+		response, err := sp.Predict(&shadow, msg)
+		if err != nil {
+			sp.Log.Info("Mirrored request failed", "node", node.Name, "shadow", shadow.Name, "error", err.Error())
+			return
+		}
+		if err := sp.logMirrored(node, &shadow, response, puid); err != nil {
+			sp.Log.Error(err, "Failed to log mirrored response", "shadow", shadow.Name)
+		}
+	}()
+}
+
+// logMirrored logs a response of a node with a shadow, unless the unit's own logger already logs it.
+func (p *PredictorProcess) logMirrored(node *v1.PredictiveUnit, unit *v1.PredictiveUnit, response payload.SeldonPayload, puid string) error {
+	if unit.Logger != nil && (unit.Logger.Mode == v1.LogResponse || unit.Logger.Mode == v1.LogAll) {
+		return nil
+	}
+	logger := node.Logger
+	if logger == nil {
+		if envRequestLoggerDefaultEndpoint == "" {
+			return nil
+		}
+		logger = &v1.Logger{}
+	}
+	return p.logPayload(unit.Name, logger, payloadLogger.InferenceResponse, response, puid)
+}
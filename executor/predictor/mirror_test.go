@@ -0,0 +1,109 @@
+package predictor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/logger"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func createMirrorGraph(logUrl string) *v1.PredictiveUnit {
+	graph := createFallbackGraph(v1.COMBINER, nil)
+	graph.Mirror = &v1.MirrorPolicy{Child: "model2"}
+	graph.Logger = &v1.Logger{Url: &logUrl}
+	return graph
+}
+
+func TestMirrorToShadowChild(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var mu sync.Mutex
+	logged := make(map[string]string)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged[r.Header.Get(modelIdHeaderName)] = r.Header.Get(logger.CloudEventsTypeHeader)
+		g.Expect(r.Header.Get(requestIdHeaderName)).To(Equal(testSeldonPuid))
+		w.Write([]byte(""))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	logf.SetLogger(zap.New())
+	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, logf.Log.WithName("entrypoint"), "", "", "", "", "", api.ProtocolSeldon)
+
+	graph := createMirrorGraph(server.URL)
+	pp := createPredictorProcess(t)
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+
+	// The shadow is not part of the primary graph
+	g.Expect(pp.Routing).Should(HaveKey("model0"))
+	g.Expect(pp.Routing).Should(HaveKey("model1"))
+	g.Expect(pp.Routing).ShouldNot(HaveKey("model2"))
+
+	// Both responses are logged so they can be compared
+	g.Eventually(func() map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		copied := make(map[string]string)
+		for k, v := range logged {
+			copied[k] = v
+		}
+		return copied
+	}).Should(Equal(map[string]string{"parent": logger.CEInferenceResponse, "model2": logger.CEInferenceResponse}))
+}
+
+func TestMirrorShadowFailureIgnored(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createMirrorGraph("")
+	graph.Logger = nil
+	_, err := createPredictorProcessWithHostError("foo3", 0).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+
+	// Feedback does not reach the shadow either
+	_, err = createPredictorProcessWithRoutes(t, v1.SEND_FEEDBACK, "foo3", nil).Feedback(graph, createFeedbackPayload(g))
+	g.Expect(err).Should(BeNil())
+}
+
+func TestWithoutShadow(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createMirrorGraph("")
+	primary := withoutShadow(graph)
+	g.Expect(primary.Children).Should(HaveLen(2))
+	g.Expect(primary.Children[1].Name).Should(Equal("model1"))
+	g.Expect(graph.Children).Should(HaveLen(3))
+
+	graph.Mirror = nil
+	g.Expect(withoutShadow(graph)).Should(BeIdenticalTo(graph))
+	g.Expect(shouldMirror(graph)).Should(BeFalse())
+}
+
+func TestMirrorSkippedWhenSlotsFull(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for i := 0; i < maxMirroredInFlight; i++ {
+		mirrorSlots <- struct{}{}
+	}
+	defer func() {
+		for i := 0; i < maxMirroredInFlight; i++ {
+			<-mirrorSlots
+		}
+	}()
+
+	graph := createMirrorGraph("")
+	graph.Logger = nil
+	pp := createPredictorProcess(t)
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(mirrorSlots).Should(HaveLen(maxMirroredInFlight))
+}
@@ -12,10 +12,12 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/api/util"
 
 	payloadLogger "github.com/seldonio/seldon-core/executor/logger"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -49,6 +51,9 @@ type PredictorProcess struct {
 	Routing           map[string]int32
 	RoutingMutex      *sync.RWMutex
 	ModelNameOverride string
+	// Version of the graph the request uses, whose node state it shares with other requests. Nil for a graph which
+	// hasn't been loaded.
+	Version *PredictorVersion
 }
 
 func NewPredictorProcess(context context.Context, client client.SeldonApiClient, log logr.Logger, serverUrl *url.URL, namespace string, meta map[string][]string, modelNameOverride string) PredictorProcess {
@@ -92,6 +97,11 @@ func (p *PredictorProcess) getModelName(node *v1.PredictiveUnit) string {
 	return modelName
 }
 
+// nodeContext returns the context for client calls to the given node, carrying the node's call policies.
+func (p *PredictorProcess) nodeContext(node *v1.PredictiveUnit) context.Context {
+	return client.ContextWithRetryPolicy(p.Ctx, node.Retry)
+}
+
 func (p *PredictorProcess) transformInput(node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (tmsg payload.SeldonPayload, err error) {
 	callModel := false
 	callTransformInput := false
@@ -110,7 +120,10 @@ func (p *PredictorProcess) transformInput(node *v1.PredictiveUnit, msg payload.S
 	modelName := p.getModelName(node)
 
 	if callModel || callTransformInput {
-		msg, err := p.Client.Chain(p.Ctx, modelName, msg)
+		p, span := p.startSpan(SpanTransformInput, node, msg)
+		defer func() { endSpan(span, tmsg, err) }()
+
+		msg, err := p.nodeClient(node).Chain(p.Ctx, modelName, msg)
 		if err != nil {
 			return nil, err
 		}
@@ -128,9 +141,11 @@ func (p *PredictorProcess) transformInput(node *v1.PredictiveUnit, msg payload.S
 		p.RoutingMutex.Unlock()
 
 		if callTransformInput {
-			tmsg, err = p.Client.TransformInput(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+			tmsg, err = p.nodeClient(node).TransformInput(p.nodeContext(node), modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+		} else if node.Batching != nil {
+			tmsg, err = p.batchedPredict(node, modelName, msg)
 		} else {
-			tmsg, err = p.Client.Predict(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+			tmsg, err = p.nodeClient(node).Predict(p.nodeContext(node), modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
 		}
 		if tmsg != nil && err == nil {
 			// Log Response
@@ -147,7 +162,7 @@ func (p *PredictorProcess) transformInput(node *v1.PredictiveUnit, msg payload.S
 	}
 }
 
-func (p *PredictorProcess) transformOutput(node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (payload.SeldonPayload, error) {
+func (p *PredictorProcess) transformOutput(node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (tmsg payload.SeldonPayload, err error) {
 	callClient := false
 	if (*node).Type != nil {
 		switch *node.Type {
@@ -162,7 +177,10 @@ func (p *PredictorProcess) transformOutput(node *v1.PredictiveUnit, msg payload.
 	modelName := p.getModelName(node)
 
 	if callClient {
-		msg, err := p.Client.Chain(p.Ctx, modelName, msg)
+		p, span := p.startSpan(SpanTransformOutput, node, msg)
+		defer func() { endSpan(span, tmsg, err) }()
+
+		msg, err := p.nodeClient(node).Chain(p.Ctx, modelName, msg)
 		if err != nil {
 			return nil, err
 		}
@@ -175,7 +193,7 @@ func (p *PredictorProcess) transformOutput(node *v1.PredictiveUnit, msg payload.
 			}
 		}
 
-		tmsg, err := p.Client.TransformOutput(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+		tmsg, err := p.nodeClient(node).TransformOutput(p.nodeContext(node), modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
 		if tmsg != nil && err == nil {
 			// Log Response
 			if node.Logger != nil && (node.Logger.Mode == v1.LogResponse || node.Logger.Mode == v1.LogAll) {
@@ -207,22 +225,33 @@ func (p *PredictorProcess) feedback(node *v1.PredictiveUnit, msg payload.SeldonP
 	modelName := p.getModelName(node)
 
 	if callClient {
-		return p.Client.Feedback(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+		return p.nodeClient(node).Feedback(p.nodeContext(node), modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+	} else if isBanditRouter(node) {
+		return msg, p.banditFeedback(node, msg)
 	} else {
 		return msg, nil
 	}
 
 }
 
-func (p *PredictorProcess) routeFeedback(node *v1.PredictiveUnit, msg payload.SeldonPayload) (int, error) {
+func (p *PredictorProcess) routeFeedback(node *v1.PredictiveUnit, msg payload.SeldonPayload) ([]int, error) {
+	if routes, ok := feedbackRoutes(node, msg); ok {
+		return routes, nil
+	}
+	// Feedback without the routing of the node is sent to all its children
+	return []int{routeToAllChildren}, nil
+}
+
+// feedbackRoutes returns the routes the node chose for the request of the feedback, or false if they weren't recorded.
+func feedbackRoutes(node *v1.PredictiveUnit, msg payload.SeldonPayload) ([]int, bool) {
 	if msg.GetContentType() == payload.APPLICATION_TYPE_PROTOBUF {
-		return util.RouteFromFeedbackMessageMeta(msg.GetPayload().(*proto.Feedback), node.Name), nil
+		return util.RouteFromFeedbackMessageMeta(msg.GetPayload().(*proto.Feedback), node.Name)
 	} else {
-		return util.RouteFromFeedbackJsonMeta(msg, node.Name), nil
+		return util.RouteFromFeedbackJsonMeta(msg, node.Name)
 	}
 }
 
-func (p *PredictorProcess) route(node *v1.PredictiveUnit, msg payload.SeldonPayload) (int, error) {
+func (p *PredictorProcess) route(node *v1.PredictiveUnit, msg payload.SeldonPayload) ([]int, error) {
 	callClient := false
 	if (*node).Type != nil {
 		switch *node.Type {
@@ -237,15 +266,135 @@ func (p *PredictorProcess) route(node *v1.PredictiveUnit, msg payload.SeldonPayl
 	modelName := p.getModelName(node)
 
 	if callClient {
-		return p.Client.Route(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+		return p.nodeClient(node).Route(p.nodeContext(node), modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
 	} else if node.Implementation != nil && *node.Implementation == v1.RANDOM_ABTEST {
-		return p.abTestRouter(node)
+		return singleRoute(p.abTestRouter(node))
+	} else if isBanditRouter(node) {
+		return singleRoute(p.banditRoute(node))
+	} else if isConditionalRouter(node) {
+		return singleRoute(p.conditionalRoute(node, msg))
 	} else {
-		return -1, nil
+		return []int{routeToAllChildren}, nil
+	}
+}
+
+func singleRoute(route int, err error) ([]int, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []int{route}, nil
+}
+
+// validateRoutes removes duplicate routes, as returned by routers for each row of a batch, and checks the children exist.
+func validateRoutes(node *v1.PredictiveUnit, routes []int) ([]int, error) {
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("router %s returned no routes", node.Name)
+	}
+	if routes[0] == routeToAllChildren || routes[0] == routeToNoChildren {
+		return routes[:1], nil
+	}
+	var unique []int
+	seen := make(map[int]bool)
+	for _, route := range routes {
+		if route < 0 || route >= len(node.Children) {
+			return nil, fmt.Errorf("router %s returned invalid route %d for %d children", node.Name, route, len(node.Children))
+		}
+		if !seen[route] {
+			seen[route] = true
+			unique = append(unique, route)
+		}
+	}
+	return unique, nil
+}
+
+// chooseRoutes returns the valid routes of a node for the request.
+func (p *PredictorProcess) chooseRoutes(node *v1.PredictiveUnit, msg payload.SeldonPayload) (routes []int, err error) {
+	p, span := p.startSpan(SpanRoute, node, msg)
+	defer func() {
+		if err == nil {
+			span.SetAttributes(AttributeNodeRoutes.IntSlice(routes))
+		}
+		endSpan(span, nil, err)
+	}()
+	routes, err = p.route(node, msg)
+	if err != nil {
+		return nil, err
+	}
+	return validateRoutes(node, routes)
+}
+
+// predictRoutedChildren sends the request to the children chosen by the routes and returns the responses of the
+// children which succeeded. If the children fail, the response of the first failed child is returned.
+func (p *PredictorProcess) predictRoutedChildren(node *v1.PredictiveUnit, routes []int, msg payload.SeldonPayload) (cmsgs []payload.SeldonPayload, children []int, err error) {
+	cp, span := p.startSpan(SpanChildren, node, msg)
+	defer func() { endSpan(span, nil, err) }()
+	route := routes[0]
+	if route == routeToAllChildren || len(routes) > 1 { // Routes msg to all children of the current node or the chosen subset.
+		selected := routes
+		if route == routeToAllChildren {
+			selected = make([]int, len(node.Children))
+			for i := range node.Children {
+				selected[i] = i
+			}
+		}
+		cmsgs = make([]payload.SeldonPayload, len(selected))
+		var errs = make([]error, len(selected))
+		wg := sync.WaitGroup{}
+		for i, child := range selected {
+			wg.Add(1)
+			go func(i int, nodeChild v1.PredictiveUnit, msg payload.SeldonPayload) {
+				cmsgs[i], errs[i] = cp.Predict(&nodeChild, msg)
+				wg.Done()
+			}(i, node.Children[child], msg)
+		}
+		wg.Wait()
+		p.setRouting(node, routes)
+		var succeeded []payload.SeldonPayload
+		var failed []int
+		for i, err := range errs {
+			if err != nil {
+				failed = append(failed, i)
+			} else {
+				succeeded = append(succeeded, cmsgs[i])
+				children = append(children, selected[i])
+			}
+		}
+		if len(failed) > 0 {
+			if !hasQuorum(node, len(succeeded)) {
+				return []payload.SeldonPayload{cmsgs[failed[0]]}, nil, errs[failed[0]]
+			}
+			p.Log.Info("Aggregating successful children only", "node", node.Name, "failed", len(failed), "error", errs[failed[0]].Error())
+			span.AddEvent("Aggregating successful children only", trace.WithAttributes(AttributeNodeRoutes.IntSlice(children)))
+			return succeeded, children, nil
+		}
+		return cmsgs, selected, nil
+	} else { // Calls SeldonApiClient.Predict.
+		cmsgs = make([]payload.SeldonPayload, 1)
+		cmsgs[0], err = cp.Predict(&node.Children[route], msg)
+		if fallback := fallbackChild(node); err != nil && fallback >= 0 && fallback != route {
+			p.Log.Info("Routed child failed so using fallback child", "node", node.Name, "child", node.Fallback.FallbackChild, "error", err.Error())
+			route = fallback
+			cmsgs[0], err = cp.Predict(&node.Children[route], msg)
+		}
+		p.setRouting(node, []int{route})
+		if err != nil {
+			return cmsgs, nil, err
+		}
+		return cmsgs, []int{route}, nil
+	}
+}
+
+// setRouting records the routes taken at a node, adding a key for each extra child when routing to several.
+func (p *PredictorProcess) setRouting(node *v1.PredictiveUnit, routes []int) {
+	trace.SpanFromContext(p.Ctx).SetAttributes(AttributeNodeRoutes.IntSlice(routes))
+	p.RoutingMutex.Lock()
+	defer p.RoutingMutex.Unlock()
+	for i, route := range routes {
+		p.Routing[util.MultiRouteKey(node.Name, i)] = int32(route)
 	}
 }
 
-func (p *PredictorProcess) aggregate(node *v1.PredictiveUnit, cmsg []payload.SeldonPayload, msg payload.SeldonPayload, puid string) (payload.SeldonPayload, error) {
+func (p *PredictorProcess) aggregate(node *v1.PredictiveUnit, cmsg []payload.SeldonPayload, children []int, msg payload.SeldonPayload, puid string) (amsg payload.SeldonPayload, err error) {
 	callClient := false
 	if (*node).Type != nil {
 		switch *node.Type {
@@ -259,6 +408,12 @@ func (p *PredictorProcess) aggregate(node *v1.PredictiveUnit, cmsg []payload.Sel
 
 	modelName := p.getModelName(node)
 
+	if callClient || (isBuiltinCombiner(node) && len(cmsg) > 1) {
+		var span trace.Span
+		p, span = p.startSpan(SpanAggregate, node, nil)
+		defer func() { endSpan(span, amsg, err) }()
+	}
+
 	if callClient {
 		//Log Request
 		if node.Logger != nil && (node.Logger.Mode == v1.LogRequest || node.Logger.Mode == v1.LogAll) {
@@ -270,7 +425,7 @@ func (p *PredictorProcess) aggregate(node *v1.PredictiveUnit, cmsg []payload.Sel
 		p.RoutingMutex.Lock()
 		p.Routing[node.Name] = -1
 		p.RoutingMutex.Unlock()
-		tmsg, err := p.Client.Combine(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), cmsg, p.Meta.Meta)
+		tmsg, err := p.nodeClient(node).Combine(p.nodeContext(node), modelName, node.Endpoint.ServiceHost, p.getPort(node), cmsg, p.Meta.Meta)
 		if tmsg != nil && err == nil {
 			// Log Response
 			if node.Logger != nil && (node.Logger.Mode == v1.LogResponse || node.Logger.Mode == v1.LogAll) {
@@ -281,6 +436,8 @@ func (p *PredictorProcess) aggregate(node *v1.PredictiveUnit, cmsg []payload.Sel
 			}
 		}
 		return tmsg, err
+	} else if isBuiltinCombiner(node) && len(cmsg) > 1 {
+		return p.combine(node, cmsg, children)
 	} else {
 		return cmsg[0], nil
 	}
@@ -295,48 +452,25 @@ func (p *PredictorProcess) predictChildren(node *v1.PredictiveUnit, msg payload.
 				return nil, err
 			}
 		}
-		route, err := p.route(node, msg)
+		routes, err := p.chooseRoutes(node, msg)
 		if err != nil {
-			return nil, err
-		}
-		var cmsgs []payload.SeldonPayload
-		if route == routeToAllChildren { // Routes msg to all children of the current node.
-			cmsgs = make([]payload.SeldonPayload, len(node.Children))
-			var errs = make([]error, len(node.Children))
-			wg := sync.WaitGroup{}
-			for i, nodeChild := range node.Children {
-				wg.Add(1)
-				go func(i int, nodeChild v1.PredictiveUnit, msg payload.SeldonPayload) {
-					cmsgs[i], errs[i] = p.Predict(&nodeChild, msg)
-					wg.Done()
-				}(i, nodeChild, msg)
-			}
-			wg.Wait()
-			p.RoutingMutex.Lock()
-			p.Routing[node.Name] = -1
-			p.RoutingMutex.Unlock()
-			for i, err := range errs {
-				if err != nil {
-					return cmsgs[i], err
-				}
+			fallback := fallbackChild(node)
+			if fallback < 0 {
+				return nil, err
 			}
-		} else if route == routeToNoChildren { // Returns msg as is.
+			p.Log.Info("Routing failed so using fallback child", "node", node.Name, "child", node.Fallback.FallbackChild, "error", err.Error())
+			routes = []int{fallback}
+		}
+		if routes[0] == routeToNoChildren { // Returns msg as is.
 			//Abort and return request
-			p.RoutingMutex.Lock()
-			p.Routing[node.Name] = -2
-			p.RoutingMutex.Unlock()
+			p.setRouting(node, routes)
 			return msg, nil
-		} else { // Calls SeldonApiClient.Predict.
-			cmsgs = make([]payload.SeldonPayload, 1)
-			cmsgs[0], err = p.Predict(&node.Children[route], msg)
-			p.RoutingMutex.Lock()
-			p.Routing[node.Name] = int32(route)
-			p.RoutingMutex.Unlock()
-			if err != nil {
-				return cmsgs[0], err
-			}
 		}
-		amsg, err := p.aggregate(node, cmsgs, msg, puid)
+		cmsgs, children, err := p.predictRoutedChildren(node, routes, msg)
+		if err != nil {
+			return cmsgs[0], err
+		}
+		amsg, err := p.aggregate(node, cmsgs, children, msg, puid)
 		if amsg != nil && err == nil {
 			// Log Response
 			if node.Logger != nil && (node.Logger.Mode == v1.LogResponse || node.Logger.Mode == v1.LogAll) {
@@ -354,23 +488,36 @@ func (p *PredictorProcess) predictChildren(node *v1.PredictiveUnit, msg payload.
 }
 
 func (p *PredictorProcess) feedbackChildren(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	node = withoutShadow(node)
 	if node.Children != nil && len(node.Children) > 0 {
 
-		route, err := p.routeFeedback(node, msg)
+		routes, err := p.routeFeedback(node, msg)
+		if err == nil {
+			routes, err = validateRoutes(node, routes)
+		}
 		if err != nil {
 			return nil, err
 		}
 		var cmsgs []payload.SeldonPayload
-		if route == -1 {
-			cmsgs = make([]payload.SeldonPayload, len(node.Children))
-			var errs = make([]error, len(node.Children))
+		if routes[0] == routeToNoChildren {
+			return msg, nil
+		} else if routes[0] == routeToAllChildren || len(routes) > 1 {
+			selected := routes
+			if routes[0] == routeToAllChildren {
+				selected = make([]int, len(node.Children))
+				for i := range node.Children {
+					selected[i] = i
+				}
+			}
+			cmsgs = make([]payload.SeldonPayload, len(selected))
+			var errs = make([]error, len(selected))
 			wg := sync.WaitGroup{}
-			for i, nodeChild := range node.Children {
+			for i, child := range selected {
 				wg.Add(1)
 				go func(i int, nodeChild v1.PredictiveUnit, msg payload.SeldonPayload) {
 					cmsgs[i], errs[i] = p.Feedback(&nodeChild, msg)
 					wg.Done()
-				}(i, nodeChild, msg)
+				}(i, node.Children[child], msg)
 			}
 			wg.Wait()
 			for i, err := range errs {
@@ -380,7 +527,7 @@ func (p *PredictorProcess) feedbackChildren(node *v1.PredictiveUnit, msg payload
 			}
 		} else {
 			cmsgs = make([]payload.SeldonPayload, 1)
-			cmsgs[0], err = p.Feedback(&node.Children[route], msg)
+			cmsgs[0], err = p.Feedback(&node.Children[routes[0]], msg)
 			if err != nil {
 				return cmsgs[0], err
 			}
@@ -406,9 +553,17 @@ func (p *PredictorProcess) logPayload(nodeName string, logger *v1.Logger, reqTyp
 		p.Log.Info("Skipped logging request with", "PUID", puid)
 		return nil
 	}
+	if !p.shouldLog(logger, puid) {
+		return nil
+	}
 
-	data, err := msg.GetBytes()
+	data, contentType, contentEncoding, err := logBytes(logger, msg)
 	if err != nil {
+		if len(logger.Redact) > 0 {
+			// Never log a payload which may not be redacted but don't fail the request either
+			p.Log.Error(err, "Failed to redact payload, not logging it", "PUID", puid)
+			return nil
+		}
 		return err
 	}
 	logUrl, err := p.getLogUrl(logger)
@@ -419,13 +574,14 @@ func (p *PredictorProcess) logPayload(nodeName string, logger *v1.Logger, reqTyp
 		err := payloadLogger.QueueLogRequest(payloadLogger.LogRequest{
 			Url:             logUrl,
 			Bytes:           &data,
-			ContentType:     msg.GetContentType(),
-			ContentEncoding: msg.GetContentEncoding(),
+			ContentType:     contentType,
+			ContentEncoding: contentEncoding,
 			ReqType:         reqType,
 			Id:              guuid.New().String(),
 			SourceUri:       p.ServerUrl,
 			ModelId:         nodeName,
 			RequestId:       puid,
+			SpanContext:     trace.SpanContextFromContext(p.Ctx),
 		})
 		if err != nil {
 			p.Log.Error(err, "failed to log request")
@@ -442,24 +598,62 @@ func (p *PredictorProcess) getPUIDHeader() (string, error) {
 	return "", fmt.Errorf(NilPUIDError)
 }
 
-func (p *PredictorProcess) Predict(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+func (p *PredictorProcess) Predict(node *v1.PredictiveUnit, msg payload.SeldonPayload) (response payload.SeldonPayload, err error) {
+	p, span := p.startSpan(node.Name, node, msg)
+	defer func() { endSpan(span, response, err) }()
+	if p.Ctx.Err() == context.DeadlineExceeded {
+		// Don't call the node if the request has already run out of time
+		err = &client.DeadlineExceededError{Node: node.Name}
+	} else if node.Cache != nil && p.Version.cacheable(node) {
+		response, err = p.cachedPredict(node, msg)
+	} else {
+		response, err = p.predict(node, msg)
+	}
+	if err != nil && p.Ctx.Err() == context.DeadlineExceeded {
+		// Report the deepest node which ran out of time
+		if _, ok := err.(*client.DeadlineExceededError); !ok {
+			err = &client.DeadlineExceededError{Node: node.Name}
+		}
+		response = p.Client.CreateErrorPayload(err)
+	}
+	if err != nil && hasDefaultResponse(node) {
+		p.Log.Info("Returning default response", "node", node.Name, "error", err.Error())
+		tracing.RecordError(span, err)
+		return p.defaultResponse(node, msg)
+	}
+	return response, err
+}
+
+func (p *PredictorProcess) predict(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
 	puid, err := p.getPUIDHeader()
 	if err != nil {
 		return nil, err
 	}
 
-	tmsg, err := p.transformInput(node, msg, puid)
+	mirrored := shouldMirror(node)
+	if mirrored {
+		p.mirror(node, msg, puid)
+	}
+	primary := withoutShadow(node)
+
+	tmsg, err := p.transformInput(primary, msg, puid)
 	if err != nil {
 		return tmsg, err
 	}
-	cmsg, err := p.predictChildren(node, tmsg, puid)
+	cmsg, err := p.predictChildren(primary, tmsg, puid)
 	if err != nil {
 		return cmsg, err
 	}
 
-	response, err := p.transformOutput(node, cmsg, puid)
+	response, err := p.transformOutput(primary, cmsg, puid)
+	if mirrored && err == nil {
+		if err := p.logMirrored(node, node, response, puid); err != nil {
+			p.Log.Error(err, "Failed to log response of mirrored node", "node", node.Name)
+		}
+	}
 
-	if envEnableRoutingInjection {
+	// Bandit routers need the route returned to the caller to learn from its feedback
+	if envEnableRoutingInjection || isBanditRouter(node) {
 		if routeResponse, err := util.InsertRouteToSeldonPredictPayload(response, &p.Routing); err == nil {
 			return routeResponse, err
 		}
@@ -471,7 +665,7 @@ func (p *PredictorProcess) Status(node *v1.PredictiveUnit, modelName string, msg
 	if nodeModel := v1.GetPredictiveUnit(node, modelName); nodeModel == nil {
 		return nil, fmt.Errorf("Failed to find model %s", modelName)
 	} else {
-		return p.Client.Status(p.Ctx, modelName, nodeModel.Endpoint.ServiceHost, p.getPort(nodeModel), msg, p.Meta.Meta)
+		return p.nodeClient(nodeModel).Status(p.Ctx, modelName, nodeModel.Endpoint.ServiceHost, p.getPort(nodeModel), msg, p.Meta.Meta)
 	}
 }
 
@@ -479,7 +673,7 @@ func (p *PredictorProcess) Metadata(node *v1.PredictiveUnit, modelName string, m
 	if nodeModel := v1.GetPredictiveUnit(node, modelName); nodeModel == nil {
 		return nil, fmt.Errorf("Failed to find model %s", modelName)
 	} else {
-		return p.Client.Metadata(p.Ctx, modelName, nodeModel.Endpoint.ServiceHost, p.getPort(nodeModel), msg, p.Meta.Meta)
+		return p.nodeClient(nodeModel).Metadata(p.Ctx, modelName, nodeModel.Endpoint.ServiceHost, p.getPort(nodeModel), msg, p.Meta.Meta)
 	}
 }
 
@@ -523,7 +717,7 @@ func (p *PredictorProcess) Feedback(node *v1.PredictiveUnit, msg payload.SeldonP
 }
 
 func (p *PredictorProcess) ModelMetadataMap(node *v1.PredictiveUnit) (map[string]payload.ModelMetadata, error) {
-	resPayload, err := p.Client.ModelMetadata(p.Ctx, node.Name, node.Endpoint.ServiceHost, p.getPort(node), nil, p.Meta.Meta)
+	resPayload, err := p.nodeClient(node).ModelMetadata(p.Ctx, node.Name, node.Endpoint.ServiceHost, p.getPort(node), nil, p.Meta.Meta)
 	if err != nil {
 		return nil, err
 	}
@@ -8,16 +8,21 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/jsonpb"
 	. "github.com/onsi/gomega"
 	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/grpc"
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/api/test"
 	"github.com/seldonio/seldon-core/executor/logger"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
@@ -114,6 +119,43 @@ func createFeedbackPayload(g *GomegaWithT) payload.SeldonPayload {
 	return &payload.ProtoPayload{Msg: &sm}
 }
 
+// createModelNode returns a model called over REST at foo:9000.
+func createModelNode(name string) *v1.PredictiveUnit {
+	model := v1.MODEL
+	return &v1.PredictiveUnit{
+		Name: name,
+		Type: &model,
+		Endpoint: &v1.Endpoint{
+			ServiceHost: "foo",
+			ServicePort: 9000,
+			Type:        v1.REST,
+		},
+	}
+}
+
+// createBuiltinGraph returns a node implemented by the executor with three models as children, model0 to model2
+// called at foo0 to foo2.
+func createBuiltinGraph(name string, implementation v1.PredictiveUnitImplementation, params []v1.Parameter) *v1.PredictiveUnit {
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:           name,
+		Implementation: &implementation,
+		Parameters:     params,
+	}
+	for i := 0; i < 3; i++ {
+		graph.Children = append(graph.Children, v1.PredictiveUnit{
+			Name: fmt.Sprintf("model%d", i),
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: fmt.Sprintf("foo%d", i),
+				ServicePort: 9000,
+				Type:        v1.REST,
+			},
+		})
+	}
+	return graph
+}
+
 func TestModel(t *testing.T) {
 	t.Logf("Started")
 	g := NewGomegaWithT(t)
@@ -696,3 +738,239 @@ func TestModelWithLogRequestsForRouter(t *testing.T) {
 	g.Eventually(func() bool { return logged }).Should(Equal(true))
 	g.Expect(logMessagesReceived).To(Equal(2))
 }
+
+func createPredictorProcessWithHostError(host string, chosenRoute int) *PredictorProcess {
+	url, _ := url.Parse(testSourceUrl)
+	ctx := context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid)
+	errMethod := v1.TRANSFORM_INPUT
+	pp := NewPredictorProcess(ctx, &test.SeldonMessageTestClient{ErrMethod: &errMethod, Err: errors.New("model down"), ErrHost: host, ChosenRoute: chosenRoute}, logf.Log.WithName("SeldonMessageRestClient"), url, "default", map[string][]string{}, "")
+	return &pp
+}
+
+func createFallbackGraph(unitType v1.PredictiveUnitType, fallback *v1.FallbackPolicy) *v1.PredictiveUnit {
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "parent",
+		Type:     &unitType,
+		Fallback: fallback,
+		Endpoint: &v1.Endpoint{
+			ServiceHost: "foo",
+			ServicePort: 9000,
+			Type:        v1.REST,
+		},
+	}
+	for i, host := range []string{"foo1", "foo2", "foo3"} {
+		graph.Children = append(graph.Children, v1.PredictiveUnit{
+			Name: fmt.Sprintf("model%d", i),
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: host,
+				ServicePort: 9001,
+				Type:        v1.REST,
+			},
+		})
+	}
+	return graph
+}
+
+func TestCombinerQuorum(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createFallbackGraph(v1.COMBINER, nil)
+	_, err := createPredictorProcessWithHostError("foo1", 0).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+
+	graph = createFallbackGraph(v1.COMBINER, &v1.FallbackPolicy{MinSuccessfulChildren: 2})
+	pResp, err := createPredictorProcessWithHostError("foo1", 0).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	smRes := pResp.GetPayload().(*proto.SeldonMessage)
+	g.Expect(smRes.GetData().GetNdarray().Values[0].GetNumberValue()).Should(Equal(1.1))
+
+	graph = createFallbackGraph(v1.COMBINER, &v1.FallbackPolicy{MinSuccessfulChildren: 3})
+	_, err = createPredictorProcessWithHostError("foo1", 0).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestRouterFallbackChild(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createFallbackGraph(v1.ROUTER, nil)
+	_, err := createPredictorProcessWithHostError("foo1", 0).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+
+	graph = createFallbackGraph(v1.ROUTER, &v1.FallbackPolicy{FallbackChild: "model2"})
+	pp := createPredictorProcessWithHostError("foo1", 0)
+	pResp, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	smRes := pResp.GetPayload().(*proto.SeldonMessage)
+	g.Expect(smRes.GetData().GetNdarray().Values[0].GetNumberValue()).Should(Equal(1.1))
+	g.Expect(pp.Routing["parent"]).Should(Equal(int32(2)))
+
+	// Fallback child failing too returns the error
+	_, err = createPredictorProcessWithHostError("foo3", 2).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestDefaultResponse(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createFallbackGraph(v1.ROUTER, &v1.FallbackPolicy{DefaultResponse: `{"data":{"ndarray":[0]}}`})
+	pResp, err := createPredictorProcessWithHostError("foo1", 0).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	smRes := pResp.GetPayload().(*proto.SeldonMessage)
+	g.Expect(smRes.GetData().GetNdarray().Values).Should(HaveLen(1))
+	g.Expect(smRes.GetData().GetNdarray().Values[0].GetNumberValue()).Should(Equal(0.0))
+
+	// Successful calls are unaffected
+	pResp, err = createPredictorProcessWithHostError("foo3", 0).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	smRes = pResp.GetPayload().(*proto.SeldonMessage)
+	g.Expect(smRes.GetData().GetNdarray().Values[0].GetNumberValue()).Should(Equal(1.1))
+}
+
+func TestPredictDeadlineExceeded(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createFallbackGraph(v1.COMBINER, nil)
+	url, _ := url.Parse(testSourceUrl)
+	ctx, cancel := context.WithDeadline(context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid), time.Now())
+	defer cancel()
+	pp := NewPredictorProcess(ctx, &test.SeldonMessageTestClient{}, logf.Log.WithName("SeldonMessageRestClient"), url, "default", map[string][]string{}, "")
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).To(Equal(&client.DeadlineExceededError{Node: "parent"}))
+}
+
+func createPredictorProcessWithRoutes(t *testing.T, errMethod v1.PredictiveUnitMethod, errHost string, chosenRoutes []int) *PredictorProcess {
+	url, _ := url.Parse(testSourceUrl)
+	ctx := context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid)
+	pp := NewPredictorProcess(ctx, &test.SeldonMessageTestClient{ErrMethod: &errMethod, Err: errors.New("model down"), ErrHost: errHost, ChosenRoutes: chosenRoutes}, logf.Log.WithName("SeldonMessageRestClient"), url, "default", map[string][]string{}, "")
+	return &pp
+}
+
+func TestRouterMultiRoute(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Only the chosen children are called so the failing one is never reached
+	graph := createFallbackGraph(v1.ROUTER, nil)
+	pp := createPredictorProcessWithRoutes(t, v1.TRANSFORM_INPUT, "foo2", []int{0, 2})
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(pp.Routing).Should(HaveKeyWithValue("parent", int32(0)))
+	g.Expect(pp.Routing).Should(HaveKeyWithValue("parent[1]", int32(2)))
+
+	_, err = createPredictorProcessWithRoutes(t, v1.TRANSFORM_INPUT, "foo2", []int{0, 1}).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+
+	// Duplicate routes from a batch are only called once
+	pp = createPredictorProcessWithRoutes(t, v1.TRANSFORM_INPUT, "foo2", []int{2, 2})
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(pp.Routing).Should(HaveKeyWithValue("parent", int32(2)))
+	g.Expect(pp.Routing).ShouldNot(HaveKey("parent[1]"))
+
+	_, err = createPredictorProcessWithRoutes(t, v1.TRANSFORM_INPUT, "foo2", []int{0, 3}).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestRouterMultiRouteFeedback(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createFallbackGraph(v1.ROUTER, nil)
+	createFeedback := func(routing string) payload.SeldonPayload {
+		var fb proto.Feedback
+		err := jsonpb.UnmarshalString(`{"request":{"data":{"ndarray":[1.1]}},"response":{"meta":{"routing":`+routing+`}},"reward":1}`, &fb)
+		g.Expect(err).Should(BeNil())
+		return &payload.ProtoPayload{Msg: &fb}
+	}
+
+	_, err := createPredictorProcessWithRoutes(t, v1.SEND_FEEDBACK, "foo2", nil).Feedback(graph, createFeedback(`{"parent":0,"parent[1]":2}`))
+	g.Expect(err).Should(BeNil())
+
+	_, err = createPredictorProcessWithRoutes(t, v1.SEND_FEEDBACK, "foo3", nil).Feedback(graph, createFeedback(`{"parent":0,"parent[1]":2}`))
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func spanAttribute(span *tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestNodeSpans(t *testing.T) {
+	g := NewGomegaWithT(t)
+	exporter, cleanup := test.SetupTestTracing()
+	defer cleanup()
+
+	graph := createFallbackGraph(v1.ROUTER, nil)
+	_, err := createPredictorProcessWithHostError("foo1", 1).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+
+	spans := exporter.GetSpans()
+	parent := findSpan(spans, "parent")
+	g.Expect(parent).ShouldNot(BeNil())
+	g.Expect(spanAttribute(parent, AttributeNodeName).AsString()).Should(Equal("parent"))
+	g.Expect(spanAttribute(parent, AttributeNodeType).AsString()).Should(Equal(string(v1.ROUTER)))
+	g.Expect(spanAttribute(parent, AttributeNodeRoutes).AsInt64Slice()).Should(Equal([]int64{1}))
+	g.Expect(spanAttribute(parent, AttributeRequestSize).AsInt64()).Should(BeNumerically(">", 0))
+	g.Expect(spanAttribute(parent, AttributeResponseSize).AsInt64()).Should(BeNumerically(">", 0))
+
+	route := findSpan(spans, SpanRoute)
+	g.Expect(route).ShouldNot(BeNil())
+	g.Expect(route.Parent.SpanID()).Should(Equal(parent.SpanContext.SpanID()))
+	g.Expect(spanAttribute(route, AttributeNodeRoutes).AsInt64Slice()).Should(Equal([]int64{1}))
+
+	children := findSpan(spans, SpanChildren)
+	g.Expect(children).ShouldNot(BeNil())
+	g.Expect(children.Parent.SpanID()).Should(Equal(parent.SpanContext.SpanID()))
+
+	model := findSpan(spans, "model1")
+	g.Expect(model).ShouldNot(BeNil())
+	g.Expect(model.Parent.SpanID()).Should(Equal(children.SpanContext.SpanID()))
+	g.Expect(spanAttribute(model, AttributeNodeType).AsString()).Should(Equal(string(v1.MODEL)))
+
+	transformInput := findSpan(spans, SpanTransformInput)
+	g.Expect(transformInput).ShouldNot(BeNil())
+	g.Expect(transformInput.Parent.SpanID()).Should(Equal(model.SpanContext.SpanID()))
+	g.Expect(findSpan(spans, SpanAggregate)).Should(BeNil())
+	g.Expect(findSpan(spans, SpanTransformOutput)).Should(BeNil())
+}
+
+func TestNodeSpansRecordErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+	exporter, cleanup := test.SetupTestTracing()
+	defer cleanup()
+
+	graph := createFallbackGraph(v1.COMBINER, nil)
+	_, err := createPredictorProcessWithHostError("foo1", 0).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+
+	spans := exporter.GetSpans()
+	g.Expect(findSpan(spans, "model0").Status.Code).Should(Equal(codes.Error))
+	g.Expect(findSpan(spans, "model1").Status.Code).Should(Equal(codes.Unset))
+	g.Expect(findSpan(spans, SpanChildren).Status.Code).Should(Equal(codes.Error))
+	g.Expect(findSpan(spans, "parent").Status.Code).Should(Equal(codes.Error))
+	g.Expect(findSpan(spans, SpanAggregate)).Should(BeNil())
+
+	exporter.Reset()
+	graph = createFallbackGraph(v1.COMBINER, &v1.FallbackPolicy{MinSuccessfulChildren: 2})
+	_, err = createPredictorProcessWithHostError("foo1", 0).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+
+	spans = exporter.GetSpans()
+	g.Expect(findSpan(spans, "model0").Status.Code).Should(Equal(codes.Error))
+	g.Expect(findSpan(spans, "parent").Status.Code).Should(Equal(codes.Unset))
+	g.Expect(spanAttribute(findSpan(spans, "parent"), AttributeNodeRoutes).AsInt64Slice()).Should(Equal([]int64{-1}))
+	g.Expect(findSpan(spans, SpanAggregate)).ShouldNot(BeNil())
+}
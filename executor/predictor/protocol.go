@@ -0,0 +1,67 @@
+package predictor
+
+import (
+	"sync"
+
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/converter"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// NodeProtocols holds the clients for nodes which speak another protocol than the executor. The protocol of nodes
+// is ignored unless it's set at startup.
+var NodeProtocols *NodeProtocolClients
+
+type nodeProtocolKey struct {
+	protocol string
+	grpc     bool
+}
+
+// NodeProtocolClients creates a client per protocol of the nodes, which converts messages between the protocol of
+// the node and the executor.
+type NodeProtocolClients struct {
+	protocol  string
+	newClient func(protocol string, grpc bool) client.SeldonApiClient
+	clients   sync.Map
+}
+
+// NewNodeProtocolClients returns the clients for an executor with the given protocol, calling newClient to create
+// the REST or gRPC client of a protocol.
+func NewNodeProtocolClients(protocol string, newClient func(protocol string, grpc bool) client.SeldonApiClient) *NodeProtocolClients {
+	return &NodeProtocolClients{protocol: protocol, newClient: newClient}
+}
+
+// Client returns the client for a node with the given protocol, or nil if it's the protocol of the executor.
+func (c *NodeProtocolClients) Client(protocol string, grpc bool) client.SeldonApiClient {
+	if protocol == "" || sameProtocol(protocol, c.protocol) {
+		return nil
+	}
+	key := nodeProtocolKey{protocol: protocol, grpc: grpc}
+	if nodeClient, ok := c.clients.Load(key); ok {
+		return nodeClient.(client.SeldonApiClient)
+	}
+	nodeClient, _ := c.clients.LoadOrStore(key, converter.NewProtocolClient(c.newClient(protocol, grpc), protocol, c.protocol))
+	return nodeClient.(client.SeldonApiClient)
+}
+
+// sameProtocol compares protocols, treating the KFServing protocol as the V2 protocol it was renamed to.
+func sameProtocol(a string, b string) bool {
+	if a == api.ProtocolKFServing {
+		a = api.ProtocolV2
+	}
+	if b == api.ProtocolKFServing {
+		b = api.ProtocolV2
+	}
+	return a == b
+}
+
+// nodeClient returns the client for calls to a node, converting messages if the node has its own protocol.
+func (p *PredictorProcess) nodeClient(node *v1.PredictiveUnit) client.SeldonApiClient {
+	if NodeProtocols != nil {
+		if nodeClient := NodeProtocols.Client(string(node.Protocol), p.Client.IsGrpc()); nodeClient != nil {
+			return nodeClient
+		}
+	}
+	return p.Client
+}
@@ -0,0 +1,100 @@
+package predictor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// v2TestClient answers V2 requests with their FP64 inputs doubled.
+type v2TestClient struct {
+	test.SeldonMessageTestClient
+}
+
+func (c v2TestClient) Predict(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	req, ok := msg.GetPayload().(*inference.ModelInferRequest)
+	if !ok {
+		return nil, fmt.Errorf("expected a V2 request not %T", msg.GetPayload())
+	}
+	res := &inference.ModelInferResponse{ModelName: modelName}
+	for _, input := range req.GetInputs() {
+		contents := &inference.InferTensorContents{}
+		for _, v := range input.GetContents().GetFp64Contents() {
+			contents.Fp64Contents = append(contents.Fp64Contents, 2*v)
+		}
+		res.Outputs = append(res.Outputs, &inference.ModelInferResponse_InferOutputTensor{
+			Name:     "output",
+			Datatype: input.GetDatatype(),
+			Shape:    input.GetShape(),
+			Contents: contents,
+		})
+	}
+	return &payload.ProtoPayload{Msg: res}, nil
+}
+
+func TestNodeProtocol(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	NodeProtocols = NewNodeProtocolClients(api.ProtocolSeldon, func(protocol string, grpc bool) client.SeldonApiClient {
+		return v2TestClient{}
+	})
+	defer func() { NodeProtocols = nil }()
+
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name: "transformer",
+		Type: &model,
+		Endpoint: &v1.Endpoint{
+			ServiceHost: "foo",
+			ServicePort: 9000,
+			Type:        v1.GRPC,
+		},
+		Children: []v1.PredictiveUnit{
+			{
+				Name:     "v2-model",
+				Type:     &model,
+				Protocol: v1.ProtocolV2,
+				Endpoint: &v1.Endpoint{
+					ServiceHost: "bar",
+					ServicePort: 9000,
+					Type:        v1.GRPC,
+				},
+			},
+		},
+	}
+	sm := &proto.SeldonMessage{
+		DataOneof: &proto.SeldonMessage_Data{Data: &proto.DefaultData{
+			DataOneof: &proto.DefaultData_Tensor{Tensor: &proto.Tensor{Shape: []int32{1, 2}, Values: []float64{1, 2}}},
+		}},
+	}
+
+	response, err := createPredictorProcess(t).Predict(graph, &payload.ProtoPayload{Msg: sm})
+	g.Expect(err).To(BeNil())
+	res, ok := response.GetPayload().(*proto.SeldonMessage)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(res.GetData().GetTensor().GetShape()).To(Equal([]int32{1, 2}))
+	g.Expect(res.GetData().GetTensor().GetValues()).To(Equal([]float64{2, 4}))
+}
+
+func TestNodeProtocolClient(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	clients := NewNodeProtocolClients(api.ProtocolKFServing, func(protocol string, grpc bool) client.SeldonApiClient {
+		return test.SeldonMessageTestClient{}
+	})
+	g.Expect(clients.Client("", true)).To(BeNil())
+	g.Expect(clients.Client(api.ProtocolV2, true)).To(BeNil())
+	seldonClient := clients.Client(api.ProtocolSeldon, true)
+	g.Expect(seldonClient).ToNot(BeNil())
+	g.Expect(clients.Client(api.ProtocolSeldon, true)).To(BeIdenticalTo(seldonClient))
+	g.Expect(clients.Client(api.ProtocolSeldon, false)).ToNot(BeIdenticalTo(seldonClient))
+}
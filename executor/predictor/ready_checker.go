@@ -1,74 +1,238 @@
 package predictor
 
 import (
+	"context"
 	"fmt"
-	"github.com/seldonio/seldon-core/executor/api"
-	"github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	"net"
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/client"
+	grpc2 "github.com/seldonio/seldon-core/executor/api/grpc"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/proto/tensorflow/serving"
+	"github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Time allowed to check a single node
+const readyTimeout = 2 * time.Second
+
+// ReadyCacheTTL is how long the result of checking a node is reused, so frequent probes of the executor don't
+// check every model each time.
+var ReadyCacheTTL = 2 * time.Second
+
+type readyResult struct {
+	err     error
+	expires time.Time
+}
+
+var (
+	readyCacheMu sync.Mutex
+	// Results of node checks, keyed by check and address
+	readyCache = make(map[string]readyResult)
 )
 
+type readyCheck struct {
+	// Identifies the check of the node for caching
+	key   string
+	check func(ctx context.Context) error
+}
+
+// Ready checks all nodes of the graph with an endpoint, in parallel, and returns the error of the first node in the
+// graph which isn't ready. Without full health checks nodes are ready if their port accepts connections. Otherwise,
+// the health API of the protocol of the node is called, over gRPC for nodes with a gRPC endpoint.
 func Ready(protocol string, node *v1.PredictiveUnit, fullHealthCheck bool) error {
-	if !fullHealthCheck {
-		return ReadyTCP(node)
+	var checks []readyCheck
+	for _, n := range v1.GetPredictiveUnitList(node) {
+		if n.Endpoint == nil || n.Endpoint.ServiceHost == "" || n.Endpoint.ServicePort <= 0 {
+			continue
+		}
+		if !fullHealthCheck {
+			checks = append(checks, tcpCheck(n))
+			continue
+		}
+		nodeProtocol := protocol
+		if n.Protocol != "" {
+			nodeProtocol = string(n.Protocol)
+		}
+		check, err := healthCheck(nodeProtocol, n)
+		if err != nil {
+			return err
+		}
+		checks = append(checks, check)
 	}
+	return runReadyChecks(checks)
+}
+
+func healthCheck(protocol string, node *v1.PredictiveUnit) (readyCheck, error) {
+	grpcEndpoint := node.Endpoint.Type == v1.GRPC
 	switch protocol {
 	case api.ProtocolSeldon:
-		return ReadyHealth(node, "/api/v1.0/health/status")
+		if grpcEndpoint {
+			return grpcHealthCheck(node), nil
+		}
+		return httpCheck(node, "/api/v1.0/health/status"), nil
 	case api.ProtocolTensorflow:
-		return ReadyTCP(node)
+		if grpcEndpoint {
+			return tensorflowStatusCheck(node), nil
+		}
+		return httpCheck(node, "/v1/models/"+node.Name), nil
 	case api.ProtocolV2, api.ProtocolKFServing:
-		return ReadyHealth(node, "/v2/health/ready")
+		if grpcEndpoint {
+			return v2ModelReadyCheck(node), nil
+		}
+		return httpCheck(node, "/v2/health/ready"), nil
 	default:
-		return fmt.Errorf("Unknown protocol for health check: %s", protocol)
+		return readyCheck{}, fmt.Errorf("Unknown protocol for health check: %s", protocol)
 	}
 }
 
-func ReadyTCP(node *v1.PredictiveUnit) error {
-	for _, child := range node.Children {
-		err := ReadyTCP(&child)
+func runReadyChecks(checks []readyCheck) error {
+	errs := make([]error, len(checks))
+	wg := sync.WaitGroup{}
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check readyCheck) {
+			defer wg.Done()
+			errs[i] = cachedReadyCheck(check)
+		}(i, check)
+	}
+	wg.Wait()
+	for _, err := range errs {
 		if err != nil {
 			return err
 		}
 	}
-	if node.Endpoint != nil && node.Endpoint.ServiceHost != "" && node.Endpoint.ServicePort > 0 {
-		c, err := net.Dial("tcp", fmt.Sprintf("%s:%d", node.Endpoint.ServiceHost, node.Endpoint.ServicePort))
+	return nil
+}
+
+func cachedReadyCheck(check readyCheck) error {
+	readyCacheMu.Lock()
+	result, ok := readyCache[check.key]
+	readyCacheMu.Unlock()
+	if ok && time.Now().Before(result.expires) {
+		return result.err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+	defer cancel()
+	err := check.check(ctx)
+	readyCacheMu.Lock()
+	readyCache[check.key] = readyResult{err: err, expires: time.Now().Add(ReadyCacheTTL)}
+	readyCacheMu.Unlock()
+	return err
+}
+
+func serviceAddress(node *v1.PredictiveUnit) string {
+	return net.JoinHostPort(node.Endpoint.ServiceHost, strconv.Itoa(int(node.Endpoint.ServicePort)))
+}
+
+func grpcAddress(node *v1.PredictiveUnit) string {
+	port := node.Endpoint.GrpcPort
+	if port <= 0 {
+		port = node.Endpoint.ServicePort
+	}
+	return net.JoinHostPort(node.Endpoint.ServiceHost, strconv.Itoa(int(port)))
+}
+
+func tcpCheck(node *v1.PredictiveUnit) readyCheck {
+	address := serviceAddress(node)
+	return readyCheck{
+		key: "tcp/" + address,
+		check: func(ctx context.Context) error {
+			c, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+			if err != nil {
+				return err
+			}
+			return c.Close()
+		},
+	}
+}
+
+func httpCheck(node *v1.PredictiveUnit, healthPath string) readyCheck {
+	urlHealth := &url.URL{
+		Scheme: client.HttpScheme(),
+		Host:   serviceAddress(node),
+		Path:   healthPath,
+	}
+	return readyCheck{
+		key: "http/" + urlHealth.String(),
+		check: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlHealth.String(), nil)
+			if err != nil {
+				return err
+			}
+			res, err := (&http.Client{Transport: client.HttpTransport()}).Do(req)
+			if err != nil {
+				return err
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				return fmt.Errorf("Bad status %d from %s", res.StatusCode, urlHealth.Host)
+			}
+			return nil
+		},
+	}
+}
+
+// grpcCheck calls the node with a new connection, so checks don't depend on the state of the clients' connections.
+func grpcCheck(key string, address string, call func(ctx context.Context, conn *grpc.ClientConn) error) readyCheck {
+	return readyCheck{
+		key: key + "/" + address,
+		check: func(ctx context.Context) error {
+			conn, err := grpc.DialContext(ctx, address, grpc2.ClientTransportCredentials())
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			return call(ctx, conn)
+		},
+	}
+}
+
+func grpcHealthCheck(node *v1.PredictiveUnit) readyCheck {
+	return grpcCheck("grpc-health", grpcAddress(node), func(ctx context.Context, conn *grpc.ClientConn) error {
+		res, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
 		if err != nil {
 			return err
-		} else {
-			err = c.Close()
-			return nil
 		}
-	} else {
+		if res.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+			return fmt.Errorf("Node %s is %s", node.Name, res.Status)
+		}
 		return nil
-	}
+	})
 }
 
-func ReadyHealth(node *v1.PredictiveUnit, healthPath string) error {
-	for _, child := range node.Children {
-		err := ReadyHealth(&child, healthPath)
+func tensorflowStatusCheck(node *v1.PredictiveUnit) readyCheck {
+	return grpcCheck("tensorflow-status/"+node.Name, grpcAddress(node), func(ctx context.Context, conn *grpc.ClientConn) error {
+		req := &serving.GetModelStatusRequest{ModelSpec: &serving.ModelSpec{Name: node.Name}}
+		res, err := serving.NewModelServiceClient(conn).GetModelStatus(ctx, req)
 		if err != nil {
 			return err
 		}
-	}
-	if node.Endpoint != nil && node.Endpoint.ServiceHost != "" && node.Endpoint.ServicePort > 0 {
-		urlHealth := &url.URL{
-			Scheme: "http",
-			Host:   net.JoinHostPort(node.Endpoint.ServiceHost, strconv.Itoa(int(node.Endpoint.ServicePort))),
-			Path:   healthPath,
+		for _, status := range res.ModelVersionStatus {
+			if status.State == serving.ModelVersionStatus_AVAILABLE {
+				return nil
+			}
 		}
-		res, err := http.Get(urlHealth.String())
+		return fmt.Errorf("Model %s has no available version", node.Name)
+	})
+}
+
+func v2ModelReadyCheck(node *v1.PredictiveUnit) readyCheck {
+	return grpcCheck("v2-model-ready/"+node.Name, grpcAddress(node), func(ctx context.Context, conn *grpc.ClientConn) error {
+		res, err := inference.NewGRPCInferenceServiceClient(conn).ModelReady(ctx, &inference.ModelReadyRequest{Name: node.Name})
 		if err != nil {
 			return err
-		} else {
-			if res.StatusCode != http.StatusOK {
-				return fmt.Errorf("Bad status from %s:%d", node.Endpoint.ServiceHost, node.Endpoint.ServicePort)
-			}
-			return nil
 		}
-	} else {
+		if !res.Ready {
+			return fmt.Errorf("Model %s is not ready", node.Name)
+		}
 		return nil
-	}
+	})
 }
@@ -0,0 +1,87 @@
+package predictor
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func createReadyTestNode(name string, address string, endpointType v1.EndpointType) v1.PredictiveUnit {
+	host, portStr, _ := net.SplitHostPort(address)
+	port, _ := strconv.Atoi(portStr)
+	return v1.PredictiveUnit{
+		Name:     name,
+		Endpoint: &v1.Endpoint{ServiceHost: host, ServicePort: int32(port), Type: endpointType},
+	}
+}
+
+func TestReadyChecksInParallel(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Each node only answers once both have been called
+	var calls int32
+	both := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 2 {
+			close(both)
+		}
+		select {
+		case <-both:
+			w.WriteHeader(http.StatusOK)
+		case <-time.After(time.Second):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	server1 := httptest.NewServer(handler)
+	defer server1.Close()
+	server2 := httptest.NewServer(handler)
+	defer server2.Close()
+	url1, _ := url.Parse(server1.URL)
+	url2, _ := url.Parse(server2.URL)
+
+	graph := createReadyTestNode("a", url1.Host, v1.REST)
+	graph.Children = []v1.PredictiveUnit{createReadyTestNode("b", url2.Host, v1.REST)}
+	g.Expect(Ready(api.ProtocolSeldon, &graph, true)).Should(BeNil())
+	g.Expect(atomic.LoadInt32(&calls)).Should(Equal(int32(2)))
+
+	// Results are reused for probes soon after
+	g.Expect(Ready(api.ProtocolSeldon, &graph, true)).Should(BeNil())
+	g.Expect(atomic.LoadInt32(&calls)).Should(Equal(int32(2)))
+}
+
+func TestReadyGrpcHealth(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).Should(BeNil())
+	healthServer := health.NewServer()
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	ttl := ReadyCacheTTL
+	ReadyCacheTTL = 0
+	defer func() { ReadyCacheTTL = ttl }()
+
+	graph := createReadyTestNode("a", lis.Addr().String(), v1.GRPC)
+	g.Expect(Ready(api.ProtocolSeldon, &graph, true)).Should(BeNil())
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	g.Expect(Ready(api.ProtocolSeldon, &graph, true)).ShouldNot(BeNil())
+
+	// Without full health checks the port only needs to accept connections
+	g.Expect(Ready(api.ProtocolSeldon, &graph, false)).Should(BeNil())
+	g.Expect(Ready("unknown", &graph, true)).ShouldNot(BeNil())
+}
@@ -0,0 +1,121 @@
+package predictor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/k8s"
+)
+
+const redisTimeout = 500 * time.Millisecond
+
+type RedisCacheSettings struct {
+	// host:port of the server
+	Address string
+	// ACL user, the default user if empty
+	Username string
+	// File with the password, read again for new connections so a rotated secret is picked up
+	PasswordFile string
+	// Database selected after connecting
+	DB int
+	// Connect over TLS, verifying the server with the CA bundle or the system roots if it is empty
+	TLS       bool
+	TLSCAFile string
+}
+
+// RedisCacheSettingsFromAnnotations returns nil settings if no Redis server is configured.
+func RedisCacheSettingsFromAnnotations(annotations map[string]string) (*RedisCacheSettings, error) {
+	settings := &RedisCacheSettings{
+		Address:      annotations[k8s.ANNOTATION_CACHE_REDIS_ADDRESS],
+		Username:     annotations[k8s.ANNOTATION_CACHE_REDIS_USERNAME],
+		PasswordFile: annotations[k8s.ANNOTATION_CACHE_REDIS_PASSWORD_FILE],
+		TLSCAFile:    annotations[k8s.ANNOTATION_CACHE_REDIS_TLS_CA_FILE],
+	}
+	if settings.Address == "" {
+		return nil, nil
+	}
+	if db := annotations[k8s.ANNOTATION_CACHE_REDIS_DB]; db != "" {
+		var err error
+		if settings.DB, err = strconv.Atoi(db); err != nil || settings.DB < 0 {
+			return nil, fmt.Errorf("annotation %s must be a database number: %s", k8s.ANNOTATION_CACHE_REDIS_DB, db)
+		}
+	}
+	if val := annotations[k8s.ANNOTATION_CACHE_REDIS_TLS]; val != "" {
+		var err error
+		if settings.TLS, err = strconv.ParseBool(val); err != nil {
+			return nil, fmt.Errorf("annotation %s must be a boolean: %w", k8s.ANNOTATION_CACHE_REDIS_TLS, err)
+		}
+	}
+	settings.TLS = settings.TLS || settings.TLSCAFile != ""
+	return settings, nil
+}
+
+// RedisResponseCache stores responses in a Redis compatible server so they are shared by all replicas.
+// Entries expire in the server and eviction of the least recently used is left to its maxmemory policy.
+type RedisResponseCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisResponseCache creates a cache for the configured server. Keys are prefixed, e.g. with the deployment
+// and predictor name, so several deployments can share a server.
+func NewRedisResponseCache(settings RedisCacheSettings, prefix string) (*RedisResponseCache, error) {
+	opts := &redis.Options{
+		Addr:         settings.Address,
+		Username:     settings.Username,
+		DB:           settings.DB,
+		DialTimeout:  redisTimeout,
+		ReadTimeout:  redisTimeout,
+		WriteTimeout: redisTimeout,
+	}
+	if settings.PasswordFile != "" {
+		if _, err := readRedisPassword(settings.PasswordFile); err != nil {
+			return nil, err
+		}
+		opts.CredentialsProviderContext = func(ctx context.Context) (string, string, error) {
+			password, err := readRedisPassword(settings.PasswordFile)
+			return settings.Username, password, err
+		}
+	}
+	if settings.TLS {
+		tlsConfig, err := client.NewClientTLSConfig(client.ClientTLSSettings{CAFile: settings.TLSCAFile})
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig.Config()
+	}
+	return &RedisResponseCache{
+		client: redis.NewClient(opts),
+		prefix: prefix,
+	}, nil
+}
+
+func readRedisPassword(file string) (string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func (c *RedisResponseCache) Get(key string) ([]byte, bool, error) {
+	value, err := c.client.Get(context.Background(), c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *RedisResponseCache) Set(key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(context.Background(), c.prefix+key, value, ttl).Err()
+}
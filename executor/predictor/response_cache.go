@@ -0,0 +1,232 @@
+package predictor
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	protov1 "github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	anypb "github.com/golang/protobuf/ptypes/any"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+const (
+	defaultCacheTTLSeconds = 300
+	defaultCacheMaxEntries = 1000
+)
+
+// ResponseCache stores the serialized responses of graph nodes keyed on their request.
+type ResponseCache interface {
+	// Get returns the cached value or false if there is none or it has expired.
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+}
+
+// ResponseCacheBackend returns the cache for a node with a cache policy. Responses are kept in memory unless
+// replaced at startup.
+var ResponseCacheBackend = func(node *v1.PredictiveUnit) ResponseCache {
+	return NewInMemoryResponseCache(cacheMaxEntries(node))
+}
+
+var (
+	// Caches shared by all requests, keyed by nodeStateKey and freed once no loaded version uses them.
+	responseCaches sync.Map
+	cacheMetrics   *metric.CacheMetrics
+	cacheMetricsMu sync.Mutex
+)
+
+func cacheTTL(node *v1.PredictiveUnit) time.Duration {
+	if node.Cache.TTLSeconds > 0 {
+		return time.Duration(node.Cache.TTLSeconds) * time.Second
+	}
+	return defaultCacheTTLSeconds * time.Second
+}
+
+func cacheMaxEntries(node *v1.PredictiveUnit) int {
+	if node.Cache != nil && node.Cache.MaxEntries > 0 {
+		return int(node.Cache.MaxEntries)
+	}
+	return defaultCacheMaxEntries
+}
+
+func getResponseCache(key string, node *v1.PredictiveUnit) ResponseCache {
+	if cache, ok := responseCaches.Load(key); ok {
+		return cache.(ResponseCache)
+	}
+	cache, _ := responseCaches.LoadOrStore(key, ResponseCacheBackend(node))
+	return cache.(ResponseCache)
+}
+
+func getCacheMetrics() *metric.CacheMetrics {
+	cacheMetricsMu.Lock()
+	defer cacheMetricsMu.Unlock()
+	if cacheMetrics == nil {
+		cacheMetrics = metric.NewCacheMetrics()
+	}
+	return cacheMetrics
+}
+
+// cacheKey hashes the decompressed request together with the node and model names so nodes, and the models a
+// node is called with, never share entries.
+func cacheKey(node *v1.PredictiveUnit, modelName string, msg payload.SeldonPayload) (string, error) {
+	data, err := payload.DecompressSeldonPayload(msg)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(node.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(modelName))
+	h.Write([]byte{0})
+	h.Write([]byte(msg.GetContentType()))
+	h.Write([]byte{0})
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type cachedResponse struct {
+	ContentType     string `json:"contentType"`
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+	// Protobuf responses are stored as an Any so they can be decoded without knowing their type
+	Data []byte `json:"data"`
+}
+
+func encodeResponse(msg payload.SeldonPayload) ([]byte, error) {
+	cached := cachedResponse{ContentType: msg.GetContentType(), ContentEncoding: msg.GetContentEncoding()}
+	if pp, ok := msg.(*payload.ProtoPayload); ok {
+		packed, err := ptypes.MarshalAny(pp.Msg)
+		if err != nil {
+			return nil, err
+		}
+		if cached.Data, err = protov1.Marshal(packed); err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := msg.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+		cached.Data = data
+	}
+	return json.Marshal(cached)
+}
+
+func decodeResponse(data []byte) (payload.SeldonPayload, error) {
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	if cached.ContentType != payload.APPLICATION_TYPE_PROTOBUF {
+		return &payload.BytesPayload{Msg: cached.Data, ContentType: cached.ContentType, ContentEncoding: cached.ContentEncoding}, nil
+	}
+	var packed anypb.Any
+	if err := protov1.Unmarshal(cached.Data, &packed); err != nil {
+		return nil, err
+	}
+	var msg ptypes.DynamicAny
+	if err := ptypes.UnmarshalAny(&packed, &msg); err != nil {
+		return nil, err
+	}
+	return &payload.ProtoPayload{Msg: msg.Message}, nil
+}
+
+// cachedPredict returns the cached response of the node for the request if there is one and otherwise calls the
+// node and caches its response. Cache failures never fail the request.
+func (p *PredictorProcess) cachedPredict(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	if p.Meta.GetAsBoolean(payload.SeldonCacheBypassHeader, false) {
+		return p.predict(node, msg)
+	}
+	key, err := cacheKey(node, p.getModelName(node), msg)
+	if err != nil {
+		p.Log.Error(err, "Failed to create cache key", "node", node.Name)
+		return p.predict(node, msg)
+	}
+	cache := getResponseCache(p.Version.nodeStateKey(node), node)
+	if data, ok, err := cache.Get(key); err != nil {
+		p.Log.Error(err, "Failed to read response cache", "node", node.Name)
+	} else if ok {
+		if response, err := decodeResponse(data); err == nil {
+			getCacheMetrics().HitsCounter.WithLabelValues(node.Name).Inc()
+			return response, nil
+		} else {
+			p.Log.Error(err, "Failed to decode cached response", "node", node.Name)
+		}
+	}
+	getCacheMetrics().MissesCounter.WithLabelValues(node.Name).Inc()
+
+	response, err := p.predict(node, msg)
+	if err != nil {
+		return response, err
+	}
+	if data, err := encodeResponse(response); err != nil {
+		p.Log.Error(err, "Failed to encode response for cache", "node", node.Name)
+	} else if err := cache.Set(key, data, cacheTTL(node)); err != nil {
+		p.Log.Error(err, "Failed to write response cache", "node", node.Name)
+	}
+	return response, nil
+}
+
+type cacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// InMemoryResponseCache keeps up to a maximum number of entries, evicting the least recently used.
+type InMemoryResponseCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+func NewInMemoryResponseCache(maxEntries int) *InMemoryResponseCache {
+	return &InMemoryResponseCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (c *InMemoryResponseCache) Get(key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.lru.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+func (c *InMemoryResponseCache) Set(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := time.Now().Add(ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.value = value
+		entry.expires = expires
+		c.lru.MoveToFront(elem)
+		return nil
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+	return nil
+}
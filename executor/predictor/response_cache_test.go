@@ -0,0 +1,196 @@
+package predictor
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang/protobuf/jsonpb"
+	protov1 "github.com/golang/protobuf/proto"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/k8s"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func TestCachedPredict(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	graph := createModelNode("cached")
+	graph.Cache = &v1.CachePolicy{}
+	_, err := createPredictorProcess(t).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+
+	// The model is down but the same request is served from the cache
+	pResp, err := createPredictorProcessWithHostError("foo", 0).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(pResp.GetPayload().(*proto.SeldonMessage).GetData().GetNdarray().GetValues()).Should(HaveLen(2))
+
+	// Unless the cache is bypassed
+	pp := createPredictorProcessWithHostError("foo", 0)
+	pp.Meta.Meta[payload.SeldonCacheBypassHeader] = []string{"true"}
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+
+	// or the request is different
+	var sm proto.SeldonMessage
+	g.Expect(jsonpb.UnmarshalString(`{"data":{"ndarray":[3.0]}}`, &sm)).Should(BeNil())
+	_, err = createPredictorProcessWithHostError("foo", 0).Predict(graph, &payload.ProtoPayload{Msg: &sm})
+	g.Expect(err).ShouldNot(BeNil())
+
+	// or it is for another model
+	pp = createPredictorProcessWithHostError("foo", 0)
+	pp.ModelNameOverride = "other"
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestCachedPredictAboveRandomRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	abtest := v1.RANDOM_ABTEST
+	model := v1.MODEL
+	endpoint := &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000, Type: v1.REST}
+	graph := &v1.PredictiveUnit{
+		Name:           "abtest",
+		Implementation: &abtest,
+		Parameters:     []v1.Parameter{{Name: "ratioA", Value: "0.5", Type: v1.FLOAT}},
+		Cache:          &v1.CachePolicy{},
+		Children: []v1.PredictiveUnit{
+			{Name: "a", Type: &model, Endpoint: endpoint},
+			{Name: "b", Type: &model, Endpoint: endpoint},
+		},
+	}
+	version := &PredictorVersion{keys: newNodeStateKeys(&v1.PredictorSpec{Graph: *graph})}
+	g.Expect(version.cacheable(graph)).Should(BeFalse())
+	g.Expect(version.cacheable(&graph.Children[0])).Should(BeTrue())
+
+	pp := createPredictorProcess(t)
+	pp.Version = version
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+
+	// The same request is routed again rather than served from the cache
+	pp = createPredictorProcessWithHostError("foo", 0)
+	pp.Version = version
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestResponseEncoding(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	msg := createPredictPayload(g)
+	data, err := encodeResponse(msg)
+	g.Expect(err).Should(BeNil())
+	decoded, err := decodeResponse(data)
+	g.Expect(err).Should(BeNil())
+	g.Expect(protov1.Equal(decoded.GetPayload().(*proto.SeldonMessage), msg.GetPayload().(*proto.SeldonMessage))).Should(BeTrue())
+
+	msg = &payload.BytesPayload{Msg: []byte(`{"outputs":[]}`), ContentType: "application/json"}
+	data, err = encodeResponse(msg)
+	g.Expect(err).Should(BeNil())
+	decoded, err = decodeResponse(data)
+	g.Expect(err).Should(BeNil())
+	g.Expect(decoded).Should(Equal(msg))
+}
+
+func TestInMemoryResponseCache(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cache := NewInMemoryResponseCache(2)
+	g.Expect(cache.Set("a", []byte("1"), time.Minute)).Should(BeNil())
+	g.Expect(cache.Set("b", []byte("2"), time.Minute)).Should(BeNil())
+	_, ok, _ := cache.Get("a")
+	g.Expect(ok).Should(BeTrue())
+
+	// b is now the least recently used
+	g.Expect(cache.Set("c", []byte("3"), time.Minute)).Should(BeNil())
+	_, ok, _ = cache.Get("b")
+	g.Expect(ok).Should(BeFalse())
+	value, ok, _ := cache.Get("a")
+	g.Expect(ok).Should(BeTrue())
+	g.Expect(value).Should(Equal([]byte("1")))
+
+	g.Expect(cache.Set("d", []byte("4"), time.Millisecond)).Should(BeNil())
+	time.Sleep(5 * time.Millisecond)
+	_, ok, _ = cache.Get("d")
+	g.Expect(ok).Should(BeFalse())
+}
+
+func TestRedisCacheSettingsFromAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	settings, err := RedisCacheSettingsFromAnnotations(map[string]string{})
+	g.Expect(err).Should(BeNil())
+	g.Expect(settings).Should(BeNil())
+
+	settings, err = RedisCacheSettingsFromAnnotations(map[string]string{
+		k8s.ANNOTATION_CACHE_REDIS_ADDRESS:       "redis:6379",
+		k8s.ANNOTATION_CACHE_REDIS_PASSWORD_FILE: "/secrets/redis/password",
+		k8s.ANNOTATION_CACHE_REDIS_DB:            "2",
+		k8s.ANNOTATION_CACHE_REDIS_TLS_CA_FILE:   "/secrets/redis/ca.crt",
+	})
+	g.Expect(err).Should(BeNil())
+	g.Expect(*settings).Should(Equal(RedisCacheSettings{
+		Address:      "redis:6379",
+		PasswordFile: "/secrets/redis/password",
+		DB:           2,
+		TLS:          true,
+		TLSCAFile:    "/secrets/redis/ca.crt",
+	}))
+
+	_, err = RedisCacheSettingsFromAnnotations(map[string]string{
+		k8s.ANNOTATION_CACHE_REDIS_ADDRESS: "redis:6379",
+		k8s.ANNOTATION_CACHE_REDIS_DB:      "-1",
+	})
+	g.Expect(err).ShouldNot(BeNil())
+	_, err = RedisCacheSettingsFromAnnotations(map[string]string{
+		k8s.ANNOTATION_CACHE_REDIS_ADDRESS: "redis:6379",
+		k8s.ANNOTATION_CACHE_REDIS_TLS:     "yes please",
+	})
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestRedisResponseCache(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	g.Expect(ioutil.WriteFile(passwordFile, []byte("secret\n"), 0600)).Should(BeNil())
+
+	cache, err := NewRedisResponseCache(RedisCacheSettings{Address: server.Addr(), PasswordFile: passwordFile, DB: 3}, "dep/pred/")
+	g.Expect(err).Should(BeNil())
+	_, ok, err := cache.Get("a")
+	g.Expect(err).Should(BeNil())
+	g.Expect(ok).Should(BeFalse())
+
+	g.Expect(cache.Set("a", []byte(`{"data":1}`), time.Minute)).Should(BeNil())
+	value, ok, err := cache.Get("a")
+	g.Expect(err).Should(BeNil())
+	g.Expect(ok).Should(BeTrue())
+	g.Expect(value).Should(Equal([]byte(`{"data":1}`)))
+	server.Select(3)
+	g.Expect(server.Exists("dep/pred/a")).Should(BeTrue())
+	g.Expect(server.TTL("dep/pred/a")).Should(Equal(time.Minute))
+
+	// The wrong password is refused
+	wrongFile := filepath.Join(t.TempDir(), "password")
+	g.Expect(ioutil.WriteFile(wrongFile, []byte("wrong"), 0600)).Should(BeNil())
+	cache, err = NewRedisResponseCache(RedisCacheSettings{Address: server.Addr(), PasswordFile: wrongFile}, "")
+	g.Expect(err).Should(BeNil())
+	_, _, err = cache.Get("a")
+	g.Expect(err).ShouldNot(BeNil())
+
+	_, err = NewRedisResponseCache(RedisCacheSettings{Address: server.Addr(), PasswordFile: filepath.Join(t.TempDir(), "missing")}, "")
+	g.Expect(err).ShouldNot(BeNil())
+
+	cache, err = NewRedisResponseCache(RedisCacheSettings{Address: "127.0.0.1:1"}, "")
+	g.Expect(err).Should(BeNil())
+	_, _, err = cache.Get("a")
+	g.Expect(err).ShouldNot(BeNil())
+}
@@ -0,0 +1,81 @@
+package predictor
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Names of the spans of the phases of the processing of a node.
+const (
+	SpanTransformInput  = "transform-input"
+	SpanRoute           = "route"
+	SpanChildren        = "children"
+	SpanAggregate       = "aggregate"
+	SpanTransformOutput = "transform-output"
+)
+
+// Attributes of the spans of nodes.
+const (
+	AttributeNodeName           = attribute.Key("seldon.node.name")
+	AttributeNodeType           = attribute.Key("seldon.node.type")
+	AttributeNodeImplementation = attribute.Key("seldon.node.implementation")
+	AttributeNodeRoutes         = attribute.Key("seldon.node.routes")
+	AttributeRequestSize        = attribute.Key("seldon.payload.request_size")
+	AttributeResponseSize       = attribute.Key("seldon.payload.response_size")
+)
+
+func nodeAttributes(node *v1.PredictiveUnit) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{AttributeNodeName.String(node.Name)}
+	if node.Type != nil {
+		attrs = append(attrs, AttributeNodeType.String(string(*node.Type)))
+	}
+	if node.Implementation != nil {
+		attrs = append(attrs, AttributeNodeImplementation.String(string(*node.Implementation)))
+	}
+	return attrs
+}
+
+// payloadSize returns the size in bytes of the payload, without serializing it, or false if it isn't known.
+func payloadSize(msg payload.SeldonPayload) (int, bool) {
+	switch msg := msg.(type) {
+	case *payload.BytesPayload:
+		return len(msg.Msg), true
+	case *payload.ProtoPayload:
+		return proto.Size(msg.Msg), true
+	default:
+		return 0, false
+	}
+}
+
+func setPayloadSize(span trace.Span, key attribute.Key, msg payload.SeldonPayload) {
+	if msg == nil || !span.IsRecording() {
+		return
+	}
+	if size, ok := payloadSize(msg); ok {
+		span.SetAttributes(key.Int(size))
+	}
+}
+
+// startSpan starts a span for the node, or a phase of it, returning a copy of the process whose calls are traced
+// under the span.
+func (p *PredictorProcess) startSpan(name string, node *v1.PredictiveUnit, msg payload.SeldonPayload) (*PredictorProcess, trace.Span) {
+	ctx, span := tracing.Tracer().Start(p.Ctx, name, trace.WithAttributes(nodeAttributes(node)...))
+	setPayloadSize(span, AttributeRequestSize, msg)
+	traced := *p
+	traced.Ctx = ctx
+	return &traced, span
+}
+
+// endSpan records the response and any error of the node, or phase, and ends its span.
+func endSpan(span trace.Span, response payload.SeldonPayload, err error) {
+	if err != nil {
+		tracing.RecordError(span, err)
+	} else {
+		setPayloadSize(span, AttributeResponseSize, response)
+	}
+	span.End()
+}
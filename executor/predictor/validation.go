@@ -0,0 +1,347 @@
+package predictor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	_struct "github.com/golang/protobuf/ptypes/struct"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/k8s"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataCacheTTL is how long the graph inputs from the metadata of the nodes are used to validate requests before
+// they are fetched again.
+var MetadataCacheTTL = time.Minute
+
+// Time allowed to fetch the metadata of the graph
+const metadataTimeout = 5 * time.Second
+
+// ValidationError is returned for requests which don't match the inputs of the graph.
+type ValidationError struct {
+	// Path of the invalid field in the request
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid request %s: %s", e.Field, e.Message)
+}
+
+// GRPCStatus allows the error to be returned from gRPC servers as INVALID_ARGUMENT.
+func (e *ValidationError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, e.Error())
+}
+
+// RequestValidator checks requests against the inputs of the graph, from the metadata of its nodes. A nil validator
+// accepts every request.
+type RequestValidator struct {
+	protocol string
+
+	mu   sync.Mutex
+	spec *v1.PredictorSpec
+	// Inputs of the graph, nil if the metadata is unavailable
+	inputs  []MetadataTensor
+	expires time.Time
+	// Closed when the running fetch of the metadata is done, nil if there is none
+	fetching     chan struct{}
+	fetchingSpec *v1.PredictorSpec
+}
+
+func NewRequestValidator(protocol string) *RequestValidator {
+	return &RequestValidator{protocol: protocol}
+}
+
+// RequestValidatorFromAnnotations returns a nil validator unless validation is enabled by annotation.
+func RequestValidatorFromAnnotations(annotations map[string]string, protocol string) (*RequestValidator, error) {
+	val := annotations[k8s.ANNOTATION_VALIDATE_REQUESTS]
+	if val == "" {
+		return nil, nil
+	}
+	enabled, err := strconv.ParseBool(val)
+	if err != nil {
+		return nil, fmt.Errorf("annotation %s must be a boolean: %w", k8s.ANNOTATION_VALIDATE_REQUESTS, err)
+	}
+	if !enabled {
+		return nil, nil
+	}
+	return NewRequestValidator(protocol), nil
+}
+
+// Validate returns a ValidationError if the request doesn't match the inputs of the graph. Requests aren't checked
+// while the metadata of the graph is unavailable.
+func (v *RequestValidator) Validate(p *PredictorProcess, spec *v1.PredictorSpec, msg payload.SeldonPayload) error {
+	if v == nil {
+		return nil
+	}
+	inputs := v.graphInputs(p, spec)
+	if len(inputs) == 0 {
+		return nil
+	}
+	switch v.protocol {
+	case api.ProtocolSeldon:
+		return validateSeldonRequest(inputs, msg)
+	case api.ProtocolV2, api.ProtocolKFServing:
+		return validateV2Request(inputs, msg)
+	}
+	return nil
+}
+
+// graphInputs returns the cached inputs of the graph, fetching the metadata of the nodes when the graph changes or
+// the cache expires. The metadata is fetched without holding the lock. Expired inputs are used while they are fetched
+// again in the background, and requests for a new graph share the fetch of its inputs.
+func (v *RequestValidator) graphInputs(p *PredictorProcess, spec *v1.PredictorSpec) []MetadataTensor {
+	v.mu.Lock()
+	if v.spec == spec {
+		defer v.mu.Unlock()
+		if !time.Now().Before(v.expires) && v.fetching == nil {
+			v.fetch(p, spec)
+		}
+		return v.inputs
+	}
+	done := v.fetching
+	if done == nil || v.fetchingSpec != spec {
+		done = v.fetch(p, spec)
+	}
+	v.mu.Unlock()
+	<-done
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	// Another version of the graph may have been fetched since, its inputs don't apply
+	if v.spec != spec {
+		return nil
+	}
+	return v.inputs
+}
+
+// fetch fetches the inputs of the graph in the background, returning a channel closed once they are cached. It is
+// called with the lock held.
+func (v *RequestValidator) fetch(p *PredictorProcess, spec *v1.PredictorSpec) chan struct{} {
+	done := make(chan struct{})
+	v.fetching, v.fetchingSpec = done, spec
+	// The metadata is fetched independently of the request, which may be cancelled or finish first
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	process := NewPredictorProcess(ctx, p.Client, p.Log, p.ServerUrl, p.Namespace, p.Meta.Meta, p.ModelNameOverride)
+	go func() {
+		defer cancel()
+		var inputs []MetadataTensor
+		if graphMetadata, err := process.GraphMetadata(spec); err != nil {
+			process.Log.Info("Requests are not validated as the graph metadata is unavailable", "error", err.Error())
+		} else {
+			inputs = metadataTensors(graphMetadata.GraphInputs)
+		}
+		v.mu.Lock()
+		v.spec, v.expires, v.inputs = spec, time.Now().Add(MetadataCacheTTL), inputs
+		if v.fetching == done {
+			v.fetching, v.fetchingSpec = nil, nil
+		}
+		v.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+// metadataTensors converts the inputs of node metadata, decoded from JSON or from the gRPC protocols, to tensors.
+// The shape of a Seldon message schema doesn't include the batch dimension, which may be any size.
+func metadataTensors(inputs interface{}) []MetadataTensor {
+	var tensors []MetadataTensor
+	switch inputs := inputs.(type) {
+	case []MetadataTensor:
+		tensors = inputs
+	case []interface{}:
+		for _, input := range inputs {
+			m, ok := input.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			tensor := MetadataTensor{}
+			tensor.Name, _ = m["name"].(string)
+			tensor.DataType, _ = m["datatype"].(string)
+			tensor.Shape, _ = jsonShape(m["shape"])
+			if schema, ok := m["schema"].(map[string]interface{}); ok && tensor.Shape == nil {
+				if shape, ok := jsonShape(schema["shape"]); ok {
+					tensor.Shape = append([]int{-1}, shape...)
+				}
+			}
+			tensors = append(tensors, tensor)
+		}
+	case []*proto.SeldonMessageMetadata:
+		for _, input := range inputs {
+			tensor := MetadataTensor{Name: input.GetName(), DataType: input.GetDatatype()}
+			for _, d := range input.GetShape() {
+				tensor.Shape = append(tensor.Shape, int(d))
+			}
+			if schema := input.GetSchema().GetStructValue(); schema != nil && tensor.Shape == nil {
+				if shape := schema.GetFields()["shape"].GetListValue(); shape != nil {
+					tensor.Shape = []int{-1}
+					for _, d := range shape.GetValues() {
+						tensor.Shape = append(tensor.Shape, int(d.GetNumberValue()))
+					}
+				}
+			}
+			tensors = append(tensors, tensor)
+		}
+	case []*inference.ModelMetadataResponse_TensorMetadata:
+		for _, input := range inputs {
+			tensor := MetadataTensor{Name: input.GetName(), DataType: input.GetDatatype()}
+			for _, d := range input.GetShape() {
+				tensor.Shape = append(tensor.Shape, int(d))
+			}
+			tensors = append(tensors, tensor)
+		}
+	}
+	return tensors
+}
+
+// validateV2Request checks the names, datatypes and shapes of the input tensors of a V2 request.
+func validateV2Request(inputs []MetadataTensor, msg payload.SeldonPayload) error {
+	var tensors []MetadataTensor
+	switch req := msg.GetPayload().(type) {
+	case []byte:
+		var body struct {
+			Inputs []MetadataTensor `json:"inputs"`
+		}
+		if err := json.Unmarshal(req, &body); err != nil {
+			return &ValidationError{Field: "body", Message: err.Error()}
+		}
+		tensors = body.Inputs
+	case *inference.ModelInferRequest:
+		for _, input := range req.GetInputs() {
+			tensor := MetadataTensor{Name: input.GetName(), DataType: input.GetDatatype()}
+			for _, d := range input.GetShape() {
+				tensor.Shape = append(tensor.Shape, int(d))
+			}
+			tensors = append(tensors, tensor)
+		}
+	default:
+		return nil
+	}
+
+	expected := make(map[string]MetadataTensor, len(inputs))
+	for _, input := range inputs {
+		// Inputs can only be matched by name
+		if input.Name == "" {
+			return nil
+		}
+		expected[input.Name] = input
+	}
+	received := make(map[string]bool, len(tensors))
+	for i, tensor := range tensors {
+		input, ok := expected[tensor.Name]
+		if !ok {
+			return &ValidationError{Field: fmt.Sprintf("inputs[%d].name", i), Message: fmt.Sprintf("unknown input %q", tensor.Name)}
+		}
+		received[tensor.Name] = true
+		if input.DataType != "" && tensor.DataType != input.DataType {
+			return &ValidationError{Field: fmt.Sprintf("inputs[%d].datatype", i), Message: fmt.Sprintf("input %q must be %s not %s", tensor.Name, input.DataType, tensor.DataType)}
+		}
+		if !shapeMatches(input.Shape, tensor.Shape) {
+			return &ValidationError{Field: fmt.Sprintf("inputs[%d].shape", i), Message: fmt.Sprintf("input %q must have shape %v not %v", tensor.Name, input.Shape, tensor.Shape)}
+		}
+	}
+	for _, input := range inputs {
+		if !received[input.Name] {
+			return &ValidationError{Field: "inputs", Message: fmt.Sprintf("missing input %q", input.Name)}
+		}
+	}
+	return nil
+}
+
+// validateSeldonRequest checks the dimensions of the ndarray or tensor of a Seldon message against a graph with a
+// single input.
+func validateSeldonRequest(inputs []MetadataTensor, msg payload.SeldonPayload) error {
+	if len(inputs) != 1 || inputs[0].Shape == nil {
+		return nil
+	}
+	var field string
+	var shape []int
+	switch req := msg.GetPayload().(type) {
+	case []byte:
+		var body struct {
+			Data struct {
+				Tensor *struct {
+					Shape []int `json:"shape"`
+				} `json:"tensor"`
+				Ndarray json.RawMessage `json:"ndarray"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(req, &body); err != nil {
+			return &ValidationError{Field: "body", Message: err.Error()}
+		}
+		if body.Data.Tensor != nil {
+			field, shape = "data.tensor.shape", body.Data.Tensor.Shape
+		} else if body.Data.Ndarray != nil {
+			var ndarray interface{}
+			if err := json.Unmarshal(body.Data.Ndarray, &ndarray); err != nil {
+				return &ValidationError{Field: "data.ndarray", Message: err.Error()}
+			}
+			field, shape = "data.ndarray", jsonArrayShape(ndarray)
+		}
+	case *proto.SeldonMessage:
+		if tensor := req.GetData().GetTensor(); tensor != nil {
+			field = "data.tensor.shape"
+			for _, d := range tensor.GetShape() {
+				shape = append(shape, int(d))
+			}
+		} else if ndarray := req.GetData().GetNdarray(); ndarray != nil {
+			field, shape = "data.ndarray", listValueShape(ndarray)
+		}
+	}
+	if field != "" && !shapeMatches(inputs[0].Shape, shape) {
+		return &ValidationError{Field: field, Message: fmt.Sprintf("must have shape %v not %v", inputs[0].Shape, shape)}
+	}
+	return nil
+}
+
+// shapeMatches compares shapes where an expected dimension of -1 matches any size.
+func shapeMatches(expected []int, actual []int) bool {
+	if expected == nil {
+		return true
+	}
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i, d := range expected {
+		if d >= 0 && d != actual[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonArrayShape returns the dimensions of nested arrays, from their first elements.
+func jsonArrayShape(value interface{}) []int {
+	shape := []int{}
+	for {
+		array, ok := value.([]interface{})
+		if !ok {
+			return shape
+		}
+		shape = append(shape, len(array))
+		if len(array) == 0 {
+			return shape
+		}
+		value = array[0]
+	}
+}
+
+func listValueShape(list *_struct.ListValue) []int {
+	shape := []int{}
+	for list != nil {
+		shape = append(shape, len(list.GetValues()))
+		if len(list.GetValues()) == 0 {
+			return shape
+		}
+		list = list.GetValues()[0].GetListValue()
+	}
+	return shape
+}
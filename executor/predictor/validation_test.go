@@ -0,0 +1,188 @@
+package predictor
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	"github.com/seldonio/seldon-core/executor/k8s"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func createValidationSpec() *v1.PredictorSpec {
+	model := v1.MODEL
+	return &v1.PredictorSpec{
+		Name: "predictor-name",
+		Graph: v1.PredictiveUnit{
+			Name: "model-1",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: "foo",
+				ServicePort: 9000,
+				Type:        v1.REST,
+			},
+		},
+	}
+}
+
+func TestRequestValidatorFromAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	validator, err := RequestValidatorFromAnnotations(map[string]string{}, api.ProtocolSeldon)
+	g.Expect(err).To(BeNil())
+	g.Expect(validator).To(BeNil())
+
+	validator, err = RequestValidatorFromAnnotations(map[string]string{k8s.ANNOTATION_VALIDATE_REQUESTS: "true"}, api.ProtocolSeldon)
+	g.Expect(err).To(BeNil())
+	g.Expect(validator).ToNot(BeNil())
+
+	_, err = RequestValidatorFromAnnotations(map[string]string{k8s.ANNOTATION_VALIDATE_REQUESTS: "yes please"}, api.ProtocolSeldon)
+	g.Expect(err).ToNot(BeNil())
+
+	// A nil validator accepts every request
+	var disabled *RequestValidator
+	g.Expect(disabled.Validate(nil, nil, nil)).To(BeNil())
+}
+
+func TestValidateV2JsonRequest(t *testing.T) {
+	g := NewGomegaWithT(t)
+	validator := NewRequestValidator(api.ProtocolV2)
+	spec := createValidationSpec()
+	pp := createPredictorProcessWithMetadata(t, nil, metadataMap)
+
+	valid := `{"inputs":[{"name":"input","datatype":"BYTES","shape":[1,5],"data":["a","b","c","d","e"]}]}`
+	g.Expect(validator.Validate(pp, spec, &payload.BytesPayload{Msg: []byte(valid)})).To(BeNil())
+
+	tests := map[string]string{
+		"inputs[0].name":     `{"inputs":[{"name":"other","datatype":"BYTES","shape":[1,5]}]}`,
+		"inputs[0].datatype": `{"inputs":[{"name":"input","datatype":"FP32","shape":[1,5]}]}`,
+		"inputs[0].shape":    `{"inputs":[{"name":"input","datatype":"BYTES","shape":[1,4]}]}`,
+		"inputs":             `{"inputs":[]}`,
+		"body":               `{"inputs":`,
+	}
+	for field, body := range tests {
+		err := validator.Validate(pp, spec, &payload.BytesPayload{Msg: []byte(body)})
+		g.Expect(err).ToNot(BeNil())
+		g.Expect(err.(*ValidationError).Field).To(Equal(field))
+	}
+}
+
+func TestValidateV2ProtoRequest(t *testing.T) {
+	g := NewGomegaWithT(t)
+	validator := NewRequestValidator(api.ProtocolV2)
+	spec := createValidationSpec()
+	pp := createPredictorProcessWithMetadata(t, nil, metadataMap)
+
+	req := &inference.ModelInferRequest{
+		Inputs: []*inference.ModelInferRequest_InferInputTensor{{Name: "input", Datatype: "BYTES", Shape: []int64{1, 5}}},
+	}
+	g.Expect(validator.Validate(pp, spec, &payload.ProtoPayload{Msg: req})).To(BeNil())
+
+	req.Inputs[0].Shape = []int64{2, 5}
+	err := validator.Validate(pp, spec, &payload.ProtoPayload{Msg: req})
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(err.(*ValidationError).Field).To(Equal("inputs[0].shape"))
+}
+
+func TestValidateSeldonRequest(t *testing.T) {
+	g := NewGomegaWithT(t)
+	validator := NewRequestValidator(api.ProtocolSeldon)
+	spec := createValidationSpec()
+	metadata := map[string]payload.ModelMetadata{
+		"model-1": {
+			Name:   "model-1",
+			Inputs: []interface{}{map[string]interface{}{"messagetype": "ndarray", "schema": map[string]interface{}{"shape": []interface{}{3.0}}}},
+		},
+	}
+	pp := createPredictorProcessWithMetadata(t, nil, metadata)
+
+	g.Expect(validator.Validate(pp, spec, &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2,3],[4,5,6]]}}`)})).To(BeNil())
+	g.Expect(validator.Validate(pp, spec, &payload.BytesPayload{Msg: []byte(`{"data":{"tensor":{"shape":[1,3],"values":[1,2,3]}}}`)})).To(BeNil())
+
+	err := validator.Validate(pp, spec, &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2]]}}`)})
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(err.(*ValidationError).Field).To(Equal("data.ndarray"))
+
+	var sm proto.SeldonMessage
+	g.Expect(jsonpb.UnmarshalString(`{"data":{"tensor":{"shape":[1,2],"values":[1,2]}}}`, &sm)).To(BeNil())
+	err = validator.Validate(pp, spec, &payload.ProtoPayload{Msg: &sm})
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(err.(*ValidationError).Field).To(Equal("data.tensor.shape"))
+}
+
+func TestValidateWithoutMetadata(t *testing.T) {
+	g := NewGomegaWithT(t)
+	validator := NewRequestValidator(api.ProtocolV2)
+
+	// Requests aren't rejected when the nodes have no metadata
+	pp := createPredictorProcessWithMetadata(t, nil, map[string]payload.ModelMetadata{})
+	g.Expect(validator.Validate(pp, createValidationSpec(), &payload.BytesPayload{Msg: []byte(`{"inputs":[]}`)})).To(BeNil())
+}
+
+// blockingMetadataClient returns the test metadata once calls are released.
+type blockingMetadataClient struct {
+	test.SeldonMessageTestClient
+	called  chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingMetadataClient) ModelMetadata(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.ModelMetadata, error) {
+	c.called <- struct{}{}
+	<-c.release
+	return c.SeldonMessageTestClient.ModelMetadata(ctx, modelName, host, port, msg, meta)
+}
+
+func TestValidateWhileMetadataRefreshes(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ttl := MetadataCacheTTL
+	MetadataCacheTTL = 0
+	defer func() { MetadataCacheTTL = ttl }()
+
+	client := &blockingMetadataClient{
+		SeldonMessageTestClient: test.SeldonMessageTestClient{ModelMetadataMap: metadataMap},
+		called:                  make(chan struct{}, 2),
+		release:                 make(chan struct{}),
+	}
+	url, _ := url.Parse(testSourceUrl)
+	pp := NewPredictorProcess(context.TODO(), client, logf.Log.WithName("validation"), url, "default", map[string][]string{}, "")
+	validator := NewRequestValidator(api.ProtocolV2)
+	spec := createValidationSpec()
+	invalid := &payload.BytesPayload{Msg: []byte(`{"inputs":[]}`)}
+
+	// Requests for a new graph wait for its inputs
+	validated := make(chan error)
+	go func() {
+		validated <- validator.Validate(&pp, spec, invalid)
+	}()
+	<-client.called
+	client.release <- struct{}{}
+	g.Expect(<-validated).ToNot(BeNil())
+
+	// Expired inputs are used while they are fetched again
+	g.Expect(validator.Validate(&pp, spec, invalid)).ToNot(BeNil())
+	g.Eventually(client.called, time.Second).Should(Receive())
+	g.Expect(validator.Validate(&pp, spec, invalid)).ToNot(BeNil())
+	validator.mu.Lock()
+	done := validator.fetching
+	validator.mu.Unlock()
+	close(client.release)
+	<-done
+}
+
+func TestShapeMatches(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(shapeMatches(nil, []int{1, 2})).To(BeTrue())
+	g.Expect(shapeMatches([]int{-1, 3}, []int{10, 3})).To(BeTrue())
+	g.Expect(shapeMatches([]int{-1, 3}, []int{10, 4})).To(BeFalse())
+	g.Expect(shapeMatches([]int{-1, 3}, []int{3})).To(BeFalse())
+}
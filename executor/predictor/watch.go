@@ -0,0 +1,50 @@
+package predictor
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+)
+
+// Time to wait for the writes of a file update to settle before reloading
+const watchDebounce = 200 * time.Millisecond
+
+// WatchPredictorFile calls reload when the predictor file changes, until stop is closed. The directory of the file is
+// watched as mounted ConfigMaps are updated by swapping a symlink rather than writing the file.
+func WatchPredictorFile(filename string, reload func(), stop <-chan struct{}, log logr.Logger) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(filename)); err != nil {
+		watcher.Close()
+		return err
+	}
+	go func() {
+		defer watcher.Close()
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-stop:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				log.V(1).Info("Predictor file event", "event", event.String())
+				debounce = time.After(watchDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error(err, "Failed to watch predictor file", "file", filename)
+			case <-debounce:
+				debounce = nil
+				reload()
+			}
+		}
+	}()
+	return nil
+}
@@ -144,7 +144,7 @@ func getRelatedImageConfig() map[string]PredictorServerConfig {
 }
 
 func IsPrepack(pu *PredictiveUnit) bool {
-	isPrepack := len(*pu.Implementation) > 0 && *pu.Implementation != SIMPLE_MODEL && *pu.Implementation != SIMPLE_ROUTER && *pu.Implementation != RANDOM_ABTEST && *pu.Implementation != AVERAGE_COMBINER && *pu.Implementation != UNKNOWN_IMPLEMENTATION
+	isPrepack := len(*pu.Implementation) > 0 && *pu.Implementation != SIMPLE_MODEL && *pu.Implementation != SIMPLE_ROUTER && *pu.Implementation != RANDOM_ABTEST && *pu.Implementation != AVERAGE_COMBINER && *pu.Implementation != MAJORITY_VOTE_COMBINER && *pu.Implementation != CONDITIONAL_ROUTER && *pu.Implementation != EPSILON_GREEDY && *pu.Implementation != THOMPSON_SAMPLING && *pu.Implementation != UNKNOWN_IMPLEMENTATION
 	return isPrepack
 }
 
@@ -55,6 +55,8 @@ const (
 	OLD_PODINFO_VOLUME_NAME = "podinfo"
 	PODINFO_VOLUME_PATH     = "/etc/podinfo"
 
+	LOGGER_SPOOL_VOLUME_NAME = "seldon-logger-spool"
+
 	ENV_PREDICTIVE_UNIT_SERVICE_PORT         = "PREDICTIVE_UNIT_SERVICE_PORT"
 	ENV_PREDICTIVE_UNIT_HTTP_SERVICE_PORT    = "PREDICTIVE_UNIT_HTTP_SERVICE_PORT"
 	ENV_PREDICTIVE_UNIT_GRPC_SERVICE_PORT    = "PREDICTIVE_UNIT_GRPC_SERVICE_PORT"
@@ -77,6 +79,7 @@ const (
 	ANNOTATION_CUSTOM_SVC_NAME         = "seldon.io/svc-name"
 	ANNOTATION_LOGGER_WORK_QUEUE_SIZE  = "seldon.io/executor-logger-queue-size"
 	ANNOTATION_LOGGER_WRITE_TIMEOUT_MS = "seldon.io/executor-logger-write-timeout-ms"
+	ANNOTATION_LOGGER_SPOOL_DIR        = "seldon.io/logger-spool-dir"
 
 	DeploymentNamePrefix = "seldon"
 )
@@ -553,6 +556,10 @@ const (
 	SIMPLE_ROUTER          PredictiveUnitImplementation = "SIMPLE_ROUTER"
 	RANDOM_ABTEST          PredictiveUnitImplementation = "RANDOM_ABTEST"
 	AVERAGE_COMBINER       PredictiveUnitImplementation = "AVERAGE_COMBINER"
+	EPSILON_GREEDY         PredictiveUnitImplementation = "EPSILON_GREEDY"
+	THOMPSON_SAMPLING      PredictiveUnitImplementation = "THOMPSON_SAMPLING"
+	MAJORITY_VOTE_COMBINER PredictiveUnitImplementation = "MAJORITY_VOTE_COMBINER"
+	CONDITIONAL_ROUTER     PredictiveUnitImplementation = "CONDITIONAL_ROUTER"
 )
 
 type PredictiveUnitMethod string
@@ -609,6 +616,79 @@ type PredictiveUnit struct {
 	EnvSecretRefName        string                        `json:"envSecretRefName,omitempty" protobuf:"bytes,10,opt,name=envSecretRefName"`
 	StorageInitializerImage string                        `json:"storageInitializerImage,omitempty" protobuf:"bytes,11,opt,name=storageInitializerImage"`
 	Logger                  *Logger                       `json:"logger,omitempty" protobuf:"bytes,12,opt,name=logger"`
+	Retry                   *RetryPolicy                  `json:"retry,omitempty" protobuf:"bytes,13,opt,name=retry"`
+	Fallback                *FallbackPolicy               `json:"fallback,omitempty" protobuf:"bytes,14,opt,name=fallback"`
+	Mirror                  *MirrorPolicy                 `json:"mirror,omitempty" protobuf:"bytes,15,opt,name=mirror"`
+	Cache                   *CachePolicy                  `json:"cache,omitempty" protobuf:"bytes,16,opt,name=cache"`
+	Batching                *BatchingPolicy               `json:"batching,omitempty" protobuf:"bytes,17,opt,name=batching"`
+	Protocol                Protocol                      `json:"protocol,omitempty" protobuf:"bytes,18,opt,name=protocol"`
+}
+
+// RetryPolicy controls how the executor retries failed calls to a predictive unit
+type RetryPolicy struct {
+	// Maximum number of calls made to the unit including the first one
+	// +optional
+	MaxAttempts int32 `json:"maxAttempts,omitempty" protobuf:"int32,1,opt,name=maxAttempts"`
+	// Backoff in milliseconds before the first retry. It doubles on each further retry.
+	// +optional
+	InitialBackoffMs int32 `json:"initialBackoffMs,omitempty" protobuf:"int32,2,opt,name=initialBackoffMs"`
+	// Upper bound in milliseconds for the backoff between retries
+	// +optional
+	MaxBackoffMs int32 `json:"maxBackoffMs,omitempty" protobuf:"int32,3,opt,name=maxBackoffMs"`
+	// HTTP status codes returned by the unit which will be retried
+	// +optional
+	RetryableStatusCodes []int32 `json:"retryableStatusCodes,omitempty" protobuf:"int32,4,rep,name=retryableStatusCodes"`
+	// gRPC status codes, e.g. UNAVAILABLE, returned by the unit which will be retried
+	// +optional
+	RetryableGrpcCodes []string `json:"retryableGrpcCodes,omitempty" protobuf:"bytes,5,rep,name=retryableGrpcCodes"`
+	// Whether calls may also be retried after failures where the unit may have processed the request,
+	// e.g. connection resets or timeouts
+	// +optional
+	Idempotent bool `json:"idempotent,omitempty" protobuf:"varint,6,opt,name=idempotent"`
+}
+
+// FallbackPolicy controls how the executor handles failures of a predictive unit and its children
+type FallbackPolicy struct {
+	// Minimum number of children which must succeed for their responses to be aggregated.
+	// Failed children are left out of the aggregation. Defaults to requiring all children.
+	// +optional
+	MinSuccessfulChildren int32 `json:"minSuccessfulChildren,omitempty" protobuf:"int32,1,opt,name=minSuccessfulChildren"`
+	// Name of the child a router sends the request to if routing or the routed child fails
+	// +optional
+	FallbackChild string `json:"fallbackChild,omitempty" protobuf:"bytes,2,opt,name=fallbackChild"`
+	// JSON response returned in place of an error if the unit still fails
+	// +optional
+	DefaultResponse string `json:"defaultResponse,omitempty" protobuf:"bytes,3,opt,name=defaultResponse"`
+}
+
+// MirrorPolicy sends copies of the requests to a predictive unit to one of its children acting as a shadow
+type MirrorPolicy struct {
+	// Name of the shadow child. It only receives mirrored requests and its responses are discarded.
+	Child string `json:"child" protobuf:"bytes,1,opt,name=child"`
+	// Percentage of requests to mirror between 1 and 100. Defaults to 100.
+	// +optional
+	Percent int32 `json:"percent,omitempty" protobuf:"int32,2,opt,name=percent"`
+}
+
+// CachePolicy makes the executor cache the responses of a predictive unit keyed on the request payload
+type CachePolicy struct {
+	// Seconds a cached response is used for. Defaults to 300.
+	// +optional
+	TTLSeconds int32 `json:"ttlSeconds,omitempty" protobuf:"int32,1,opt,name=ttlSeconds"`
+	// Maximum number of responses kept by the in-memory cache before the least recently used are evicted.
+	// Defaults to 1000.
+	// +optional
+	MaxEntries int32 `json:"maxEntries,omitempty" protobuf:"int32,2,opt,name=maxEntries"`
+}
+
+// BatchingPolicy makes the executor combine concurrent requests to a model into batches
+type BatchingPolicy struct {
+	// Maximum number of rows sent to the model in one call. Defaults to 32.
+	// +optional
+	MaxBatchSize int32 `json:"maxBatchSize,omitempty" protobuf:"int32,1,opt,name=maxBatchSize"`
+	// Maximum time in milliseconds a request waits for others to join its batch. Defaults to 10.
+	// +optional
+	MaxLatencyMs int32 `json:"maxLatencyMs,omitempty" protobuf:"int32,2,opt,name=maxLatencyMs"`
 }
 
 type LoggerMode string
@@ -627,6 +707,17 @@ type Logger struct {
 	Url *string `json:"url,omitempty"`
 	// What payloads to log
 	Mode LoggerMode `json:"mode,omitempty"`
+	// Percentage of requests whose payloads are logged between 0 and 100. Defaults to 100.
+	// +optional
+	SamplePercent *int32 `json:"samplePercent,omitempty"`
+	// Request header which, set to true, logs the payloads of the request whatever the sample percentage.
+	// Defaults to Seldon-Force-Logging.
+	// +optional
+	ForceHeader string `json:"forceHeader,omitempty"`
+	// Fields replaced by REDACTED in the logged payloads, as JSON pointers, e.g. /data/names, or as names of
+	// V2 tensors whose data is replaced
+	// +optional
+	Redact []string `json:"redact,omitempty"`
 }
 
 // +genclient
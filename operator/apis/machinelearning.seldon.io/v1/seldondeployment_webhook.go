@@ -17,14 +17,19 @@ limitations under the License.
 package v1
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/seldonio/seldon-core/operator/constants"
+	"github.com/seldonio/seldon-core/operator/utils/condition"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -130,6 +135,43 @@ func (r *SeldonDeploymentSpec) checkPredictiveUnits(pu *PredictiveUnit, p *Predi
 		if pu.Logger.Mode == "" {
 			allErrs = append(allErrs, field.Invalid(fldPath, pu.Logger.Mode, "No logger mode specified"))
 		}
+		allErrs = r.checkLogger(pu.Logger, fldPath.Child("logger"), allErrs)
+	}
+
+	if pu.Retry != nil {
+		allErrs = checkRetryPolicy(pu.Retry, fldPath.Child("retry"), allErrs)
+	}
+
+	if pu.Fallback != nil {
+		allErrs = r.checkFallbackPolicy(pu, fldPath.Child("fallback"), allErrs)
+	}
+
+	if pu.Mirror != nil {
+		allErrs = checkMirrorPolicy(pu, fldPath.Child("mirror"), allErrs)
+	}
+
+	if pu.Cache != nil {
+		allErrs = checkCachePolicy(pu, fldPath.Child("cache"), allErrs)
+	}
+
+	if pu.Batching != nil {
+		allErrs = checkBatchingPolicy(pu, fldPath.Child("batching"), allErrs)
+	}
+
+	if pu.Protocol != "" {
+		allErrs = r.checkNodeProtocol(pu, fldPath.Child("protocol"), allErrs)
+	}
+
+	if pu.Implementation != nil && (*pu.Implementation == EPSILON_GREEDY || *pu.Implementation == THOMPSON_SAMPLING) {
+		allErrs = checkBanditRouter(pu, fldPath, allErrs)
+	}
+
+	if pu.Implementation != nil && (*pu.Implementation == AVERAGE_COMBINER || *pu.Implementation == MAJORITY_VOTE_COMBINER) {
+		allErrs = checkBuiltinCombiner(pu, fldPath, allErrs)
+	}
+
+	if pu.Implementation != nil && *pu.Implementation == CONDITIONAL_ROUTER {
+		allErrs = checkConditionalRouter(pu, fldPath, allErrs)
 	}
 
 	for i := 0; i < len(pu.Children); i++ {
@@ -139,6 +181,256 @@ func (r *SeldonDeploymentSpec) checkPredictiveUnits(pu *PredictiveUnit, p *Predi
 	return allErrs
 }
 
+// Canonical names of the gRPC status codes which can be used in a retry policy.
+var grpcStatusCodeNames = map[string]bool{
+	"OK": true, "CANCELLED": true, "UNKNOWN": true, "INVALID_ARGUMENT": true, "DEADLINE_EXCEEDED": true,
+	"NOT_FOUND": true, "ALREADY_EXISTS": true, "PERMISSION_DENIED": true, "RESOURCE_EXHAUSTED": true,
+	"FAILED_PRECONDITION": true, "ABORTED": true, "OUT_OF_RANGE": true, "UNIMPLEMENTED": true,
+	"INTERNAL": true, "UNAVAILABLE": true, "DATA_LOSS": true, "UNAUTHENTICATED": true,
+}
+
+func checkRetryPolicy(retry *RetryPolicy, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if retry.MaxAttempts < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxAttempts"), retry.MaxAttempts, "maxAttempts can not be negative"))
+	}
+	if retry.InitialBackoffMs < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("initialBackoffMs"), retry.InitialBackoffMs, "initialBackoffMs can not be negative"))
+	}
+	if retry.MaxBackoffMs < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxBackoffMs"), retry.MaxBackoffMs, "maxBackoffMs can not be negative"))
+	} else if retry.MaxBackoffMs > 0 && retry.MaxBackoffMs < retry.InitialBackoffMs {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxBackoffMs"), retry.MaxBackoffMs, "maxBackoffMs must not be less than initialBackoffMs"))
+	}
+	for _, code := range retry.RetryableStatusCodes {
+		if code < 100 || code > 599 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("retryableStatusCodes"), code, "Invalid HTTP status code"))
+		}
+	}
+	for _, code := range retry.RetryableGrpcCodes {
+		if !grpcStatusCodeNames[code] {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("retryableGrpcCodes"), code, "Unknown gRPC status code"))
+		}
+	}
+	return allErrs
+}
+
+func (r *SeldonDeploymentSpec) checkLogger(logger *Logger, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if logger.SamplePercent != nil && (*logger.SamplePercent < 0 || *logger.SamplePercent > 100) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("samplePercent"), *logger.SamplePercent, "samplePercent must be between 0 and 100"))
+	}
+	if logger.ForceHeader != "" {
+		for _, msg := range validation.IsHTTPHeaderName(logger.ForceHeader) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("forceHeader"), logger.ForceHeader, msg))
+		}
+	}
+	for i, redact := range logger.Redact {
+		if strings.HasPrefix(redact, "/") {
+			if !isJSONPointer(redact) {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("redact").Index(i), redact, "Invalid JSON pointer, ~ must be escaped as ~0"))
+			}
+		} else if redact == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("redact").Index(i), redact, "Must be a JSON pointer or a tensor name"))
+		} else if r.Protocol != ProtocolV2 && r.Protocol != ProtocolKFServing {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("redact").Index(i), redact, "Tensor names can only be redacted with the V2 protocol, use a JSON pointer starting with /"))
+		}
+	}
+	return allErrs
+}
+
+// isJSONPointer checks the escapes of a JSON pointer, the only way it can be invalid once it starts with /.
+func isJSONPointer(pointer string) bool {
+	for i := 0; i < len(pointer); i++ {
+		if pointer[i] == '~' && (i+1 == len(pointer) || (pointer[i+1] != '0' && pointer[i+1] != '1')) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *SeldonDeploymentSpec) checkFallbackPolicy(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	fallback := pu.Fallback
+	if fallback.MinSuccessfulChildren < 0 || int(fallback.MinSuccessfulChildren) > len(primaryChildren(pu)) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minSuccessfulChildren"), fallback.MinSuccessfulChildren, "minSuccessfulChildren must be between 0 and the number of children"))
+	}
+	if fallback.FallbackChild != "" {
+		found := false
+		for _, child := range pu.Children {
+			if child.Name == fallback.FallbackChild {
+				found = true
+				break
+			}
+		}
+		if !found {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("fallbackChild"), fallback.FallbackChild, "fallbackChild must be the name of a child"))
+		}
+	}
+	if fallback.DefaultResponse != "" {
+		if !json.Valid([]byte(fallback.DefaultResponse)) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("defaultResponse"), fallback.DefaultResponse, "defaultResponse must be valid JSON"))
+		} else if r.Transport == TransportGrpc && r.Protocol != "" && r.Protocol != ProtocolSeldon {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("defaultResponse"), r.Protocol, "defaultResponse is only supported for the seldon protocol when using gRPC"))
+		}
+	}
+	return allErrs
+}
+
+func checkMirrorPolicy(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	mirror := pu.Mirror
+	if mirror.Percent < 0 || mirror.Percent > 100 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("percent"), mirror.Percent, "percent must be between 0 and 100"))
+	}
+	found := false
+	for _, child := range pu.Children {
+		found = found || child.Name == mirror.Child
+	}
+	if !found {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("child"), mirror.Child, "child must be the name of a child"))
+	} else if pu.Fallback != nil && pu.Fallback.FallbackChild == mirror.Child {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("child"), mirror.Child, "the shadow child can not also be the fallback child"))
+	}
+	return allErrs
+}
+
+// primaryChildren returns the children of a unit which serve its requests, i.e. all but the shadow child.
+func primaryChildren(pu *PredictiveUnit) []PredictiveUnit {
+	if pu.Mirror == nil {
+		return pu.Children
+	}
+	children := make([]PredictiveUnit, 0, len(pu.Children))
+	for _, child := range pu.Children {
+		if child.Name != pu.Mirror.Child {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+func checkCachePolicy(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	cache := pu.Cache
+	if cache.TTLSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("ttlSeconds"), cache.TTLSeconds, "ttlSeconds must not be negative"))
+	}
+	if cache.MaxEntries < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxEntries"), cache.MaxEntries, "maxEntries must not be negative"))
+	}
+	if name := NondeterministicRouter(pu); name != "" {
+		allErrs = append(allErrs, field.Invalid(fldPath, pu.Name, "responses can't be cached as router "+name+" may route the same request differently"))
+	}
+	return allErrs
+}
+
+// NondeterministicRouter returns the name of a router in the graph of the unit which may route the same request
+// payload differently, e.g. at random, as learnt by a bandit or on request headers, which are not part of the cache
+// key. Routers in containers are assumed not to be deterministic.
+func NondeterministicRouter(pu *PredictiveUnit) string {
+	if pu.Implementation != nil && *pu.Implementation != UNKNOWN_IMPLEMENTATION {
+		switch *pu.Implementation {
+		case RANDOM_ABTEST, EPSILON_GREEDY, THOMPSON_SAMPLING:
+			return pu.Name
+		case CONDITIONAL_ROUTER:
+			for _, param := range pu.Parameters {
+				if expr, err := condition.Compile(param.Value); err == nil && expr.Reads("headers") {
+					return pu.Name
+				}
+			}
+		}
+	} else if pu.Type != nil && *pu.Type == ROUTER {
+		return pu.Name
+	}
+	for i := range pu.Children {
+		if name := NondeterministicRouter(&pu.Children[i]); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+func checkBatchingPolicy(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if pu.Type == nil || *pu.Type != MODEL {
+		allErrs = append(allErrs, field.Invalid(fldPath, pu.Name, "batching is only supported for MODEL units"))
+	}
+	if pu.Batching.MaxBatchSize < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxBatchSize"), pu.Batching.MaxBatchSize, "maxBatchSize must not be negative"))
+	}
+	if pu.Batching.MaxLatencyMs < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxLatencyMs"), pu.Batching.MaxLatencyMs, "maxLatencyMs must not be negative"))
+	}
+	return allErrs
+}
+
+// checkNodeProtocol allows nodes to override the protocol where the executor can convert messages between them.
+func (r *SeldonDeploymentSpec) checkNodeProtocol(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if !(pu.Protocol == ProtocolSeldon || pu.Protocol == ProtocolKFServing || pu.Protocol == ProtocolV2) {
+		allErrs = append(allErrs, field.Invalid(fldPath, pu.Protocol, "protocol of a unit must be seldon, kfserving or v2"))
+	} else if r.Protocol == ProtocolTensorflow {
+		allErrs = append(allErrs, field.Invalid(fldPath, pu.Protocol, "protocol of a unit can't be overridden for the tensorflow protocol"))
+	}
+	return allErrs
+}
+
+func checkBanditRouter(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if len(primaryChildren(pu)) < 2 {
+		allErrs = append(allErrs, field.Invalid(fldPath, pu.Name, "Bandit router "+string(*pu.Implementation)+" needs at least two children"))
+	}
+	for i, param := range pu.Parameters {
+		if param.Name == "epsilon" && *pu.Implementation == EPSILON_GREEDY {
+			epsilon, err := strconv.ParseFloat(param.Value, 64)
+			if err != nil || epsilon < 0 || epsilon > 1 {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("parameters").Index(i), param.Value, "epsilon must be a number between 0 and 1"))
+			}
+		}
+	}
+	return allErrs
+}
+
+func checkBuiltinCombiner(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	for i, param := range pu.Parameters {
+		if param.Name != "weights" {
+			continue
+		}
+		weights := strings.Split(param.Value, ",")
+		if len(weights) != len(primaryChildren(pu)) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("parameters").Index(i), param.Value, "weights must have one value for each child"))
+			continue
+		}
+		total := 0.0
+		for _, w := range weights {
+			weight, err := strconv.ParseFloat(strings.TrimSpace(w), 64)
+			if err != nil || weight < 0 {
+				total = -1
+				break
+			}
+			total += weight
+		}
+		if total <= 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("parameters").Index(i), param.Value, "weights must be non-negative numbers with a positive sum"))
+		}
+	}
+	return allErrs
+}
+
+// checkConditionalRouter checks each parameter is a rule named after a child with a valid condition.
+func checkConditionalRouter(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if len(pu.Parameters) == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, pu.Name, "Conditional router needs at least one rule"))
+	}
+	for i, param := range pu.Parameters {
+		found := false
+		for _, child := range pu.Children {
+			found = found || child.Name == param.Name
+		}
+		if !found {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("parameters").Index(i).Child("name"), param.Name, "Conditional router rule must be named after a child"))
+		} else if pu.Mirror != nil && pu.Mirror.Child == param.Name {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("parameters").Index(i).Child("name"), param.Name, "Conditional router rule can not route to the shadow child"))
+		}
+		if _, err := condition.Compile(param.Value); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("parameters").Index(i).Child("value"), param.Value, "Invalid condition: "+err.Error()))
+		}
+	}
+	return allErrs
+}
+
 func checkTraffic(spec *SeldonDeploymentSpec, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
 	var trafficSum int32 = 0
 	var shadows int = 0
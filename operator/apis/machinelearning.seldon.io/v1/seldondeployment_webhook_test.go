@@ -2,6 +2,7 @@ package v1
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -1559,3 +1560,517 @@ func TestSvcNameAnnotationNotDuplicate(t *testing.T) {
 	err = spec.ValidateSeldonDeployment()
 	g.Expect(err).To(BeNil())
 }
+
+func TestValidateRetryPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+	spec := &SeldonDeploymentSpec{
+		Predictors: []PredictorSpec{
+			{
+				Name: "p1",
+				ComponentSpecs: []*SeldonPodSpec{
+					{
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{
+								{
+									Image: "seldonio/mock_classifier:1.0",
+									Name:  "classifier",
+								},
+							},
+						},
+					},
+				},
+				Graph: PredictiveUnit{
+					Name: "classifier",
+					Retry: &RetryPolicy{
+						MaxAttempts:          3,
+						InitialBackoffMs:     10,
+						MaxBackoffMs:         100,
+						RetryableStatusCodes: []int32{503},
+						RetryableGrpcCodes:   []string{"UNAVAILABLE"},
+					},
+				},
+			},
+		},
+	}
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+}
+
+func TestValidateRetryPolicyInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+	spec := &SeldonDeploymentSpec{
+		Predictors: []PredictorSpec{
+			{
+				Name: "p1",
+				ComponentSpecs: []*SeldonPodSpec{
+					{
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{
+								{
+									Image: "seldonio/mock_classifier:1.0",
+									Name:  "classifier",
+								},
+							},
+						},
+					},
+				},
+				Graph: PredictiveUnit{
+					Name: "classifier",
+					Retry: &RetryPolicy{
+						MaxAttempts:        3,
+						InitialBackoffMs:   100,
+						MaxBackoffMs:       10,
+						RetryableGrpcCodes: []string{"Unavailable"},
+					},
+				},
+			},
+		},
+	}
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Code).To(Equal(int32(422)))
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(2))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.retry.maxBackoffMs"))
+	g.Expect(serr.Status().Details.Causes[1].Field).To(Equal("spec.predictors[0].graph.retry.retryableGrpcCodes"))
+}
+
+func createFallbackSpec(fallback *FallbackPolicy) *SeldonDeploymentSpec {
+	combiner := COMBINER
+	return &SeldonDeploymentSpec{
+		Predictors: []PredictorSpec{
+			{
+				Name: "p1",
+				ComponentSpecs: []*SeldonPodSpec{
+					{
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{
+								{
+									Image: "seldonio/mock_combiner:1.0",
+									Name:  "combiner",
+								},
+								{
+									Image: "seldonio/mock_classifier:1.0",
+									Name:  "classifier1",
+								},
+								{
+									Image: "seldonio/mock_classifier:1.0",
+									Name:  "classifier2",
+								},
+							},
+						},
+					},
+				},
+				Graph: PredictiveUnit{
+					Name:     "combiner",
+					Type:     &combiner,
+					Fallback: fallback,
+					Children: []PredictiveUnit{
+						{Name: "classifier1"},
+						{Name: "classifier2"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateFallbackPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+	spec := createFallbackSpec(&FallbackPolicy{
+		MinSuccessfulChildren: 1,
+		FallbackChild:         "classifier2",
+		DefaultResponse:       `{"data":{"ndarray":[0]}}`,
+	})
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+}
+
+func TestValidateFallbackPolicyInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+	spec := createFallbackSpec(&FallbackPolicy{
+		MinSuccessfulChildren: 3,
+		FallbackChild:         "classifier3",
+		DefaultResponse:       `{"data":`,
+	})
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(3))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.fallback.minSuccessfulChildren"))
+	g.Expect(serr.Status().Details.Causes[1].Field).To(Equal("spec.predictors[0].graph.fallback.fallbackChild"))
+	g.Expect(serr.Status().Details.Causes[2].Field).To(Equal("spec.predictors[0].graph.fallback.defaultResponse"))
+}
+
+func TestValidateFallbackDefaultResponseProtocol(t *testing.T) {
+	g := NewGomegaWithT(t)
+	spec := createFallbackSpec(&FallbackPolicy{DefaultResponse: `{"outputs":[]}`})
+	spec.Protocol = ProtocolV2
+	spec.Transport = TransportGrpc
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(1))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.fallback.defaultResponse"))
+}
+
+func createBuiltinSpec(implementation PredictiveUnitImplementation, children int, params []Parameter) *SeldonDeploymentSpec {
+	spec := &SeldonDeploymentSpec{
+		Predictors: []PredictorSpec{
+			{
+				Name: "p1",
+				ComponentSpecs: []*SeldonPodSpec{
+					{
+						Spec: v1.PodSpec{},
+					},
+				},
+				Graph: PredictiveUnit{
+					Name:           "root",
+					Implementation: &implementation,
+					Parameters:     params,
+				},
+			},
+		},
+	}
+	for i := 0; i < children; i++ {
+		name := fmt.Sprintf("classifier%d", i)
+		spec.Predictors[0].ComponentSpecs[0].Spec.Containers = append(spec.Predictors[0].ComponentSpecs[0].Spec.Containers, v1.Container{Image: "seldonio/mock_classifier:1.0", Name: name})
+		spec.Predictors[0].Graph.Children = append(spec.Predictors[0].Graph.Children, PredictiveUnit{Name: name})
+	}
+	return spec
+}
+
+func TestValidateBanditRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createBuiltinSpec(EPSILON_GREEDY, 3, []Parameter{{Name: "epsilon", Value: "0.2", Type: DOUBLE}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+
+	spec = createBuiltinSpec(THOMPSON_SAMPLING, 2, nil)
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+}
+
+func TestValidateBanditRouterInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createBuiltinSpec(EPSILON_GREEDY, 1, []Parameter{{Name: "epsilon", Value: "1.5", Type: DOUBLE}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(2))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph"))
+	g.Expect(serr.Status().Details.Causes[1].Field).To(Equal("spec.predictors[0].graph.parameters[0]"))
+}
+
+func TestValidateBuiltinCombiner(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createBuiltinSpec(AVERAGE_COMBINER, 3, []Parameter{{Name: "weights", Value: "0.5, 0.3, 0.2", Type: STRING}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+
+	spec = createBuiltinSpec(MAJORITY_VOTE_COMBINER, 3, nil)
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+}
+
+func TestValidateBuiltinCombinerInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createBuiltinSpec(AVERAGE_COMBINER, 3, []Parameter{{Name: "weights", Value: "0.5,0.5", Type: STRING}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(1))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.parameters[0]"))
+
+	spec = createBuiltinSpec(MAJORITY_VOTE_COMBINER, 2, []Parameter{{Name: "weights", Value: "1,-1", Type: STRING}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+}
+
+func TestValidateConditionalRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createBuiltinSpec(CONDITIONAL_ROUTER, 2, []Parameter{
+		{Name: "classifier1", Value: `tags.country == "DE"`, Type: STRING},
+		{Name: "classifier0", Value: `true`, Type: STRING},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+}
+
+func TestValidateConditionalRouterInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createBuiltinSpec(CONDITIONAL_ROUTER, 2, []Parameter{
+		{Name: "classifier1", Value: `body.country == "DE"`, Type: STRING},
+		{Name: "classifier5", Value: `true`, Type: STRING},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(2))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.parameters[0].value"))
+	g.Expect(serr.Status().Details.Causes[1].Field).To(Equal("spec.predictors[0].graph.parameters[1].name"))
+}
+
+func TestValidateMirrorPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createFallbackSpec(nil)
+	spec.Predictors[0].Graph.Mirror = &MirrorPolicy{Child: "classifier2", Percent: 10}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+}
+
+func TestValidateMirrorPolicyInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createFallbackSpec(nil)
+	spec.Predictors[0].Graph.Mirror = &MirrorPolicy{Child: "classifier3", Percent: 101}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(2))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.mirror.percent"))
+	g.Expect(serr.Status().Details.Causes[1].Field).To(Equal("spec.predictors[0].graph.mirror.child"))
+
+	spec = createFallbackSpec(&FallbackPolicy{FallbackChild: "classifier2"})
+	spec.Predictors[0].Graph.Mirror = &MirrorPolicy{Child: "classifier2"}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+}
+
+func TestValidateMirrorPolicyShadowChild(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createBuiltinSpec(AVERAGE_COMBINER, 3, []Parameter{{Name: "weights", Value: "0.5,0.5", Type: STRING}})
+	spec.Predictors[0].Graph.Mirror = &MirrorPolicy{Child: "classifier2"}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+
+	// The shadow child doesn't count towards the children a bandit routes between
+	spec = createBuiltinSpec(THOMPSON_SAMPLING, 2, nil)
+	spec.Predictors[0].Graph.Mirror = &MirrorPolicy{Child: "classifier1"}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(1))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph"))
+
+	spec = createFallbackSpec(&FallbackPolicy{MinSuccessfulChildren: 2})
+	spec.Predictors[0].Graph.Mirror = &MirrorPolicy{Child: "classifier2"}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err = spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr = err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(1))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.fallback.minSuccessfulChildren"))
+
+	spec = createBuiltinSpec(CONDITIONAL_ROUTER, 3, []Parameter{
+		{Name: "classifier2", Value: `tags.country == "DE"`, Type: STRING},
+		{Name: "classifier0", Value: `true`, Type: STRING},
+	})
+	spec.Predictors[0].Graph.Mirror = &MirrorPolicy{Child: "classifier2"}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err = spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr = err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(1))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.parameters[0].name"))
+}
+
+func TestValidateCachePolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createFallbackSpec(nil)
+	spec.Predictors[0].Graph.Cache = &CachePolicy{TTLSeconds: 60, MaxEntries: 100}
+	spec.Predictors[0].Graph.Children[0].Cache = &CachePolicy{}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+}
+
+func TestValidateCachePolicyInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createFallbackSpec(nil)
+	spec.Predictors[0].Graph.Cache = &CachePolicy{TTLSeconds: -1, MaxEntries: -1}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(2))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.cache.ttlSeconds"))
+	g.Expect(serr.Status().Details.Causes[1].Field).To(Equal("spec.predictors[0].graph.cache.maxEntries"))
+}
+
+func TestValidateCachePolicyRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for _, spec := range []*SeldonDeploymentSpec{
+		createBuiltinSpec(CONDITIONAL_ROUTER, 2, []Parameter{
+			{Name: "classifier1", Value: `tags.country == "DE"`, Type: STRING},
+			{Name: "classifier0", Value: `true`, Type: STRING},
+		}),
+		createBuiltinSpec(SIMPLE_ROUTER, 2, nil),
+	} {
+		spec.Predictors[0].Graph.Cache = &CachePolicy{}
+		spec.DefaultSeldonDeployment("mydep", "default")
+		g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+	}
+
+	// A container router may route at random
+	router := ROUTER
+	containerRouter := createBuiltinSpec(UNKNOWN_IMPLEMENTATION, 2, nil)
+	containerRouter.Predictors[0].Graph.Implementation = nil
+	containerRouter.Predictors[0].Graph.Type = &router
+	containerRouter.Predictors[0].ComponentSpecs[0].Spec.Containers = append(containerRouter.Predictors[0].ComponentSpecs[0].Spec.Containers, v1.Container{Image: "seldonio/mock_router:1.0", Name: "root"})
+	for _, spec := range []*SeldonDeploymentSpec{
+		createBuiltinSpec(CONDITIONAL_ROUTER, 2, []Parameter{
+			{Name: "classifier1", Value: `headers["x-tier"] == "gold"`, Type: STRING},
+			{Name: "classifier0", Value: `true`, Type: STRING},
+		}),
+		createBuiltinSpec(RANDOM_ABTEST, 2, []Parameter{{Name: "ratioA", Value: "0.5", Type: FLOAT}}),
+		createBuiltinSpec(EPSILON_GREEDY, 2, nil),
+		createBuiltinSpec(THOMPSON_SAMPLING, 2, nil),
+		containerRouter,
+	} {
+		spec.Predictors[0].Graph.Cache = &CachePolicy{}
+		spec.DefaultSeldonDeployment("mydep", "default")
+		err := spec.ValidateSeldonDeployment()
+		g.Expect(err).ToNot(BeNil())
+		serr := err.(*errors.StatusError)
+		g.Expect(serr.Status().Details.Causes).To(HaveLen(1))
+		g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.cache"))
+	}
+
+	// Caching above a router skips its routing too
+	spec := createFallbackSpec(nil)
+	spec.Predictors[0].Graph.Cache = &CachePolicy{}
+	abtest := RANDOM_ABTEST
+	spec.Predictors[0].Graph.Children[0] = PredictiveUnit{
+		Name:           "abtest",
+		Implementation: &abtest,
+		Parameters:     []Parameter{{Name: "ratioA", Value: "0.5", Type: FLOAT}},
+		Children:       []PredictiveUnit{{Name: "classifier1"}, {Name: "classifier3"}},
+	}
+	spec.Predictors[0].ComponentSpecs[0].Spec.Containers = append(spec.Predictors[0].ComponentSpecs[0].Spec.Containers, v1.Container{Image: "seldonio/mock_classifier:1.0", Name: "classifier3"})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(1))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.cache"))
+}
+
+func TestValidateBatchingPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createFallbackSpec(nil)
+	spec.Predictors[0].Graph.Children[0].Batching = &BatchingPolicy{MaxBatchSize: 64, MaxLatencyMs: 5}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+}
+
+func TestValidateBatchingPolicyInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createFallbackSpec(nil)
+	spec.Predictors[0].Graph.Batching = &BatchingPolicy{}
+	spec.Predictors[0].Graph.Children[0].Batching = &BatchingPolicy{MaxBatchSize: -1, MaxLatencyMs: -1}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(3))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.batching"))
+	g.Expect(serr.Status().Details.Causes[1].Field).To(Equal("spec.predictors[0].graph[0].batching.maxBatchSize"))
+	g.Expect(serr.Status().Details.Causes[2].Field).To(Equal("spec.predictors[0].graph[0].batching.maxLatencyMs"))
+}
+
+func TestValidateNodeProtocol(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createFallbackSpec(nil)
+	spec.Protocol = ProtocolSeldon
+	spec.Predictors[0].Graph.Children[0].Protocol = ProtocolV2
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+}
+
+func TestValidateNodeProtocolInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createFallbackSpec(nil)
+	spec.Predictors[0].Graph.Protocol = ProtocolTensorflow
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(1))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.protocol"))
+
+	spec = createFallbackSpec(nil)
+	spec.Protocol = ProtocolTensorflow
+	spec.Predictors[0].Graph.Children[0].Protocol = ProtocolSeldon
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+}
+
+func TestValidateLoggerSamplingAndRedaction(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	samplePercent := int32(0)
+	spec := createFallbackSpec(nil)
+	spec.Protocol = ProtocolV2
+	spec.Predictors[0].Graph.Logger = &Logger{
+		Mode:          LogAll,
+		SamplePercent: &samplePercent,
+		ForceHeader:   "X-Debug-Logging",
+		Redact:        []string{"/parameters/user", "/inputs/0/data", "ssn"},
+	}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+}
+
+func TestValidateLoggerSamplingAndRedactionInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	samplePercent := int32(101)
+	spec := createFallbackSpec(nil)
+	spec.Predictors[0].Graph.Logger = &Logger{
+		Mode:          LogAll,
+		SamplePercent: &samplePercent,
+		ForceHeader:   "X Debug",
+		Redact:        []string{"/data/names", "/data/~2", "", "ssn"},
+	}
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(5))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0].graph.logger.samplePercent"))
+	g.Expect(serr.Status().Details.Causes[1].Field).To(Equal("spec.predictors[0].graph.logger.forceHeader"))
+	g.Expect(serr.Status().Details.Causes[2].Field).To(Equal("spec.predictors[0].graph.logger.redact[1]"))
+	g.Expect(serr.Status().Details.Causes[3].Field).To(Equal("spec.predictors[0].graph.logger.redact[2]"))
+	// Tensor names need the V2 protocol
+	g.Expect(serr.Status().Details.Causes[4].Field).To(Equal("spec.predictors[0].graph.logger.redact[3]"))
+}
@@ -121,6 +121,22 @@ func addEngineToDeployment(mlDep *machinelearningv1.SeldonDeployment, p *machine
 		}
 	}
 
+	// The spool is kept on an emptyDir, which survives restarts of the executor, unless a volume is given for it
+	if getLoggerSpoolDir(mlDep, p) != "" {
+		spoolVolFound := false
+		for _, vol := range deploy.Spec.Template.Spec.Volumes {
+			if vol.Name == machinelearningv1.LOGGER_SPOOL_VOLUME_NAME {
+				spoolVolFound = true
+			}
+		}
+		if !spoolVolFound {
+			deploy.Spec.Template.Spec.Volumes = append(deploy.Spec.Template.Spec.Volumes, corev1.Volume{
+				Name:         machinelearningv1.LOGGER_SPOOL_VOLUME_NAME,
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			})
+		}
+	}
+
 	if !volFound {
 		var defaultMode = corev1.DownwardAPIVolumeSourceDefaultMode
 		//Add downwardAPI
@@ -186,6 +202,15 @@ func getSvcOrchUser(mlDep *machinelearningv1.SeldonDeployment) (*int64, error) {
 	return nil, nil
 }
 
+// getLoggerSpoolDir returns the directory of the spool of the executor's payload logger, which the executor reads from
+// the predictor or deployment annotations of its pod.
+func getLoggerSpoolDir(mlDep *machinelearningv1.SeldonDeployment, p *machinelearningv1.PredictorSpec) string {
+	if spoolDir, ok := p.Annotations[machinelearningv1.ANNOTATION_LOGGER_SPOOL_DIR]; ok {
+		return spoolDir
+	}
+	return mlDep.Spec.Annotations[machinelearningv1.ANNOTATION_LOGGER_SPOOL_DIR]
+}
+
 func createExecutorContainer(mlDep *machinelearningv1.SeldonDeployment, p *machinelearningv1.PredictorSpec, predictorB64 string, http_port int, grpc_port int, resources *corev1.ResourceRequirements) (*corev1.Container, error) {
 	protocol := mlDep.Spec.Protocol
 	//Backwards compatibility for older resources
@@ -228,6 +253,19 @@ func createExecutorContainer(mlDep *machinelearningv1.SeldonDeployment, p *machi
 		return nil, fmt.Errorf("Failed to parse %s as integer for %s. %w", executorReqLoggerWriteTimeoutMs, ENV_EXECUTOR_REQUEST_LOGGER_WRITE_TIMEOUT_MS, err)
 	}
 
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      machinelearningv1.PODINFO_VOLUME_NAME,
+			MountPath: machinelearningv1.PODINFO_VOLUME_PATH,
+		},
+	}
+	if spoolDir := getLoggerSpoolDir(mlDep, p); spoolDir != "" {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      machinelearningv1.LOGGER_SPOOL_VOLUME_NAME,
+			MountPath: spoolDir,
+		})
+	}
+
 	return &corev1.Container{
 		Name:  EngineContainerName,
 		Image: executorImage,
@@ -248,12 +286,7 @@ func createExecutorContainer(mlDep *machinelearningv1.SeldonDeployment, p *machi
 		ImagePullPolicy:          corev1.PullPolicy(utils.GetEnv("EXECUTOR_CONTAINER_IMAGE_PULL_POLICY", "IfNotPresent")),
 		TerminationMessagePath:   "/dev/termination-log",
 		TerminationMessagePolicy: corev1.TerminationMessageReadFile,
-		VolumeMounts: []corev1.VolumeMount{
-			{
-				Name:      machinelearningv1.PODINFO_VOLUME_NAME,
-				MountPath: machinelearningv1.PODINFO_VOLUME_PATH,
-			},
-		},
+		VolumeMounts:             volumeMounts,
 		Env: []corev1.EnvVar{
 			{Name: "ENGINE_PREDICTOR", Value: predictorB64},
 			{Name: "REQUEST_LOGGER_DEFAULT_ENDPOINT", Value: utils.GetEnv("EXECUTOR_REQUEST_LOGGER_DEFAULT_ENDPOINT", "http://default-broker")},
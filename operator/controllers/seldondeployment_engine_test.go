@@ -6,6 +6,7 @@ import (
 	. "github.com/onsi/gomega"
 	machinelearningv1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	"github.com/seldonio/seldon-core/operator/constants"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -167,3 +168,28 @@ func TestEngineCreateLoggerAnnotation(t *testing.T) {
 	}
 	cleanEnvImagesExecutor()
 }
+
+func TestEngineCreateLoggerSpool(t *testing.T) {
+	g := NewGomegaWithT(t)
+	cleanEnvImagesExecutor()
+	envExecutorImage = "executor"
+	mlDep := createTestSeldonDeployment()
+	mlDep.Spec.Annotations[machinelearningv1.ANNOTATION_LOGGER_SPOOL_DIR] = "/spool"
+	con, err := createExecutorContainer(mlDep, &mlDep.Spec.Predictors[0], "", 1, 2, &v1.ResourceRequirements{})
+	g.Expect(err).To(BeNil())
+	g.Expect(con.VolumeMounts).To(ContainElement(v1.VolumeMount{Name: machinelearningv1.LOGGER_SPOOL_VOLUME_NAME, MountPath: "/spool"}))
+
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{}, Annotations: map[string]string{}},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{}},
+			Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{}}},
+		},
+	}
+	g.Expect(addEngineToDeployment(mlDep, &mlDep.Spec.Predictors[0], 1, 2, "svc", deploy)).To(BeNil())
+	g.Expect(deploy.Spec.Template.Spec.Volumes).To(ContainElement(v1.Volume{
+		Name:         machinelearningv1.LOGGER_SPOOL_VOLUME_NAME,
+		VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+	}))
+	cleanEnvImagesExecutor()
+}
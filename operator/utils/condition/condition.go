@@ -0,0 +1,457 @@
+// Package condition implements the expressions used by the rules of a CONDITIONAL_ROUTER.
+//
+// An expression compares values from the request, e.g.
+//
+//	tags.country == "DE" && headers["x-tier"] in ["gold", "silver"]
+//
+// It supports the operators ==, !=, <, <=, >, >=, in, &&, || and ! along with parentheses and string, number,
+// boolean, null and list literals. Values are looked up from the Variables by name, then by field with "." or by
+// key with "[...]". Missing values are null, and comparing values of different types is false.
+package condition
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Variables which expressions can read from the request.
+var Variables = []string{"tags", "headers", "parameters"}
+
+// Expression is a compiled condition which can be matched against many requests.
+type Expression struct {
+	source string
+	root   node
+	reads  map[string]bool
+}
+
+// Compile parses the expression and checks it only reads from the Variables.
+func Compile(expr string) (*Expression, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, reads: make(map[string]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return &Expression{source: expr, root: root, reads: p.reads}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Reads returns whether the expression reads the given variable.
+func (e *Expression) Reads(variable string) bool {
+	return e.reads[variable]
+}
+
+// Match evaluates the expression against the values of the Variables and returns whether it is true.
+func (e *Expression) Match(vars map[string]interface{}) bool {
+	return e.root.eval(vars) == true
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// Operators, with the longer ones first so they are matched before their prefixes.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: i})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[i:j]), pos: i})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '-') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[i:j]), pos: i})
+			i = j
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	reads  map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokenOperator && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at position %d", op, tok.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	isComparison := tok.kind == tokenOperator && (tok.text == "==" || tok.text == "!=" || tok.text == "<" || tok.text == "<=" || tok.text == ">" || tok.text == ">=")
+	if !isComparison && !(tok.kind == tokenIdent && tok.text == "in") {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &compareNode{op: tok.text, left: left, right: right}, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return &literalNode{value: tok.text}, nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return &literalNode{value: value}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		return p.parsePath(tok)
+	case tokenOperator:
+		if tok.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		} else if tok.text == "[" {
+			list := &listNode{}
+			for !p.accept("]") {
+				if len(list.items) > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				item, err := p.parseOperand()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+			}
+			return list, nil
+		}
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+func (p *parser) parsePath(root token) (node, error) {
+	known := false
+	for _, variable := range Variables {
+		known = known || variable == root.text
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown variable %q at position %d, expected one of %s", root.text, root.pos, strings.Join(Variables, ", "))
+	}
+	p.reads[root.text] = true
+	path := &pathNode{root: root.text}
+	for {
+		if p.accept(".") {
+			tok := p.next()
+			if tok.kind != tokenIdent {
+				return nil, fmt.Errorf("expected a field name at position %d", tok.pos)
+			}
+			path.keys = append(path.keys, tok.text)
+		} else if p.accept("[") {
+			tok := p.next()
+			if tok.kind != tokenString {
+				return nil, fmt.Errorf("expected a quoted key at position %d", tok.pos)
+			}
+			path.keys = append(path.keys, tok.text)
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		} else {
+			return path, nil
+		}
+	}
+}
+
+type node interface {
+	eval(vars map[string]interface{}) interface{}
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(vars map[string]interface{}) interface{} {
+	return n.value
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(vars map[string]interface{}) interface{} {
+	values := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		values[i] = item.eval(vars)
+	}
+	return values
+}
+
+type pathNode struct {
+	root string
+	keys []string
+}
+
+func (n *pathNode) eval(vars map[string]interface{}) interface{} {
+	value := vars[n.root]
+	for _, key := range n.keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(vars map[string]interface{}) interface{} {
+	return n.operand.eval(vars) != true
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) eval(vars map[string]interface{}) interface{} {
+	return n.left.eval(vars) == true && n.right.eval(vars) == true
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) eval(vars map[string]interface{}) interface{} {
+	return n.left.eval(vars) == true || n.right.eval(vars) == true
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(vars map[string]interface{}) interface{} {
+	left := normalize(n.left.eval(vars))
+	right := normalize(n.right.eval(vars))
+	switch n.op {
+	case "==":
+		return reflect.DeepEqual(left, right)
+	case "!=":
+		return !reflect.DeepEqual(left, right)
+	case "in":
+		switch container := right.(type) {
+		case []interface{}:
+			for _, item := range container {
+				if reflect.DeepEqual(left, normalize(item)) {
+					return true
+				}
+			}
+		case map[string]interface{}:
+			if key, ok := left.(string); ok {
+				_, found := container[key]
+				return found
+			}
+		case string:
+			if s, ok := left.(string); ok {
+				return strings.Contains(container, s)
+			}
+		}
+		return false
+	}
+	var cmp int
+	if l, ok := left.(float64); ok {
+		r, ok := right.(float64)
+		if !ok {
+			return false
+		}
+		cmp = compareFloats(l, r)
+	} else if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(l, r)
+	} else {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func compareFloats(l, r float64) int {
+	if l < r {
+		return -1
+	} else if l > r {
+		return 1
+	}
+	return 0
+}
+
+// normalize converts the numbers of the request to float64 so they compare equal to number literals.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return value
+}
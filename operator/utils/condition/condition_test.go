@@ -0,0 +1,81 @@
+package condition
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestMatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	vars := map[string]interface{}{
+		"tags": map[string]interface{}{
+			"country": "DE",
+			"age":     42.0,
+			"premium": true,
+			"user":    map[string]interface{}{"segment": "b"},
+		},
+		"headers":    map[string]interface{}{"x-tier": "gold"},
+		"parameters": map[string]interface{}{"priority": int64(2)},
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{expr: `tags.country == "DE"`, expected: true},
+		{expr: `tags.country == 'FR'`, expected: false},
+		{expr: `tags.country != "FR"`, expected: true},
+		{expr: `tags.age >= 18 && tags.age < 65`, expected: true},
+		{expr: `tags.age > 50 || tags.premium`, expected: true},
+		{expr: `!tags.premium`, expected: false},
+		{expr: `tags.user.segment in ["a", "b"]`, expected: true},
+		{expr: `tags["user"]["segment"] == "b"`, expected: true},
+		{expr: `headers["x-tier"] == "gold" && (tags.country == "FR" || tags.country == "DE")`, expected: true},
+		{expr: `parameters.priority == 2`, expected: true},
+		{expr: `"country" in tags`, expected: true},
+		{expr: `tags.missing == null`, expected: true},
+		{expr: `tags.missing.field == "x"`, expected: false},
+		{expr: `tags.country > 1`, expected: false},
+		{expr: `true`, expected: true},
+	}
+
+	for _, test := range tests {
+		expr, err := Compile(test.expr)
+		g.Expect(err).To(BeNil(), test.expr)
+		g.Expect(expr.Match(vars)).To(Equal(test.expected), test.expr)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for _, expr := range []string{
+		``,
+		`tags.country ==`,
+		`tags.country == "DE`,
+		`body.country == "DE"`,
+		`tags.country == "DE" extra`,
+		`(tags.country == "DE"`,
+		`tags[country] == "DE"`,
+		`tags.country = "DE"`,
+	} {
+		_, err := Compile(expr)
+		g.Expect(err).ToNot(BeNil(), expr)
+	}
+}
+
+func TestReads(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	expr, err := Compile(`headers["x-tier"] == "gold" || "country" in tags`)
+	g.Expect(err).To(BeNil())
+	g.Expect(expr.Reads("headers")).To(BeTrue())
+	g.Expect(expr.Reads("tags")).To(BeTrue())
+	g.Expect(expr.Reads("parameters")).To(BeFalse())
+
+	expr, err = Compile(`true`)
+	g.Expect(err).To(BeNil())
+	g.Expect(expr.Reads("headers")).To(BeFalse())
+}